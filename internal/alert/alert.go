@@ -0,0 +1,177 @@
+// Package alert delivers key pool and group health alerts to webhook endpoints.
+package alert
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"gpt-load/internal/config"
+	"gpt-load/internal/store"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Event types
+const (
+	EventKeyBlacklisted        = "key_blacklisted"
+	EventGroupKeysLow          = "group_keys_low"
+	EventGroupKeysExhausted    = "group_keys_exhausted"
+	EventGroupErrorRate        = "group_error_rate"
+	EventValidationInvalidKeys = "validation_invalid_keys"
+	EventEncryptionMismatch    = "encryption_mismatch"
+)
+
+// Severities
+const (
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+const (
+	webhookTimeout              = 10 * time.Second
+	webhookMaxAttempts          = 3
+	webhookInitialRetryInterval = 2 * time.Second
+)
+
+// Event describes a single alert.
+type Event struct {
+	Type      string         `json:"event"`
+	Severity  string         `json:"severity"`
+	Title     string         `json:"title"`
+	Message   string         `json:"message"`
+	GroupName string         `json:"group,omitempty"`
+	Fields    map[string]any `json:"fields,omitempty"`
+	Timestamp time.Time      `json:"timestamp"`
+
+	// DedupKey distinguishes alerts of the same type and group, e.g. the key ID for blacklisting.
+	DedupKey string `json:"-"`
+}
+
+// Service deduplicates alerts and delivers them to the configured webhooks.
+type Service struct {
+	settingsManager *config.SystemSettingsManager
+	store           store.Store
+	client          *http.Client
+}
+
+// NewService creates a new alert service.
+func NewService(settingsManager *config.SystemSettingsManager, store store.Store) *Service {
+	return &Service{
+		settingsManager: settingsManager,
+		store:           store,
+		client:          &http.Client{Timeout: webhookTimeout},
+	}
+}
+
+// Notify sends an alert unless the same alert was already sent within the cooldown.
+// Delivery is asynchronous and never blocks the caller.
+func (s *Service) Notify(event Event) {
+	settings := s.settingsManager.GetSettings()
+	webhooks := parseWebhooks(settings.AlertWebhooks)
+	if len(webhooks) == 0 {
+		return
+	}
+
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	// 通过 store 去重，所有节点共享冷却时间
+	cooldown := time.Duration(settings.AlertCooldownMinutes) * time.Minute
+	ok, err := s.store.SetNX(dedupStoreKey(event), []byte("1"), cooldown)
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to check alert cooldown, sending anyway")
+	} else if !ok {
+		logrus.WithFields(logrus.Fields{"event": event.Type, "group": event.GroupName}).Debug("Alert suppressed by cooldown")
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{"event": event.Type, "group": event.GroupName}).Warn(event.Title)
+
+	for _, webhook := range webhooks {
+		go s.deliver(webhook, event)
+	}
+}
+
+// deliver posts an alert to a webhook, retrying on failures.
+func (s *Service) deliver(hook webhook, event Event) {
+	body, err := buildPayload(hook.format, event)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to build alert payload")
+		return
+	}
+
+	interval := webhookInitialRetryInterval
+	for attempt := 1; ; attempt++ {
+		err = s.post(hook.url, body)
+		if err == nil {
+			return
+		}
+		if attempt >= webhookMaxAttempts {
+			logrus.WithError(err).Errorf("Failed to deliver %s alert to %s webhook", event.Type, hook.format)
+			return
+		}
+		time.Sleep(interval)
+		interval *= 2
+	}
+}
+
+func (s *Service) post(url string, body []byte) error {
+	resp, err := s.client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func dedupStoreKey(event Event) string {
+	hash := sha256.Sum256([]byte(event.GroupName + "\x00" + event.DedupKey))
+	return fmt.Sprintf("alert:dedup:%s:%s", event.Type, hex.EncodeToString(hash[:8]))
+}
+
+// webhook is a parsed webhook endpoint.
+type webhook struct {
+	format string
+	url    string
+}
+
+// parseWebhooks parses "slack=https://...,https://..." into webhooks. Entries without a known prefix use the generic format.
+func parseWebhooks(value string) []webhook {
+	var webhooks []webhook
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		hook := webhook{format: FormatGeneric, url: entry}
+		if prefix, url, found := strings.Cut(entry, "="); found && isKnownFormat(prefix) {
+			hook.format = prefix
+			hook.url = strings.TrimSpace(url)
+		}
+		webhooks = append(webhooks, hook)
+	}
+	return webhooks
+}
+
+// ValidateWebhooks checks the alert webhook setting value.
+func ValidateWebhooks(value string) error {
+	for _, hook := range parseWebhooks(value) {
+		if !strings.HasPrefix(hook.url, "http://") && !strings.HasPrefix(hook.url, "https://") {
+			return fmt.Errorf("invalid alert webhook URL '%s'", hook.url)
+		}
+	}
+	return nil
+}
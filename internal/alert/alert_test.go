@@ -0,0 +1,115 @@
+package alert
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseWebhooks(t *testing.T) {
+	hooks := parseWebhooks(" slack=https://hooks.slack.com/x , https://example.com/hook,unknown=https://example.com/y,, ")
+	want := []webhook{
+		{FormatSlack, "https://hooks.slack.com/x"},
+		{FormatGeneric, "https://example.com/hook"},
+		{FormatGeneric, "unknown=https://example.com/y"},
+	}
+	if len(hooks) != len(want) {
+		t.Fatalf("parseWebhooks() = %v, want %v", hooks, want)
+	}
+	for i := range want {
+		if hooks[i] != want[i] {
+			t.Errorf("hook %d = %v, want %v", i, hooks[i], want[i])
+		}
+	}
+}
+
+func TestValidateWebhooks(t *testing.T) {
+	for _, value := range []string{"", "https://example.com", "dingtalk=http://example.com,feishu=https://example.com"} {
+		if err := ValidateWebhooks(value); err != nil {
+			t.Errorf("ValidateWebhooks(%q) error = %v", value, err)
+		}
+	}
+	for _, value := range []string{"example.com", "slack=ftp://example.com", "https://ok.example.com,file:///etc/passwd"} {
+		if err := ValidateWebhooks(value); err == nil {
+			t.Errorf("ValidateWebhooks(%q) = nil, want an error", value)
+		}
+	}
+}
+
+func TestDedupStoreKey(t *testing.T) {
+	base := Event{Type: EventKeyBlacklisted, GroupName: "g", DedupKey: "1"}
+	key := dedupStoreKey(base)
+	if !strings.HasPrefix(key, "alert:dedup:key_blacklisted:") {
+		t.Errorf("dedupStoreKey() = %q", key)
+	}
+
+	other := base
+	other.DedupKey = "2"
+	if dedupStoreKey(other) == key {
+		t.Error("different dedup keys share a store key")
+	}
+	other = base
+	other.GroupName = "g2"
+	if dedupStoreKey(other) == key {
+		t.Error("different groups share a store key")
+	}
+	// 消息内容不影响去重
+	other = base
+	other.Message = "changed"
+	if dedupStoreKey(other) != key {
+		t.Error("message changed the store key")
+	}
+}
+
+func TestBuildPayload(t *testing.T) {
+	event := Event{
+		Type:      EventGroupErrorRate,
+		Severity:  SeverityCritical,
+		Title:     "Error rate high",
+		Message:   "50% of requests failed",
+		GroupName: "g",
+		Fields:    map[string]any{"b": 2, "a": 1},
+		Timestamp: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+
+	tests := map[string]func(map[string]any) string{
+		FormatSlack:    func(m map[string]any) string { return m["text"].(string) },
+		FormatDingTalk: func(m map[string]any) string { return m["markdown"].(map[string]any)["text"].(string) },
+		FormatFeishu:   func(m map[string]any) string { return m["content"].(map[string]any)["text"].(string) },
+	}
+	for format, text := range tests {
+		body, err := buildPayload(format, event)
+		if err != nil {
+			t.Fatalf("buildPayload(%s) error = %v", format, err)
+		}
+		var payload map[string]any
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Fatalf("buildPayload(%s) returned invalid JSON: %v", format, err)
+		}
+		got := text(payload)
+		for _, want := range []string{"[CRITICAL] Error rate high", "50% of requests failed", "group: g", "time: 2026-01-02 03:04:05"} {
+			if !strings.Contains(got, want) {
+				t.Errorf("%s payload %q does not contain %q", format, got, want)
+			}
+		}
+		if strings.Index(got, "a: 1") > strings.Index(got, "b: 2") {
+			t.Errorf("%s payload fields not sorted: %q", format, got)
+		}
+	}
+
+	body, err := buildPayload(FormatGeneric, event)
+	if err != nil {
+		t.Fatalf("buildPayload(generic) error = %v", err)
+	}
+	var generic map[string]any
+	if err := json.Unmarshal(body, &generic); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if generic["event"] != EventGroupErrorRate || generic["group"] != "g" {
+		t.Errorf("generic payload = %v", generic)
+	}
+	if _, ok := generic["DedupKey"]; ok {
+		t.Error("generic payload exposes the dedup key")
+	}
+}
@@ -0,0 +1,164 @@
+package alert
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"gpt-load/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// errorRateBucketTTL keeps per-minute buckets a little longer than the largest window.
+const errorRateBucketTTL = 61 * time.Minute
+
+// KeyBlacklisted alerts that a key was disabled after reaching the blacklist threshold.
+func (s *Service) KeyBlacklisted(group *models.Group, keyID uint, maskedKey string, failureCount int64) {
+	s.Notify(Event{
+		Type:      EventKeyBlacklisted,
+		Severity:  SeverityWarning,
+		Title:     fmt.Sprintf("Key blacklisted in group '%s'", group.Name),
+		Message:   "A key reached the blacklist threshold and was disabled.",
+		GroupName: group.Name,
+		Fields: map[string]any{
+			"key_id":        keyID,
+			"key":           maskedKey,
+			"failure_count": failureCount,
+		},
+		DedupKey: strconv.FormatUint(uint64(keyID), 10),
+	})
+}
+
+// CheckActiveKeys alerts when a group has run out of active keys or dropped below its threshold.
+func (s *Service) CheckActiveKeys(group *models.Group, activeCount int64) {
+	if activeCount == 0 {
+		s.Notify(Event{
+			Type:      EventGroupKeysExhausted,
+			Severity:  SeverityCritical,
+			Title:     fmt.Sprintf("Group '%s' has no active keys", group.Name),
+			Message:   "All keys of the group are invalid, requests to this group will fail.",
+			GroupName: group.Name,
+			Fields:    map[string]any{"active_keys": activeCount},
+		})
+		return
+	}
+
+	threshold := group.EffectiveConfig.AlertLowActiveKeysThreshold
+	if threshold > 0 && activeCount < int64(threshold) {
+		s.Notify(Event{
+			Type:      EventGroupKeysLow,
+			Severity:  SeverityWarning,
+			Title:     fmt.Sprintf("Group '%s' is running low on active keys", group.Name),
+			Message:   "The number of active keys dropped below the alert threshold.",
+			GroupName: group.Name,
+			Fields: map[string]any{
+				"active_keys": activeCount,
+				"threshold":   threshold,
+			},
+		})
+	}
+}
+
+// RecordRequestResult counts a finished request in per-minute buckets and alerts
+// when the group's error rate over the configured window exceeds the threshold.
+func (s *Service) RecordRequestResult(group *models.Group, success bool) {
+	cfg := group.EffectiveConfig
+	if cfg.AlertErrorRateThreshold <= 0 || s.settingsManager.GetSettings().AlertWebhooks == "" {
+		return
+	}
+
+	now := time.Now()
+	s.incrBucket(errorRateBucketKey(group.ID, now, "total"))
+	if success {
+		// 仅在失败时计算错误率，避免成功请求带来额外开销
+		return
+	}
+	s.incrBucket(errorRateBucketKey(group.ID, now, "failure"))
+
+	var total, failures int64
+	for i := range cfg.AlertErrorRateWindowMinutes {
+		bucketTime := now.Add(-time.Duration(i) * time.Minute)
+		total += s.readBucket(errorRateBucketKey(group.ID, bucketTime, "total"))
+		failures += s.readBucket(errorRateBucketKey(group.ID, bucketTime, "failure"))
+	}
+
+	if total < int64(cfg.AlertErrorRateMinRequests) {
+		return
+	}
+	rate := float64(failures) * 100 / float64(total)
+	if rate < float64(cfg.AlertErrorRateThreshold) {
+		return
+	}
+
+	s.Notify(Event{
+		Type:      EventGroupErrorRate,
+		Severity:  SeverityWarning,
+		Title:     fmt.Sprintf("High error rate in group '%s'", group.Name),
+		Message:   fmt.Sprintf("%.1f%% of requests failed in the last %d minutes.", rate, cfg.AlertErrorRateWindowMinutes),
+		GroupName: group.Name,
+		Fields: map[string]any{
+			"error_rate":     fmt.Sprintf("%.1f%%", rate),
+			"threshold":      fmt.Sprintf("%d%%", cfg.AlertErrorRateThreshold),
+			"failed":         failures,
+			"total":          total,
+			"window_minutes": cfg.AlertErrorRateWindowMinutes,
+		},
+	})
+}
+
+// ValidationFinished alerts when a validation task found keys that were active but failed validation.
+func (s *Service) ValidationFinished(group *models.Group, totalKeys, invalidKeys, newlyInvalidKeys int) {
+	if newlyInvalidKeys == 0 {
+		return
+	}
+	s.Notify(Event{
+		Type:      EventValidationInvalidKeys,
+		Severity:  SeverityWarning,
+		Title:     fmt.Sprintf("Validation found %d newly invalid keys in group '%s'", newlyInvalidKeys, group.Name),
+		Message:   "Keys that were active failed validation.",
+		GroupName: group.Name,
+		Fields: map[string]any{
+			"total_keys":         totalKeys,
+			"invalid_keys":       invalidKeys,
+			"newly_invalid_keys": newlyInvalidKeys,
+		},
+	})
+}
+
+// EncryptionMismatch alerts that the configured ENCRYPTION_KEY does not match the stored keys.
+func (s *Service) EncryptionMismatch(message, suggestion string) {
+	s.Notify(Event{
+		Type:     EventEncryptionMismatch,
+		Severity: SeverityCritical,
+		Title:    "Encryption configuration mismatch detected",
+		Message:  message,
+		Fields:   map[string]any{"suggestion": suggestion},
+	})
+}
+
+func (s *Service) incrBucket(key string) {
+	count, err := s.store.IncrBy(key, 1)
+	if err != nil {
+		logrus.WithError(err).Debug("Failed to record request result for alerting")
+		return
+	}
+	if count == 1 {
+		if err := s.store.Expire(key, errorRateBucketTTL); err != nil {
+			logrus.WithError(err).Debug("Failed to set expiry on alert bucket")
+		}
+	}
+}
+
+func (s *Service) readBucket(key string) int64 {
+	value, err := s.store.Get(key)
+	if err != nil {
+		return 0
+	}
+	count, _ := strconv.ParseInt(string(value), 10, 64)
+	return count
+}
+
+func errorRateBucketKey(groupID uint, t time.Time, kind string) string {
+	return fmt.Sprintf("alert:errors:group:%d:%d:%s", groupID, t.Unix()/60, kind)
+}
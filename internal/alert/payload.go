@@ -0,0 +1,71 @@
+package alert
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Webhook payload formats
+const (
+	FormatGeneric  = "generic"
+	FormatSlack    = "slack"
+	FormatDingTalk = "dingtalk"
+	FormatFeishu   = "feishu"
+)
+
+func isKnownFormat(format string) bool {
+	switch format {
+	case FormatGeneric, FormatSlack, FormatDingTalk, FormatFeishu:
+		return true
+	}
+	return false
+}
+
+// buildPayload renders an event in the payload format expected by the webhook.
+func buildPayload(format string, event Event) ([]byte, error) {
+	switch format {
+	case FormatSlack:
+		return json.Marshal(map[string]any{
+			"text": fmt.Sprintf("*[%s] %s*\n%s", strings.ToUpper(event.Severity), event.Title, formatText(event, "• ")),
+		})
+	case FormatDingTalk:
+		return json.Marshal(map[string]any{
+			"msgtype": "markdown",
+			"markdown": map[string]any{
+				"title": event.Title,
+				"text":  fmt.Sprintf("### [%s] %s\n\n%s", strings.ToUpper(event.Severity), event.Title, formatText(event, "- ")),
+			},
+		})
+	case FormatFeishu:
+		return json.Marshal(map[string]any{
+			"msg_type": "text",
+			"content": map[string]any{
+				"text": fmt.Sprintf("[%s] %s\n%s", strings.ToUpper(event.Severity), event.Title, formatText(event, "")),
+			},
+		})
+	default:
+		return json.Marshal(event)
+	}
+}
+
+// formatText renders the message and fields as plain lines for chat-style webhooks.
+func formatText(event Event, bullet string) string {
+	lines := []string{event.Message}
+	if event.GroupName != "" {
+		lines = append(lines, fmt.Sprintf("%sgroup: %s", bullet, event.GroupName))
+	}
+
+	keys := make([]string, 0, len(event.Fields))
+	for key := range event.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		lines = append(lines, fmt.Sprintf("%s%s: %v", bullet, key, event.Fields[key]))
+	}
+
+	lines = append(lines, fmt.Sprintf("%stime: %s", bullet, event.Timestamp.Format("2006-01-02 15:04:05")))
+	return strings.Join(lines, "\n")
+}
@@ -8,13 +8,17 @@ import (
 	"sync"
 	"time"
 
+	"gpt-load/internal/alert"
 	"gpt-load/internal/config"
 	db "gpt-load/internal/db/migrations"
+	"gpt-load/internal/encryption"
 	"gpt-load/internal/keypool"
-	"gpt-load/internal/models"
+	"gpt-load/internal/logsink"
+	"gpt-load/internal/metrics"
 	"gpt-load/internal/proxy"
 	"gpt-load/internal/services"
 	"gpt-load/internal/store"
+	"gpt-load/internal/tracing"
 	"gpt-load/internal/types"
 	"gpt-load/internal/version"
 
@@ -37,6 +41,10 @@ type App struct {
 	proxyServer       *proxy.ProxyServer
 	storage           store.Store
 	db                *gorm.DB
+	tracer            *tracing.Tracer
+	logSinks          *logsink.Manager
+	alerts            *alert.Service
+	encryptionSvc     encryption.Service
 	httpServer        *http.Server
 }
 
@@ -54,10 +62,17 @@ type AppParams struct {
 	ProxyServer       *proxy.ProxyServer
 	Storage           store.Store
 	DB                *gorm.DB
+	Tracer            *tracing.Tracer
+	Metrics           *metrics.Metrics
+	LogSinks          *logsink.Manager
+	Alerts            *alert.Service
+	EncryptionSvc     encryption.Service
 }
 
 // NewApp is the constructor for App, with dependencies injected by dig.
 func NewApp(params AppParams) *App {
+	params.Metrics.SetRequestLogQueueSource(params.RequestLogService.PendingCount)
+
 	return &App{
 		engine:            params.Engine,
 		configManager:     params.ConfigManager,
@@ -70,6 +85,10 @@ func NewApp(params AppParams) *App {
 		proxyServer:       params.ProxyServer,
 		storage:           params.Storage,
 		db:                params.DB,
+		tracer:            params.Tracer,
+		logSinks:          params.LogSinks,
+		alerts:            params.Alerts,
+		encryptionSvc:     params.EncryptionSvc,
 	}
 }
 
@@ -84,19 +103,8 @@ func (a *App) Start() error {
 		}
 
 		// 数据库迁移
-		db.HandleLegacyIndexes(a.db)
-		if err := a.db.AutoMigrate(
-			&models.SystemSetting{},
-			&models.Group{},
-			&models.APIKey{},
-			&models.RequestLog{},
-			&models.GroupHourlyStat{},
-		); err != nil {
-			return fmt.Errorf("database auto-migration failed: %w", err)
-		}
-		// 数据修复
-		if err := db.MigrateDatabase(a.db); err != nil {
-			return fmt.Errorf("database data migration failed: %w", err)
+		if _, err := db.Migrate(a.db); err != nil {
+			return err
 		}
 		logrus.Info("Database auto-migration completed.")
 
@@ -114,6 +122,11 @@ func (a *App) Start() error {
 		}
 		logrus.Debug("API keys loaded into Redis cache by master.")
 
+		if hasMismatch, message, suggestion := services.CheckEncryptionMismatch(a.db, a.encryptionSvc); hasMismatch {
+			logrus.Warnf("%s %s", message, suggestion)
+			a.alerts.EncryptionMismatch(message, suggestion)
+		}
+
 		// 仅 Master 节点启动的服务
 		a.requestLogService.Start()
 		a.logCleanupService.Start()
@@ -177,6 +190,7 @@ func (a *App) Stop(ctx context.Context) {
 	stoppableServices := []func(context.Context){
 		a.groupManager.Stop,
 		a.settingsManager.Stop,
+		a.tracer.Stop,
 	}
 
 	if serverConfig.IsMaster {
@@ -185,6 +199,9 @@ func (a *App) Stop(ctx context.Context) {
 			a.logCleanupService.Stop,
 			a.requestLogService.Stop,
 		)
+	} else {
+		// Slave 节点在同步写日志模式下也会写入日志输出端
+		stoppableServices = append(stoppableServices, a.logSinks.Close)
 	}
 
 	var wg sync.WaitGroup
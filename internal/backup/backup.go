@@ -0,0 +1,207 @@
+// Package backup writes the database to a portable archive and restores it into any supported driver.
+//
+// An archive is a gzip compressed stream of JSON lines. The first line is the header, then every
+// table follows as a start line, one line per row and an end line with the row count and checksum:
+//
+//	{"header":{"format":"gpt-load-backup","schema_version":1,...}}
+//	{"table":"groups"}
+//	{"row":{"id":1,"name":"openai",...}}
+//	{"end":{"table":"groups","rows":1,"sha256":"..."}}
+//
+// Rows are keyed by column name and hold the stored values, so encrypted keys, password and
+// token hashes are copied as they are.
+package backup
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"reflect"
+	"time"
+
+	migrations "gpt-load/internal/db/migrations"
+	"gpt-load/internal/models"
+	"gpt-load/internal/version"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	archiveFormat = "gpt-load-backup"
+	// FormatVersion is the version of the archive layout, independent of the schema version.
+	FormatVersion = 1
+	batchSize     = 500
+)
+
+// Header describes an archive.
+type Header struct {
+	Format        string    `json:"format"`
+	FormatVersion int       `json:"format_version"`
+	SchemaVersion int       `json:"schema_version"`
+	AppVersion    string    `json:"app_version"`
+	Driver        string    `json:"driver"`
+	CreatedAt     time.Time `json:"created_at"`
+	IncludeLogs   bool      `json:"include_logs"`
+	Tables        []string  `json:"tables"`
+}
+
+// TableSummary closes the rows of a table in the archive.
+type TableSummary struct {
+	Table  string `json:"table"`
+	Rows   int64  `json:"rows"`
+	SHA256 string `json:"sha256"`
+}
+
+// Summary is the result of a backup or a restore.
+type Summary struct {
+	Header Header         `json:"header"`
+	Tables []TableSummary `json:"tables"`
+}
+
+// record is one line of an archive, exactly one of the fields is set.
+type record struct {
+	Header *Header         `json:"header,omitempty"`
+	Table  string          `json:"table,omitempty"`
+	Row    json.RawMessage `json:"row,omitempty"`
+	End    *TableSummary   `json:"end,omitempty"`
+}
+
+// table is a table of the archive with the schema gorm parsed from its model.
+type table struct {
+	model  any
+	schema *schema.Schema
+}
+
+func (t *table) name() string {
+	return t.schema.Table
+}
+
+// loadTables parses the models of every table, request logs only when includeLogs is set.
+func loadTables(db *gorm.DB, includeLogs bool) ([]*table, error) {
+	var tables []*table
+	for _, model := range migrations.Models() {
+		if _, ok := model.(*models.RequestLog); ok && !includeLogs {
+			continue
+		}
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return nil, fmt.Errorf("failed to parse model %T: %w", model, err)
+		}
+		tables = append(tables, &table{model: model, schema: stmt.Schema})
+	}
+	return tables, nil
+}
+
+// Backup streams every table of the database into w.
+func Backup(db *gorm.DB, w io.Writer, includeLogs bool) (*Summary, error) {
+	pending, err := migrations.Pending(db)
+	if err != nil {
+		return nil, err
+	}
+	if len(pending) > 0 {
+		return nil, fmt.Errorf("the database has %d pending migrations, run 'gpt-load migrate up' first", len(pending))
+	}
+	tables, err := loadTables(db, includeLogs)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &Summary{Header: Header{
+		Format:        archiveFormat,
+		FormatVersion: FormatVersion,
+		SchemaVersion: migrations.SchemaVersion(),
+		AppVersion:    version.Version,
+		Driver:        db.Dialector.Name(),
+		CreatedAt:     time.Now().UTC(),
+		IncludeLogs:   includeLogs,
+	}}
+	for _, t := range tables {
+		summary.Header.Tables = append(summary.Header.Tables, t.name())
+	}
+
+	gz := gzip.NewWriter(w)
+	writer := bufio.NewWriter(gz)
+	if err := writeRecord(writer, record{Header: &summary.Header}); err != nil {
+		return nil, err
+	}
+	for _, t := range tables {
+		tableSummary, err := backupTable(db, writer, t)
+		if err != nil {
+			return nil, fmt.Errorf("failed to back up table %s: %w", t.name(), err)
+		}
+		summary.Tables = append(summary.Tables, *tableSummary)
+	}
+
+	if err := writer.Flush(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+func backupTable(db *gorm.DB, writer *bufio.Writer, t *table) (*TableSummary, error) {
+	if err := writeRecord(writer, record{Table: t.name()}); err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	checksum := sha256.New()
+	summary := &TableSummary{Table: t.name()}
+
+	batch := reflect.New(reflect.SliceOf(t.schema.ModelType))
+	result := db.Model(t.model).FindInBatches(batch.Interface(), batchSize, func(_ *gorm.DB, _ int) error {
+		rows := batch.Elem()
+		for i := 0; i < rows.Len(); i++ {
+			row := make(map[string]any, len(t.schema.DBNames))
+			for _, name := range t.schema.DBNames {
+				row[name], _ = t.schema.FieldsByDBName[name].ValueOf(ctx, rows.Index(i))
+			}
+			data, err := json.Marshal(row)
+			if err != nil {
+				return err
+			}
+
+			writeChecksum(checksum, data)
+			// 直接写入序列化后的行，保证恢复时读到的字节与校验和一致
+			writer.WriteString(`{"row":`)
+			writer.Write(data)
+			if _, err := writer.WriteString("}\n"); err != nil {
+				return err
+			}
+			summary.Rows++
+		}
+		return nil
+	})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	summary.SHA256 = hex.EncodeToString(checksum.Sum(nil))
+	if err := writeRecord(writer, record{End: summary}); err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+func writeRecord(writer io.Writer, rec record) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(append(data, '\n'))
+	return err
+}
+
+func writeChecksum(checksum hash.Hash, row []byte) {
+	checksum.Write(row)
+	checksum.Write([]byte{'\n'})
+}
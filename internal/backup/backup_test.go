@@ -0,0 +1,281 @@
+package backup
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	migrations "gpt-load/internal/db/migrations"
+	"gpt-load/internal/models"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	if _, err := migrations.Migrate(db); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	return db
+}
+
+// seed stores a group with two keys and a request log.
+func seed(t *testing.T, db *gorm.DB, name string) {
+	t.Helper()
+
+	group := models.Group{
+		Name:        name,
+		Upstreams:   datatypes.JSON(`[{"url":"http://upstream","weight":1}]`),
+		ChannelType: "openai",
+		TestModel:   "gpt-4o-mini",
+		Config:      datatypes.JSONMap{"max_retries": float64(2)},
+	}
+	if err := db.Create(&group).Error; err != nil {
+		t.Fatalf("failed to create group: %v", err)
+	}
+	keys := []models.APIKey{
+		{GroupID: group.ID, KeyValue: "enc:" + name + "-1", KeyHash: name + "-hash-1", Status: models.KeyStatusActive},
+		{GroupID: group.ID, KeyValue: "enc:" + name + "-2", KeyHash: name + "-hash-2", Status: models.KeyStatusInvalid, FailureCount: 3},
+	}
+	if err := db.Create(&keys).Error; err != nil {
+		t.Fatalf("failed to create keys: %v", err)
+	}
+	if err := db.Create(&models.RequestLog{ID: name + "-log", GroupID: group.ID, GroupName: name}).Error; err != nil {
+		t.Fatalf("failed to create request log: %v", err)
+	}
+}
+
+func backup(t *testing.T, db *gorm.DB, includeLogs bool) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if _, err := Backup(db, &buf, includeLogs); err != nil {
+		t.Fatalf("Backup() error = %v", err)
+	}
+	return buf.Bytes()
+}
+
+// rewrite decompresses an archive, edits its lines and compresses it again.
+func rewrite(t *testing.T, archive []byte, edit func(lines []string) []string) []byte {
+	t.Helper()
+
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		t.Fatalf("failed to open archive: %v", err)
+	}
+	data, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatalf("failed to read archive: %v", err)
+	}
+	lines := edit(strings.Split(strings.TrimSuffix(string(data), "\n"), "\n"))
+
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	w.Write([]byte(strings.Join(lines, "\n") + "\n"))
+	w.Close()
+	return buf.Bytes()
+}
+
+func keyValues(t *testing.T, db *gorm.DB) []string {
+	t.Helper()
+
+	var values []string
+	if err := db.Model(&models.APIKey{}).Order("id").Pluck("key_value", &values).Error; err != nil {
+		t.Fatalf("failed to load keys: %v", err)
+	}
+	return values
+}
+
+func TestBackupRestoreRoundTrip(t *testing.T) {
+	source := newTestDB(t)
+	seed(t, source, "openai")
+
+	var buf bytes.Buffer
+	summary, err := Backup(source, &buf, false)
+	if err != nil {
+		t.Fatalf("Backup() error = %v", err)
+	}
+	if summary.Header.SchemaVersion != migrations.SchemaVersion() || summary.Header.Driver != "sqlite" {
+		t.Errorf("header = %+v", summary.Header)
+	}
+	for _, table := range summary.Header.Tables {
+		if table == "request_logs" {
+			t.Error("request logs backed up without includeLogs")
+		}
+	}
+
+	target := newTestDB(t)
+	restored, err := Restore(target, bytes.NewReader(buf.Bytes()), RestoreOptions{})
+	if err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if len(restored.Tables) != len(summary.Tables) {
+		t.Fatalf("restored %d tables, want %d", len(restored.Tables), len(summary.Tables))
+	}
+	for i, table := range restored.Tables {
+		if table != summary.Tables[i] {
+			t.Errorf("restored table %+v, want %+v", table, summary.Tables[i])
+		}
+	}
+
+	// 加密后的密钥原样复制
+	if got := keyValues(t, target); strings.Join(got, ",") != "enc:openai-1,enc:openai-2" {
+		t.Errorf("restored keys = %v", got)
+	}
+	var group models.Group
+	if err := target.First(&group).Error; err != nil {
+		t.Fatalf("failed to load restored group: %v", err)
+	}
+	if group.Name != "openai" || fmt.Sprint(group.Config["max_retries"]) != "2" || string(group.Upstreams) != `[{"url":"http://upstream","weight":1}]` {
+		t.Errorf("restored group = %+v", group)
+	}
+	var key models.APIKey
+	target.Where("key_hash = ?", "openai-hash-2").First(&key)
+	if key.Status != models.KeyStatusInvalid || key.FailureCount != 3 || key.GroupID != group.ID {
+		t.Errorf("restored key = %+v", key)
+	}
+
+	var logs int64
+	target.Model(&models.RequestLog{}).Count(&logs)
+	if logs != 0 {
+		t.Errorf("restored %d request logs without includeLogs", logs)
+	}
+}
+
+func TestBackupIncludeLogs(t *testing.T) {
+	source := newTestDB(t)
+	seed(t, source, "openai")
+
+	target := newTestDB(t)
+	if _, err := Restore(target, bytes.NewReader(backup(t, source, true)), RestoreOptions{}); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	var log models.RequestLog
+	if err := target.First(&log).Error; err != nil || log.ID != "openai-log" {
+		t.Errorf("restored request log = %+v, %v", log, err)
+	}
+}
+
+func TestRestoreRefusesNonEmptyTables(t *testing.T) {
+	source := newTestDB(t)
+	seed(t, source, "openai")
+	archive := backup(t, source, false)
+
+	target := newTestDB(t)
+	seed(t, target, "existing")
+
+	_, err := Restore(target, bytes.NewReader(archive), RestoreOptions{})
+	if err == nil || !strings.Contains(err.Error(), "not empty") {
+		t.Fatalf("Restore() error = %v, want tables not empty", err)
+	}
+	if got := keyValues(t, target); strings.Join(got, ",") != "enc:existing-1,enc:existing-2" {
+		t.Errorf("keys after refused restore = %v", got)
+	}
+
+	if _, err := Restore(target, bytes.NewReader(archive), RestoreOptions{Force: true}); err != nil {
+		t.Fatalf("Restore() with force error = %v", err)
+	}
+	if got := keyValues(t, target); strings.Join(got, ",") != "enc:openai-1,enc:openai-2" {
+		t.Errorf("keys after forced restore = %v", got)
+	}
+}
+
+func TestRestoreRollsBackCorruptedArchive(t *testing.T) {
+	source := newTestDB(t)
+	seed(t, source, "openai")
+	archive := backup(t, source, false)
+
+	isKeyRow := func(line string) bool {
+		return strings.HasPrefix(line, `{"row":`) && strings.Contains(line, "openai-hash-")
+	}
+	tests := []struct {
+		name    string
+		archive []byte
+		want    string
+	}{
+		{
+			name: "modified row",
+			archive: rewrite(t, archive, func(lines []string) []string {
+				for i, line := range lines {
+					if isKeyRow(line) {
+						lines[i] = strings.Replace(line, "enc:openai-1", "enc:tampered", 1)
+					}
+				}
+				return lines
+			}),
+			want: "checksum mismatch in table api_keys",
+		},
+		{
+			name: "missing row",
+			archive: rewrite(t, archive, func(lines []string) []string {
+				var kept []string
+				for _, line := range lines {
+					if !isKeyRow(line) || !strings.Contains(line, "openai-hash-2") {
+						kept = append(kept, line)
+					}
+				}
+				return kept
+			}),
+			want: "table api_keys has 1 rows in the backup, expected 2",
+		},
+		{
+			name: "truncated",
+			archive: rewrite(t, archive, func(lines []string) []string {
+				for i, line := range lines {
+					if isKeyRow(line) {
+						return lines[:i+1]
+					}
+				}
+				return lines
+			}),
+			want: "backup is truncated in table api_keys",
+		},
+		{
+			name: "newer schema",
+			archive: rewrite(t, archive, func(lines []string) []string {
+				lines[0] = strings.Replace(lines[0], `"schema_version":`, `"schema_version":9999`, 1)
+				return lines
+			}),
+			want: "upgrade gpt-load first",
+		},
+		{
+			name:    "not an archive",
+			archive: []byte("plain text"),
+			want:    "not a gpt-load backup",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := newTestDB(t)
+			seed(t, target, "existing")
+
+			_, err := Restore(target, bytes.NewReader(tt.archive), RestoreOptions{Force: true})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Restore() error = %v, want %q", err, tt.want)
+			}
+
+			// 任何不一致都回滚整个恢复，原有数据保持不变
+			if got := keyValues(t, target); strings.Join(got, ",") != "enc:existing-1,enc:existing-2" {
+				t.Errorf("keys after failed restore = %v", got)
+			}
+			var groups []string
+			target.Model(&models.Group{}).Pluck("name", &groups)
+			if strings.Join(groups, ",") != "existing" {
+				t.Errorf("groups after failed restore = %v", groups)
+			}
+		})
+	}
+}
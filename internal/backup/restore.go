@@ -0,0 +1,276 @@
+package backup
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"reflect"
+	"slices"
+	"strings"
+
+	migrations "gpt-load/internal/db/migrations"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RestoreOptions controls how an archive is restored.
+type RestoreOptions struct {
+	// Force replaces the data of tables that are not empty, restore refuses them otherwise.
+	Force bool
+}
+
+// readHeader reads and checks the first line of an archive.
+func readHeader(decoder *json.Decoder) (*Header, error) {
+	var rec record
+	if err := decoder.Decode(&rec); err != nil || rec.Header == nil || rec.Header.Format != archiveFormat {
+		return nil, errors.New("not a gpt-load backup")
+	}
+	header := rec.Header
+	if header.FormatVersion > FormatVersion {
+		return nil, fmt.Errorf("backup format version %d is not supported, this build reads up to version %d", header.FormatVersion, FormatVersion)
+	}
+	if header.SchemaVersion > migrations.SchemaVersion() {
+		return nil, fmt.Errorf("backup schema version %d is newer than version %d of this build, upgrade gpt-load first", header.SchemaVersion, migrations.SchemaVersion())
+	}
+	return header, nil
+}
+
+// Restore migrates the schema of the database and loads the archive into it in a single
+// transaction. Every table is checked against the row count and checksum of the archive,
+// any mismatch rolls the whole restore back.
+func Restore(db *gorm.DB, r io.Reader, opts RestoreOptions) (*Summary, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("not a gpt-load backup: %w", err)
+	}
+	defer gz.Close()
+	decoder := json.NewDecoder(bufio.NewReader(gz))
+
+	header, err := readHeader(decoder)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := migrations.Migrate(db); err != nil {
+		return nil, err
+	}
+	known, err := loadTables(db, true)
+	if err != nil {
+		return nil, err
+	}
+	tables := make(map[string]*table, len(known))
+	for _, t := range known {
+		tables[t.name()] = t
+	}
+
+	var nonEmpty []string
+	for _, name := range header.Tables {
+		t, ok := tables[name]
+		if !ok {
+			return nil, fmt.Errorf("backup contains unknown table %s", name)
+		}
+		var count int64
+		if err := db.Model(t.model).Count(&count).Error; err != nil {
+			return nil, fmt.Errorf("failed to count rows of table %s: %w", name, err)
+		}
+		if count > 0 {
+			nonEmpty = append(nonEmpty, name)
+		}
+	}
+	if len(nonEmpty) > 0 && !opts.Force {
+		return nil, fmt.Errorf("tables %s are not empty, restore with --force to replace their data", strings.Join(nonEmpty, ", "))
+	}
+
+	summary := &Summary{Header: *header}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// 逆序清空，避免外键约束阻止删除
+		for i := len(header.Tables) - 1; i >= 0; i-- {
+			if !slices.Contains(nonEmpty, header.Tables[i]) {
+				continue
+			}
+			if err := tx.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(tables[header.Tables[i]].model).Error; err != nil {
+				return fmt.Errorf("failed to clear table %s: %w", header.Tables[i], err)
+			}
+		}
+
+		restorer := &tableRestorer{tx: tx}
+		for {
+			var rec record
+			if err := decoder.Decode(&rec); err == io.EOF {
+				break
+			} else if err != nil {
+				return fmt.Errorf("failed to read backup: %w", err)
+			}
+
+			switch {
+			case rec.Table != "":
+				if err := restorer.start(tables[rec.Table], rec.Table); err != nil {
+					return err
+				}
+			case rec.Row != nil:
+				if err := restorer.add(rec.Row); err != nil {
+					return err
+				}
+			case rec.End != nil:
+				if err := restorer.finish(rec.End); err != nil {
+					return err
+				}
+				summary.Tables = append(summary.Tables, *rec.End)
+			}
+		}
+
+		if restorer.current != nil {
+			return fmt.Errorf("backup is truncated in table %s", restorer.current.name())
+		}
+		for _, name := range header.Tables {
+			if !slices.ContainsFunc(summary.Tables, func(s TableSummary) bool { return s.Table == name }) {
+				return fmt.Errorf("backup is missing table %s", name)
+			}
+		}
+		return resetSequences(tx, known)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+// tableRestorer inserts the rows of the table being read in batches.
+type tableRestorer struct {
+	tx       *gorm.DB
+	current  *table
+	rows     reflect.Value
+	count    int64
+	checksum hash.Hash
+	unknown  map[string]bool
+}
+
+func (r *tableRestorer) start(t *table, name string) error {
+	if t == nil {
+		return fmt.Errorf("backup contains unknown table %s", name)
+	}
+	if r.current != nil {
+		return fmt.Errorf("backup is truncated in table %s", r.current.name())
+	}
+	r.current = t
+	r.rows = reflect.MakeSlice(reflect.SliceOf(t.schema.ModelType), 0, batchSize)
+	r.count = 0
+	r.checksum = sha256.New()
+	r.unknown = make(map[string]bool)
+	return nil
+}
+
+func (r *tableRestorer) add(data json.RawMessage) error {
+	if r.current == nil {
+		return errors.New("backup contains a row outside of a table")
+	}
+	writeChecksum(r.checksum, data)
+
+	var columns map[string]json.RawMessage
+	if err := json.Unmarshal(data, &columns); err != nil {
+		return fmt.Errorf("invalid row in table %s: %w", r.current.name(), err)
+	}
+
+	ctx := context.Background()
+	row := reflect.New(r.current.schema.ModelType).Elem()
+	for name, raw := range columns {
+		field := r.current.schema.FieldsByDBName[name]
+		if field == nil {
+			// 旧版本的列，当前版本已不存在
+			r.unknown[name] = true
+			continue
+		}
+		if string(raw) == "null" {
+			continue
+		}
+		value := reflect.New(field.FieldType)
+		if err := json.Unmarshal(raw, value.Interface()); err != nil {
+			return fmt.Errorf("invalid value of column %s.%s: %w", r.current.name(), name, err)
+		}
+		if err := field.Set(ctx, row, value.Elem().Interface()); err != nil {
+			return fmt.Errorf("invalid value of column %s.%s: %w", r.current.name(), name, err)
+		}
+	}
+
+	r.rows = reflect.Append(r.rows, row)
+	r.count++
+	if r.rows.Len() >= batchSize {
+		return r.flush()
+	}
+	return nil
+}
+
+func (r *tableRestorer) flush() error {
+	if r.rows.Len() == 0 {
+		return nil
+	}
+	if err := r.tx.Omit(clause.Associations).Create(r.rows.Interface()).Error; err != nil {
+		return fmt.Errorf("failed to insert rows into table %s: %w", r.current.name(), err)
+	}
+	r.rows = r.rows.Slice(0, 0)
+	return nil
+}
+
+func (r *tableRestorer) finish(end *TableSummary) error {
+	if r.current == nil || end.Table != r.current.name() {
+		return fmt.Errorf("backup has an unexpected end of table %s", end.Table)
+	}
+	if err := r.flush(); err != nil {
+		return err
+	}
+
+	name := r.current.name()
+	if r.count != end.Rows {
+		return fmt.Errorf("table %s has %d rows in the backup, expected %d", name, r.count, end.Rows)
+	}
+	if checksum := hex.EncodeToString(r.checksum.Sum(nil)); checksum != end.SHA256 {
+		return fmt.Errorf("checksum mismatch in table %s, the backup is corrupted", name)
+	}
+	var count int64
+	if err := r.tx.Model(r.current.model).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to count rows of table %s: %w", name, err)
+	}
+	if count != end.Rows {
+		return fmt.Errorf("table %s has %d rows after restore, expected %d", name, count, end.Rows)
+	}
+
+	if len(r.unknown) > 0 {
+		columns := make([]string, 0, len(r.unknown))
+		for column := range r.unknown {
+			columns = append(columns, column)
+		}
+		slices.Sort(columns)
+		logrus.Warnf("Ignored columns of table %s that no longer exist: %s", name, strings.Join(columns, ", "))
+	}
+	r.current = nil
+	return nil
+}
+
+// resetSequences moves the Postgres sequences past the restored ids.
+// MySQL and SQLite advance their auto increment counters on explicit ids.
+func resetSequences(tx *gorm.DB, tables []*table) error {
+	if tx.Dialector.Name() != "postgres" {
+		return nil
+	}
+	for _, t := range tables {
+		field := t.schema.PrioritizedPrimaryField
+		if field == nil || !field.AutoIncrement {
+			continue
+		}
+		sql := fmt.Sprintf(`SELECT setval(pg_get_serial_sequence('"%s"', '%s'), COALESCE(MAX("%s"), 0) + 1, false) FROM "%s"`,
+			t.name(), field.DBName, field.DBName, t.name())
+		if err := tx.Exec(sql).Error; err != nil {
+			return fmt.Errorf("failed to reset the id sequence of table %s: %w", t.name(), err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package commands
+
+import (
+	"flag"
+	"fmt"
+	"gpt-load/internal/backup"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RunBackup handles the backup command entry point
+func RunBackup(args []string) {
+	backupCmd := flag.NewFlagSet("backup", flag.ExitOnError)
+	backupCmd.Usage = printBackupUsage
+	file := backupCmd.String("file", "", "Archive path, '-' for stdout")
+	includeLogs := backupCmd.Bool("include-logs", false, "Include the request logs")
+	parseFlags(backupCmd, args)
+	if *file == "" {
+		printBackupUsage()
+		os.Exit(1)
+	}
+
+	db := openDatabase()
+
+	if *file == "-" {
+		summary, err := backup.Backup(db, os.Stdout, *includeLogs)
+		if err != nil {
+			logrus.Fatalf("Backup failed: %v", err)
+		}
+		// 结果输出到 stderr，stdout 是备份数据
+		printBackupSummary(os.Stderr, summary)
+		return
+	}
+
+	// 先写入临时文件，失败时不会留下不完整的备份
+	tmpFile := *file + ".tmp"
+	output, err := os.OpenFile(tmpFile, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		logrus.Fatalf("Failed to create %s: %v", tmpFile, err)
+	}
+	summary, err := backup.Backup(db, output, *includeLogs)
+	if err == nil {
+		err = output.Sync()
+	}
+	output.Close()
+	if err == nil {
+		err = os.Rename(tmpFile, *file)
+	}
+	if err != nil {
+		os.Remove(tmpFile)
+		logrus.Fatalf("Backup failed: %v", err)
+	}
+	printBackupSummary(os.Stdout, summary)
+}
+
+// RunRestore handles the restore command entry point
+func RunRestore(args []string) {
+	restoreCmd := flag.NewFlagSet("restore", flag.ExitOnError)
+	restoreCmd.Usage = printBackupUsage
+	file := restoreCmd.String("file", "", "Archive path, '-' for stdin")
+	force := restoreCmd.Bool("force", false, "Replace the data of tables that are not empty")
+	parseFlags(restoreCmd, args)
+	if *file == "" {
+		printBackupUsage()
+		os.Exit(1)
+	}
+
+	var input io.Reader = os.Stdin
+	if *file != "-" {
+		f, err := os.Open(*file)
+		if err != nil {
+			logrus.Fatalf("Failed to open %s: %v", *file, err)
+		}
+		defer f.Close()
+		input = f
+	}
+
+	summary, err := backup.Restore(openDatabase(), input, backup.RestoreOptions{Force: *force})
+	if err != nil {
+		logrus.Fatalf("Restore failed: %v", err)
+	}
+	printBackupSummary(os.Stdout, summary)
+	fmt.Println("Restore completed. Restart running instances to reload the restored data.")
+}
+
+func printBackupUsage() {
+	fmt.Println("GPT-Load Backup and Restore")
+	fmt.Println()
+	fmt.Println("Usage:")
+	fmt.Println("  gpt-load backup  --file backup.jsonl.gz [--include-logs]")
+	fmt.Println("  gpt-load restore --file backup.jsonl.gz [--force]")
+	fmt.Println()
+	fmt.Println("Notes:")
+	fmt.Println("  1. Both commands use the database configured by DATABASE_DSN, set it to restore into another driver")
+	fmt.Println("  2. Keys are copied encrypted, the target instance needs the same ENCRYPTION_KEY or ENCRYPTION_DATA_KEY")
+	fmt.Println("  3. Restore refuses tables that are not empty unless --force is given")
+}
+
+func printBackupSummary(w io.Writer, summary *backup.Summary) {
+	header := summary.Header
+	fmt.Fprintf(w, "Backup of %s database, schema version %d, created %s by gpt-load %s\n",
+		header.Driver, header.SchemaVersion, header.CreatedAt.Local().Format("2006-01-02 15:04:05"), header.AppVersion)
+
+	writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "TABLE\tROWS\tSHA256")
+	for _, table := range summary.Tables {
+		fmt.Fprintf(writer, "%s\t%d\t%s\n", table.Table, table.Rows, table.SHA256)
+	}
+	writer.Flush()
+}
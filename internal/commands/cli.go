@@ -0,0 +1,139 @@
+package commands
+
+import (
+	"fmt"
+	"gpt-load/internal/config"
+	"gpt-load/internal/container"
+	"gpt-load/internal/keypool"
+	"gpt-load/internal/models"
+	"gpt-load/internal/services"
+	"gpt-load/internal/store"
+	"gpt-load/internal/types"
+	"gpt-load/internal/utils"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.uber.org/dig"
+	"gorm.io/gorm"
+)
+
+// commandServices holds the services used by the administration commands.
+type commandServices struct {
+	dig.In
+	DB                         *gorm.DB
+	ConfigManager              types.ConfigManager
+	Store                      store.Store
+	SettingsManager            *config.SystemSettingsManager
+	GroupManager               *services.GroupManager
+	KeyService                 *services.KeyService
+	KeyProvider                *keypool.KeyProvider
+	KeyImportService           *services.KeyImportService
+	KeyManualValidationService *services.KeyManualValidationService
+	TaskService                *services.TaskService
+	AuditService               *services.AuditService
+	ConfigDocumentService      *services.ConfigDocumentService
+	LogCleanupService          *services.LogCleanupService
+	KeyReencryptService        *services.KeyReencryptService
+}
+
+// buildCommandServices builds the container against the configured database and
+// initializes the caches the services rely on. The server does not need to be running.
+func buildCommandServices() *commandServices {
+	cont := buildCommandContainer()
+
+	var svc *commandServices
+	err := cont.Invoke(func(params commandServices) error {
+		if err := params.SettingsManager.Initialize(params.Store, params.GroupManager, false); err != nil {
+			return err
+		}
+		if err := params.GroupManager.Initialize(); err != nil {
+			return err
+		}
+		// 未配置 Redis 时密钥池只存在于本进程，需从数据库加载，状态更新才能基于当前的失败计数
+		if params.ConfigManager.GetRedisDSN() == "" {
+			if err := params.KeyProvider.LoadKeysFromDB(); err != nil {
+				return fmt.Errorf("failed to load keys into key pool: %w", err)
+			}
+		}
+		svc = &params
+		return nil
+	})
+	if err != nil {
+		logrus.Fatalf("Failed to initialize services: %v", err)
+	}
+	return svc
+}
+
+// openDatabase connects to the configured database, without touching its tables.
+func openDatabase() *gorm.DB {
+	var db *gorm.DB
+	if err := buildCommandContainer().Invoke(func(conn *gorm.DB) {
+		db = conn
+	}); err != nil {
+		logrus.Fatalf("Failed to connect to database: %v", err)
+	}
+	return db
+}
+
+func buildCommandContainer() *dig.Container {
+	cont, err := container.BuildContainer()
+	if err != nil {
+		logrus.Fatalf("Failed to build container: %v", err)
+	}
+
+	if err := cont.Invoke(func(configManager types.ConfigManager) {
+		utils.SetupLogger(configManager)
+		// 日志输出到 stderr，避免混入输出到 stdout 的结果
+		logrus.SetOutput(os.Stderr)
+	}); err != nil {
+		logrus.Fatalf("Failed to setup logger: %v", err)
+	}
+	return cont
+}
+
+// findGroup loads a group by name, with its effective configuration.
+func (svc *commandServices) findGroup(name string) *models.Group {
+	if name == "" {
+		fmt.Println("--group is required")
+		os.Exit(1)
+	}
+	group, err := svc.GroupManager.GetGroupByName(name)
+	if err != nil {
+		logrus.Fatalf("Group '%s' not found: %v", name, err)
+	}
+	return group
+}
+
+// record writes an audit log entry for a change made from the command line.
+func (svc *commandServices) record(entry services.AuditEntry) {
+	svc.AuditService.RecordAs(cliActor(), "", entry)
+}
+
+// waitForTask waits for the running background task to finish and returns its final status.
+func (svc *commandServices) waitForTask() *services.TaskStatus {
+	for {
+		status, err := svc.TaskService.GetTaskStatus()
+		if err != nil {
+			logrus.Fatalf("Failed to get task status: %v", err)
+		}
+		if !status.IsRunning {
+			return status
+		}
+		logrus.Infof("%s: %d/%d", status.TaskType, status.Processed, status.Total)
+		time.Sleep(time.Second)
+	}
+}
+
+// cliActor is the audit log actor of changes made from the command line.
+func cliActor() string {
+	if user := os.Getenv("USER"); user != "" {
+		return "cli:" + user
+	}
+	return "cli"
+}
+
+// isHelpArg reports whether the subcommand asks for help.
+func isHelpArg(args []string) bool {
+	return len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help"
+}
@@ -0,0 +1,198 @@
+package commands
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"gpt-load/internal/services"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RunConfig handles the config command entry point
+func RunConfig(args []string) {
+	if isHelpArg(args) {
+		printConfigUsage()
+		return
+	}
+
+	subcommand := args[0]
+	configCmd := flag.NewFlagSet("config "+subcommand, flag.ExitOnError)
+	file := configCmd.String("file", "", "Config document path, '-' for stdin/stdout")
+	format := configCmd.String("format", "", "Document format: yaml or json (default: from file extension, otherwise yaml)")
+	var includeKeys, prune, dryRun, jsonOutput *bool
+	switch subcommand {
+	case "export":
+		includeKeys = configCmd.Bool("include-keys", false, "Include the API keys of every group")
+	case "diff", "apply":
+		prune = configCmd.Bool("prune", false, "Delete groups missing from the document and extra keys of groups that list keys")
+		jsonOutput = configCmd.Bool("json", false, "Print the plan as JSON")
+		if subcommand == "apply" {
+			dryRun = configCmd.Bool("dry-run", false, "Show the changes without applying them")
+		}
+	default:
+		fmt.Printf("Unknown config subcommand: %s\n", subcommand)
+		printConfigUsage()
+		os.Exit(1)
+	}
+	configCmd.Usage = printConfigUsage
+
+	if err := configCmd.Parse(args[1:]); err != nil {
+		logrus.Fatalf("Parameter parsing failed: %v", err)
+	}
+	if subcommand != "export" && *file == "" {
+		fmt.Println("--file is required")
+		os.Exit(1)
+	}
+
+	docFormat := *format
+	if docFormat == "" {
+		docFormat = services.ConfigFormatYAML
+		if strings.EqualFold(filepath.Ext(*file), ".json") {
+			docFormat = services.ConfigFormatJSON
+		}
+	}
+	if docFormat != services.ConfigFormatYAML && docFormat != services.ConfigFormatJSON {
+		logrus.Fatalf("Invalid format %q, expected yaml or json", docFormat)
+	}
+
+	svc := buildCommandServices().ConfigDocumentService
+
+	switch subcommand {
+	case "export":
+		doc, err := svc.Export(*includeKeys)
+		if err != nil {
+			logrus.Fatalf("Export failed: %v", err)
+		}
+		data, err := services.MarshalDocument(doc, docFormat)
+		if err != nil {
+			logrus.Fatalf("Export failed: %v", err)
+		}
+		if *file == "" || *file == "-" {
+			os.Stdout.Write(data)
+			return
+		}
+		if err := os.WriteFile(*file, data, 0600); err != nil {
+			logrus.Fatalf("Failed to write %s: %v", *file, err)
+		}
+		logrus.Infof("Configuration exported to %s", *file)
+
+	case "diff", "apply":
+		doc, err := readConfigDocument(*file, docFormat)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		var plan *services.ConfigPlan
+		if subcommand == "diff" {
+			plan, err = svc.Plan(doc, *prune)
+		} else {
+			plan, err = svc.Apply(doc, services.ConfigApplyOptions{
+				DryRun: *dryRun,
+				Prune:  *prune,
+				Actor:  cliActor(),
+			})
+		}
+		if err != nil {
+			logrus.Fatalf("Config %s failed: %v", subcommand, err)
+		}
+
+		if *jsonOutput {
+			data, _ := json.MarshalIndent(plan, "", "  ")
+			fmt.Println(string(data))
+			return
+		}
+		printConfigPlan(plan)
+	}
+}
+
+func printConfigUsage() {
+	fmt.Println("GPT-Load Declarative Configuration")
+	fmt.Println()
+	fmt.Println("Usage:")
+	fmt.Println("  gpt-load config export [--file config.yaml] [--format yaml|json] [--include-keys]")
+	fmt.Println("  gpt-load config diff   --file config.yaml [--prune] [--json]")
+	fmt.Println("  gpt-load config apply  --file config.yaml [--prune] [--dry-run] [--json]")
+	fmt.Println()
+	fmt.Println("Notes:")
+	fmt.Println("  1. Groups are matched by name. Settings missing from the document are left unchanged")
+	fmt.Println("  2. Keys are only managed for groups that list them; without --prune they are only added")
+	fmt.Println("  3. --prune deletes groups missing from the document, together with their keys")
+}
+
+func readConfigDocument(file, format string) (*services.ConfigDocument, error) {
+	var data []byte
+	var err error
+	if file == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+	return services.ParseConfigDocument(data, format)
+}
+
+func printConfigPlan(plan *services.ConfigPlan) {
+	if len(plan.Changes) == 0 {
+		fmt.Println("No changes. The instance matches the document.")
+		return
+	}
+
+	counts := make(map[string]int)
+	for _, change := range plan.Changes {
+		counts[change.Action]++
+
+		symbol := map[string]string{
+			services.ConfigActionCreate: "+",
+			services.ConfigActionUpdate: "~",
+			services.ConfigActionDelete: "-",
+		}[change.Action]
+		line := fmt.Sprintf("%s %s %s", symbol, change.Kind, change.Name)
+		if change.Kind == services.ConfigKindKeys {
+			line += fmt.Sprintf(" (%d)", change.Count)
+		}
+		fmt.Println(line)
+
+		fields := make([]string, 0, len(change.Changes))
+		for field := range change.Changes {
+			fields = append(fields, field)
+		}
+		slices.Sort(fields)
+		for _, field := range fields {
+			fieldChange := change.Changes[field]
+			switch change.Action {
+			case services.ConfigActionCreate:
+				fmt.Printf("    %s: %s\n", field, formatPlanValue(fieldChange.After))
+			default:
+				fmt.Printf("    %s: %s -> %s\n", field, formatPlanValue(fieldChange.Before), formatPlanValue(fieldChange.After))
+			}
+		}
+	}
+
+	fmt.Println()
+	summary := fmt.Sprintf("%d to create, %d to update, %d to delete.",
+		counts[services.ConfigActionCreate], counts[services.ConfigActionUpdate], counts[services.ConfigActionDelete])
+	if plan.Applied {
+		fmt.Println("Applied: " + summary)
+	} else {
+		fmt.Println("Plan: " + summary)
+	}
+}
+
+func formatPlanValue(value any) string {
+	if value == nil {
+		return "(none)"
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
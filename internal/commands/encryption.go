@@ -0,0 +1,89 @@
+package commands
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"gpt-load/internal/encryption"
+	"gpt-load/internal/types"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RunEncryption handles the encryption command entry point
+func RunEncryption(args []string) {
+	if isHelpArg(args) {
+		printEncryptionUsage()
+		return
+	}
+
+	subcommand := args[0]
+	encryptionCmd := flag.NewFlagSet("encryption "+subcommand, flag.ExitOnError)
+	encryptionCmd.Usage = printEncryptionUsage
+	parseFlags(encryptionCmd, args[1:])
+
+	switch subcommand {
+	case "generate-data-key":
+		runGenerateDataKey()
+	case "status":
+		runEncryptionStatus()
+	default:
+		fmt.Printf("Unknown encryption subcommand: %s\n", subcommand)
+		printEncryptionUsage()
+		os.Exit(1)
+	}
+}
+
+func printEncryptionUsage() {
+	fmt.Println("GPT-Load Encryption")
+	fmt.Println()
+	fmt.Println("Usage:")
+	fmt.Println("  gpt-load encryption generate-data-key   Create a data key wrapped by the configured KMS")
+	fmt.Println("  gpt-load encryption status              Show the primary key and the values not yet re-encrypted")
+	fmt.Println()
+	fmt.Println("Set ENCRYPTION_BACKEND to vault or envelope with its KMS settings before generating a data key,")
+	fmt.Println("then put the printed value in ENCRYPTION_DATA_KEY on every instance.")
+}
+
+func runGenerateDataKey() {
+	var encryptionConfig types.EncryptionConfig
+	if err := buildCommandContainer().Invoke(func(configManager types.ConfigManager) {
+		encryptionConfig = configManager.GetEncryptionConfig()
+	}); err != nil {
+		logrus.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	wrapper, err := encryption.NewKeyWrapper(encryptionConfig)
+	if err != nil {
+		logrus.Fatalf("Failed to create key wrapper: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(encryptionConfig.KMSTimeout)*time.Second)
+	defer cancel()
+
+	wrapped, keyID, err := encryption.GenerateDataKey(ctx, wrapper)
+	if err != nil {
+		logrus.Fatalf("Failed to generate data key: %v", err)
+	}
+
+	// 结果输出到 stdout 便于脚本使用，说明输出到 stderr
+	fmt.Fprintf(os.Stderr, "Generated data key %s with the %s backend.\n", keyID, encryptionConfig.Backend)
+	fmt.Fprintln(os.Stderr, "Set it as ENCRYPTION_DATA_KEY; when replacing a data key, move the old value to ENCRYPTION_PREVIOUS_DATA_KEYS.")
+	fmt.Println(wrapped)
+}
+
+func runEncryptionStatus() {
+	svc := buildCommandServices()
+	status, err := svc.KeyReencryptService.GetStatus()
+	if err != nil {
+		logrus.Fatalf("Failed to get encryption status: %v", err)
+	}
+	if !status.Enabled {
+		fmt.Println("Encryption is disabled.")
+		return
+	}
+	fmt.Printf("Primary key: %s\n", status.KeyID)
+	fmt.Printf("Keys not encrypted with the primary key: %d\n", status.PendingKeys)
+	fmt.Printf("Request logs not encrypted with the primary key: %d\n", status.PendingLogs)
+}
@@ -0,0 +1,229 @@
+package commands
+
+import (
+	"flag"
+	"fmt"
+	"gpt-load/internal/models"
+	"gpt-load/internal/services"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RunGroup handles the group command entry point
+func RunGroup(args []string) {
+	if isHelpArg(args) {
+		printGroupUsage()
+		return
+	}
+
+	subcommand := args[0]
+	name, rest := splitNameArg(args[1:])
+	groupCmd := flag.NewFlagSet("group "+subcommand, flag.ExitOnError)
+	groupCmd.Usage = printGroupUsage
+
+	switch subcommand {
+	case "list":
+		parseFlags(groupCmd, rest)
+		runGroupList(buildCommandServices())
+	case "show":
+		parseFlags(groupCmd, rest)
+		runGroupShow(buildCommandServices(), requireName(name))
+	case "create":
+		file := groupCmd.String("file", "", "Group definition in YAML or JSON, the same format as a group of 'config export'")
+		doc := services.GroupDocument{}
+		groupCmd.StringVar(&doc.ChannelType, "channel-type", "", "Channel type, e.g. openai, gemini, anthropic")
+		groupCmd.StringVar(&doc.TestModel, "test-model", "", "Model used to validate keys")
+		upstreams := groupCmd.String("upstreams", "", "Comma-separated upstream URLs")
+		groupCmd.StringVar(&doc.DisplayName, "display-name", "", "Display name")
+		groupCmd.StringVar(&doc.Description, "description", "", "Description")
+		groupCmd.StringVar(&doc.ValidationEndpoint, "validation-endpoint", "", "Custom validation path")
+		groupCmd.StringVar(&doc.ProxyKeys, "proxy-keys", "", "Comma-separated group proxy keys")
+		groupCmd.IntVar(&doc.Sort, "sort", 0, "Sort order")
+		parseFlags(groupCmd, rest)
+
+		if *file != "" {
+			doc = readGroupDocument(*file)
+			if name != "" {
+				doc.Name = name
+			}
+		} else {
+			doc.Name = requireName(name)
+			for _, upstream := range strings.Split(*upstreams, ",") {
+				if upstream = strings.TrimSpace(upstream); upstream != "" {
+					doc.Upstreams = append(doc.Upstreams, services.UpstreamDefinition{URL: upstream, Weight: 1})
+				}
+			}
+		}
+		runGroupCreate(buildCommandServices(), &doc)
+	case "delete":
+		yes := groupCmd.Bool("yes", false, "Confirm the deletion of the group and all of its keys")
+		parseFlags(groupCmd, rest)
+		runGroupDelete(buildCommandServices(), requireName(name), *yes)
+	default:
+		fmt.Printf("Unknown group subcommand: %s\n", subcommand)
+		printGroupUsage()
+		os.Exit(1)
+	}
+}
+
+func printGroupUsage() {
+	fmt.Println("GPT-Load Group Administration")
+	fmt.Println()
+	fmt.Println("Usage:")
+	fmt.Println("  gpt-load group list")
+	fmt.Println("  gpt-load group show <name>")
+	fmt.Println("  gpt-load group create <name> --channel-type openai --test-model gpt-4o-mini --upstreams https://api.openai.com")
+	fmt.Println("  gpt-load group create --file group.yaml")
+	fmt.Println("  gpt-load group delete <name> --yes")
+	fmt.Println()
+	fmt.Println("Create options: --display-name, --description, --validation-endpoint, --proxy-keys, --sort")
+}
+
+func runGroupList(svc *commandServices) {
+	var groups []models.Group
+	if err := svc.DB.Order("sort asc, id desc").Find(&groups).Error; err != nil {
+		logrus.Fatalf("Failed to list groups: %v", err)
+	}
+	counts := loadKeyCounts(svc)
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "ID\tNAME\tCHANNEL\tTEST MODEL\tACTIVE KEYS\tINVALID KEYS")
+	for _, group := range groups {
+		fmt.Fprintf(writer, "%d\t%s\t%s\t%s\t%d\t%d\n", group.ID, group.Name, group.ChannelType, group.TestModel,
+			counts[group.ID][models.KeyStatusActive], counts[group.ID][models.KeyStatusInvalid])
+	}
+	writer.Flush()
+}
+
+func runGroupShow(svc *commandServices, name string) {
+	var group models.Group
+	if err := svc.DB.Where("name = ?", name).First(&group).Error; err != nil {
+		logrus.Fatalf("Group '%s' not found: %v", name, err)
+	}
+	doc, err := services.NewGroupDocument(&group)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	data, err := services.MarshalDocument(doc, services.ConfigFormatYAML)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	fmt.Printf("id: %d\n%s", group.ID, data)
+
+	counts := loadKeyCounts(svc)[group.ID]
+	fmt.Printf("keys: %d active, %d invalid\n", counts[models.KeyStatusActive], counts[models.KeyStatusInvalid])
+}
+
+func runGroupCreate(svc *commandServices, doc *services.GroupDocument) {
+	var count int64
+	if err := svc.DB.Model(&models.Group{}).Where("name = ?", doc.Name).Count(&count).Error; err != nil {
+		logrus.Fatalf("Failed to check group: %v", err)
+	}
+	if count > 0 {
+		logrus.Fatalf("Group '%s' already exists", doc.Name)
+	}
+
+	// 与配置文档共用校验和审计逻辑
+	_, err := svc.ConfigDocumentService.Apply(&services.ConfigDocument{
+		Version: services.ConfigDocumentVersion,
+		Groups:  []services.GroupDocument{*doc},
+	}, services.ConfigApplyOptions{Actor: cliActor()})
+	if err != nil {
+		logrus.Fatalf("Failed to create group: %v", err)
+	}
+	fmt.Printf("Group '%s' created\n", doc.Name)
+}
+
+func runGroupDelete(svc *commandServices, name string, yes bool) {
+	var group models.Group
+	if err := svc.DB.Where("name = ?", name).First(&group).Error; err != nil {
+		logrus.Fatalf("Group '%s' not found: %v", name, err)
+	}
+	if !yes {
+		var keyCount int64
+		svc.DB.Model(&models.APIKey{}).Where("group_id = ?", group.ID).Count(&keyCount)
+		fmt.Printf("Group '%s' and its %d keys will be deleted. Re-run with --yes to confirm.\n", name, keyCount)
+		os.Exit(1)
+	}
+
+	deletedKeys, err := svc.KeyService.DeleteGroup(group.ID)
+	if err != nil {
+		logrus.Fatalf("Failed to delete group: %v", err)
+	}
+	if err := svc.GroupManager.Invalidate(); err != nil {
+		logrus.WithError(err).Error("failed to invalidate group cache")
+	}
+
+	svc.record(services.AuditEntry{
+		Action:     models.AuditActionGroupDelete,
+		TargetType: models.AuditTargetGroup,
+		TargetID:   group.ID,
+		TargetName: group.Name,
+		Before:     &group,
+		Details:    map[string]any{"deleted_keys": deletedKeys},
+	})
+	fmt.Printf("Group '%s' deleted with %d keys\n", name, deletedKeys)
+}
+
+// loadKeyCounts returns the number of keys per group and status.
+func loadKeyCounts(svc *commandServices) map[uint]map[string]int64 {
+	var rows []struct {
+		GroupID uint
+		Status  string
+		Count   int64
+	}
+	if err := svc.DB.Model(&models.APIKey{}).Select("group_id, status, count(*) as count").Group("group_id, status").Scan(&rows).Error; err != nil {
+		logrus.Fatalf("Failed to count keys: %v", err)
+	}
+
+	counts := make(map[uint]map[string]int64)
+	for _, row := range rows {
+		if counts[row.GroupID] == nil {
+			counts[row.GroupID] = make(map[string]int64)
+		}
+		counts[row.GroupID][row.Status] = row.Count
+	}
+	return counts
+}
+
+func readGroupDocument(file string) services.GroupDocument {
+	format := services.ConfigFormatYAML
+	if strings.EqualFold(filepath.Ext(file), ".json") {
+		format = services.ConfigFormatJSON
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		logrus.Fatalf("Failed to read %s: %v", file, err)
+	}
+	var doc services.GroupDocument
+	if err := services.ParseDocument(data, format, &doc); err != nil {
+		logrus.Fatalf("Invalid group definition: %v", err)
+	}
+	return doc
+}
+
+// splitNameArg takes the leading positional name argument, if any, off the arguments.
+func splitNameArg(args []string) (string, []string) {
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		return args[0], args[1:]
+	}
+	return "", args
+}
+
+func requireName(name string) string {
+	if name == "" {
+		fmt.Println("A group name is required")
+		os.Exit(1)
+	}
+	return name
+}
+
+func parseFlags(flagSet *flag.FlagSet, args []string) {
+	if err := flagSet.Parse(args); err != nil {
+		logrus.Fatalf("Parameter parsing failed: %v", err)
+	}
+}
@@ -0,0 +1,189 @@
+package commands
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"gpt-load/internal/models"
+	"gpt-load/internal/services"
+	"io"
+	"os"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RunKey handles the key command entry point
+func RunKey(args []string) {
+	if isHelpArg(args) {
+		printKeyUsage()
+		return
+	}
+
+	subcommand := args[0]
+	keyCmd := flag.NewFlagSet("key "+subcommand, flag.ExitOnError)
+	keyCmd.Usage = printKeyUsage
+	groupName := keyCmd.String("group", "", "Group name")
+
+	switch subcommand {
+	case "import":
+		file := keyCmd.String("file", "", "File with one key per line, '-' for stdin")
+		parseFlags(keyCmd, args[1:])
+		if *file == "" {
+			fmt.Println("--file is required")
+			os.Exit(1)
+		}
+		svc := buildCommandServices()
+		runKeyImport(svc, svc.findGroup(*groupName), *file)
+	case "export":
+		status := keyCmd.String("status", "all", "Key status: all, active or invalid")
+		file := keyCmd.String("file", "", "Output file (default: stdout)")
+		parseFlags(keyCmd, args[1:])
+		runKeyExport(buildCommandServices(), *groupName, *status, *file)
+	case "validate":
+		status := keyCmd.String("status", "", "Only validate keys with this status: active or invalid (default: all)")
+		parseFlags(keyCmd, args[1:])
+		if *status != "" && *status != models.KeyStatusActive && *status != models.KeyStatusInvalid {
+			logrus.Fatalf("Invalid status %q", *status)
+		}
+		svc := buildCommandServices()
+		runKeyValidate(svc, svc.findGroup(*groupName), *status)
+	case "reencrypt":
+		parseFlags(keyCmd, args[1:])
+		runKeyReencrypt(buildCommandServices())
+	default:
+		fmt.Printf("Unknown key subcommand: %s\n", subcommand)
+		printKeyUsage()
+		os.Exit(1)
+	}
+}
+
+func printKeyUsage() {
+	fmt.Println("GPT-Load Key Administration")
+	fmt.Println()
+	fmt.Println("Usage:")
+	fmt.Println("  gpt-load key import   --group <name> --file keys.txt")
+	fmt.Println("  gpt-load key export   [--group <name>] [--status all|active|invalid] [--file keys.txt]")
+	fmt.Println("  gpt-load key validate --group <name> [--status active|invalid]")
+	fmt.Println("  gpt-load key reencrypt")
+	fmt.Println()
+	fmt.Println("Without --group, export writes the keys of every group.")
+	fmt.Println("Reencrypt rewrites keys and request logs still encrypted with ENCRYPTION_PREVIOUS_KEYS.")
+}
+
+func runKeyImport(svc *commandServices, group *models.Group, file string) {
+	var data []byte
+	var err error
+	if file == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(file)
+	}
+	if err != nil {
+		logrus.Fatalf("Failed to read %s: %v", file, err)
+	}
+
+	if _, err := svc.KeyImportService.StartImportTask(group, string(data)); err != nil {
+		logrus.Fatalf("Failed to start import: %v", err)
+	}
+	status := finishTask(svc)
+
+	svc.record(services.AuditEntry{
+		Action:     models.AuditActionKeysAdd,
+		TargetType: models.AuditTargetGroup,
+		TargetID:   group.ID,
+		TargetName: group.Name,
+		Details:    status.Result,
+	})
+	printTaskResult(status)
+}
+
+func runKeyExport(svc *commandServices, groupName, status, file string) {
+	var groups []models.Group
+	query := svc.DB.Order("sort asc, id asc")
+	if groupName != "" {
+		query = query.Where("name = ?", groupName)
+	}
+	if err := query.Find(&groups).Error; err != nil {
+		logrus.Fatalf("Failed to load groups: %v", err)
+	}
+	if groupName != "" && len(groups) == 0 {
+		logrus.Fatalf("Group '%s' not found", groupName)
+	}
+
+	output := os.Stdout
+	if file != "" {
+		var err error
+		if output, err = os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600); err != nil {
+			logrus.Fatalf("Failed to create %s: %v", file, err)
+		}
+		defer output.Close()
+	}
+
+	writer := bufio.NewWriter(output)
+	for _, group := range groups {
+		if err := svc.KeyService.StreamKeysToWriter(group.ID, status, writer); err != nil {
+			logrus.Fatalf("Failed to export keys of group '%s': %v", group.Name, err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		logrus.Fatalf("Failed to write keys: %v", err)
+	}
+}
+
+func runKeyValidate(svc *commandServices, group *models.Group, status string) {
+	if _, err := svc.KeyManualValidationService.StartValidationTask(group, status); err != nil {
+		logrus.Fatalf("Failed to start validation: %v", err)
+	}
+	svc.record(services.AuditEntry{
+		Action:     models.AuditActionKeysValidate,
+		TargetType: models.AuditTargetGroup,
+		TargetID:   group.ID,
+		TargetName: group.Name,
+		Details:    map[string]any{"status": status},
+	})
+	printTaskResult(finishTask(svc))
+}
+
+func runKeyReencrypt(svc *commandServices) {
+	status, err := svc.KeyReencryptService.GetStatus()
+	if err != nil {
+		logrus.Fatalf("Failed to get encryption status: %v", err)
+	}
+	if !status.Enabled {
+		logrus.Fatal(services.ErrEncryptionDisabled)
+	}
+	if status.PendingKeys == 0 && status.PendingLogs == 0 {
+		fmt.Printf("Everything is encrypted with key %s.\n", status.KeyID)
+		return
+	}
+
+	if _, err := svc.KeyReencryptService.StartReencryptTask(); err != nil {
+		logrus.Fatalf("Failed to start re-encryption: %v", err)
+	}
+	taskStatus := finishTask(svc)
+
+	svc.record(services.AuditEntry{
+		Action:     models.AuditActionKeysReencrypt,
+		TargetType: models.AuditTargetKeys,
+		TargetName: status.KeyID,
+		Details:    taskStatus.Result,
+	})
+	printTaskResult(taskStatus)
+}
+
+// finishTask waits for the background task and exits if it failed.
+func finishTask(svc *commandServices) *services.TaskStatus {
+	status := svc.waitForTask()
+	// 验证结果通过异步的状态更新写入，退出前需等待其完成
+	svc.KeyProvider.WaitForUpdates()
+	if status.Error != "" {
+		logrus.Fatalf("%s failed: %s", status.TaskType, status.Error)
+	}
+	return status
+}
+
+func printTaskResult(status *services.TaskStatus) {
+	data, _ := json.MarshalIndent(status.Result, "", "  ")
+	fmt.Println(string(data))
+}
@@ -0,0 +1,91 @@
+package commands
+
+import (
+	"flag"
+	"fmt"
+	"gpt-load/internal/models"
+	"gpt-load/internal/services"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RunLogs handles the logs command entry point
+func RunLogs(args []string) {
+	if isHelpArg(args) {
+		printLogsUsage()
+		return
+	}
+
+	subcommand := args[0]
+	logsCmd := flag.NewFlagSet("logs "+subcommand, flag.ExitOnError)
+	logsCmd.Usage = printLogsUsage
+
+	switch subcommand {
+	case "purge":
+		before := logsCmd.String("before", "", "Delete request logs older than this time, e.g. 2025-01-31 or 2025-01-31T00:00:00Z")
+		dryRun := logsCmd.Bool("dry-run", false, "Only count the logs that would be deleted")
+		parseFlags(logsCmd, args[1:])
+		if *before == "" {
+			fmt.Println("--before is required")
+			os.Exit(1)
+		}
+		cutoff, err := parseCutoffTime(*before)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		runLogsPurge(buildCommandServices(), cutoff, *dryRun)
+	default:
+		fmt.Printf("Unknown logs subcommand: %s\n", subcommand)
+		printLogsUsage()
+		os.Exit(1)
+	}
+}
+
+func printLogsUsage() {
+	fmt.Println("GPT-Load Request Logs")
+	fmt.Println()
+	fmt.Println("Usage:")
+	fmt.Println("  gpt-load logs purge --before 2025-01-31 [--dry-run]")
+	fmt.Println()
+	fmt.Println("Dates without a time are interpreted as midnight UTC. When request log archiving is")
+	fmt.Println("enabled the logs are written to the archive directory before they are deleted.")
+}
+
+func parseCutoffTime(value string) (time.Time, error) {
+	if cutoff, err := time.Parse(time.RFC3339, value); err == nil {
+		return cutoff, nil
+	}
+	if cutoff, err := time.Parse(time.DateOnly, value); err == nil {
+		return cutoff, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q, expected YYYY-MM-DD or RFC 3339", value)
+}
+
+func runLogsPurge(svc *commandServices, cutoff time.Time, dryRun bool) {
+	if dryRun {
+		var count int64
+		if err := svc.DB.Model(&models.RequestLog{}).Where("timestamp < ?", cutoff).Count(&count).Error; err != nil {
+			logrus.Fatalf("Failed to count request logs: %v", err)
+		}
+		fmt.Printf("%d request logs before %s would be deleted\n", count, cutoff.Format(time.RFC3339))
+		return
+	}
+
+	archived := svc.SettingsManager.GetSettings().RequestLogArchiveEnabled
+	deleted, err := svc.LogCleanupService.PurgeLogsBefore(cutoff)
+	if err != nil {
+		logrus.Fatalf("Failed to purge request logs: %v", err)
+	}
+	svc.record(services.AuditEntry{
+		Action:     models.AuditActionLogsPurge,
+		TargetType: models.AuditTargetLogs,
+		Details:    map[string]any{"before": cutoff.Format(time.RFC3339), "deleted_count": deleted, "archived": archived},
+	})
+	if archived {
+		fmt.Printf("Archived and deleted %d request logs before %s\n", deleted, cutoff.Format(time.RFC3339))
+		return
+	}
+	fmt.Printf("Deleted %d request logs before %s\n", deleted, cutoff.Format(time.RFC3339))
+}
@@ -0,0 +1,91 @@
+package commands
+
+import (
+	"flag"
+	"fmt"
+	db "gpt-load/internal/db/migrations"
+	"os"
+	"text/tabwriter"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RunMigrate handles the migrate command entry point
+func RunMigrate(args []string) {
+	if isHelpArg(args) {
+		printMigrateUsage()
+		return
+	}
+
+	subcommand := args[0]
+	migrateCmd := flag.NewFlagSet("migrate "+subcommand, flag.ExitOnError)
+	migrateCmd.Usage = printMigrateUsage
+	parseFlags(migrateCmd, args[1:])
+
+	switch subcommand {
+	case "status":
+		runMigrateStatus()
+	case "up":
+		runMigrateUp()
+	default:
+		fmt.Printf("Unknown migrate subcommand: %s\n", subcommand)
+		printMigrateUsage()
+		os.Exit(1)
+	}
+}
+
+func printMigrateUsage() {
+	fmt.Println("GPT-Load Schema Migration")
+	fmt.Println()
+	fmt.Println("Usage:")
+	fmt.Println("  gpt-load migrate status   Show the applied and pending migrations")
+	fmt.Println("  gpt-load migrate up       Apply the pending migrations")
+	fmt.Println()
+	fmt.Println("The master node also applies pending migrations on startup.")
+}
+
+func runMigrateStatus() {
+	statuses, err := db.Status(openDatabase())
+	if err != nil {
+		logrus.Fatalf("Failed to load migration status: %v", err)
+	}
+
+	pending := 0
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "VERSION\tDESCRIPTION\tSTATUS\tAPPLIED AT")
+	for _, status := range statuses {
+		state, appliedAt := "pending", ""
+		if status.AppliedAt != nil {
+			appliedAt = status.AppliedAt.Local().Format("2006-01-02 15:04:05")
+			switch {
+			case status.Unknown:
+				state = "applied (unknown)"
+			case status.Baseline:
+				state = "baseline"
+			default:
+				state = "applied"
+			}
+		} else {
+			pending++
+		}
+		fmt.Fprintf(writer, "%d\t%s\t%s\t%s\n", status.Version, status.Description, state, appliedAt)
+	}
+	writer.Flush()
+
+	fmt.Println()
+	fmt.Printf("Latest schema version %d, %d pending\n", db.SchemaVersion(), pending)
+}
+
+func runMigrateUp() {
+	applied, err := db.Migrate(openDatabase())
+	if err != nil {
+		logrus.Fatalf("Migration failed: %v", err)
+	}
+	if len(applied) == 0 {
+		fmt.Println("No pending migrations. The schema is up to date.")
+		return
+	}
+	for _, migration := range applied {
+		fmt.Printf("Applied %d: %s\n", migration.Version, migration.Description)
+	}
+}
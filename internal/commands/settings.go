@@ -0,0 +1,130 @@
+package commands
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"gpt-load/internal/services"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RunSettings handles the settings command entry point
+func RunSettings(args []string) {
+	if isHelpArg(args) {
+		printSettingsUsage()
+		return
+	}
+
+	subcommand := args[0]
+	settingsCmd := flag.NewFlagSet("settings "+subcommand, flag.ExitOnError)
+	settingsCmd.Usage = printSettingsUsage
+	parseFlags(settingsCmd, args[1:])
+
+	switch subcommand {
+	case "get":
+		runSettingsGet(buildCommandServices(), settingsCmd.Args())
+	case "set":
+		if settingsCmd.NArg() == 0 {
+			printSettingsUsage()
+			os.Exit(1)
+		}
+		runSettingsSet(buildCommandServices(), settingsCmd.Args())
+	default:
+		fmt.Printf("Unknown settings subcommand: %s\n", subcommand)
+		printSettingsUsage()
+		os.Exit(1)
+	}
+}
+
+func printSettingsUsage() {
+	fmt.Println("GPT-Load System Settings")
+	fmt.Println()
+	fmt.Println("Usage:")
+	fmt.Println("  gpt-load settings get [key...]")
+	fmt.Println("  gpt-load settings set <key>=<value> [<key>=<value>...]")
+	fmt.Println()
+	fmt.Println("Example: gpt-load settings set request_timeout=300 enable_request_body_logging=false")
+}
+
+// currentSettings returns the current system settings keyed by their JSON names.
+func currentSettings(svc *commandServices) map[string]any {
+	data, err := json.Marshal(svc.SettingsManager.GetSettings())
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	var settings map[string]any
+	if err := json.Unmarshal(data, &settings); err != nil {
+		logrus.Fatal(err)
+	}
+	return settings
+}
+
+func runSettingsGet(svc *commandServices, keys []string) {
+	settings := currentSettings(svc)
+	if len(keys) == 1 {
+		value, ok := settings[keys[0]]
+		if !ok {
+			logrus.Fatalf("Unknown setting: %s", keys[0])
+		}
+		fmt.Println(value)
+		return
+	}
+
+	if len(keys) == 0 {
+		for key := range settings {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+	}
+	for _, key := range keys {
+		value, ok := settings[key]
+		if !ok {
+			logrus.Fatalf("Unknown setting: %s", key)
+		}
+		fmt.Printf("%s=%v\n", key, value)
+	}
+}
+
+func runSettingsSet(svc *commandServices, assignments []string) {
+	current := currentSettings(svc)
+	updates := make(map[string]any, len(assignments))
+	for _, assignment := range assignments {
+		key, raw, ok := strings.Cut(assignment, "=")
+		if !ok {
+			logrus.Fatalf("Invalid assignment %q, expected key=value", assignment)
+		}
+
+		// 按当前值的类型解析，与设置接口提交的 JSON 类型一致
+		switch current[key].(type) {
+		case float64:
+			value, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				logrus.Fatalf("Invalid value for %s: expected a number", key)
+			}
+			updates[key] = value
+		case bool:
+			value, err := strconv.ParseBool(raw)
+			if err != nil {
+				logrus.Fatalf("Invalid value for %s: expected true or false", key)
+			}
+			updates[key] = value
+		default:
+			updates[key] = raw
+		}
+	}
+
+	// 与配置文档共用校验和审计逻辑
+	plan, err := svc.ConfigDocumentService.Apply(&services.ConfigDocument{
+		Version:  services.ConfigDocumentVersion,
+		Settings: updates,
+	}, services.ConfigApplyOptions{Actor: cliActor()})
+	if err != nil {
+		logrus.Fatalf("Failed to update settings: %v", err)
+	}
+	printConfigPlan(plan)
+}
@@ -3,10 +3,14 @@ package config
 
 import (
 	"fmt"
+	"net"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
 
 	"gpt-load/internal/errors"
+	"gpt-load/internal/models"
 	"gpt-load/internal/types"
 	"gpt-load/internal/utils"
 
@@ -42,14 +46,17 @@ type Manager struct {
 
 // Config represents the application configuration
 type Config struct {
-	Server        types.ServerConfig
-	Auth          types.AuthConfig
-	CORS          types.CORSConfig
-	Performance   types.PerformanceConfig
-	Log           types.LogConfig
-	Database      types.DatabaseConfig
-	RedisDSN      string
-	EncryptionKey string
+	Server      types.ServerConfig
+	Auth        types.AuthConfig
+	OIDC        types.OIDCConfig
+	CORS        types.CORSConfig
+	Performance types.PerformanceConfig
+	Log         types.LogConfig
+	Database    types.DatabaseConfig
+	Tracing     types.TracingConfig
+	LogSinks    types.LogSinkConfig
+	RedisDSN    string
+	Encryption  types.EncryptionConfig
 }
 
 // NewManager creates a new configuration manager
@@ -78,9 +85,27 @@ func (m *Manager) ReloadConfig() error {
 			WriteTimeout:            utils.ParseInteger(os.Getenv("SERVER_WRITE_TIMEOUT"), 600),
 			IdleTimeout:             utils.ParseInteger(os.Getenv("SERVER_IDLE_TIMEOUT"), 120),
 			GracefulShutdownTimeout: utils.ParseInteger(os.Getenv("SERVER_GRACEFUL_SHUTDOWN_TIMEOUT"), 10),
+			TrustedProxies:          utils.ParseArray(os.Getenv("TRUSTED_PROXIES"), nil),
 		},
 		Auth: types.AuthConfig{
-			Key: os.Getenv("AUTH_KEY"),
+			Key:                     os.Getenv("AUTH_KEY"),
+			MetricsToken:            os.Getenv("METRICS_TOKEN"),
+			SessionTTLMinutes:       utils.ParseInteger(os.Getenv("ADMIN_SESSION_TTL_MINUTES"), 720),
+			SessionMaxLifetimeHours: utils.ParseInteger(os.Getenv("ADMIN_SESSION_MAX_LIFETIME_HOURS"), 168),
+			LoginMaxAttempts:        utils.ParseInteger(os.Getenv("LOGIN_MAX_ATTEMPTS"), 5),
+			LoginLockoutMinutes:     utils.ParseInteger(os.Getenv("LOGIN_LOCKOUT_MINUTES"), 15),
+		},
+		OIDC: types.OIDCConfig{
+			Enabled:       utils.ParseBoolean(os.Getenv("OIDC_ENABLED"), false),
+			IssuerURL:     strings.TrimSuffix(os.Getenv("OIDC_ISSUER_URL"), "/"),
+			ClientID:      os.Getenv("OIDC_CLIENT_ID"),
+			ClientSecret:  os.Getenv("OIDC_CLIENT_SECRET"),
+			RedirectURL:   os.Getenv("OIDC_REDIRECT_URL"),
+			Scopes:        utils.ParseArray(os.Getenv("OIDC_SCOPES"), []string{"openid", "profile", "email"}),
+			ProviderName:  utils.GetEnvOrDefault("OIDC_PROVIDER_NAME", "SSO"),
+			UsernameClaim: utils.GetEnvOrDefault("OIDC_USERNAME_CLAIM", "preferred_username"),
+			RoleClaim:     utils.GetEnvOrDefault("OIDC_ROLE_CLAIM", "groups"),
+			DefaultRole:   os.Getenv("OIDC_DEFAULT_ROLE"),
 		},
 		CORS: types.CORSConfig{
 			Enabled:          utils.ParseBoolean(os.Getenv("ENABLE_CORS"), false),
@@ -91,6 +116,7 @@ func (m *Manager) ReloadConfig() error {
 		},
 		Performance: types.PerformanceConfig{
 			MaxConcurrentRequests: utils.ParseInteger(os.Getenv("MAX_CONCURRENT_REQUESTS"), 100),
+			MaxConcurrentWait:     utils.ParseInteger(os.Getenv("MAX_CONCURRENT_WAIT_SECONDS"), 0),
 		},
 		Log: types.LogConfig{
 			Level:      utils.GetEnvOrDefault("LOG_LEVEL", "info"),
@@ -101,9 +127,70 @@ func (m *Manager) ReloadConfig() error {
 		Database: types.DatabaseConfig{
 			DSN: utils.GetEnvOrDefault("DATABASE_DSN", "./data/gpt-load.db"),
 		},
-		RedisDSN:      os.Getenv("REDIS_DSN"),
-		EncryptionKey: os.Getenv("ENCRYPTION_KEY"),
+		Tracing: types.TracingConfig{
+			Enabled:     utils.ParseBoolean(os.Getenv("TRACING_ENABLED"), false),
+			Endpoint:    utils.GetEnvOrDefault("OTEL_EXPORTER_OTLP_ENDPOINT", "localhost:4318"),
+			Insecure:    utils.ParseBoolean(os.Getenv("OTEL_EXPORTER_OTLP_INSECURE"), true),
+			ServiceName: utils.GetEnvOrDefault("OTEL_SERVICE_NAME", "gpt-load"),
+			SampleRatio: utils.ParseFloat(os.Getenv("TRACING_SAMPLE_RATIO"), 1.0),
+		},
+		LogSinks: types.LogSinkConfig{
+			DatabaseEnabled: utils.ParseBoolean(os.Getenv("REQUEST_LOG_DB_ENABLED"), true),
+			ArchiveDir:      utils.GetEnvOrDefault("REQUEST_LOG_ARCHIVE_DIR", "./data/archives"),
+			File: types.FileSinkConfig{
+				SinkFieldConfig: parseSinkFieldConfig("LOG_SINK_FILE"),
+				Enabled:         utils.ParseBoolean(os.Getenv("LOG_SINK_FILE_ENABLED"), false),
+				Path:            utils.GetEnvOrDefault("LOG_SINK_FILE_PATH", "./data/logs/requests.jsonl"),
+				MaxSizeMB:       utils.ParseInteger(os.Getenv("LOG_SINK_FILE_MAX_SIZE_MB"), 100),
+				MaxBackups:      utils.ParseInteger(os.Getenv("LOG_SINK_FILE_MAX_BACKUPS"), 7),
+			},
+			Syslog: types.SyslogSinkConfig{
+				SinkFieldConfig: parseSinkFieldConfig("LOG_SINK_SYSLOG"),
+				Enabled:         utils.ParseBoolean(os.Getenv("LOG_SINK_SYSLOG_ENABLED"), false),
+				Network:         utils.GetEnvOrDefault("LOG_SINK_SYSLOG_NETWORK", "udp"),
+				Address:         utils.GetEnvOrDefault("LOG_SINK_SYSLOG_ADDRESS", "localhost:514"),
+				AppName:         utils.GetEnvOrDefault("LOG_SINK_SYSLOG_APP_NAME", "gpt-load"),
+				MaxMessageBytes: utils.ParseInteger(os.Getenv("LOG_SINK_SYSLOG_MAX_MESSAGE_BYTES"), 8192),
+			},
+			HTTP: types.HTTPSinkConfig{
+				SinkFieldConfig:      parseSinkFieldConfig("LOG_SINK_HTTP"),
+				Enabled:              utils.ParseBoolean(os.Getenv("LOG_SINK_HTTP_ENABLED"), false),
+				URL:                  os.Getenv("LOG_SINK_HTTP_URL"),
+				Headers:              utils.ParseArray(os.Getenv("LOG_SINK_HTTP_HEADERS"), nil),
+				BatchSize:            utils.ParseInteger(os.Getenv("LOG_SINK_HTTP_BATCH_SIZE"), 100),
+				FlushIntervalSeconds: utils.ParseInteger(os.Getenv("LOG_SINK_HTTP_FLUSH_INTERVAL_SECONDS"), 5),
+				MaxRetries:           utils.ParseInteger(os.Getenv("LOG_SINK_HTTP_MAX_RETRIES"), 3),
+				TimeoutSeconds:       utils.ParseInteger(os.Getenv("LOG_SINK_HTTP_TIMEOUT_SECONDS"), 10),
+			},
+		},
+		RedisDSN: os.Getenv("REDIS_DSN"),
+		Encryption: types.EncryptionConfig{
+			Backend:          strings.ToLower(utils.GetEnvOrDefault("ENCRYPTION_BACKEND", types.EncryptionBackendLocal)),
+			Key:              os.Getenv("ENCRYPTION_KEY"),
+			PreviousKeys:     utils.ParseArray(os.Getenv("ENCRYPTION_PREVIOUS_KEYS"), nil),
+			DataKey:          os.Getenv("ENCRYPTION_DATA_KEY"),
+			PreviousDataKeys: utils.ParseArray(os.Getenv("ENCRYPTION_PREVIOUS_DATA_KEYS"), nil),
+			KMSTimeout:       utils.ParseInteger(os.Getenv("ENCRYPTION_KMS_TIMEOUT_SECONDS"), 10),
+			Vault: types.VaultTransitConfig{
+				Address:   os.Getenv("VAULT_ADDR"),
+				Token:     os.Getenv("VAULT_TOKEN"),
+				Namespace: os.Getenv("VAULT_NAMESPACE"),
+				Mount:     utils.GetEnvOrDefault("VAULT_TRANSIT_MOUNT", "transit"),
+				KeyName:   utils.GetEnvOrDefault("VAULT_TRANSIT_KEY", "gpt-load"),
+			},
+			Envelope: types.EnvelopeKMSConfig{
+				URL:   os.Getenv("KMS_URL"),
+				Token: os.Getenv("KMS_TOKEN"),
+				KeyID: os.Getenv("KMS_KEY_ID"),
+			},
+		},
+	}
+	roleMappings, err := parseOIDCRoleMappings(os.Getenv("OIDC_ROLE_MAPPING"))
+	if err != nil {
+		return err
 	}
+	config.OIDC.RoleMappings = roleMappings
+
 	m.config = config
 
 	// Validate configuration
@@ -124,6 +211,11 @@ func (m *Manager) GetAuthConfig() types.AuthConfig {
 	return m.config.Auth
 }
 
+// GetOIDCConfig returns OpenID Connect configuration
+func (m *Manager) GetOIDCConfig() types.OIDCConfig {
+	return m.config.OIDC
+}
+
 // GetCORSConfig returns CORS configuration
 func (m *Manager) GetCORSConfig() types.CORSConfig {
 	return m.config.CORS
@@ -134,6 +226,114 @@ func (m *Manager) GetPerformanceConfig() types.PerformanceConfig {
 	return m.config.Performance
 }
 
+// GetLogSinkConfig returns request log sink configuration
+func (m *Manager) GetLogSinkConfig() types.LogSinkConfig {
+	return m.config.LogSinks
+}
+
+// parseSinkFieldConfig reads the field selection and redaction settings of a sink.
+// Key values are always redacted unless <PREFIX>_INCLUDE_KEY_VALUE is enabled.
+func parseSinkFieldConfig(prefix string) types.SinkFieldConfig {
+	return types.SinkFieldConfig{
+		Fields:          utils.ParseArray(os.Getenv(prefix+"_FIELDS"), nil),
+		Redact:          utils.ParseArray(os.Getenv(prefix+"_REDACT"), nil),
+		IncludeKeyValue: utils.ParseBoolean(os.Getenv(prefix+"_INCLUDE_KEY_VALUE"), false),
+	}
+}
+
+// parseOIDCRoleMappings parses OIDC_ROLE_MAPPING entries of the form "claim_value=role",
+// where group_operator takes its groups as "claim_value=group_operator:1|2".
+func parseOIDCRoleMappings(value string) ([]types.OIDCRoleMapping, error) {
+	var mappings []types.OIDCRoleMapping
+	for _, entry := range utils.ParseArray(value, nil) {
+		claimValue, role, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(claimValue) == "" {
+			return nil, errors.NewAPIError(errors.ErrValidation, fmt.Sprintf("invalid OIDC_ROLE_MAPPING entry '%s', expected claim_value=role", entry))
+		}
+
+		mapping := types.OIDCRoleMapping{ClaimValue: strings.TrimSpace(claimValue)}
+		role, groups, _ := strings.Cut(strings.TrimSpace(role), ":")
+		mapping.Role = role
+		for _, group := range utils.ParseArray(strings.ReplaceAll(groups, "|", ","), nil) {
+			groupID, err := strconv.ParseUint(group, 10, 32)
+			if err != nil {
+				return nil, errors.NewAPIError(errors.ErrValidation, fmt.Sprintf("invalid group ID '%s' in OIDC_ROLE_MAPPING", group))
+			}
+			mapping.GroupIDs = append(mapping.GroupIDs, uint(groupID))
+		}
+		mappings = append(mappings, mapping)
+	}
+	return mappings, nil
+}
+
+// validateOIDCConfig checks the OIDC settings and role mappings.
+func validateOIDCConfig(oidc types.OIDCConfig) []string {
+	var validationErrors []string
+	if oidc.IssuerURL == "" || oidc.ClientID == "" {
+		validationErrors = append(validationErrors, "OIDC_ISSUER_URL and OIDC_CLIENT_ID are required when OIDC_ENABLED is true")
+	}
+	if !slices.Contains(oidc.Scopes, "openid") {
+		validationErrors = append(validationErrors, "OIDC_SCOPES must include 'openid'")
+	}
+
+	isRole := func(role string) bool {
+		return slices.Contains([]string{models.AdminRoleOwner, models.AdminRoleOperator, models.AdminRoleGroupOperator, models.AdminRoleViewer}, role)
+	}
+	for _, mapping := range oidc.RoleMappings {
+		switch {
+		case !isRole(mapping.Role):
+			validationErrors = append(validationErrors, fmt.Sprintf("OIDC_ROLE_MAPPING has unknown role '%s'", mapping.Role))
+		case mapping.Role == models.AdminRoleGroupOperator && len(mapping.GroupIDs) == 0:
+			validationErrors = append(validationErrors, fmt.Sprintf("OIDC_ROLE_MAPPING entry '%s' needs group IDs, e.g. %s=group_operator:1|2", mapping.ClaimValue, mapping.ClaimValue))
+		case mapping.Role != models.AdminRoleGroupOperator && len(mapping.GroupIDs) > 0:
+			validationErrors = append(validationErrors, fmt.Sprintf("OIDC_ROLE_MAPPING entry '%s' can only list groups for the group_operator role", mapping.ClaimValue))
+		}
+	}
+	if oidc.DefaultRole != "" && (!isRole(oidc.DefaultRole) || oidc.DefaultRole == models.AdminRoleGroupOperator) {
+		validationErrors = append(validationErrors, "OIDC_DEFAULT_ROLE must be empty, owner, operator or viewer")
+	}
+	return validationErrors
+}
+
+// validateEncryptionConfig checks the encryption backend and its keys.
+func validateEncryptionConfig(encryption types.EncryptionConfig) []string {
+	var validationErrors []string
+	switch encryption.Backend {
+	case types.EncryptionBackendLocal:
+		if encryption.Key == "" && len(encryption.PreviousKeys) > 0 {
+			validationErrors = append(validationErrors, "ENCRYPTION_PREVIOUS_KEYS requires ENCRYPTION_KEY, use the migrate-keys command to disable encryption")
+		}
+		if encryption.DataKey != "" || len(encryption.PreviousDataKeys) > 0 {
+			validationErrors = append(validationErrors, "ENCRYPTION_DATA_KEY requires ENCRYPTION_BACKEND to be vault or envelope")
+		}
+		return validationErrors
+	case types.EncryptionBackendVault:
+		if encryption.Vault.Address == "" || encryption.Vault.Token == "" {
+			validationErrors = append(validationErrors, "VAULT_ADDR and VAULT_TOKEN are required when ENCRYPTION_BACKEND is vault")
+		}
+	case types.EncryptionBackendEnvelope:
+		if encryption.Envelope.URL == "" {
+			validationErrors = append(validationErrors, "KMS_URL is required when ENCRYPTION_BACKEND is envelope")
+		}
+	default:
+		return append(validationErrors, fmt.Sprintf("ENCRYPTION_BACKEND must be local, vault or envelope, got '%s'", encryption.Backend))
+	}
+
+	// 使用 KMS 时本地密钥只能作为旧密钥，用于迁移已有数据
+	if encryption.Key != "" {
+		validationErrors = append(validationErrors, fmt.Sprintf("ENCRYPTION_KEY cannot be used with ENCRYPTION_BACKEND %s, move it to ENCRYPTION_PREVIOUS_KEYS to re-encrypt the existing keys", encryption.Backend))
+	}
+	if encryption.KMSTimeout < 1 {
+		validationErrors = append(validationErrors, "ENCRYPTION_KMS_TIMEOUT_SECONDS must be at least 1")
+	}
+	return validationErrors
+}
+
+// GetTracingConfig returns tracing configuration
+func (m *Manager) GetTracingConfig() types.TracingConfig {
+	return m.config.Tracing
+}
+
 // GetLogConfig returns logging configuration
 func (m *Manager) GetLogConfig() types.LogConfig {
 	return m.config.Log
@@ -151,7 +351,12 @@ func (m *Manager) GetDatabaseConfig() types.DatabaseConfig {
 
 // GetEncryptionKey returns the encryption key.
 func (m *Manager) GetEncryptionKey() string {
-	return m.config.EncryptionKey
+	return m.config.Encryption.Key
+}
+
+// GetEncryptionConfig returns the encryption keys, the primary key and the keys it replaced.
+func (m *Manager) GetEncryptionConfig() types.EncryptionConfig {
+	return m.config.Encryption
 }
 
 // GetEffectiveServerConfig returns server configuration merged with system settings
@@ -168,10 +373,22 @@ func (m *Manager) Validate() error {
 		validationErrors = append(validationErrors, fmt.Sprintf("port must be between %d-%d", DefaultConstants.MinPort, DefaultConstants.MaxPort))
 	}
 
+	for _, proxy := range m.config.Server.TrustedProxies {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				validationErrors = append(validationErrors, fmt.Sprintf("invalid TRUSTED_PROXIES entry '%s', expected an IP address or CIDR", proxy))
+			}
+		}
+	}
+
 	if m.config.Performance.MaxConcurrentRequests < 1 {
 		validationErrors = append(validationErrors, "max concurrent requests cannot be less than 1")
 	}
 
+	if m.config.Performance.MaxConcurrentWait < 0 {
+		validationErrors = append(validationErrors, "max concurrent wait cannot be negative")
+	}
+
 	// Validate auth key
 	if m.config.Auth.Key == "" {
 		validationErrors = append(validationErrors, "AUTH_KEY is required and cannot be empty")
@@ -179,12 +396,46 @@ func (m *Manager) Validate() error {
 		utils.ValidatePasswordStrength(m.config.Auth.Key, "AUTH_KEY")
 	}
 
+	if m.config.Auth.SessionTTLMinutes < 1 {
+		validationErrors = append(validationErrors, "ADMIN_SESSION_TTL_MINUTES must be at least 1")
+	}
+	if m.config.Auth.SessionMaxLifetimeHours*60 < m.config.Auth.SessionTTLMinutes {
+		validationErrors = append(validationErrors, "ADMIN_SESSION_MAX_LIFETIME_HOURS cannot be shorter than ADMIN_SESSION_TTL_MINUTES")
+	}
+	if m.config.Auth.LoginMaxAttempts < 0 || m.config.Auth.LoginLockoutMinutes < 1 {
+		validationErrors = append(validationErrors, "LOGIN_MAX_ATTEMPTS cannot be negative and LOGIN_LOCKOUT_MINUTES must be at least 1")
+	}
+
+	if m.config.OIDC.Enabled {
+		validationErrors = append(validationErrors, validateOIDCConfig(m.config.OIDC)...)
+	}
+
+	validationErrors = append(validationErrors, validateEncryptionConfig(m.config.Encryption)...)
+
 	// Validate GracefulShutdownTimeout and reset if necessary
 	if m.config.Server.GracefulShutdownTimeout < 10 {
 		logrus.Warnf("SERVER_GRACEFUL_SHUTDOWN_TIMEOUT value %ds is too short, resetting to minimum 10s.", m.config.Server.GracefulShutdownTimeout)
 		m.config.Server.GracefulShutdownTimeout = 10
 	}
 
+	if m.config.Tracing.SampleRatio < 0 || m.config.Tracing.SampleRatio > 1 {
+		validationErrors = append(validationErrors, "TRACING_SAMPLE_RATIO must be between 0 and 1")
+	}
+
+	sinks := m.config.LogSinks
+	if sinks.HTTP.Enabled && sinks.HTTP.URL == "" {
+		validationErrors = append(validationErrors, "LOG_SINK_HTTP_URL is required when LOG_SINK_HTTP_ENABLED is true")
+	}
+	if sinks.Syslog.Enabled && sinks.Syslog.Network != "udp" && sinks.Syslog.Network != "tcp" {
+		validationErrors = append(validationErrors, "LOG_SINK_SYSLOG_NETWORK must be 'udp' or 'tcp'")
+	}
+	if sinks.Syslog.Enabled && sinks.Syslog.MaxMessageBytes < 1024 {
+		validationErrors = append(validationErrors, "LOG_SINK_SYSLOG_MAX_MESSAGE_BYTES must be at least 1024")
+	}
+	if !sinks.DatabaseEnabled && !sinks.File.Enabled && !sinks.Syslog.Enabled && !sinks.HTTP.Enabled {
+		logrus.Warn("REQUEST_LOG_DB_ENABLED is false and no log sink is enabled, request logs will be discarded")
+	}
+
 	if m.config.CORS.Enabled {
 		if len(m.config.CORS.AllowedOrigins) == 0 {
 			validationErrors = append(validationErrors, "CORS is enabled but ALLOWED_ORIGINS is not set. UI will not work from a browser.")
@@ -210,6 +461,8 @@ func (m *Manager) DisplayServerConfig() {
 	corsConfig := m.GetCORSConfig()
 	perfConfig := m.GetPerformanceConfig()
 	logConfig := m.GetLogConfig()
+	tracingConfig := m.GetTracingConfig()
+	sinkConfig := m.GetLogSinkConfig()
 	dbConfig := m.GetDatabaseConfig()
 	redisDSN := m.GetRedisDSN()
 	encryptionKey := m.GetEncryptionKey()
@@ -222,14 +475,37 @@ func (m *Manager) DisplayServerConfig() {
 	logrus.Infof("    Read Timeout: %d seconds", serverConfig.ReadTimeout)
 	logrus.Infof("    Write Timeout: %d seconds", serverConfig.WriteTimeout)
 	logrus.Infof("    Idle Timeout: %d seconds", serverConfig.IdleTimeout)
+	if len(serverConfig.TrustedProxies) > 0 {
+		logrus.Infof("    Trusted Proxies: %s", strings.Join(serverConfig.TrustedProxies, ", "))
+	} else {
+		logrus.Info("    Trusted Proxies: none (client IP is the connection address)")
+	}
 
 	logrus.Info("  --- Performance ---")
 	logrus.Infof("    Max Concurrent Requests: %d", perfConfig.MaxConcurrentRequests)
+	logrus.Infof("    Max Concurrent Wait: %d seconds", perfConfig.MaxConcurrentWait)
 
 	logrus.Info("  --- Security ---")
 	logrus.Infof("    Authentication: enabled (key loaded)")
-	if encryptionKey != "" {
-		logrus.Info("    Encryption: enabled")
+	authConfig := m.GetAuthConfig()
+	logrus.Infof("    Admin Sessions: TTL %d minutes, max lifetime %d hours", authConfig.SessionTTLMinutes, authConfig.SessionMaxLifetimeHours)
+	if authConfig.LoginMaxAttempts > 0 {
+		logrus.Infof("    Login Lockout: %d failed attempts, %d minutes", authConfig.LoginMaxAttempts, authConfig.LoginLockoutMinutes)
+	} else {
+		logrus.Info("    Login Lockout: disabled")
+	}
+	if oidcConfig := m.GetOIDCConfig(); oidcConfig.Enabled {
+		logrus.Infof("    OIDC SSO: enabled (Issuer: %s, %d role mappings)", oidcConfig.IssuerURL, len(oidcConfig.RoleMappings))
+	}
+	if encryptionConfig := m.GetEncryptionConfig(); encryptionConfig.Backend != types.EncryptionBackendLocal {
+		logrus.Infof("    Encryption: enabled (%s backend, %d previous data keys, %d previous keys for rotation)",
+			encryptionConfig.Backend, len(encryptionConfig.PreviousDataKeys), len(encryptionConfig.PreviousKeys))
+	} else if encryptionKey != "" {
+		if previousKeys := len(encryptionConfig.PreviousKeys); previousKeys > 0 {
+			logrus.Infof("    Encryption: enabled (%d previous keys for rotation)", previousKeys)
+		} else {
+			logrus.Info("    Encryption: enabled")
+		}
 	} else {
 		logrus.Warn("    Encryption: disabled - WARNING: Sensitive data may be stored unencrypted, which poses security risks including potential key exposure")
 	}
@@ -247,6 +523,26 @@ func (m *Manager) DisplayServerConfig() {
 		logrus.Infof("    Log File Path: %s", logConfig.FilePath)
 	}
 
+	logrus.Info("  --- Request Log Sinks ---")
+	logrus.Infof("    Database: %t", sinkConfig.DatabaseEnabled)
+	logrus.Infof("    Archive Directory: %s", sinkConfig.ArchiveDir)
+	if sinkConfig.File.Enabled {
+		logrus.Infof("    File: %s (Max Size: %dMB, Backups: %d)", sinkConfig.File.Path, sinkConfig.File.MaxSizeMB, sinkConfig.File.MaxBackups)
+	}
+	if sinkConfig.Syslog.Enabled {
+		logrus.Infof("    Syslog: %s://%s", sinkConfig.Syslog.Network, sinkConfig.Syslog.Address)
+	}
+	if sinkConfig.HTTP.Enabled {
+		logrus.Infof("    HTTP: enabled (Batch Size: %d)", sinkConfig.HTTP.BatchSize)
+	}
+
+	logrus.Info("  --- Tracing ---")
+	if tracingConfig.Enabled {
+		logrus.Infof("    OpenTelemetry: enabled (Endpoint: %s, Sample Ratio: %.2f)", tracingConfig.Endpoint, tracingConfig.SampleRatio)
+	} else {
+		logrus.Info("    OpenTelemetry: disabled")
+	}
+
 	logrus.Info("  --- Dependencies ---")
 	if dbConfig.DSN != "" {
 		logrus.Info("    Database: configured")
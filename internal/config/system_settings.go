@@ -6,17 +6,20 @@ import (
 	"fmt"
 	"gpt-load/internal/db"
 	"gpt-load/internal/models"
+	"gpt-load/internal/redact"
 	"gpt-load/internal/store"
 	"gpt-load/internal/syncer"
 	"gpt-load/internal/types"
 	"gpt-load/internal/utils"
 	"os"
 	"reflect"
+	"slices"
 	"strconv"
 	"strings"
 
 	"github.com/sirupsen/logrus"
 	"gorm.io/datatypes"
+	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
@@ -180,6 +183,17 @@ func (sm *SystemSettingsManager) GetAppUrl() string {
 
 // UpdateSettings 更新系统配置
 func (sm *SystemSettingsManager) UpdateSettings(settingsMap map[string]any) error {
+	if err := sm.SaveSettings(db.DB, settingsMap); err != nil {
+		return err
+	}
+
+	// 触发所有实例重新加载
+	return sm.Invalidate()
+}
+
+// SaveSettings 验证并写入系统配置，但不触发重新加载，供外部事务使用。
+// 事务提交后需调用 Invalidate 使配置生效。
+func (sm *SystemSettingsManager) SaveSettings(tx *gorm.DB, settingsMap map[string]any) error {
 	// 验证配置项
 	if err := sm.ValidateSettings(settingsMap); err != nil {
 		return err
@@ -195,15 +209,18 @@ func (sm *SystemSettingsManager) UpdateSettings(settingsMap map[string]any) erro
 	}
 
 	if len(settingsToUpdate) > 0 {
-		if err := db.DB.Clauses(clause.OnConflict{
+		if err := tx.Clauses(clause.OnConflict{
 			Columns:   []clause.Column{{Name: "setting_key"}},
 			DoUpdates: clause.AssignmentColumns([]string{"setting_value", "updated_at"}),
 		}).Create(&settingsToUpdate).Error; err != nil {
 			return fmt.Errorf("failed to update system settings: %w", err)
 		}
 	}
+	return nil
+}
 
-	// 触发所有实例重新加载
+// Invalidate 触发所有实例重新加载系统配置
+func (sm *SystemSettingsManager) Invalidate() error {
 	return sm.syncer.Invalidate()
 }
 
@@ -289,6 +306,13 @@ func (sm *SystemSettingsManager) ValidateSettings(settingsMap map[string]any) er
 						return fmt.Errorf("value for %s (%d) is below minimum value (%d)", key, intVal, minVal)
 					}
 				}
+				if strings.HasPrefix(trimmedRule, "max=") {
+					maxValStr := strings.TrimPrefix(trimmedRule, "max=")
+					maxVal, _ := strconv.Atoi(maxValStr)
+					if intVal > maxVal {
+						return fmt.Errorf("value for %s (%d) is above maximum value (%d)", key, intVal, maxVal)
+					}
+				}
 			}
 		case reflect.Bool:
 			if _, ok := value.(bool); !ok {
@@ -306,6 +330,15 @@ func (sm *SystemSettingsManager) ValidateSettings(settingsMap map[string]any) er
 						return fmt.Errorf("value for %s is required", key)
 					}
 				}
+				if strings.HasPrefix(trimmedRule, "oneof=") {
+					options := strings.Fields(strings.TrimPrefix(trimmedRule, "oneof="))
+					if !slices.Contains(options, strVal) {
+						return fmt.Errorf("value for %s must be one of: %s", key, strings.Join(options, ", "))
+					}
+				}
+			}
+			if err := validateLogRedactionSetting(key, strVal); err != nil {
+				return err
 			}
 		default:
 			return fmt.Errorf("unsupported type for setting key validation: %s", key)
@@ -363,6 +396,13 @@ func (sm *SystemSettingsManager) ValidateGroupConfigOverrides(configMap map[stri
 						return fmt.Errorf("value for %s (%d) is below minimum value (%d)", key, intVal, minVal)
 					}
 				}
+				if strings.HasPrefix(trimmedRule, "max=") {
+					maxValStr := strings.TrimPrefix(trimmedRule, "max=")
+					maxVal, _ := strconv.Atoi(maxValStr)
+					if intVal > maxVal {
+						return fmt.Errorf("value for %s (%d) is above maximum value (%d)", key, intVal, maxVal)
+					}
+				}
 			}
 		case reflect.String:
 			strVal, ok := value.(string)
@@ -376,6 +416,15 @@ func (sm *SystemSettingsManager) ValidateGroupConfigOverrides(configMap map[stri
 						return fmt.Errorf("value for %s is required", key)
 					}
 				}
+				if strings.HasPrefix(trimmedRule, "oneof=") {
+					options := strings.Fields(strings.TrimPrefix(trimmedRule, "oneof="))
+					if !slices.Contains(options, strVal) {
+						return fmt.Errorf("value for %s must be one of: %s", key, strings.Join(options, ", "))
+					}
+				}
+			}
+			if err := validateLogRedactionSetting(key, strVal); err != nil {
+				return err
 			}
 		case reflect.Bool:
 			_, ok := value.(bool)
@@ -390,6 +439,23 @@ func (sm *SystemSettingsManager) ValidateGroupConfigOverrides(configMap map[stri
 	return nil
 }
 
+// validateLogRedactionSetting checks the detector names and JSON paths of the log redaction settings.
+func validateLogRedactionSetting(key, value string) error {
+	switch key {
+	case "log_redaction_detectors":
+		if err := redact.ValidateDetectors(utils.ParseArray(value, nil)); err != nil {
+			return fmt.Errorf("invalid value for %s: %w", key, err)
+		}
+	case "log_redaction_fields":
+		for _, field := range utils.ParseArray(value, nil) {
+			if _, err := utils.ParseJSONPath(field); err != nil {
+				return fmt.Errorf("invalid value for %s: %w", key, err)
+			}
+		}
+	}
+	return nil
+}
+
 // DisplaySystemConfig displays the current system settings.
 func (sm *SystemSettingsManager) DisplaySystemConfig(settings types.SystemSettings) {
 	logrus.Info("")
@@ -397,6 +463,12 @@ func (sm *SystemSettingsManager) DisplaySystemConfig(settings types.SystemSettin
 	logrus.Info("  --- Basic Settings ---")
 	logrus.Infof("    App URL: %s", settings.AppUrl)
 	logrus.Infof("    Request Log Retention: %d days", settings.RequestLogRetentionDays)
+	if settings.RequestLogFailedRetentionDays > 0 {
+		logrus.Infof("    Failed Request Log Retention: %d days", settings.RequestLogFailedRetentionDays)
+	}
+	if settings.RequestLogArchiveEnabled {
+		logrus.Infof("    Request Log Archive: enabled (%s)", settings.RequestLogArchiveFormat)
+	}
 	logrus.Infof("    Request Log Write Interval: %d minutes", settings.RequestLogWriteIntervalMinutes)
 
 	logrus.Info("  --- Request Behavior ---")
@@ -411,6 +483,14 @@ func (sm *SystemSettingsManager) DisplaySystemConfig(settings types.SystemSettin
 	logrus.Infof("    Max Retries: %d", settings.MaxRetries)
 	logrus.Infof("    Blacklist Threshold: %d", settings.BlacklistThreshold)
 	logrus.Infof("    Key Validation Interval: %d minutes", settings.KeyValidationIntervalMinutes)
+
+	logrus.Info("  --- Alerting ---")
+	if settings.AlertWebhooks != "" {
+		logrus.Infof("    Webhooks: %d configured", len(strings.Split(settings.AlertWebhooks, ",")))
+		logrus.Infof("    Cooldown: %d minutes", settings.AlertCooldownMinutes)
+	} else {
+		logrus.Info("    Webhooks: disabled")
+	}
 	logrus.Info("====================================")
 	logrus.Info("")
 }
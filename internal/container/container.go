@@ -2,6 +2,7 @@
 package container
 
 import (
+	"gpt-load/internal/alert"
 	"gpt-load/internal/app"
 	"gpt-load/internal/channel"
 	"gpt-load/internal/config"
@@ -10,10 +11,13 @@ import (
 	"gpt-load/internal/handler"
 	"gpt-load/internal/httpclient"
 	"gpt-load/internal/keypool"
+	"gpt-load/internal/logsink"
+	"gpt-load/internal/metrics"
 	"gpt-load/internal/proxy"
 	"gpt-load/internal/router"
 	"gpt-load/internal/services"
 	"gpt-load/internal/store"
+	"gpt-load/internal/tracing"
 	"gpt-load/internal/types"
 
 	"go.uber.org/dig"
@@ -28,10 +32,13 @@ func BuildContainer() (*dig.Container, error) {
 		return nil, err
 	}
 	if err := container.Provide(func(configManager types.ConfigManager) (encryption.Service, error) {
-		return encryption.NewService(configManager.GetEncryptionKey())
+		return encryption.New(configManager.GetEncryptionConfig())
 	}); err != nil {
 		return nil, err
 	}
+	if err := container.Provide(tracing.NewTracer); err != nil {
+		return nil, err
+	}
 	if err := container.Provide(db.NewDB); err != nil {
 		return nil, err
 	}
@@ -41,6 +48,14 @@ func BuildContainer() (*dig.Container, error) {
 	if err := container.Provide(store.NewStore); err != nil {
 		return nil, err
 	}
+	if err := container.Provide(metrics.NewMetrics); err != nil {
+		return nil, err
+	}
+	if err := container.Decorate(func(s store.Store, m *metrics.Metrics) store.Store {
+		return store.NewInstrumentedStore(s, m.ObserveStoreOperation)
+	}); err != nil {
+		return nil, err
+	}
 	if err := container.Provide(httpclient.NewHTTPClientManager); err != nil {
 		return nil, err
 	}
@@ -64,9 +79,36 @@ func BuildContainer() (*dig.Container, error) {
 	if err := container.Provide(services.NewKeyDeleteService); err != nil {
 		return nil, err
 	}
+	if err := container.Provide(logsink.NewManager); err != nil {
+		return nil, err
+	}
 	if err := container.Provide(services.NewLogService); err != nil {
 		return nil, err
 	}
+	if err := container.Provide(services.NewAuditService); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(services.NewAdminUserService); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(services.NewAdminSessionService); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(services.NewOIDCService); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(services.NewAPITokenService); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(services.NewConfigDocumentService); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(services.NewKeyReencryptService); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(services.NewLogArchiver); err != nil {
+		return nil, err
+	}
 	if err := container.Provide(services.NewLogCleanupService); err != nil {
 		return nil, err
 	}
@@ -76,6 +118,21 @@ func BuildContainer() (*dig.Container, error) {
 	if err := container.Provide(services.NewGroupManager); err != nil {
 		return nil, err
 	}
+	if err := container.Provide(services.NewRateLimitService); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(services.NewRequestQueueService); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(services.NewResponseCacheService); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(services.NewRequestCoalescer); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(alert.NewService); err != nil {
+		return nil, err
+	}
 	if err := container.Provide(keypool.NewProvider); err != nil {
 		return nil, err
 	}
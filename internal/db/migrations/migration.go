@@ -1,17 +1,217 @@
 package db
 
 import (
+	"fmt"
+	"gpt-load/internal/models"
+	"gpt-load/internal/version"
+	"slices"
+	"time"
+
+	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 )
 
-func MigrateDatabase(db *gorm.DB) error {
-	// Run v1.0.22 migration
-	if err := V1_0_22_DropRetriesColumn(db); err != nil {
-		return err
+const (
+	migrationLockName = "gpt-load:schema-migration"
+	// migrationLockID is the Postgres advisory lock key, an arbitrary constant.
+	migrationLockID      = 7243105
+	migrationLockTimeout = 300
+)
+
+// Models returns the models of every table, in dependency order.
+func Models() []any {
+	return []any{
+		&models.SystemSetting{},
+		&models.Group{},
+		&models.APIKey{},
+		&models.RequestLog{},
+		&models.GroupHourlyStat{},
+		&models.AuditLog{},
+		&models.AdminUser{},
+		&models.APIToken{},
+	}
+}
+
+// SchemaMigration records a migration applied to the database.
+type SchemaMigration struct {
+	Version     int       `gorm:"primaryKey;autoIncrement:false"`
+	Description string    `gorm:"type:varchar(255);not null"`
+	AppVersion  string    `gorm:"type:varchar(32)"`
+	Baseline    bool      `gorm:"not null;default:false"` // 新建数据库时直接记录，未实际执行
+	AppliedAt   time.Time `gorm:"not null"`
+}
+
+// TableName 指定迁移历史表名
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// MigrationStatus is the state of a migration in the database.
+type MigrationStatus struct {
+	Version     int        `json:"version"`
+	Description string     `json:"description"`
+	AppliedAt   *time.Time `json:"applied_at,omitempty"`
+	Baseline    bool       `json:"baseline"`
+	// Unknown marks migrations applied by a newer version that this build does not know.
+	Unknown bool `json:"unknown"`
+}
+
+// Migrate brings the schema of the database up to date and returns the migrations it applied.
+//
+// The tables are created and extended from the models, then the pending migrations of the
+// registry run in order, each in its own transaction together with its history record.
+// A database-level lock makes sure only one node migrates at a time.
+func Migrate(db *gorm.DB) ([]Migration, error) {
+	var applied []Migration
+	err := withMigrationLock(db, func(conn *gorm.DB) error {
+		// 全新的数据库由模型直接建成最新结构，迁移只记录不执行
+		fresh := !conn.Migrator().HasTable(&models.Group{})
+
+		HandleLegacyIndexes(conn)
+		if err := conn.AutoMigrate(append(Models(), &SchemaMigration{})...); err != nil {
+			return fmt.Errorf("database auto-migration failed: %w", err)
+		}
+
+		history, err := loadHistory(conn)
+		if err != nil {
+			return err
+		}
+		for version := range history {
+			if version > SchemaVersion() {
+				return fmt.Errorf("database schema version %d is newer than version %d of this build, upgrade gpt-load first", version, SchemaVersion())
+			}
+		}
+
+		for _, migration := range registry {
+			if _, ok := history[migration.Version]; ok {
+				continue
+			}
+			if fresh {
+				if err := conn.Create(newHistoryRecord(migration, true)).Error; err != nil {
+					return fmt.Errorf("failed to record migration %d: %w", migration.Version, err)
+				}
+				continue
+			}
+
+			logrus.Infof("Applying migration %d: %s", migration.Version, migration.Description)
+			err := conn.Transaction(func(tx *gorm.DB) error {
+				if err := migration.run(tx); err != nil {
+					return err
+				}
+				return tx.Create(newHistoryRecord(migration, false)).Error
+			})
+			if err != nil {
+				return fmt.Errorf("migration %d (%s) failed: %w", migration.Version, migration.Description, err)
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Status returns the state of every registered migration, followed by the migrations
+// applied by newer versions.
+func Status(db *gorm.DB) ([]MigrationStatus, error) {
+	history := make(map[int]SchemaMigration)
+	if db.Migrator().HasTable(&SchemaMigration{}) {
+		var err error
+		if history, err = loadHistory(db); err != nil {
+			return nil, err
+		}
+	}
+
+	statuses := make([]MigrationStatus, 0, len(registry))
+	for _, migration := range registry {
+		status := MigrationStatus{Version: migration.Version, Description: migration.Description}
+		if record, ok := history[migration.Version]; ok {
+			status.AppliedAt = &record.AppliedAt
+			status.Baseline = record.Baseline
+			delete(history, migration.Version)
+		}
+		statuses = append(statuses, status)
 	}
 
-	// Run v1.1.0 migration
-	return V1_1_0_AddKeyHashColumn(db)
+	unknown := make([]MigrationStatus, 0, len(history))
+	for _, record := range history {
+		unknown = append(unknown, MigrationStatus{
+			Version:     record.Version,
+			Description: record.Description,
+			AppliedAt:   &record.AppliedAt,
+			Baseline:    record.Baseline,
+			Unknown:     true,
+		})
+	}
+	slices.SortFunc(unknown, func(a, b MigrationStatus) int { return a.Version - b.Version })
+	return append(statuses, unknown...), nil
+}
+
+// Pending returns the registered migrations that are not applied to the database yet.
+func Pending(db *gorm.DB) ([]MigrationStatus, error) {
+	statuses, err := Status(db)
+	if err != nil {
+		return nil, err
+	}
+	var pending []MigrationStatus
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			pending = append(pending, status)
+		}
+	}
+	return pending, nil
+}
+
+func loadHistory(db *gorm.DB) (map[int]SchemaMigration, error) {
+	var records []SchemaMigration
+	if err := db.Order("version asc").Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to load migration history: %w", err)
+	}
+	history := make(map[int]SchemaMigration, len(records))
+	for _, record := range records {
+		history[record.Version] = record
+	}
+	return history, nil
+}
+
+func newHistoryRecord(migration Migration, baseline bool) *SchemaMigration {
+	return &SchemaMigration{
+		Version:     migration.Version,
+		Description: migration.Description,
+		AppVersion:  version.Version,
+		Baseline:    baseline,
+		AppliedAt:   time.Now(),
+	}
+}
+
+// withMigrationLock runs fn on a single connection holding the migration lock.
+// MySQL and Postgres use session-level advisory locks, so the lock is released
+// even if the process dies. SQLite only allows a single writer and needs no lock.
+func withMigrationLock(db *gorm.DB, fn func(conn *gorm.DB) error) error {
+	return db.Connection(func(pinned *gorm.DB) error {
+		// 新建会话，避免各语句共享同一个 Statement
+		conn := pinned.Session(&gorm.Session{})
+		switch conn.Dialector.Name() {
+		case "mysql":
+			var acquired int
+			if err := conn.Raw("SELECT GET_LOCK(?, ?)", migrationLockName, migrationLockTimeout).Scan(&acquired).Error; err != nil {
+				return fmt.Errorf("failed to acquire migration lock: %w", err)
+			}
+			if acquired != 1 {
+				return fmt.Errorf("timed out waiting for the migration lock held by another node")
+			}
+			defer conn.Exec("SELECT RELEASE_LOCK(?)", migrationLockName)
+		case "postgres":
+			if err := conn.Exec(fmt.Sprintf("SET lock_timeout = '%ds'", migrationLockTimeout)).Error; err != nil {
+				return fmt.Errorf("failed to acquire migration lock: %w", err)
+			}
+			if err := conn.Exec("SELECT pg_advisory_lock(?)", migrationLockID).Error; err != nil {
+				return fmt.Errorf("failed to acquire migration lock: %w", err)
+			}
+			defer conn.Exec("SELECT pg_advisory_unlock(?)", migrationLockID)
+			defer conn.Exec("SET lock_timeout = 0")
+		}
+		return fn(conn)
+	})
 }
 
 // HandleLegacyIndexes removes old indexes from previous versions to prevent migration errors
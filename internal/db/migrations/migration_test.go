@@ -0,0 +1,153 @@
+package db
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"gpt-load/internal/models"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	return db
+}
+
+// withRegistry replaces the registered migrations for the duration of a test.
+func withRegistry(t *testing.T, migrations ...Migration) {
+	t.Helper()
+
+	original := registry
+	registry = append(append([]Migration{}, original...), migrations...)
+	t.Cleanup(func() { registry = original })
+}
+
+func TestMigrateFreshDatabase(t *testing.T) {
+	db := newTestDB(t)
+
+	pending, err := Pending(db)
+	if err != nil || len(pending) != len(registry) {
+		t.Fatalf("Pending() = %v, %v, want every migration", pending, err)
+	}
+
+	applied, err := Migrate(db)
+	if err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+	// 新建的数据库由模型建成最新结构，迁移只记录为基线
+	if len(applied) != 0 {
+		t.Errorf("Migrate() applied %v on a fresh database", applied)
+	}
+	statuses, _ := Status(db)
+	for _, status := range statuses {
+		if status.AppliedAt == nil || !status.Baseline {
+			t.Errorf("status = %+v, want a baseline record", status)
+		}
+	}
+	if pending, _ := Pending(db); len(pending) != 0 {
+		t.Errorf("Pending() after Migrate() = %v", pending)
+	}
+}
+
+func TestMigrateExistingDatabase(t *testing.T) {
+	db := newTestDB(t)
+	// 早于迁移历史表的数据库，已有表但没有历史记录
+	if err := db.AutoMigrate(&models.Group{}, &models.APIKey{}, &models.RequestLog{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	db.Create(&models.APIKey{GroupID: 1, KeyValue: "sk-legacy"})
+
+	var ran []string
+	withRegistry(t, Migration{
+		Version:     100,
+		Description: "invalidate keys",
+		SQL:         map[string][]string{"sqlite": {"UPDATE api_keys SET status = 'invalid'"}},
+		Up: func(tx *gorm.DB) error {
+			ran = append(ran, "up")
+			return nil
+		},
+	})
+
+	applied, err := Migrate(db)
+	if err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+	if len(applied) != len(registry) || len(ran) != 1 {
+		t.Fatalf("Migrate() applied %d migrations, ran %v", len(applied), ran)
+	}
+
+	var key models.APIKey
+	db.First(&key)
+	if key.KeyHash == "" || key.Status != models.KeyStatusInvalid {
+		t.Errorf("migrated key = %+v", key)
+	}
+	statuses, _ := Status(db)
+	for _, status := range statuses {
+		if status.AppliedAt == nil || status.Baseline {
+			t.Errorf("status = %+v, want an applied record", status)
+		}
+	}
+
+	// 已执行的迁移不会再次运行
+	if applied, err := Migrate(db); err != nil || len(applied) != 0 || len(ran) != 1 {
+		t.Errorf("second Migrate() = %v, %v, ran %v", applied, err, ran)
+	}
+}
+
+func TestMigrateFailureRollsBack(t *testing.T) {
+	db := newTestDB(t)
+	if _, err := Migrate(db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+	db.Create(&models.Group{Name: "openai", ChannelType: "openai", TestModel: "m", Upstreams: []byte("[]")})
+
+	withRegistry(t, Migration{
+		Version:     100,
+		Description: "broken",
+		Up: func(tx *gorm.DB) error {
+			tx.Model(&models.Group{}).Where("name = ?", "openai").Update("name", "renamed")
+			return errors.New("boom")
+		},
+	})
+
+	_, err := Migrate(db)
+	if err == nil || !strings.Contains(err.Error(), "migration 100 (broken) failed: boom") {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+	// 迁移与其历史记录在同一事务中，失败时一起回滚
+	var group models.Group
+	db.First(&group)
+	if group.Name != "openai" {
+		t.Errorf("group name = %q after a failed migration", group.Name)
+	}
+	if pending, _ := Pending(db); len(pending) != 1 || pending[0].Version != 100 {
+		t.Errorf("Pending() = %+v, want the failed migration", pending)
+	}
+}
+
+func TestMigrateRefusesNewerSchema(t *testing.T) {
+	db := newTestDB(t)
+	if _, err := Migrate(db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+	db.Create(&SchemaMigration{Version: 999, Description: "from a newer build"})
+
+	if _, err := Migrate(db); err == nil || !strings.Contains(err.Error(), "upgrade gpt-load first") {
+		t.Errorf("Migrate() error = %v, want newer schema error", err)
+	}
+	statuses, _ := Status(db)
+	if last := statuses[len(statuses)-1]; last.Version != 999 || !last.Unknown {
+		t.Errorf("Status() = %+v, want the unknown migration last", statuses)
+	}
+}
@@ -0,0 +1,67 @@
+package db
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// Migration is a versioned change to the schema or data.
+//
+// Tables and new columns come from the models, migrations cover what AutoMigrate cannot do:
+// dropping or renaming columns, converting and backfilling data. Each migration runs exactly
+// once per database, so it does not need to detect whether it was applied before.
+type Migration struct {
+	// Version orders the migrations, it must be unique and increase with every new migration.
+	Version     int
+	Description string
+	// SQL holds the statements per dialect (sqlite, mysql, postgres), run before Up.
+	SQL map[string][]string
+	// Up runs changes that plain SQL cannot express.
+	Up func(tx *gorm.DB) error
+}
+
+// registry lists every migration in order. Append new migrations at the end.
+// Note that MySQL commits DDL statements implicitly, keep them in migrations of their own.
+var registry = []Migration{
+	// 以下两个迁移早于迁移历史表，保留各自的检测以兼容已经执行过它们的数据库
+	{
+		Version:     1,
+		Description: "drop request_logs.retries",
+		Up:          V1_0_22_DropRetriesColumn,
+	},
+	{
+		Version:     2,
+		Description: "populate key_hash of api_keys",
+		Up:          V1_1_0_AddKeyHashColumn,
+	},
+}
+
+func init() {
+	for i := 1; i < len(registry); i++ {
+		if registry[i].Version <= registry[i-1].Version {
+			panic(fmt.Sprintf("migration %d must have a higher version than migration %d", registry[i].Version, registry[i-1].Version))
+		}
+	}
+}
+
+// SchemaVersion returns the version of the newest migration known to this build.
+// Backups record it to refuse archives of newer versions.
+func SchemaVersion() int {
+	if len(registry) == 0 {
+		return 0
+	}
+	return registry[len(registry)-1].Version
+}
+
+func (m Migration) run(tx *gorm.DB) error {
+	for _, statement := range m.SQL[tx.Dialector.Name()] {
+		if err := tx.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	if m.Up != nil {
+		return m.Up(tx)
+	}
+	return nil
+}
@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"gpt-load/internal/utils"
 	"io"
+	"strings"
 )
 
 // Service defines the encryption interface
@@ -17,20 +18,68 @@ type Service interface {
 	Encrypt(plaintext string) (string, error)
 	Decrypt(ciphertext string) (string, error)
 	Hash(plaintext string) string
+	// Hashes returns the hash of the plaintext under every key, the primary key first.
+	// Lookups use it to find values that have not been re-encrypted after a key rotation.
+	Hashes(plaintext string) []string
+	// IsCurrent reports whether the ciphertext is encrypted with the primary key.
+	IsCurrent(ciphertext string) bool
+	// KeyID returns the ID of the primary key, empty when encryption is disabled.
+	KeyID() string
 }
 
-// NewService creates encryption service
-func NewService(encryptionKey string) (Service, error) {
+// keyIDSeparator separates the key ID prefix from the hex encoded ciphertext.
+const keyIDSeparator = ":"
+
+// NewService creates encryption service.
+// New values are encrypted with encryptionKey, previousKeys are only used to decrypt
+// values written before a key rotation.
+func NewService(encryptionKey string, previousKeys ...string) (Service, error) {
 	if encryptionKey == "" {
 		return &noopService{}, nil
 	}
 
-	// Derive AES-256 key from user input and validate strength
-	aesKey := utils.DeriveAESKey(encryptionKey)
+	// Validate strength of the primary key only, previous keys are being retired
 	utils.ValidatePasswordStrength(encryptionKey, "ENCRYPTION_KEY")
 
+	keys := make([]*aesKey, 0, len(previousKeys)+1)
+	for _, secret := range append([]string{encryptionKey}, previousKeys...) {
+		key, err := newAESKey(secret)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return newAESService(keys), nil
+}
+
+// newAESService builds the key ring, the first key is the primary key.
+func newAESService(keys []*aesKey) *aesService {
+	s := &aesService{byID: make(map[string]*aesKey)}
+	for _, key := range keys {
+		if _, exists := s.byID[key.id]; exists {
+			continue
+		}
+		s.byID[key.id] = key
+		s.keys = append(s.keys, key)
+	}
+	return s
+}
+
+// aesKey is one AES-256-GCM key of the key ring
+type aesKey struct {
+	id  string
+	key []byte
+	gcm cipher.AEAD
+}
+
+func newAESKey(secret string) (*aesKey, error) {
+	// Derive AES-256 key from user input
+	return newAESKeyFromBytes(utils.DeriveAESKey(secret))
+}
+
+func newAESKeyFromBytes(key []byte) (*aesKey, error) {
 	// Initialize cipher and GCM once for reuse
-	block, err := aes.NewCipher(aesKey)
+	block, err := aes.NewCipher(key)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create cipher: %w", err)
 	}
@@ -40,53 +89,106 @@ func NewService(encryptionKey string) (Service, error) {
 		return nil, fmt.Errorf("failed to create GCM: %w", err)
 	}
 
-	return &aesService{key: aesKey, gcm: gcm}, nil
+	// 密钥 ID 由密钥派生，无需单独配置，且不会泄露密钥本身
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("gpt-load-key-id"))
+	id := hex.EncodeToString(mac.Sum(nil))[:8]
+
+	return &aesKey{id: id, key: key, gcm: gcm}, nil
+}
+
+func (k *aesKey) open(data string) (string, error) {
+	ciphertext, err := hex.DecodeString(data)
+	if err != nil {
+		return "", fmt.Errorf("invalid hex data: %w", err)
+	}
+
+	nonceSize := k.gcm.NonceSize()
+	if len(ciphertext) < nonceSize {
+		return "", fmt.Errorf("ciphertext too short")
+	}
+
+	nonce, encrypted := ciphertext[:nonceSize], ciphertext[nonceSize:]
+	plaintext, err := k.gcm.Open(nil, nonce, encrypted, nil)
+	if err != nil {
+		return "", fmt.Errorf("decryption failed: %w", err)
+	}
+
+	return string(plaintext), nil
+}
+
+func (k *aesKey) hash(plaintext string) string {
+	mac := hmac.New(sha256.New, k.key)
+	mac.Write([]byte(plaintext))
+	return hex.EncodeToString(mac.Sum(nil))
 }
 
-// aesService implements AES-256-GCM encryption
+// aesService implements AES-256-GCM encryption with a key ring.
+// Ciphertexts are prefixed with the ID of their key, "<key id>:<hex>".
 type aesService struct {
-	key []byte
-	gcm cipher.AEAD
+	keys []*aesKey // 第一个为主密钥
+	byID map[string]*aesKey
 }
 
 func (s *aesService) Encrypt(plaintext string) (string, error) {
-	nonce := make([]byte, s.gcm.NonceSize())
+	primary := s.keys[0]
+	nonce := make([]byte, primary.gcm.NonceSize())
 	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
 		return "", err
 	}
 
-	ciphertext := s.gcm.Seal(nonce, nonce, []byte(plaintext), nil)
-	return hex.EncodeToString(ciphertext), nil
+	ciphertext := primary.gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return primary.id + keyIDSeparator + hex.EncodeToString(ciphertext), nil
 }
 
 func (s *aesService) Decrypt(ciphertext string) (string, error) {
-	data, err := hex.DecodeString(ciphertext)
-	if err != nil {
-		return "", fmt.Errorf("invalid hex data: %w", err)
-	}
-
-	nonceSize := s.gcm.NonceSize()
-	if len(data) < nonceSize {
-		return "", fmt.Errorf("ciphertext too short")
+	if id, data, found := strings.Cut(ciphertext, keyIDSeparator); found {
+		key, ok := s.byID[id]
+		if !ok {
+			return "", fmt.Errorf("ciphertext was encrypted with unknown key %s", id)
+		}
+		return key.open(data)
 	}
 
-	nonce, encrypted := data[:nonceSize], data[nonceSize:]
-	plaintext, err := s.gcm.Open(nil, nonce, encrypted, nil)
-	if err != nil {
-		return "", fmt.Errorf("decryption failed: %w", err)
+	// 轮换前写入的密文没有密钥 ID，依次尝试所有密钥
+	var firstErr error
+	for _, key := range s.keys {
+		plaintext, err := key.open(ciphertext)
+		if err == nil {
+			return plaintext, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
 	}
-
-	return string(plaintext), nil
+	return "", firstErr
 }
 
-// Hash generates a hash of the plaintext using HMAC-SHA256
+// Hash generates a hash of the plaintext using HMAC-SHA256 with the primary key
 func (s *aesService) Hash(plaintext string) string {
 	if plaintext == "" {
 		return ""
 	}
-	mac := hmac.New(sha256.New, s.key)
-	mac.Write([]byte(plaintext))
-	return hex.EncodeToString(mac.Sum(nil))
+	return s.keys[0].hash(plaintext)
+}
+
+func (s *aesService) Hashes(plaintext string) []string {
+	if plaintext == "" {
+		return nil
+	}
+	hashes := make([]string, len(s.keys))
+	for i, key := range s.keys {
+		hashes[i] = key.hash(plaintext)
+	}
+	return hashes
+}
+
+func (s *aesService) IsCurrent(ciphertext string) bool {
+	return strings.HasPrefix(ciphertext, s.keys[0].id+keyIDSeparator)
+}
+
+func (s *aesService) KeyID() string {
+	return s.keys[0].id
 }
 
 // noopService disables encryption
@@ -108,3 +210,18 @@ func (s *noopService) Hash(plaintext string) string {
 	hash := sha256.Sum256([]byte(plaintext))
 	return hex.EncodeToString(hash[:])
 }
+
+func (s *noopService) Hashes(plaintext string) []string {
+	if plaintext == "" {
+		return nil
+	}
+	return []string{s.Hash(plaintext)}
+}
+
+func (s *noopService) IsCurrent(ciphertext string) bool {
+	return true
+}
+
+func (s *noopService) KeyID() string {
+	return ""
+}
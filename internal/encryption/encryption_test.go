@@ -0,0 +1,163 @@
+package encryption
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const (
+	oldKey     = "old-encryption-key-Aa1!"
+	currentKey = "current-encryption-key-Bb2@"
+)
+
+func newTestService(t *testing.T, key string, previousKeys ...string) Service {
+	t.Helper()
+
+	svc, err := NewService(key, previousKeys...)
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	return svc
+}
+
+// legacyEncrypt encrypts like versions before key rotation, without the key ID prefix.
+func legacyEncrypt(t *testing.T, secret, plaintext string) string {
+	t.Helper()
+
+	key, err := newAESKey(secret)
+	if err != nil {
+		t.Fatalf("newAESKey() error = %v", err)
+	}
+	nonce := make([]byte, key.gcm.NonceSize())
+	rand.Read(nonce)
+	return hex.EncodeToString(key.gcm.Seal(nonce, nonce, []byte(plaintext), nil))
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	svc := newTestService(t, currentKey)
+
+	ciphertext, err := svc.Encrypt("sk-secret")
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	if !strings.HasPrefix(ciphertext, svc.KeyID()+keyIDSeparator) || strings.Contains(ciphertext, "sk-secret") {
+		t.Errorf("Encrypt() = %q", ciphertext)
+	}
+	if other, _ := svc.Encrypt("sk-secret"); other == ciphertext {
+		t.Error("Encrypt() reused a nonce")
+	}
+
+	plaintext, err := svc.Decrypt(ciphertext)
+	if err != nil || plaintext != "sk-secret" {
+		t.Errorf("Decrypt() = %q, %v", plaintext, err)
+	}
+	if !svc.IsCurrent(ciphertext) {
+		t.Error("IsCurrent() = false for a new ciphertext")
+	}
+
+	// 密钥 ID 由密钥派生，重启后保持不变
+	if again := newTestService(t, currentKey); again.KeyID() != svc.KeyID() || len(svc.KeyID()) != 8 {
+		t.Errorf("KeyID() = %q, then %q", svc.KeyID(), again.KeyID())
+	}
+}
+
+func TestDecryptErrors(t *testing.T) {
+	svc := newTestService(t, currentKey)
+	ciphertext, _ := svc.Encrypt("sk-secret")
+	id, data, _ := strings.Cut(ciphertext, keyIDSeparator)
+
+	raw, _ := hex.DecodeString(data)
+	raw[len(raw)-1] ^= 1
+	tampered := hex.EncodeToString(raw)
+
+	tests := []struct {
+		name       string
+		ciphertext string
+		want       string
+	}{
+		{"unknown key", "deadbeef" + keyIDSeparator + data, "unknown key deadbeef"},
+		{"invalid hex", id + keyIDSeparator + "zz", "invalid hex"},
+		{"too short", id + keyIDSeparator + "00", "too short"},
+		{"tampered", id + keyIDSeparator + tampered, "decryption failed"},
+		{"plaintext", "sk-secret", "invalid hex"},
+	}
+	for _, tt := range tests {
+		if _, err := svc.Decrypt(tt.ciphertext); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: Decrypt() error = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestKeyRingRotation(t *testing.T) {
+	old := newTestService(t, oldKey)
+	oldCiphertext, _ := old.Encrypt("sk-secret")
+	legacyCiphertext := legacyEncrypt(t, oldKey, "sk-legacy")
+
+	rotated := newTestService(t, currentKey, oldKey)
+	if rotated.KeyID() == old.KeyID() {
+		t.Fatal("rotated service kept the old primary key")
+	}
+
+	// 旧密钥仅用于解密，新值使用主密钥加密
+	for ciphertext, want := range map[string]string{oldCiphertext: "sk-secret", legacyCiphertext: "sk-legacy"} {
+		plaintext, err := rotated.Decrypt(ciphertext)
+		if err != nil || plaintext != want {
+			t.Errorf("Decrypt(%q) = %q, %v, want %q", ciphertext, plaintext, err, want)
+		}
+		if rotated.IsCurrent(ciphertext) {
+			t.Errorf("IsCurrent(%q) = true for a value of the old key", ciphertext)
+		}
+	}
+	newCiphertext, _ := rotated.Encrypt("sk-secret")
+	if !rotated.IsCurrent(newCiphertext) || !strings.HasPrefix(newCiphertext, rotated.KeyID()) {
+		t.Errorf("Encrypt() after rotation = %q", newCiphertext)
+	}
+
+	// 新值无法由只持有旧密钥的节点解密
+	if _, err := old.Decrypt(newCiphertext); err == nil {
+		t.Error("old service decrypted a value of the new key")
+	}
+	// 移除旧密钥后，未重新加密的值无法解密
+	if _, err := newTestService(t, currentKey).Decrypt(oldCiphertext); err == nil {
+		t.Error("Decrypt() of an old value without the previous key succeeded")
+	}
+}
+
+func TestKeyRingHashes(t *testing.T) {
+	old := newTestService(t, oldKey)
+	rotated := newTestService(t, currentKey, oldKey, currentKey)
+
+	hashes := rotated.Hashes("sk-secret")
+	if len(hashes) != 2 {
+		t.Fatalf("Hashes() = %v, want one hash per distinct key", hashes)
+	}
+	if hashes[0] != rotated.Hash("sk-secret") {
+		t.Error("Hashes() does not start with the primary key hash")
+	}
+	// 轮换前写入的哈希仍可被查到
+	if !slices.Contains(hashes, old.Hash("sk-secret")) {
+		t.Error("Hashes() does not include the previous key hash")
+	}
+	if rotated.Hash("") != "" || rotated.Hashes("") != nil {
+		t.Error("hash of an empty value is not empty")
+	}
+}
+
+func TestNoopService(t *testing.T) {
+	svc := newTestService(t, "")
+
+	ciphertext, _ := svc.Encrypt("sk-secret")
+	plaintext, _ := svc.Decrypt(ciphertext)
+	if ciphertext != "sk-secret" || plaintext != "sk-secret" {
+		t.Errorf("Encrypt() = %q, Decrypt() = %q", ciphertext, plaintext)
+	}
+	if svc.KeyID() != "" || !svc.IsCurrent("anything") {
+		t.Errorf("KeyID() = %q", svc.KeyID())
+	}
+	if hashes := svc.Hashes("sk-secret"); len(hashes) != 1 || hashes[0] != svc.Hash("sk-secret") || len(hashes[0]) != 64 {
+		t.Errorf("Hashes() = %v", hashes)
+	}
+}
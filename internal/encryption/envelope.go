@@ -0,0 +1,73 @@
+package encryption
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"gpt-load/internal/types"
+	"net/http"
+	"strings"
+)
+
+// envelopeKMS wraps data keys through a generic KMS HTTP API:
+//
+//	POST {KMS_URL}/encrypt {"key_id": "...", "plaintext": "<base64>"}  -> {"ciphertext": "..."}
+//	POST {KMS_URL}/decrypt {"key_id": "...", "ciphertext": "..."}      -> {"plaintext": "<base64>"}
+//
+// KMS_TOKEN, when set, is sent as a bearer token. Cloud KMS services can be used through a
+// small proxy that maps these two calls.
+type envelopeKMS struct {
+	url     string
+	keyID   string
+	headers http.Header
+	client  *http.Client
+}
+
+type envelopeRequest struct {
+	KeyID      string `json:"key_id,omitempty"`
+	Plaintext  string `json:"plaintext,omitempty"`
+	Ciphertext string `json:"ciphertext,omitempty"`
+}
+
+type envelopeResponse struct {
+	Plaintext  string `json:"plaintext"`
+	Ciphertext string `json:"ciphertext"`
+}
+
+func newEnvelopeKMS(cfg types.EnvelopeKMSConfig, client *http.Client) *envelopeKMS {
+	headers := make(http.Header)
+	if cfg.Token != "" {
+		headers.Set("Authorization", "Bearer "+cfg.Token)
+	}
+	return &envelopeKMS{
+		url:     strings.TrimRight(cfg.URL, "/"),
+		keyID:   cfg.KeyID,
+		headers: headers,
+		client:  client,
+	}
+}
+
+func (e *envelopeKMS) Wrap(ctx context.Context, dataKey []byte) (string, error) {
+	var resp envelopeResponse
+	req := envelopeRequest{KeyID: e.keyID, Plaintext: base64.StdEncoding.EncodeToString(dataKey)}
+	if err := postJSON(ctx, e.client, e.url+"/encrypt", e.headers, req, &resp); err != nil {
+		return "", fmt.Errorf("kms encrypt failed: %w", err)
+	}
+	if resp.Ciphertext == "" {
+		return "", fmt.Errorf("kms encrypt returned no ciphertext")
+	}
+	return resp.Ciphertext, nil
+}
+
+func (e *envelopeKMS) Unwrap(ctx context.Context, wrapped string) ([]byte, error) {
+	var resp envelopeResponse
+	req := envelopeRequest{KeyID: e.keyID, Ciphertext: wrapped}
+	if err := postJSON(ctx, e.client, e.url+"/decrypt", e.headers, req, &resp); err != nil {
+		return nil, fmt.Errorf("kms decrypt failed: %w", err)
+	}
+	dataKey, err := base64.StdEncoding.DecodeString(resp.Plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("kms returned an invalid plaintext: %w", err)
+	}
+	return dataKey, nil
+}
@@ -0,0 +1,135 @@
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"gpt-load/internal/types"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// dataKeySize is the size of an AES-256 data key.
+const dataKeySize = 32
+
+// KeyWrapper encrypts data keys with a master key that never leaves the KMS.
+type KeyWrapper interface {
+	Wrap(ctx context.Context, dataKey []byte) (string, error)
+	Unwrap(ctx context.Context, wrapped string) ([]byte, error)
+}
+
+// New creates the encryption service of the configured backend.
+//
+// The vault and envelope backends use data keys: random AES keys stored wrapped by the KMS in
+// ENCRYPTION_DATA_KEY. They are unwrapped once on startup and cached in memory, so the KMS is
+// not called for every key the proxy decrypts, and the master key stays outside the gateway.
+func New(cfg types.EncryptionConfig) (Service, error) {
+	if cfg.Backend == "" || cfg.Backend == types.EncryptionBackendLocal {
+		return NewService(cfg.Key, cfg.PreviousKeys...)
+	}
+	if cfg.DataKey == "" {
+		return nil, fmt.Errorf("ENCRYPTION_DATA_KEY is required for the %s backend, create one with 'gpt-load encryption generate-data-key'", cfg.Backend)
+	}
+
+	wrapper, err := NewKeyWrapper(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.KMSTimeout)*time.Second)
+	defer cancel()
+
+	keys := make([]*aesKey, 0, len(cfg.PreviousDataKeys)+len(cfg.PreviousKeys)+1)
+	for _, wrapped := range append([]string{cfg.DataKey}, cfg.PreviousDataKeys...) {
+		dataKey, err := wrapper.Unwrap(ctx, wrapped)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unwrap data key with the %s backend: %w", cfg.Backend, err)
+		}
+		if len(dataKey) != dataKeySize {
+			return nil, fmt.Errorf("unwrapped data key has %d bytes, expected %d", len(dataKey), dataKeySize)
+		}
+		key, err := newAESKeyFromBytes(dataKey)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	// 从本地密钥迁移到 KMS 时，旧的本地密钥仅用于解密
+	for _, secret := range cfg.PreviousKeys {
+		key, err := newAESKey(secret)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return newAESService(keys), nil
+}
+
+// NewKeyWrapper creates the key wrapper of the vault or envelope backend.
+func NewKeyWrapper(cfg types.EncryptionConfig) (KeyWrapper, error) {
+	client := &http.Client{Timeout: time.Duration(cfg.KMSTimeout) * time.Second}
+	switch cfg.Backend {
+	case types.EncryptionBackendVault:
+		return newVaultTransit(cfg.Vault, client), nil
+	case types.EncryptionBackendEnvelope:
+		return newEnvelopeKMS(cfg.Envelope, client), nil
+	default:
+		return nil, fmt.Errorf("encryption backend %q does not use data keys", cfg.Backend)
+	}
+}
+
+// GenerateDataKey creates a random data key and returns it wrapped, with the key ID its
+// ciphertexts will carry.
+func GenerateDataKey(ctx context.Context, wrapper KeyWrapper) (string, string, error) {
+	dataKey := make([]byte, dataKeySize)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return "", "", err
+	}
+	key, err := newAESKeyFromBytes(dataKey)
+	if err != nil {
+		return "", "", err
+	}
+
+	wrapped, err := wrapper.Wrap(ctx, dataKey)
+	if err != nil {
+		return "", "", err
+	}
+	return wrapped, key.id, nil
+}
+
+// postJSON sends a JSON request to a KMS and decodes the JSON response into out.
+func postJSON(ctx context.Context, client *http.Client, url string, headers http.Header, in, out any) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header = headers.Clone()
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s returned status %d: %s", url, resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("invalid response from %s: %w", url, err)
+	}
+	return nil
+}
@@ -0,0 +1,242 @@
+package encryption
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"gpt-load/internal/types"
+)
+
+// fakeKMS is a KMS that "wraps" data keys by prefixing them, and counts its calls.
+type fakeKMS struct {
+	*httptest.Server
+	calls atomic.Int32
+	fail  atomic.Bool
+}
+
+// newFakeVault serves the encrypt and decrypt endpoints of the Vault Transit key "gpt-load".
+func newFakeVault(t *testing.T) *fakeKMS {
+	t.Helper()
+
+	kms := &fakeKMS{}
+	kms.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		kms.calls.Add(1)
+		if kms.fail.Load() || r.Header.Get("X-Vault-Token") != "vault-token" || r.Header.Get("X-Vault-Namespace") != "team" {
+			http.Error(w, `{"errors":["permission denied"]}`, http.StatusForbidden)
+			return
+		}
+		var req map[string]string
+		json.NewDecoder(r.Body).Decode(&req)
+
+		switch r.URL.Path {
+		case "/v1/transit/encrypt/gpt-load":
+			json.NewEncoder(w).Encode(map[string]any{"data": map[string]string{"ciphertext": "vault:v1:" + req["plaintext"]}})
+		case "/v1/transit/decrypt/gpt-load":
+			plaintext, ok := strings.CutPrefix(req["ciphertext"], "vault:v1:")
+			if !ok {
+				http.Error(w, `{"errors":["invalid ciphertext"]}`, http.StatusBadRequest)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]any{"data": map[string]string{"plaintext": plaintext}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(kms.Close)
+	return kms
+}
+
+// newFakeEnvelope serves the generic KMS API of the envelope backend for the key "master".
+func newFakeEnvelope(t *testing.T) *fakeKMS {
+	t.Helper()
+
+	kms := &fakeKMS{}
+	kms.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		kms.calls.Add(1)
+		var req envelopeRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if kms.fail.Load() || r.Header.Get("Authorization") != "Bearer kms-token" || req.KeyID != "master" {
+			http.Error(w, "denied", http.StatusForbidden)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/encrypt":
+			json.NewEncoder(w).Encode(envelopeResponse{Ciphertext: "wrapped:" + req.Plaintext})
+		case "/decrypt":
+			plaintext, _ := strings.CutPrefix(req.Ciphertext, "wrapped:")
+			json.NewEncoder(w).Encode(envelopeResponse{Plaintext: plaintext})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(kms.Close)
+	return kms
+}
+
+func vaultConfig(kms *fakeKMS) types.EncryptionConfig {
+	return types.EncryptionConfig{
+		Backend:    types.EncryptionBackendVault,
+		KMSTimeout: 5,
+		Vault: types.VaultTransitConfig{
+			Address:   kms.URL + "/",
+			Token:     "vault-token",
+			Namespace: "team",
+			Mount:     "/transit/",
+			KeyName:   "gpt-load",
+		},
+	}
+}
+
+func envelopeConfig(kms *fakeKMS) types.EncryptionConfig {
+	return types.EncryptionConfig{
+		Backend:    types.EncryptionBackendEnvelope,
+		KMSTimeout: 5,
+		Envelope:   types.EnvelopeKMSConfig{URL: kms.URL, Token: "kms-token", KeyID: "master"},
+	}
+}
+
+func generateDataKey(t *testing.T, cfg types.EncryptionConfig) (string, string) {
+	t.Helper()
+
+	wrapper, err := NewKeyWrapper(cfg)
+	if err != nil {
+		t.Fatalf("NewKeyWrapper() error = %v", err)
+	}
+	wrapped, keyID, err := GenerateDataKey(context.Background(), wrapper)
+	if err != nil {
+		t.Fatalf("GenerateDataKey() error = %v", err)
+	}
+	return wrapped, keyID
+}
+
+func TestKMSBackends(t *testing.T) {
+	tests := []struct {
+		name   string
+		kms    *fakeKMS
+		config func(*fakeKMS) types.EncryptionConfig
+	}{
+		{"vault", newFakeVault(t), vaultConfig},
+		{"envelope", newFakeEnvelope(t), envelopeConfig},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.config(tt.kms)
+			wrapped, keyID := generateDataKey(t, cfg)
+			cfg.DataKey = wrapped
+
+			svc, err := New(cfg)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			if svc.KeyID() != keyID {
+				t.Errorf("KeyID() = %q, want %q from GenerateDataKey", svc.KeyID(), keyID)
+			}
+
+			// 数据密钥启动时解密一次，加解密不再调用 KMS
+			calls := tt.kms.calls.Load()
+			ciphertext, _ := svc.Encrypt("sk-secret")
+			plaintext, err := svc.Decrypt(ciphertext)
+			if err != nil || plaintext != "sk-secret" {
+				t.Errorf("Decrypt() = %q, %v", plaintext, err)
+			}
+			if tt.kms.calls.Load() != calls {
+				t.Error("encryption called the KMS")
+			}
+
+			// 重启后使用同一数据密钥，可以解密之前的值
+			restarted, err := New(cfg)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			if plaintext, err := restarted.Decrypt(ciphertext); err != nil || plaintext != "sk-secret" {
+				t.Errorf("Decrypt() after restart = %q, %v", plaintext, err)
+			}
+
+			tt.kms.fail.Store(true)
+			defer tt.kms.fail.Store(false)
+			if _, err := New(cfg); err == nil || !strings.Contains(err.Error(), "failed to unwrap data key with the "+tt.name+" backend") {
+				t.Errorf("New() with a failing KMS error = %v", err)
+			}
+		})
+	}
+}
+
+func TestKMSDataKeyRotation(t *testing.T) {
+	kms := newFakeEnvelope(t)
+	cfg := envelopeConfig(kms)
+
+	// 从本地密钥迁移到 KMS
+	local := newTestService(t, oldKey)
+	localCiphertext, _ := local.Encrypt("sk-local")
+
+	oldDataKey, _ := generateDataKey(t, cfg)
+	cfg.DataKey = oldDataKey
+	before, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	oldCiphertext, _ := before.Encrypt("sk-old")
+
+	cfg.DataKey, _ = generateDataKey(t, cfg)
+	cfg.PreviousDataKeys = []string{oldDataKey}
+	cfg.PreviousKeys = []string{oldKey}
+	svc, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	for ciphertext, want := range map[string]string{localCiphertext: "sk-local", oldCiphertext: "sk-old"} {
+		plaintext, err := svc.Decrypt(ciphertext)
+		if err != nil || plaintext != want {
+			t.Errorf("Decrypt() = %q, %v, want %q", plaintext, err, want)
+		}
+		if svc.IsCurrent(ciphertext) {
+			t.Errorf("IsCurrent(%q) = true for a value of a previous key", ciphertext)
+		}
+	}
+	if len(svc.Hashes("sk-secret")) != 3 {
+		t.Errorf("Hashes() = %v, want one hash per key", svc.Hashes("sk-secret"))
+	}
+}
+
+func TestNewKMSConfigErrors(t *testing.T) {
+	kms := newFakeEnvelope(t)
+	shortKey := "wrapped:" + base64.StdEncoding.EncodeToString([]byte("too short"))
+
+	tests := []struct {
+		name string
+		cfg  types.EncryptionConfig
+		want string
+	}{
+		{"missing data key", envelopeConfig(kms), "ENCRYPTION_DATA_KEY is required"},
+		{"short data key", func() types.EncryptionConfig {
+			cfg := envelopeConfig(kms)
+			cfg.DataKey = shortKey
+			return cfg
+		}(), "unwrapped data key has 9 bytes"},
+		{"invalid plaintext", func() types.EncryptionConfig {
+			cfg := envelopeConfig(kms)
+			cfg.DataKey = "wrapped:not base64!"
+			return cfg
+		}(), "invalid plaintext"},
+		{"unknown backend", types.EncryptionConfig{Backend: "aws", DataKey: "x"}, `"aws" does not use data keys`},
+	}
+	for _, tt := range tests {
+		if _, err := New(tt.cfg); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: New() error = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+
+	// 本地后端不需要 KMS
+	svc, err := New(types.EncryptionConfig{Backend: types.EncryptionBackendLocal, Key: currentKey})
+	if err != nil || svc.KeyID() != newTestService(t, currentKey).KeyID() {
+		t.Errorf("New() local backend = %v, %v", svc, err)
+	}
+}
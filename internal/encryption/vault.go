@@ -0,0 +1,66 @@
+package encryption
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"gpt-load/internal/types"
+	"net/http"
+	"strings"
+)
+
+// vaultTransit wraps data keys with a key of the HashiCorp Vault Transit secrets engine.
+type vaultTransit struct {
+	encryptURL string
+	decryptURL string
+	headers    http.Header
+	client     *http.Client
+}
+
+func newVaultTransit(cfg types.VaultTransitConfig, client *http.Client) *vaultTransit {
+	headers := make(http.Header)
+	headers.Set("X-Vault-Token", cfg.Token)
+	if cfg.Namespace != "" {
+		headers.Set("X-Vault-Namespace", cfg.Namespace)
+	}
+
+	base := fmt.Sprintf("%s/v1/%s", strings.TrimRight(cfg.Address, "/"), strings.Trim(cfg.Mount, "/"))
+	return &vaultTransit{
+		encryptURL: base + "/encrypt/" + cfg.KeyName,
+		decryptURL: base + "/decrypt/" + cfg.KeyName,
+		headers:    headers,
+		client:     client,
+	}
+}
+
+func (v *vaultTransit) Wrap(ctx context.Context, dataKey []byte) (string, error) {
+	var resp struct {
+		Data struct {
+			Ciphertext string `json:"ciphertext"`
+		} `json:"data"`
+	}
+	req := map[string]string{"plaintext": base64.StdEncoding.EncodeToString(dataKey)}
+	if err := postJSON(ctx, v.client, v.encryptURL, v.headers, req, &resp); err != nil {
+		return "", fmt.Errorf("vault transit encrypt failed: %w", err)
+	}
+	if resp.Data.Ciphertext == "" {
+		return "", fmt.Errorf("vault transit encrypt returned no ciphertext")
+	}
+	return resp.Data.Ciphertext, nil
+}
+
+func (v *vaultTransit) Unwrap(ctx context.Context, wrapped string) ([]byte, error) {
+	var resp struct {
+		Data struct {
+			Plaintext string `json:"plaintext"`
+		} `json:"data"`
+	}
+	if err := postJSON(ctx, v.client, v.decryptURL, v.headers, map[string]string{"ciphertext": wrapped}, &resp); err != nil {
+		return nil, fmt.Errorf("vault transit decrypt failed: %w", err)
+	}
+	dataKey, err := base64.StdEncoding.DecodeString(resp.Data.Plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("vault transit returned an invalid plaintext: %w", err)
+	}
+	return dataKey, nil
+}
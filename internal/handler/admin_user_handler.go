@@ -0,0 +1,271 @@
+package handler
+
+import (
+	"fmt"
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/response"
+	"gpt-load/internal/services"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	adminPasswordMinLength = 8
+	adminPasswordMaxLength = 72 // bcrypt 只使用前 72 字节
+)
+
+var adminUsernamePattern = regexp.MustCompile(`^[a-zA-Z0-9._@-]{1,64}$`)
+
+// AdminUserCreateRequest defines the payload for creating an admin user.
+type AdminUserCreateRequest struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+	Role     string `json:"role" binding:"required"`
+	GroupIDs []uint `json:"group_ids"`
+}
+
+// AdminUserUpdateRequest defines the payload for updating an admin user. Omitted fields are unchanged.
+type AdminUserUpdateRequest struct {
+	Password *string `json:"password,omitempty"`
+	Role     *string `json:"role,omitempty"`
+	GroupIDs *[]uint `json:"group_ids,omitempty"`
+	Disabled *bool   `json:"disabled,omitempty"`
+}
+
+// CurrentAdminResponse describes the authenticated admin.
+type CurrentAdminResponse struct {
+	Username    string   `json:"username"`
+	Role        string   `json:"role"`
+	GroupIDs    []uint   `json:"group_ids,omitempty"`
+	Permissions []string `json:"permissions"`
+}
+
+func validateAdminPassword(password string) error {
+	if len(password) < adminPasswordMinLength || len(password) > adminPasswordMaxLength {
+		return fmt.Errorf("password must be between %d and %d characters", adminPasswordMinLength, adminPasswordMaxLength)
+	}
+	return nil
+}
+
+// normalizeAdminGroupIDs validates the group scope of a role and returns the group IDs to store.
+func (s *Server) normalizeAdminGroupIDs(role string, groupIDs []uint) ([]uint, error) {
+	if role != models.AdminRoleGroupOperator {
+		return nil, nil
+	}
+	if len(groupIDs) == 0 {
+		return nil, fmt.Errorf("group_ids is required for the %s role", models.AdminRoleGroupOperator)
+	}
+	return s.normalizeGroupIDs(groupIDs)
+}
+
+// normalizeGroupIDs sorts and deduplicates group IDs and checks that the groups exist.
+func (s *Server) normalizeGroupIDs(groupIDs []uint) ([]uint, error) {
+	if len(groupIDs) == 0 {
+		return nil, nil
+	}
+	slices.Sort(groupIDs)
+	groupIDs = slices.Compact(groupIDs)
+
+	var count int64
+	if err := s.DB.Model(&models.Group{}).Where("id IN ?", groupIDs).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if int(count) != len(groupIDs) {
+		return nil, fmt.Errorf("group_ids contains unknown groups")
+	}
+	return groupIDs, nil
+}
+
+// GetCurrentAdmin returns the authenticated admin and its permissions.
+func (s *Server) GetCurrentAdmin(c *gin.Context) {
+	principal := services.AdminPrincipalFromContext(c)
+	if principal == nil {
+		response.Error(c, app_errors.ErrUnauthorized)
+		return
+	}
+
+	response.Success(c, CurrentAdminResponse{
+		Username:    principal.Username,
+		Role:        principal.Role,
+		GroupIDs:    principal.GroupIDs,
+		Permissions: principal.Permissions(),
+	})
+}
+
+// ListAdminUsers handles listing all admin users.
+func (s *Server) ListAdminUsers(c *gin.Context) {
+	var users []models.AdminUser
+	if err := s.DB.Order("id asc").Find(&users).Error; err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+	response.Success(c, users)
+}
+
+// CreateAdminUser handles creating an admin user.
+func (s *Server) CreateAdminUser(c *gin.Context) {
+	var req AdminUserCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+
+	username := strings.TrimSpace(req.Username)
+	if !adminUsernamePattern.MatchString(username) || username == services.AuthKeyActor {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, "Invalid username. Use 1-64 letters, digits, '.', '_', '@' or '-'; 'admin' is reserved"))
+		return
+	}
+	if !services.IsValidAdminRole(req.Role) {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, fmt.Sprintf("Invalid role '%s'", req.Role)))
+		return
+	}
+	if err := validateAdminPassword(req.Password); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, err.Error()))
+		return
+	}
+	groupIDs, err := s.normalizeAdminGroupIDs(req.Role, req.GroupIDs)
+	if err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, err.Error()))
+		return
+	}
+
+	passwordHash, err := s.AdminUserService.HashPassword(req.Password)
+	if err != nil {
+		response.Error(c, app_errors.ErrInternalServer)
+		return
+	}
+
+	user := models.AdminUser{
+		Username:     username,
+		PasswordHash: passwordHash,
+		Role:         req.Role,
+		GroupIDs:     groupIDs,
+	}
+	if err := s.DB.Create(&user).Error; err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+
+	s.AuditService.Record(c, services.AuditEntry{
+		Action:     models.AuditActionUserCreate,
+		TargetType: models.AuditTargetUser,
+		TargetID:   user.ID,
+		TargetName: user.Username,
+		After:      &user,
+	})
+	response.Success(c, user)
+}
+
+// UpdateAdminUser handles updating the password, role, group scope or status of an admin user.
+func (s *Server) UpdateAdminUser(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, "Invalid user ID format"))
+		return
+	}
+
+	var user models.AdminUser
+	if err := s.DB.First(&user, id).Error; err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+
+	var req AdminUserUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+
+	before := user
+	passwordChanged := false
+
+	if req.Role != nil {
+		if !services.IsValidAdminRole(*req.Role) {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, fmt.Sprintf("Invalid role '%s'", *req.Role)))
+			return
+		}
+		user.Role = *req.Role
+	}
+
+	groupIDs := []uint(user.GroupIDs)
+	if req.GroupIDs != nil {
+		groupIDs = *req.GroupIDs
+	}
+	normalizedGroupIDs, err := s.normalizeAdminGroupIDs(user.Role, groupIDs)
+	if err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, err.Error()))
+		return
+	}
+	user.GroupIDs = normalizedGroupIDs
+
+	if req.Password != nil {
+		if err := validateAdminPassword(*req.Password); err != nil {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, err.Error()))
+			return
+		}
+		passwordHash, err := s.AdminUserService.HashPassword(*req.Password)
+		if err != nil {
+			response.Error(c, app_errors.ErrInternalServer)
+			return
+		}
+		user.PasswordHash = passwordHash
+		passwordChanged = true
+	}
+
+	if req.Disabled != nil {
+		user.Disabled = *req.Disabled
+	}
+
+	if err := s.DB.Save(&user).Error; err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+
+	entry := services.AuditEntry{
+		Action:     models.AuditActionUserUpdate,
+		TargetType: models.AuditTargetUser,
+		TargetID:   user.ID,
+		TargetName: user.Username,
+		Before:     &before,
+		After:      &user,
+	}
+	if passwordChanged {
+		entry.Details = gin.H{"password_changed": true}
+	}
+	s.AuditService.Record(c, entry)
+	response.Success(c, user)
+}
+
+// DeleteAdminUser handles deleting an admin user.
+func (s *Server) DeleteAdminUser(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, "Invalid user ID format"))
+		return
+	}
+
+	var user models.AdminUser
+	if err := s.DB.First(&user, id).Error; err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+
+	if err := s.DB.Delete(&user).Error; err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+
+	s.AuditService.Record(c, services.AuditEntry{
+		Action:     models.AuditActionUserDelete,
+		TargetType: models.AuditTargetUser,
+		TargetID:   user.ID,
+		TargetName: user.Username,
+		Before:     &user,
+	})
+	response.Success(c, gin.H{"message": "Admin user deleted successfully"})
+}
@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"fmt"
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/response"
+	"gpt-load/internal/services"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// APITokenCreateRequest defines the payload for creating an API token.
+type APITokenCreateRequest struct {
+	Name        string     `json:"name" binding:"required"`
+	Permissions []string   `json:"permissions" binding:"required"`
+	GroupIDs    []uint     `json:"group_ids"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+}
+
+// APITokenCreateResponse returns the new token. The raw token is only shown once.
+type APITokenCreateResponse struct {
+	models.APIToken
+	Token string `json:"token"`
+}
+
+// APITokenListResponse lists the tokens and the permissions that can be granted.
+type APITokenListResponse struct {
+	Tokens []models.APIToken `json:"tokens"`
+	Scopes []string          `json:"scopes"`
+}
+
+// ListAPITokens handles listing all API tokens.
+func (s *Server) ListAPITokens(c *gin.Context) {
+	var tokens []models.APIToken
+	if err := s.DB.Order("id asc").Find(&tokens).Error; err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+	response.Success(c, APITokenListResponse{Tokens: tokens, Scopes: services.APITokenScopes})
+}
+
+// CreateAPIToken handles creating a scoped API token.
+func (s *Server) CreateAPIToken(c *gin.Context) {
+	var req APITokenCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > 64 {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, "Token name must be between 1 and 64 characters"))
+		return
+	}
+	if err := s.APITokenService.ValidateScopes(req.Permissions, req.GroupIDs); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, err.Error()))
+		return
+	}
+	if req.ExpiresAt != nil && req.ExpiresAt.Before(time.Now()) {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, "expires_at must be in the future"))
+		return
+	}
+	groupIDs, err := s.normalizeGroupIDs(req.GroupIDs)
+	if err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, err.Error()))
+		return
+	}
+
+	token, hash, prefix, err := s.APITokenService.GenerateToken()
+	if err != nil {
+		logrus.WithError(err).Error("Failed to generate API token")
+		response.Error(c, app_errors.ErrInternalServer)
+		return
+	}
+
+	apiToken := models.APIToken{
+		Name:        name,
+		TokenHash:   hash,
+		TokenPrefix: prefix,
+		Permissions: req.Permissions,
+		GroupIDs:    groupIDs,
+		ExpiresAt:   req.ExpiresAt,
+		CreatedBy:   c.GetString("actor"),
+	}
+	if err := s.DB.Create(&apiToken).Error; err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+
+	s.AuditService.Record(c, services.AuditEntry{
+		Action:     models.AuditActionTokenCreate,
+		TargetType: models.AuditTargetAPIToken,
+		TargetID:   apiToken.ID,
+		TargetName: apiToken.Name,
+		After:      &apiToken,
+	})
+	response.Success(c, APITokenCreateResponse{APIToken: apiToken, Token: token})
+}
+
+// DeleteAPIToken handles revoking an API token.
+func (s *Server) DeleteAPIToken(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, "Invalid token ID format"))
+		return
+	}
+
+	var apiToken models.APIToken
+	if err := s.DB.First(&apiToken, id).Error; err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+
+	if err := s.DB.Delete(&apiToken).Error; err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+	s.APITokenService.Forget(apiToken.ID)
+
+	s.AuditService.Record(c, services.AuditEntry{
+		Action:     models.AuditActionTokenRevoke,
+		TargetType: models.AuditTargetAPIToken,
+		TargetID:   apiToken.ID,
+		TargetName: apiToken.Name,
+		Before:     &apiToken,
+	})
+	response.Success(c, gin.H{"message": fmt.Sprintf("API token '%s' revoked", apiToken.Name)})
+}
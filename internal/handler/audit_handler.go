@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"fmt"
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/response"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// GetAuditLogs handles fetching audit logs with filtering and pagination.
+func (s *Server) GetAuditLogs(c *gin.Context) {
+	query := s.AuditService.GetAuditLogsQuery(c).Order("timestamp desc")
+
+	var auditLogs []models.AuditLog
+	pagination, err := response.Paginate(c, query, &auditLogs)
+	if err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+
+	pagination.Items = auditLogs
+	response.Success(c, pagination)
+}
+
+// ExportAuditLogs handles exporting filtered audit logs to a CSV file.
+func (s *Server) ExportAuditLogs(c *gin.Context) {
+	filename := fmt.Sprintf("audit_logs_export_%s.csv", time.Now().Format("20060102150405"))
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+
+	if err := s.AuditService.StreamAuditLogsToCSV(c, c.Writer); err != nil {
+		logrus.WithError(err).Error("Failed to stream audit logs to CSV")
+		c.JSON(500, gin.H{"error": "Failed to export audit logs"})
+		return
+	}
+}
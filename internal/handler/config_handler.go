@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"fmt"
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/response"
+	"gpt-load/internal/services"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// maxConfigDocumentSize limits the size of uploaded configuration documents.
+const maxConfigDocumentSize = 32 << 20
+
+// ExportConfig handles downloading the configuration document of the instance.
+func (s *Server) ExportConfig(c *gin.Context) {
+	format := c.DefaultQuery("format", services.ConfigFormatYAML)
+	if format != services.ConfigFormatYAML && format != services.ConfigFormatJSON {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, "format must be yaml or json"))
+		return
+	}
+
+	doc, err := s.ConfigDocumentService.Export(c.Query("include_keys") == "true")
+	if err != nil {
+		logrus.WithError(err).Error("Failed to export config document")
+		response.Error(c, app_errors.ErrInternalServer)
+		return
+	}
+	data, err := services.MarshalDocument(doc, format)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to marshal config document")
+		response.Error(c, app_errors.ErrInternalServer)
+		return
+	}
+
+	contentType := "application/yaml"
+	if format == services.ConfigFormatJSON {
+		contentType = "application/json"
+	}
+	filename := fmt.Sprintf("gpt-load-config_%s.%s", time.Now().Format("20060102150405"), format)
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	c.Data(200, contentType+"; charset=utf-8", data)
+}
+
+// DiffConfig handles comparing a configuration document with the instance.
+func (s *Server) DiffConfig(c *gin.Context) {
+	doc, ok := s.bindConfigDocument(c)
+	if !ok {
+		return
+	}
+
+	plan, err := s.ConfigDocumentService.Plan(doc, c.Query("prune") == "true")
+	if err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, err.Error()))
+		return
+	}
+	response.Success(c, plan)
+}
+
+// ApplyConfig handles applying a configuration document to the instance.
+func (s *Server) ApplyConfig(c *gin.Context) {
+	doc, ok := s.bindConfigDocument(c)
+	if !ok {
+		return
+	}
+
+	plan, err := s.ConfigDocumentService.Apply(doc, services.ConfigApplyOptions{
+		DryRun:   c.Query("dry_run") == "true",
+		Prune:    c.Query("prune") == "true",
+		Actor:    c.GetString("actor"),
+		SourceIP: c.ClientIP(),
+	})
+	if err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, err.Error()))
+		return
+	}
+	response.Success(c, plan)
+}
+
+// bindConfigDocument reads a YAML or JSON document from the request body.
+// The format comes from the format query parameter or the Content-Type header.
+func (s *Server) bindConfigDocument(c *gin.Context) (*services.ConfigDocument, bool) {
+	data, err := io.ReadAll(io.LimitReader(c.Request.Body, maxConfigDocumentSize))
+	if err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, "Failed to read request body"))
+		return nil, false
+	}
+
+	format := c.Query("format")
+	if format == "" {
+		format = services.ConfigFormatJSON
+		if strings.Contains(c.ContentType(), "yaml") {
+			format = services.ConfigFormatYAML
+		}
+	}
+
+	doc, err := services.ParseConfigDocument(data, format)
+	if err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return nil, false
+	}
+	return doc, true
+}
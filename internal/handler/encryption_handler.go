@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"errors"
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/response"
+	"gpt-load/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// GetEncryptionStatus handles showing the primary key ID and the values still encrypted with previous keys.
+func (s *Server) GetEncryptionStatus(c *gin.Context) {
+	status, err := s.KeyReencryptService.GetStatus()
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get encryption status")
+		response.Error(c, app_errors.ErrDatabase)
+		return
+	}
+	response.Success(c, status)
+}
+
+// ReencryptKeys handles starting the task that re-encrypts keys and request logs with the primary key.
+func (s *Server) ReencryptKeys(c *gin.Context) {
+	taskStatus, err := s.KeyReencryptService.StartReencryptTask()
+	if err != nil {
+		if errors.Is(err, services.ErrEncryptionDisabled) {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, err.Error()))
+			return
+		}
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrTaskInProgress, err.Error()))
+		return
+	}
+
+	s.AuditService.Record(c, services.AuditEntry{
+		Action:     models.AuditActionKeysReencrypt,
+		TargetType: models.AuditTargetKeys,
+		TargetName: s.EncryptionSvc.KeyID(),
+		Details:    gin.H{"async": true, "total": taskStatus.Total},
+	})
+	response.Success(c, taskStatus)
+}
@@ -4,16 +4,15 @@ package handler
 import (
 	"encoding/json"
 	"fmt"
-	"net/http"
 	"net/url"
 	"sync"
 
 	app_errors "gpt-load/internal/errors"
 	"gpt-load/internal/models"
 	"gpt-load/internal/response"
+	"gpt-load/internal/services"
 	"gpt-load/internal/utils"
 	"reflect"
-	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -25,150 +24,23 @@ import (
 	"gorm.io/datatypes"
 )
 
-// isValidChannelType checks if the channel type is valid by checking against the registered channels.
-func isValidChannelType(channelType string) bool {
-	channels := channel.GetChannels()
-	for _, t := range channels {
-		if t == channelType {
-			return true
-		}
-	}
-	return false
-}
-
-// UpstreamDefinition defines the structure for an upstream in the request.
-type UpstreamDefinition struct {
-	URL    string `json:"url"`
-	Weight int    `json:"weight"`
-}
-
-// validateAndCleanUpstreams validates and cleans the upstreams JSON.
-func validateAndCleanUpstreams(upstreams json.RawMessage) (datatypes.JSON, error) {
-	if len(upstreams) == 0 {
-		return nil, fmt.Errorf("upstreams field is required")
-	}
-
-	var defs []UpstreamDefinition
-	if err := json.Unmarshal(upstreams, &defs); err != nil {
-		return nil, fmt.Errorf("invalid format for upstreams: %w", err)
-	}
-
-	if len(defs) == 0 {
-		return nil, fmt.Errorf("at least one upstream is required")
-	}
-
-	for i := range defs {
-		defs[i].URL = strings.TrimSpace(defs[i].URL)
-		if defs[i].URL == "" {
-			return nil, fmt.Errorf("upstream URL cannot be empty")
-		}
-		// Basic URL format validation
-		if !strings.HasPrefix(defs[i].URL, "http://") && !strings.HasPrefix(defs[i].URL, "https://") {
-			return nil, fmt.Errorf("invalid URL format for upstream: %s", defs[i].URL)
-		}
-		if defs[i].Weight <= 0 {
-			return nil, fmt.Errorf("upstream weight must be a positive integer")
-		}
-	}
-
-	cleanedUpstreams, err := json.Marshal(defs)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal cleaned upstreams: %w", err)
-	}
-
-	return cleanedUpstreams, nil
-}
-
-// isValidGroupName checks if the group name is valid.
-func isValidGroupName(name string) bool {
-	if name == "" {
-		return false
-	}
-	// 允许使用小写字母、数字、下划线和中划线，长度在 1 到 100 个字符之间
-	match, _ := regexp.MatchString("^[a-z0-9_-]{1,100}$", name)
-	return match
-}
-
-// isValidValidationEndpoint checks if the validation endpoint is a valid path.
-func isValidValidationEndpoint(endpoint string) bool {
-	if endpoint == "" {
-		return true
-	}
-	if !strings.HasPrefix(endpoint, "/") {
-		return false
-	}
-	if strings.Contains(endpoint, "://") {
-		return false
-	}
-	return true
-}
-
-// validateAndCleanConfig validates the group config against the GroupConfig struct and system-defined rules.
-func (s *Server) validateAndCleanConfig(configMap map[string]any) (map[string]any, error) {
-	if configMap == nil {
-		return nil, nil
-	}
-
-	// 1. Check for unknown fields by comparing against the GroupConfig struct definition.
-	var tempGroupConfig models.GroupConfig
-	groupConfigType := reflect.TypeOf(tempGroupConfig)
-	validFields := make(map[string]bool)
-	for i := 0; i < groupConfigType.NumField(); i++ {
-		jsonTag := groupConfigType.Field(i).Tag.Get("json")
-		fieldName := strings.Split(jsonTag, ",")[0]
-		if fieldName != "" && fieldName != "-" {
-			validFields[fieldName] = true
-		}
-	}
-
-	for key := range configMap {
-		if !validFields[key] {
-			return nil, fmt.Errorf("unknown config field: '%s'", key)
-		}
-	}
-
-	// 2. Validate the values of the provided fields using the central system settings validator.
-	if err := s.SettingsManager.ValidateGroupConfigOverrides(configMap); err != nil {
-		return nil, err
-	}
-
-	// 3. Unmarshal and marshal back to clean the map and ensure correct types.
-	configBytes, err := json.Marshal(configMap)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal config map: %w", err)
-	}
-
-	var validatedConfig models.GroupConfig
-	if err := json.Unmarshal(configBytes, &validatedConfig); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal into validated config: %w", err)
-	}
-
-	validatedBytes, err := json.Marshal(validatedConfig)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal validated config: %w", err)
-	}
-	var finalMap map[string]any
-	if err := json.Unmarshal(validatedBytes, &finalMap); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal into final map: %w", err)
-	}
-
-	return finalMap, nil
-}
-
 // GroupCreateRequest defines the payload for creating a group.
 type GroupCreateRequest struct {
-	Name               string              `json:"name"`
-	DisplayName        string              `json:"display_name"`
-	Description        string              `json:"description"`
-	Upstreams          json.RawMessage     `json:"upstreams"`
-	ChannelType        string              `json:"channel_type"`
-	Sort               int                 `json:"sort"`
-	TestModel          string              `json:"test_model"`
-	ValidationEndpoint string              `json:"validation_endpoint"`
-	ParamOverrides     map[string]any      `json:"param_overrides"`
-	Config             map[string]any      `json:"config"`
-	HeaderRules        []models.HeaderRule `json:"header_rules"`
-	ProxyKeys          string              `json:"proxy_keys"`
+	Name                string                 `json:"name"`
+	DisplayName         string                 `json:"display_name"`
+	Description         string                 `json:"description"`
+	Upstreams           json.RawMessage        `json:"upstreams"`
+	ChannelType         string                 `json:"channel_type"`
+	Sort                int                    `json:"sort"`
+	TestModel           string                 `json:"test_model"`
+	ValidationEndpoint  string                 `json:"validation_endpoint"`
+	ParamOverrides      map[string]any         `json:"param_overrides"`
+	Config              map[string]any         `json:"config"`
+	HeaderRules         []models.HeaderRule    `json:"header_rules"`
+	ResponseHeaderRules []models.HeaderRule    `json:"response_header_rules"`
+	BodyRules           []models.BodyRule      `json:"body_rules"`
+	RedactionRules      []models.RedactionRule `json:"redaction_rules"`
+	ProxyKeys           string                 `json:"proxy_keys"`
 }
 
 // CreateGroup handles the creation of a new group.
@@ -181,13 +53,13 @@ func (s *Server) CreateGroup(c *gin.Context) {
 
 	// Data Cleaning and Validation
 	name := strings.TrimSpace(req.Name)
-	if !isValidGroupName(name) {
+	if !services.IsValidGroupName(name) {
 		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, "无效的分组名称。只能包含小写字母、数字、中划线或下划线，长度1-100位"))
 		return
 	}
 
 	channelType := strings.TrimSpace(req.ChannelType)
-	if !isValidChannelType(channelType) {
+	if !services.IsValidChannelType(channelType) {
 		supported := strings.Join(channel.GetChannels(), ", ")
 		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, fmt.Sprintf("Invalid channel type. Supported types are: %s", supported)))
 		return
@@ -199,79 +71,65 @@ func (s *Server) CreateGroup(c *gin.Context) {
 		return
 	}
 
-	cleanedUpstreams, err := validateAndCleanUpstreams(req.Upstreams)
+	cleanedUpstreams, err := services.ValidateAndCleanUpstreams(req.Upstreams)
 	if err != nil {
 		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, err.Error()))
 		return
 	}
 
-	cleanedConfig, err := s.validateAndCleanConfig(req.Config)
+	cleanedConfig, err := services.ValidateAndCleanGroupConfig(s.SettingsManager, req.Config)
 	if err != nil {
 		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, fmt.Sprintf("Invalid config format: %v", err)))
 		return
 	}
 
 	validationEndpoint := strings.TrimSpace(req.ValidationEndpoint)
-	if !isValidValidationEndpoint(validationEndpoint) {
+	if !services.IsValidValidationEndpoint(validationEndpoint) {
 		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, "无效的测试路径。如果提供，必须是以 / 开头的有效路径，且不能是完整的URL。"))
 		return
 	}
 
 	// Validate and normalize header rules if provided
-	var headerRulesJSON datatypes.JSON
-	if len(req.HeaderRules) > 0 {
-		normalizedHeaderRules := make([]models.HeaderRule, 0)
-		seenKeys := make(map[string]bool)
-
-		for _, rule := range req.HeaderRules {
-			key := strings.TrimSpace(rule.Key)
-			if key == "" {
-				continue
-			}
-
-			// Normalize to canonical form
-			canonicalKey := http.CanonicalHeaderKey(key)
-
-			// Check for duplicate keys
-			if seenKeys[canonicalKey] {
-				response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, fmt.Sprintf("Duplicate header key: %s", canonicalKey)))
-				return
-			}
-			seenKeys[canonicalKey] = true
+	headerRulesJSON, err := services.NormalizeHeaderRules(req.HeaderRules)
+	if err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, err.Error()))
+		return
+	}
 
-			normalizedHeaderRules = append(normalizedHeaderRules, models.HeaderRule{
-				Key:    canonicalKey,
-				Value:  rule.Value,
-				Action: rule.Action,
-			})
-		}
+	responseHeaderRulesJSON, err := services.NormalizeHeaderRules(req.ResponseHeaderRules)
+	if err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, fmt.Sprintf("Invalid response header rules: %v", err)))
+		return
+	}
 
-		if len(normalizedHeaderRules) > 0 {
-			headerRulesBytes, err := json.Marshal(normalizedHeaderRules)
-			if err != nil {
-				response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, fmt.Sprintf("Failed to process header rules: %v", err)))
-				return
-			}
-			headerRulesJSON = headerRulesBytes
-		}
+	bodyRulesJSON, err := services.ValidateAndCleanBodyRules(req.BodyRules)
+	if err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, fmt.Sprintf("Invalid body rules: %v", err)))
+		return
 	}
-	if headerRulesJSON == nil {
-		headerRulesJSON = datatypes.JSON("[]")
+
+	redactionRulesJSON, err := services.ValidateAndCleanRedactionRules(req.RedactionRules)
+	if err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, fmt.Sprintf("Invalid redaction rules: %v", err)))
+		return
 	}
 
 	group := models.Group{
-		Name:               name,
-		DisplayName:        strings.TrimSpace(req.DisplayName),
-		Description:        strings.TrimSpace(req.Description),
-		Upstreams:          cleanedUpstreams,
-		ChannelType:        channelType,
-		Sort:               req.Sort,
-		TestModel:          testModel,
-		ValidationEndpoint: validationEndpoint,
-		ParamOverrides:     req.ParamOverrides,
-		Config:             cleanedConfig,
-		HeaderRules:        headerRulesJSON,
-		ProxyKeys:          strings.TrimSpace(req.ProxyKeys),
+		Name:                name,
+		DisplayName:         strings.TrimSpace(req.DisplayName),
+		Description:         strings.TrimSpace(req.Description),
+		Upstreams:           cleanedUpstreams,
+		ChannelType:         channelType,
+		Sort:                req.Sort,
+		TestModel:           testModel,
+		ValidationEndpoint:  validationEndpoint,
+		ParamOverrides:      req.ParamOverrides,
+		Config:              cleanedConfig,
+		HeaderRules:         headerRulesJSON,
+		ResponseHeaderRules: responseHeaderRulesJSON,
+		BodyRules:           bodyRulesJSON,
+		RedactionRules:      redactionRulesJSON,
+		ProxyKeys:           strings.TrimSpace(req.ProxyKeys),
 	}
 
 	if err := s.DB.Create(&group).Error; err != nil {
@@ -282,13 +140,21 @@ func (s *Server) CreateGroup(c *gin.Context) {
 	if err := s.GroupManager.Invalidate(); err != nil {
 		logrus.WithContext(c.Request.Context()).WithError(err).Error("failed to invalidate group cache")
 	}
+
+	s.AuditService.Record(c, services.AuditEntry{
+		Action:     models.AuditActionGroupCreate,
+		TargetType: models.AuditTargetGroup,
+		TargetID:   group.ID,
+		TargetName: group.Name,
+		After:      &group,
+	})
 	response.Success(c, s.newGroupResponse(&group))
 }
 
 // ListGroups handles listing all groups.
 func (s *Server) ListGroups(c *gin.Context) {
 	var groups []models.Group
-	if err := s.DB.Order("sort asc, id desc").Find(&groups).Error; err != nil {
+	if err := s.DB.Scopes(services.AdminGroupScope(c, "id")).Order("sort asc, id desc").Find(&groups).Error; err != nil {
 		response.Error(c, app_errors.ParseDBError(err))
 		return
 	}
@@ -304,18 +170,21 @@ func (s *Server) ListGroups(c *gin.Context) {
 // GroupUpdateRequest defines the payload for updating a group.
 // Using a dedicated struct avoids issues with zero values being ignored by GORM's Update.
 type GroupUpdateRequest struct {
-	Name               *string             `json:"name,omitempty"`
-	DisplayName        *string             `json:"display_name,omitempty"`
-	Description        *string             `json:"description,omitempty"`
-	Upstreams          json.RawMessage     `json:"upstreams"`
-	ChannelType        *string             `json:"channel_type,omitempty"`
-	Sort               *int                `json:"sort"`
-	TestModel          string              `json:"test_model"`
-	ValidationEndpoint *string             `json:"validation_endpoint,omitempty"`
-	ParamOverrides     map[string]any      `json:"param_overrides"`
-	Config             map[string]any      `json:"config"`
-	HeaderRules        []models.HeaderRule `json:"header_rules"`
-	ProxyKeys          *string             `json:"proxy_keys,omitempty"`
+	Name                *string                `json:"name,omitempty"`
+	DisplayName         *string                `json:"display_name,omitempty"`
+	Description         *string                `json:"description,omitempty"`
+	Upstreams           json.RawMessage        `json:"upstreams"`
+	ChannelType         *string                `json:"channel_type,omitempty"`
+	Sort                *int                   `json:"sort"`
+	TestModel           string                 `json:"test_model"`
+	ValidationEndpoint  *string                `json:"validation_endpoint,omitempty"`
+	ParamOverrides      map[string]any         `json:"param_overrides"`
+	Config              map[string]any         `json:"config"`
+	HeaderRules         []models.HeaderRule    `json:"header_rules"`
+	ResponseHeaderRules []models.HeaderRule    `json:"response_header_rules"`
+	BodyRules           []models.BodyRule      `json:"body_rules"`
+	RedactionRules      []models.RedactionRule `json:"redaction_rules"`
+	ProxyKeys           *string                `json:"proxy_keys,omitempty"`
 }
 
 // UpdateGroup handles updating an existing group.
@@ -338,6 +207,9 @@ func (s *Server) UpdateGroup(c *gin.Context) {
 		return
 	}
 
+	// 记录变更前的状态用于审计
+	before := group
+
 	// Start a transaction
 	tx := s.DB.Begin()
 	if tx.Error != nil {
@@ -349,7 +221,7 @@ func (s *Server) UpdateGroup(c *gin.Context) {
 	// Apply updates from the request, with cleaning and validation
 	if req.Name != nil {
 		cleanedName := strings.TrimSpace(*req.Name)
-		if !isValidGroupName(cleanedName) {
+		if !services.IsValidGroupName(cleanedName) {
 			response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, "无效的分组名称格式。只能包含小写字母、数字、中划线或下划线，长度1-100位"))
 			return
 		}
@@ -365,7 +237,7 @@ func (s *Server) UpdateGroup(c *gin.Context) {
 	}
 
 	if req.Upstreams != nil {
-		cleanedUpstreams, err := validateAndCleanUpstreams(req.Upstreams)
+		cleanedUpstreams, err := services.ValidateAndCleanUpstreams(req.Upstreams)
 		if err != nil {
 			response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, err.Error()))
 			return
@@ -375,7 +247,7 @@ func (s *Server) UpdateGroup(c *gin.Context) {
 
 	if req.ChannelType != nil {
 		cleanedChannelType := strings.TrimSpace(*req.ChannelType)
-		if !isValidChannelType(cleanedChannelType) {
+		if !services.IsValidChannelType(cleanedChannelType) {
 			supported := strings.Join(channel.GetChannels(), ", ")
 			response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, fmt.Sprintf("Invalid channel type. Supported types are: %s", supported)))
 			return
@@ -398,7 +270,7 @@ func (s *Server) UpdateGroup(c *gin.Context) {
 	}
 	if req.ValidationEndpoint != nil {
 		validationEndpoint := strings.TrimSpace(*req.ValidationEndpoint)
-		if !isValidValidationEndpoint(validationEndpoint) {
+		if !services.IsValidValidationEndpoint(validationEndpoint) {
 			response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, "无效的测试路径。如果提供，必须是以 / 开头的有效路径，且不能是完整的URL。"))
 			return
 		}
@@ -406,7 +278,7 @@ func (s *Server) UpdateGroup(c *gin.Context) {
 	}
 
 	if req.Config != nil {
-		cleanedConfig, err := s.validateAndCleanConfig(req.Config)
+		cleanedConfig, err := services.ValidateAndCleanGroupConfig(s.SettingsManager, req.Config)
 		if err != nil {
 			response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, fmt.Sprintf("Invalid config format: %v", err)))
 			return
@@ -420,44 +292,39 @@ func (s *Server) UpdateGroup(c *gin.Context) {
 
 	// Handle header rules update
 	if req.HeaderRules != nil {
-		var headerRulesJSON datatypes.JSON
-		normalizedHeaderRules := make([]models.HeaderRule, 0)
-		seenKeys := make(map[string]bool)
-
-		for _, rule := range req.HeaderRules {
-			key := strings.TrimSpace(rule.Key)
-			if key == "" {
-				continue
-			}
-
-			// Normalize to canonical form
-			canonicalKey := http.CanonicalHeaderKey(key)
+		headerRulesJSON, err := services.NormalizeHeaderRules(req.HeaderRules)
+		if err != nil {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, err.Error()))
+			return
+		}
+		group.HeaderRules = headerRulesJSON
+	}
 
-			// Check for duplicate keys
-			if seenKeys[canonicalKey] {
-				response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, fmt.Sprintf("Duplicate header key: %s", canonicalKey)))
-				return
-			}
-			seenKeys[canonicalKey] = true
+	if req.ResponseHeaderRules != nil {
+		responseHeaderRulesJSON, err := services.NormalizeHeaderRules(req.ResponseHeaderRules)
+		if err != nil {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, fmt.Sprintf("Invalid response header rules: %v", err)))
+			return
+		}
+		group.ResponseHeaderRules = responseHeaderRulesJSON
+	}
 
-			normalizedHeaderRules = append(normalizedHeaderRules, models.HeaderRule{
-				Key:    canonicalKey,
-				Value:  rule.Value,
-				Action: rule.Action,
-			})
+	if req.BodyRules != nil {
+		bodyRulesJSON, err := services.ValidateAndCleanBodyRules(req.BodyRules)
+		if err != nil {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, fmt.Sprintf("Invalid body rules: %v", err)))
+			return
 		}
+		group.BodyRules = bodyRulesJSON
+	}
 
-		if len(normalizedHeaderRules) > 0 {
-			headerRulesBytes, err := json.Marshal(normalizedHeaderRules)
-			if err != nil {
-				response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, fmt.Sprintf("Failed to process header rules: %v", err)))
-				return
-			}
-			headerRulesJSON = headerRulesBytes
-		} else {
-			headerRulesJSON = datatypes.JSON("[]")
+	if req.RedactionRules != nil {
+		redactionRulesJSON, err := services.ValidateAndCleanRedactionRules(req.RedactionRules)
+		if err != nil {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, fmt.Sprintf("Invalid redaction rules: %v", err)))
+			return
 		}
-		group.HeaderRules = headerRulesJSON
+		group.RedactionRules = redactionRulesJSON
 	}
 
 	// Save the updated group object
@@ -474,28 +341,40 @@ func (s *Server) UpdateGroup(c *gin.Context) {
 	if err := s.GroupManager.Invalidate(); err != nil {
 		logrus.WithContext(c.Request.Context()).WithError(err).Error("failed to invalidate group cache")
 	}
+
+	s.AuditService.Record(c, services.AuditEntry{
+		Action:     models.AuditActionGroupUpdate,
+		TargetType: models.AuditTargetGroup,
+		TargetID:   group.ID,
+		TargetName: group.Name,
+		Before:     &before,
+		After:      &group,
+	})
 	response.Success(c, s.newGroupResponse(&group))
 }
 
 // GroupResponse defines the structure for a group response, excluding sensitive or large fields.
 type GroupResponse struct {
-	ID                 uint                `json:"id"`
-	Name               string              `json:"name"`
-	Endpoint           string              `json:"endpoint"`
-	DisplayName        string              `json:"display_name"`
-	Description        string              `json:"description"`
-	Upstreams          datatypes.JSON      `json:"upstreams"`
-	ChannelType        string              `json:"channel_type"`
-	Sort               int                 `json:"sort"`
-	TestModel          string              `json:"test_model"`
-	ValidationEndpoint string              `json:"validation_endpoint"`
-	ParamOverrides     datatypes.JSONMap   `json:"param_overrides"`
-	Config             datatypes.JSONMap   `json:"config"`
-	HeaderRules        []models.HeaderRule `json:"header_rules"`
-	ProxyKeys          string              `json:"proxy_keys"`
-	LastValidatedAt    *time.Time          `json:"last_validated_at"`
-	CreatedAt          time.Time           `json:"created_at"`
-	UpdatedAt          time.Time           `json:"updated_at"`
+	ID                  uint                   `json:"id"`
+	Name                string                 `json:"name"`
+	Endpoint            string                 `json:"endpoint"`
+	DisplayName         string                 `json:"display_name"`
+	Description         string                 `json:"description"`
+	Upstreams           datatypes.JSON         `json:"upstreams"`
+	ChannelType         string                 `json:"channel_type"`
+	Sort                int                    `json:"sort"`
+	TestModel           string                 `json:"test_model"`
+	ValidationEndpoint  string                 `json:"validation_endpoint"`
+	ParamOverrides      datatypes.JSONMap      `json:"param_overrides"`
+	Config              datatypes.JSONMap      `json:"config"`
+	HeaderRules         []models.HeaderRule    `json:"header_rules"`
+	ResponseHeaderRules []models.HeaderRule    `json:"response_header_rules"`
+	BodyRules           []models.BodyRule      `json:"body_rules"`
+	RedactionRules      []models.RedactionRule `json:"redaction_rules"`
+	ProxyKeys           string                 `json:"proxy_keys"`
+	LastValidatedAt     *time.Time             `json:"last_validated_at"`
+	CreatedAt           time.Time              `json:"created_at"`
+	UpdatedAt           time.Time              `json:"updated_at"`
 }
 
 // newGroupResponse creates a new GroupResponse from a models.Group.
@@ -519,24 +398,54 @@ func (s *Server) newGroupResponse(group *models.Group) *GroupResponse {
 		}
 	}
 
+	// Parse response header rules from JSON
+	responseHeaderRules := make([]models.HeaderRule, 0)
+	if len(group.ResponseHeaderRules) > 0 {
+		if err := json.Unmarshal(group.ResponseHeaderRules, &responseHeaderRules); err != nil {
+			logrus.WithError(err).Error("Failed to unmarshal response header rules")
+			responseHeaderRules = make([]models.HeaderRule, 0)
+		}
+	}
+
+	// Parse body rules from JSON
+	bodyRules := make([]models.BodyRule, 0)
+	if len(group.BodyRules) > 0 {
+		if err := json.Unmarshal(group.BodyRules, &bodyRules); err != nil {
+			logrus.WithError(err).Error("Failed to unmarshal body rules")
+			bodyRules = make([]models.BodyRule, 0)
+		}
+	}
+
+	// Parse log redaction rules from JSON
+	redactionRules := make([]models.RedactionRule, 0)
+	if len(group.RedactionRules) > 0 {
+		if err := json.Unmarshal(group.RedactionRules, &redactionRules); err != nil {
+			logrus.WithError(err).Error("Failed to unmarshal redaction rules")
+			redactionRules = make([]models.RedactionRule, 0)
+		}
+	}
+
 	return &GroupResponse{
-		ID:                 group.ID,
-		Name:               group.Name,
-		Endpoint:           endpoint,
-		DisplayName:        group.DisplayName,
-		Description:        group.Description,
-		Upstreams:          group.Upstreams,
-		ChannelType:        group.ChannelType,
-		Sort:               group.Sort,
-		TestModel:          group.TestModel,
-		ValidationEndpoint: group.ValidationEndpoint,
-		ParamOverrides:     group.ParamOverrides,
-		Config:             group.Config,
-		HeaderRules:        headerRules,
-		ProxyKeys:          group.ProxyKeys,
-		LastValidatedAt:    group.LastValidatedAt,
-		CreatedAt:          group.CreatedAt,
-		UpdatedAt:          group.UpdatedAt,
+		ID:                  group.ID,
+		Name:                group.Name,
+		Endpoint:            endpoint,
+		DisplayName:         group.DisplayName,
+		Description:         group.Description,
+		Upstreams:           group.Upstreams,
+		ChannelType:         group.ChannelType,
+		Sort:                group.Sort,
+		TestModel:           group.TestModel,
+		ValidationEndpoint:  group.ValidationEndpoint,
+		ParamOverrides:      group.ParamOverrides,
+		Config:              group.Config,
+		HeaderRules:         headerRules,
+		ResponseHeaderRules: responseHeaderRules,
+		BodyRules:           bodyRules,
+		RedactionRules:      redactionRules,
+		ProxyKeys:           group.ProxyKeys,
+		LastValidatedAt:     group.LastValidatedAt,
+		CreatedAt:           group.CreatedAt,
+		UpdatedAt:           group.UpdatedAt,
 	}
 }
 
@@ -622,6 +531,15 @@ func (s *Server) DeleteGroup(c *gin.Context) {
 	if err := s.GroupManager.Invalidate(); err != nil {
 		logrus.WithContext(c.Request.Context()).WithError(err).Error("failed to invalidate group cache")
 	}
+
+	s.AuditService.Record(c, services.AuditEntry{
+		Action:     models.AuditActionGroupDelete,
+		TargetType: models.AuditTargetGroup,
+		TargetID:   group.ID,
+		TargetName: group.Name,
+		Before:     &group,
+		Details:    gin.H{"deleted_keys": len(keyIDs)},
+	})
 	response.Success(c, gin.H{"message": "Group and associated keys deleted successfully"})
 }
 
@@ -705,10 +623,12 @@ type RequestStats struct {
 
 // GroupStatsResponse defines the complete statistics for a group.
 type GroupStatsResponse struct {
-	KeyStats    KeyStats     `json:"key_stats"`
-	HourlyStats RequestStats `json:"hourly_stats"` // 1 hour
-	DailyStats  RequestStats `json:"daily_stats"`  // 24 hours
-	WeeklyStats RequestStats `json:"weekly_stats"` // 7 days
+	KeyStats    KeyStats            `json:"key_stats"`
+	HourlyStats RequestStats        `json:"hourly_stats"` // 1 hour
+	DailyStats  RequestStats        `json:"daily_stats"`  // 24 hours
+	WeeklyStats RequestStats        `json:"weekly_stats"` // 7 days
+	QueueStats  services.QueueStats `json:"queue_stats"`
+	Coalesced   int64               `json:"coalesced_requests"`
 }
 
 // calculateRequestStats is a helper to compute request statistics.
@@ -855,6 +775,38 @@ func (s *Server) GetGroupStats(c *gin.Context) {
 		mu.Unlock()
 	}()
 
+	// 5. 排队统计
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		stats, err := s.RequestQueueService.GetStats(groupID)
+		if err != nil {
+			mu.Lock()
+			errors = append(errors, fmt.Errorf("failed to get queue stats: %w", err))
+			mu.Unlock()
+			return
+		}
+		mu.Lock()
+		resp.QueueStats = *stats
+		mu.Unlock()
+	}()
+
+	// 6. 请求合并统计
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		count, err := s.RequestCoalescer.GetCoalescedCount(groupID)
+		if err != nil {
+			mu.Lock()
+			errors = append(errors, fmt.Errorf("failed to get coalesce stats: %w", err))
+			mu.Unlock()
+			return
+		}
+		mu.Lock()
+		resp.Coalesced = count
+		mu.Unlock()
+	}()
+
 	wg.Wait()
 
 	if len(errors) > 0 {
@@ -1023,6 +975,20 @@ func (s *Server) CopyGroup(c *gin.Context) {
 		}
 	}
 
+	s.AuditService.Record(c, services.AuditEntry{
+		Action:     models.AuditActionGroupCopy,
+		TargetType: models.AuditTargetGroup,
+		TargetID:   newGroup.ID,
+		TargetName: newGroup.Name,
+		After:      &newGroup,
+		Details: gin.H{
+			"source_group_id":   sourceGroup.ID,
+			"source_group_name": sourceGroup.Name,
+			"copy_keys":         req.CopyKeys,
+			"key_count":         len(sourceKeyValues),
+		},
+	})
+
 	// Prepare response
 	groupResponse := s.newGroupResponse(&newGroup)
 	copyResponse := &GroupCopyResponse{
@@ -1035,7 +1001,7 @@ func (s *Server) CopyGroup(c *gin.Context) {
 // List godoc
 func (s *Server) List(c *gin.Context) {
 	var groups []models.Group
-	if err := s.DB.Select("id, name,display_name").Find(&groups).Error; err != nil {
+	if err := s.DB.Scopes(services.AdminGroupScope(c, "id")).Select("id, name,display_name").Find(&groups).Error; err != nil {
 		response.Error(c, app_errors.NewAPIError(app_errors.ErrDatabase, "无法获取分组列表"))
 		return
 	}
@@ -4,14 +4,19 @@ package handler
 import (
 	"crypto/subtle"
 	"net/http"
+	"strconv"
 	"time"
 
 	"gpt-load/internal/config"
 	"gpt-load/internal/encryption"
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/response"
 	"gpt-load/internal/services"
 	"gpt-load/internal/types"
 
 	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
 	"go.uber.org/dig"
 	"gorm.io/gorm"
 )
@@ -28,6 +33,16 @@ type Server struct {
 	KeyImportService           *services.KeyImportService
 	KeyDeleteService           *services.KeyDeleteService
 	LogService                 *services.LogService
+	AuditService               *services.AuditService
+	AdminUserService           *services.AdminUserService
+	AdminSessionService        *services.AdminSessionService
+	OIDCService                *services.OIDCService
+	APITokenService            *services.APITokenService
+	ConfigDocumentService      *services.ConfigDocumentService
+	KeyReencryptService        *services.KeyReencryptService
+	LogArchiver                *services.LogArchiver
+	RequestQueueService        *services.RequestQueueService
+	RequestCoalescer           *services.RequestCoalescer
 	CommonHandler              *CommonHandler
 	EncryptionSvc              encryption.Service
 }
@@ -45,6 +60,16 @@ type NewServerParams struct {
 	KeyImportService           *services.KeyImportService
 	KeyDeleteService           *services.KeyDeleteService
 	LogService                 *services.LogService
+	AuditService               *services.AuditService
+	AdminUserService           *services.AdminUserService
+	AdminSessionService        *services.AdminSessionService
+	OIDCService                *services.OIDCService
+	APITokenService            *services.APITokenService
+	ConfigDocumentService      *services.ConfigDocumentService
+	KeyReencryptService        *services.KeyReencryptService
+	LogArchiver                *services.LogArchiver
+	RequestQueueService        *services.RequestQueueService
+	RequestCoalescer           *services.RequestCoalescer
 	CommonHandler              *CommonHandler
 	EncryptionSvc              encryption.Service
 }
@@ -62,26 +87,44 @@ func NewServer(params NewServerParams) *Server {
 		KeyImportService:           params.KeyImportService,
 		KeyDeleteService:           params.KeyDeleteService,
 		LogService:                 params.LogService,
+		AuditService:               params.AuditService,
+		AdminUserService:           params.AdminUserService,
+		AdminSessionService:        params.AdminSessionService,
+		OIDCService:                params.OIDCService,
+		APITokenService:            params.APITokenService,
+		ConfigDocumentService:      params.ConfigDocumentService,
+		KeyReencryptService:        params.KeyReencryptService,
+		LogArchiver:                params.LogArchiver,
+		RequestQueueService:        params.RequestQueueService,
+		RequestCoalescer:           params.RequestCoalescer,
 		CommonHandler:              params.CommonHandler,
 		EncryptionSvc:              params.EncryptionSvc,
 	}
 }
 
-// LoginRequest represents the login request payload
+// LoginRequest represents the login request payload.
+// Either auth_key or username and password must be provided.
 type LoginRequest struct {
-	AuthKey string `json:"auth_key" binding:"required"`
+	AuthKey  string `json:"auth_key"`
+	Username string `json:"username"`
+	Password string `json:"password"`
 }
 
 // LoginResponse represents the login response
 type LoginResponse struct {
-	Success bool   `json:"success"`
-	Message string `json:"message"`
+	Success   bool       `json:"success"`
+	Message   string     `json:"message"`
+	Username  string     `json:"username,omitempty"`
+	Role      string     `json:"role,omitempty"`
+	Token     string     `json:"token,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
 }
 
-// Login handles authentication verification
+// Login verifies the credentials and issues a session token.
+// Repeated failures from the same IP lock further attempts out for a while.
 func (s *Server) Login(c *gin.Context) {
 	var req LoginRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := c.ShouldBindJSON(&req); err != nil || (req.AuthKey == "" && req.Username == "") {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
 			"message": "Invalid request format",
@@ -89,21 +132,99 @@ func (s *Server) Login(c *gin.Context) {
 		return
 	}
 
-	authConfig := s.config.GetAuthConfig()
-
-	isValid := subtle.ConstantTimeCompare([]byte(req.AuthKey), []byte(authConfig.Key)) == 1
-
-	if isValid {
-		c.JSON(http.StatusOK, LoginResponse{
-			Success: true,
-			Message: "Authentication successful",
+	clientIP := c.ClientIP()
+	if lockedFor := s.AdminSessionService.LoginLockedFor(clientIP); lockedFor > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(lockedFor.Seconds())+1))
+		c.JSON(http.StatusTooManyRequests, LoginResponse{
+			Success: false,
+			Message: "Too many failed login attempts, please try again later",
 		})
+		return
+	}
+
+	var principal *services.AdminPrincipal
+	if req.Username != "" {
+		var err error
+		principal, err = s.AdminUserService.Authenticate(req.Username, req.Password)
+		if err != nil && err != services.ErrInvalidCredentials {
+			logrus.WithError(err).Error("Failed to authenticate admin user")
+		}
+		if principal != nil {
+			if err := s.DB.Model(&models.AdminUser{}).Where("id = ?", principal.UserID).Update("last_login_at", time.Now()).Error; err != nil {
+				logrus.WithError(err).Warn("Failed to update admin last login time")
+			}
+		}
 	} else {
+		authConfig := s.config.GetAuthConfig()
+		if subtle.ConstantTimeCompare([]byte(req.AuthKey), []byte(authConfig.Key)) == 1 {
+			principal = services.NewOwnerPrincipal()
+		}
+	}
+
+	if principal == nil {
+		if lockedFor := s.AdminSessionService.RecordLoginFailure(clientIP); lockedFor > 0 {
+			c.Header("Retry-After", strconv.Itoa(int(lockedFor.Seconds())))
+		}
 		c.JSON(http.StatusUnauthorized, LoginResponse{
 			Success: false,
 			Message: "Authentication failed",
 		})
+		return
+	}
+
+	s.AdminSessionService.ResetLoginFailures(clientIP)
+	session, err := s.AdminSessionService.Create(principal)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to create admin session")
+		c.JSON(http.StatusInternalServerError, LoginResponse{
+			Success: false,
+			Message: "Failed to create session",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, LoginResponse{
+		Success:   true,
+		Message:   "Authentication successful",
+		Username:  principal.Username,
+		Role:      principal.Role,
+		Token:     session.Token,
+		ExpiresAt: &session.ExpiresAt,
+	})
+}
+
+// RefreshSession replaces the current session token with a new one.
+func (s *Server) RefreshSession(c *gin.Context) {
+	token := c.GetString("adminSessionToken")
+	if token == "" {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, "Only session tokens can be refreshed"))
+		return
+	}
+
+	session, err := s.AdminSessionService.Refresh(token)
+	if err != nil {
+		if err == services.ErrSessionInvalid {
+			response.Error(c, app_errors.ErrUnauthorized)
+		} else {
+			logrus.WithError(err).Error("Failed to refresh admin session")
+			response.Error(c, app_errors.ErrInternalServer)
+		}
+		return
+	}
+
+	response.Success(c, session)
+}
+
+// Logout revokes the current session token.
+func (s *Server) Logout(c *gin.Context) {
+	if token := c.GetString("adminSessionToken"); token != "" {
+		if err := s.AdminSessionService.Revoke(token); err != nil {
+			logrus.WithError(err).Error("Failed to revoke admin session")
+			response.Error(c, app_errors.ErrInternalServer)
+			return
+		}
 	}
+	response.Success(c, gin.H{"message": "Logged out successfully"})
 }
 
 // Health handles health check requests
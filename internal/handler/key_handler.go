@@ -5,6 +5,7 @@ import (
 	app_errors "gpt-load/internal/errors"
 	"gpt-load/internal/models"
 	"gpt-load/internal/response"
+	"gpt-load/internal/services"
 	"log"
 	"strconv"
 	"strings"
@@ -40,7 +41,13 @@ func validateKeysText(keysText string) error {
 }
 
 // findGroupByID is a helper function to find a group by its ID.
+// It also rejects group-scoped admins accessing groups outside their scope.
 func (s *Server) findGroupByID(c *gin.Context, groupID uint) (*models.Group, bool) {
+	if principal := services.AdminPrincipalFromContext(c); principal != nil && !principal.CanAccessGroup(groupID) {
+		response.Error(c, app_errors.ErrForbidden)
+		return nil, false
+	}
+
 	var group models.Group
 	if err := s.DB.First(&group, groupID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
@@ -53,6 +60,17 @@ func (s *Server) findGroupByID(c *gin.Context, groupID uint) (*models.Group, boo
 	return &group, true
 }
 
+// recordKeyAudit records an audit entry for a key operation on a group.
+func (s *Server) recordKeyAudit(c *gin.Context, action string, group *models.Group, details any) {
+	s.AuditService.Record(c, services.AuditEntry{
+		Action:     action,
+		TargetType: models.AuditTargetGroup,
+		TargetID:   group.ID,
+		TargetName: group.Name,
+		Details:    details,
+	})
+}
+
 // KeyTextRequest defines a generic payload for operations requiring a group ID and a text block of keys.
 type KeyTextRequest struct {
 	GroupID  uint   `json:"group_id" binding:"required"`
@@ -78,7 +96,8 @@ func (s *Server) AddMultipleKeys(c *gin.Context) {
 		return
 	}
 
-	if _, ok := s.findGroupByID(c, req.GroupID); !ok {
+	group, ok := s.findGroupByID(c, req.GroupID)
+	if !ok {
 		return
 	}
 
@@ -99,6 +118,7 @@ func (s *Server) AddMultipleKeys(c *gin.Context) {
 		return
 	}
 
+	s.recordKeyAudit(c, models.AuditActionKeysAdd, group, result)
 	response.Success(c, result)
 }
 
@@ -126,6 +146,7 @@ func (s *Server) AddMultipleKeysAsync(c *gin.Context) {
 		return
 	}
 
+	s.recordKeyAudit(c, models.AuditActionKeysAdd, group, gin.H{"async": true, "total": taskStatus.Total})
 	response.Success(c, taskStatus)
 }
 
@@ -147,13 +168,9 @@ func (s *Server) ListKeysInGroup(c *gin.Context) {
 		return
 	}
 
-	searchKeyword := c.Query("key_value")
-	searchHash := ""
-	if searchKeyword != "" {
-		searchHash = s.EncryptionSvc.Hash(searchKeyword)
-	}
+	searchHashes := s.EncryptionSvc.Hashes(c.Query("key_value"))
 
-	query := s.KeyService.ListKeysInGroupQuery(groupID, statusFilter, searchHash)
+	query := s.KeyService.ListKeysInGroupQuery(groupID, statusFilter, searchHashes)
 
 	var keys []models.APIKey
 	paginatedResult, err := response.Paginate(c, query, &keys)
@@ -185,7 +202,8 @@ func (s *Server) DeleteMultipleKeys(c *gin.Context) {
 		return
 	}
 
-	if _, ok := s.findGroupByID(c, req.GroupID); !ok {
+	group, ok := s.findGroupByID(c, req.GroupID)
+	if !ok {
 		return
 	}
 
@@ -206,6 +224,7 @@ func (s *Server) DeleteMultipleKeys(c *gin.Context) {
 		return
 	}
 
+	s.recordKeyAudit(c, models.AuditActionKeysDelete, group, result)
 	response.Success(c, result)
 }
 
@@ -233,6 +252,7 @@ func (s *Server) DeleteMultipleKeysAsync(c *gin.Context) {
 		return
 	}
 
+	s.recordKeyAudit(c, models.AuditActionKeysDelete, group, gin.H{"async": true, "total": taskStatus.Total})
 	response.Success(c, taskStatus)
 }
 
@@ -244,7 +264,8 @@ func (s *Server) RestoreMultipleKeys(c *gin.Context) {
 		return
 	}
 
-	if _, ok := s.findGroupByID(c, req.GroupID); !ok {
+	group, ok := s.findGroupByID(c, req.GroupID)
+	if !ok {
 		return
 	}
 
@@ -265,6 +286,7 @@ func (s *Server) RestoreMultipleKeys(c *gin.Context) {
 		return
 	}
 
+	s.recordKeyAudit(c, models.AuditActionKeysRestore, group, result)
 	response.Success(c, result)
 }
 
@@ -343,6 +365,7 @@ func (s *Server) ValidateGroupKeys(c *gin.Context) {
 		return
 	}
 
+	s.recordKeyAudit(c, models.AuditActionKeysValidate, groupDB, gin.H{"status": req.Status})
 	response.Success(c, taskStatus)
 }
 
@@ -354,7 +377,8 @@ func (s *Server) RestoreAllInvalidKeys(c *gin.Context) {
 		return
 	}
 
-	if _, ok := s.findGroupByID(c, req.GroupID); !ok {
+	group, ok := s.findGroupByID(c, req.GroupID)
+	if !ok {
 		return
 	}
 
@@ -364,6 +388,7 @@ func (s *Server) RestoreAllInvalidKeys(c *gin.Context) {
 		return
 	}
 
+	s.recordKeyAudit(c, models.AuditActionKeysRestoreInvalid, group, gin.H{"restored_count": rowsAffected})
 	response.Success(c, gin.H{"message": fmt.Sprintf("%d keys restored.", rowsAffected)})
 }
 
@@ -375,7 +400,8 @@ func (s *Server) ClearAllInvalidKeys(c *gin.Context) {
 		return
 	}
 
-	if _, ok := s.findGroupByID(c, req.GroupID); !ok {
+	group, ok := s.findGroupByID(c, req.GroupID)
+	if !ok {
 		return
 	}
 
@@ -385,6 +411,7 @@ func (s *Server) ClearAllInvalidKeys(c *gin.Context) {
 		return
 	}
 
+	s.recordKeyAudit(c, models.AuditActionKeysClearInvalid, group, gin.H{"deleted_count": rowsAffected})
 	response.Success(c, gin.H{"message": fmt.Sprintf("%d invalid keys cleared.", rowsAffected)})
 }
 
@@ -396,7 +423,8 @@ func (s *Server) ClearAllKeys(c *gin.Context) {
 		return
 	}
 
-	if _, ok := s.findGroupByID(c, req.GroupID); !ok {
+	group, ok := s.findGroupByID(c, req.GroupID)
+	if !ok {
 		return
 	}
 
@@ -406,6 +434,7 @@ func (s *Server) ClearAllKeys(c *gin.Context) {
 		return
 	}
 
+	s.recordKeyAudit(c, models.AuditActionKeysClearAll, group, gin.H{"deleted_count": rowsAffected})
 	response.Success(c, gin.H{"message": fmt.Sprintf("%d keys cleared.", rowsAffected)})
 }
 
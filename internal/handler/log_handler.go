@@ -5,6 +5,8 @@ import (
 	app_errors "gpt-load/internal/errors"
 	"gpt-load/internal/models"
 	"gpt-load/internal/response"
+	"gpt-load/internal/services"
+	"gpt-load/internal/utils"
 	"log"
 	"time"
 
@@ -21,8 +23,9 @@ type LogResponse struct {
 func (s *Server) GetLogs(c *gin.Context) {
 	query := s.LogService.GetLogsQuery(c)
 
+	// 响应内容只在详情接口返回，减小列表的数据量
 	var logs []models.RequestLog
-	query = query.Order("timestamp desc")
+	query = query.Omit("response_body").Order("timestamp desc")
 	pagination, err := response.Paginate(c, query, &logs)
 	if err != nil {
 		response.Error(c, app_errors.ParseDBError(err))
@@ -30,22 +33,69 @@ func (s *Server) GetLogs(c *gin.Context) {
 	}
 
 	// 解密所有日志中的密钥用于前端显示
+	canReadKeys := s.canReadLogKeys(c)
 	for i := range logs {
-		if logs[i].KeyValue != "" {
-			decryptedValue, err := s.EncryptionSvc.Decrypt(logs[i].KeyValue)
-			if err != nil {
-				logrus.WithError(err).WithField("log_id", logs[i].ID).Error("Failed to decrypt log key value")
-				logs[i].KeyValue = "failed-to-decrypt"
-			} else {
-				logs[i].KeyValue = decryptedValue
-			}
-		}
+		s.decryptLogKey(&logs[i], canReadKeys)
 	}
 
 	pagination.Items = logs
 	response.Success(c, pagination)
 }
 
+// GetLog handles fetching a single request log including the captured response body.
+func (s *Server) GetLog(c *gin.Context) {
+	var entry models.RequestLog
+	if err := s.DB.Scopes(services.AdminGroupScope(c, "group_id")).Where("id = ?", c.Param("id")).First(&entry).Error; err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+
+	s.decryptLogKey(&entry, s.canReadLogKeys(c))
+	response.Success(c, entry)
+}
+
+// canReadLogKeys reports whether the caller may see the full key values of request logs.
+func (s *Server) canReadLogKeys(c *gin.Context) bool {
+	// 无密钥查看权限时只显示脱敏后的密钥
+	principal := services.AdminPrincipalFromContext(c)
+	return principal != nil && principal.Can(services.PermKeysRead)
+}
+
+// decryptLogKey replaces the encrypted key value of a log with the plain or masked key.
+func (s *Server) decryptLogKey(entry *models.RequestLog, canReadKeys bool) {
+	if entry.KeyValue == "" {
+		return
+	}
+	decryptedValue, err := s.EncryptionSvc.Decrypt(entry.KeyValue)
+	if err != nil {
+		logrus.WithError(err).WithField("log_id", entry.ID).Error("Failed to decrypt log key value")
+		entry.KeyValue = "failed-to-decrypt"
+	} else if canReadKeys {
+		entry.KeyValue = decryptedValue
+	} else {
+		entry.KeyValue = utils.MaskAPIKey(decryptedValue)
+	}
+}
+
+// GetLogArchives lists the archive files of expired request logs.
+func (s *Server) GetLogArchives(c *gin.Context) {
+	// 归档文件包含所有分组的日志，仅限不受分组限制的管理员
+	if principal := services.AdminPrincipalFromContext(c); principal == nil || principal.IsGroupScoped() {
+		response.Error(c, app_errors.ErrForbidden)
+		return
+	}
+
+	archives, err := s.LogArchiver.List()
+	if err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, err.Error()))
+		return
+	}
+	response.Success(c, gin.H{
+		"dir":      s.LogArchiver.Dir(),
+		"archives": archives,
+	})
+}
+
 // ExportLogs handles exporting filtered log keys to a CSV file.
 func (s *Server) ExportLogs(c *gin.Context) {
 	filename := fmt.Sprintf("log_keys_export_%s.csv", time.Now().Format("20060102150405"))
@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/response"
+	"gpt-load/internal/services"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// OIDCConfigResponse tells the login page whether single sign-on is available.
+type OIDCConfigResponse struct {
+	Enabled      bool   `json:"enabled"`
+	ProviderName string `json:"provider_name,omitempty"`
+}
+
+// GetOIDCConfig returns the public single sign-on settings.
+func (s *Server) GetOIDCConfig(c *gin.Context) {
+	if !s.OIDCService.Enabled() {
+		response.Success(c, OIDCConfigResponse{Enabled: false})
+		return
+	}
+	response.Success(c, OIDCConfigResponse{Enabled: true, ProviderName: s.OIDCService.ProviderName()})
+}
+
+// OIDCLogin redirects the browser to the identity provider.
+func (s *Server) OIDCLogin(c *gin.Context) {
+	authURL, err := s.OIDCService.AuthorizationURL(c.Request.Context())
+	if err != nil {
+		logrus.WithError(err).Error("Failed to start OIDC login")
+		redirectToLogin(c, url.Values{"error": {"Single sign-on is unavailable"}})
+		return
+	}
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// OIDCCallback completes single sign-on and hands the session token to the login page.
+// The token is passed in the URL fragment, so it never reaches server logs or the Referer header.
+func (s *Server) OIDCCallback(c *gin.Context) {
+	if providerError := c.Query("error"); providerError != "" {
+		logrus.WithField("error", providerError).Warnf("OIDC provider returned an error: %s", c.Query("error_description"))
+		redirectToLogin(c, url.Values{"error": {"Single sign-on was cancelled or rejected"}})
+		return
+	}
+
+	result, err := s.OIDCService.Login(c.Request.Context(), c.Query("state"), c.Query("code"))
+	if err != nil {
+		logrus.WithError(err).Warn("OIDC login failed")
+		message := "Single sign-on failed"
+		if errors.Is(err, services.ErrOIDCAccessDenied) || errors.Is(err, services.ErrOIDCUsernameTaken) {
+			message = err.Error()
+		}
+		redirectToLogin(c, url.Values{"error": {message}})
+		return
+	}
+
+	services.SetAdminPrincipal(c, result.Principal)
+	s.recordOIDCUserSync(c, result)
+
+	session, err := s.AdminSessionService.Create(result.Principal)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to create admin session")
+		redirectToLogin(c, url.Values{"error": {"Failed to create session"}})
+		return
+	}
+
+	redirectToLogin(c, url.Values{
+		"token":      {session.Token},
+		"expires_at": {session.ExpiresAt.Format(time.RFC3339)},
+	})
+}
+
+// recordOIDCUserSync audits accounts created on first login and role changes synchronized from the provider.
+func (s *Server) recordOIDCUserSync(c *gin.Context, result *services.OIDCLoginResult) {
+	entry := services.AuditEntry{
+		Action:     models.AuditActionUserCreate,
+		TargetType: models.AuditTargetUser,
+		TargetID:   result.User.ID,
+		TargetName: result.User.Username,
+		After:      result.User,
+		Details:    gin.H{"source": "oidc"},
+	}
+	if result.Previous != nil {
+		if result.Previous.Role == result.User.Role && slices.Equal(result.Previous.GroupIDs, result.User.GroupIDs) {
+			return
+		}
+		entry.Action = models.AuditActionUserUpdate
+		entry.Before = result.Previous
+	}
+	s.AuditService.Record(c, entry)
+}
+
+func redirectToLogin(c *gin.Context, fragment url.Values) {
+	c.Redirect(http.StatusFound, "/login#"+fragment.Encode())
+}
@@ -1,9 +1,13 @@
 package handler
 
 import (
+	"encoding/json"
+	"gpt-load/internal/alert"
 	app_errors "gpt-load/internal/errors"
 	"gpt-load/internal/models"
 	"gpt-load/internal/response"
+	"gpt-load/internal/services"
+	"gpt-load/internal/types"
 	"gpt-load/internal/utils"
 	"strings"
 	"time"
@@ -60,15 +64,45 @@ func (s *Server) UpdateSettings(c *gin.Context) {
 		}
 	}
 
+	if webhooks, ok := settingsMap["alert_webhooks"].(string); ok {
+		if err := alert.ValidateWebhooks(webhooks); err != nil {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, err.Error()))
+			return
+		}
+	}
+
+	beforeSettings := s.SettingsManager.GetSettings()
+
 	// 更新配置
 	if err := s.SettingsManager.UpdateSettings(settingsMap); err != nil {
 		response.Error(c, app_errors.NewAPIError(app_errors.ErrDatabase, err.Error()))
 		return
 	}
 
+	s.AuditService.Record(c, services.AuditEntry{
+		Action:     models.AuditActionSettingsUpdate,
+		TargetType: models.AuditTargetSettings,
+		TargetName: "system",
+		Before:     &beforeSettings,
+		After:      mergeSettingsForAudit(&beforeSettings, settingsMap),
+	})
+
 	time.Sleep(100 * time.Millisecond) // 等待异步更新配置
 
 	response.Success(c, gin.H{
 		"message": "Settings updated successfully. Configuration will be reloaded in the background across all instances.",
 	})
 }
+
+// mergeSettingsForAudit overlays the submitted values on the current settings,
+// so the audit diff only contains the fields that actually changed.
+func mergeSettingsForAudit(current *types.SystemSettings, updates map[string]any) map[string]any {
+	merged := make(map[string]any)
+	if data, err := json.Marshal(current); err == nil {
+		_ = json.Unmarshal(data, &merged)
+	}
+	for key, value := range updates {
+		merged[key] = value
+	}
+	return merged
+}
@@ -3,6 +3,7 @@ package handler
 import (
 	app_errors "gpt-load/internal/errors"
 	"gpt-load/internal/response"
+	"gpt-load/internal/services"
 
 	"github.com/gin-gonic/gin"
 )
@@ -14,5 +15,13 @@ func (s *Server) GetTaskStatus(c *gin.Context) {
 		response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, "Failed to get task status"))
 		return
 	}
+
+	// 分组范围内的管理员只能看到任务是否在运行，看不到其他分组任务的详情
+	if principal := services.AdminPrincipalFromContext(c); principal != nil && principal.IsGroupScoped() && taskStatus.GroupName != "" {
+		group, err := s.GroupManager.GetGroupByName(taskStatus.GroupName)
+		if err != nil || !principal.CanAccessGroup(group.ID) {
+			taskStatus = &services.TaskStatus{TaskType: taskStatus.TaskType, IsRunning: taskStatus.IsRunning}
+		}
+	}
 	response.Success(c, taskStatus)
 }
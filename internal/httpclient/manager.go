@@ -1,11 +1,13 @@
 package httpclient
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"net/http"
 	"net/url"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -35,6 +37,20 @@ type Config struct {
 type HTTPClientManager struct {
 	clients map[string]*http.Client
 	lock    sync.RWMutex
+
+	openConns  atomic.Int64
+	dials      atomic.Int64
+	dialErrors atomic.Int64
+	inFlight   atomic.Int64
+}
+
+// PoolStats is a snapshot of the connection usage across all managed clients.
+type PoolStats struct {
+	Clients          int
+	OpenConnections  int64
+	DialsTotal       int64
+	DialErrorsTotal  int64
+	InFlightRequests int64
 }
 
 // NewHTTPClientManager creates a new client manager.
@@ -68,11 +84,12 @@ func (m *HTTPClientManager) GetClient(config *Config) *http.Client {
 	}
 
 	// Create a new transport and client with the specified configuration.
+	dialer := &net.Dialer{
+		Timeout:   config.ConnectTimeout,
+		KeepAlive: 30 * time.Second,
+	}
 	transport := &http.Transport{
-		DialContext: (&net.Dialer{
-			Timeout:   config.ConnectTimeout,
-			KeepAlive: 30 * time.Second,
-		}).DialContext,
+		DialContext:           m.trackDial(dialer.DialContext),
 		ForceAttemptHTTP2:     config.ForceAttemptHTTP2,
 		MaxIdleConns:          config.MaxIdleConns,
 		MaxIdleConnsPerHost:   config.MaxIdleConnsPerHost,
@@ -99,7 +116,7 @@ func (m *HTTPClientManager) GetClient(config *Config) *http.Client {
 	}
 
 	newClient := &http.Client{
-		Transport: transport,
+		Transport: &trackingRoundTripper{base: transport, inFlight: &m.inFlight},
 		Timeout:   config.RequestTimeout,
 	}
 
@@ -107,6 +124,68 @@ func (m *HTTPClientManager) GetClient(config *Config) *http.Client {
 	return newClient
 }
 
+// Stats returns the current connection pool statistics.
+func (m *HTTPClientManager) Stats() PoolStats {
+	m.lock.RLock()
+	clients := len(m.clients)
+	m.lock.RUnlock()
+
+	return PoolStats{
+		Clients:          clients,
+		OpenConnections:  m.openConns.Load(),
+		DialsTotal:       m.dials.Load(),
+		DialErrorsTotal:  m.dialErrors.Load(),
+		InFlightRequests: m.inFlight.Load(),
+	}
+}
+
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// trackDial wraps a dial function to count opened and closed connections.
+func (m *HTTPClientManager) trackDial(dial dialFunc) dialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		m.dials.Add(1)
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			m.dialErrors.Add(1)
+			return nil, err
+		}
+		m.openConns.Add(1)
+		return &trackedConn{Conn: conn, openConns: &m.openConns}, nil
+	}
+}
+
+// trackedConn decrements the open connection counter exactly once on close.
+type trackedConn struct {
+	net.Conn
+	openConns *atomic.Int64
+	closeOnce sync.Once
+}
+
+func (c *trackedConn) Close() error {
+	c.closeOnce.Do(func() { c.openConns.Add(-1) })
+	return c.Conn.Close()
+}
+
+// trackingRoundTripper counts requests that are waiting for response headers.
+type trackingRoundTripper struct {
+	base     http.RoundTripper
+	inFlight *atomic.Int64
+}
+
+func (t *trackingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.inFlight.Add(1)
+	defer t.inFlight.Add(-1)
+	return t.base.RoundTrip(req)
+}
+
+// CloseIdleConnections forwards to the underlying transport so that http.Client.CloseIdleConnections keeps working.
+func (t *trackingRoundTripper) CloseIdleConnections() {
+	if closer, ok := t.base.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+}
+
 // getFingerprint generates a unique string representation of the client configuration.
 func (c *Config) getFingerprint() string {
 	return fmt.Sprintf(
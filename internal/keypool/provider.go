@@ -3,14 +3,17 @@ package keypool
 import (
 	"errors"
 	"fmt"
+	"gpt-load/internal/alert"
 	"gpt-load/internal/config"
 	"gpt-load/internal/encryption"
 	app_errors "gpt-load/internal/errors"
 	"gpt-load/internal/models"
 	"gpt-load/internal/store"
+	"gpt-load/internal/utils"
 	"math/rand"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -22,15 +25,18 @@ type KeyProvider struct {
 	store           store.Store
 	settingsManager *config.SystemSettingsManager
 	encryptionSvc   encryption.Service
+	alerts          *alert.Service
+	pendingUpdates  sync.WaitGroup
 }
 
 // NewProvider 创建一个新的 KeyProvider 实例。
-func NewProvider(db *gorm.DB, store store.Store, settingsManager *config.SystemSettingsManager, encryptionSvc encryption.Service) *KeyProvider {
+func NewProvider(db *gorm.DB, store store.Store, settingsManager *config.SystemSettingsManager, encryptionSvc encryption.Service, alerts *alert.Service) *KeyProvider {
 	return &KeyProvider{
 		db:              db,
 		store:           store,
 		settingsManager: settingsManager,
 		encryptionSvc:   encryptionSvc,
+		alerts:          alerts,
 	}
 }
 
@@ -89,7 +95,9 @@ func (p *KeyProvider) SelectKey(groupID uint) (*models.APIKey, error) {
 
 // UpdateStatus 异步地提交一个 Key 状态更新任务。
 func (p *KeyProvider) UpdateStatus(apiKey *models.APIKey, group *models.Group, isSuccess bool, errorMessage string) {
+	p.pendingUpdates.Add(1)
 	go func() {
+		defer p.pendingUpdates.Done()
 		keyHashKey := fmt.Sprintf("key:%d", apiKey.ID)
 		activeKeysListKey := fmt.Sprintf("group:%d:active_keys", group.ID)
 
@@ -112,6 +120,11 @@ func (p *KeyProvider) UpdateStatus(apiKey *models.APIKey, group *models.Group, i
 	}()
 }
 
+// WaitForUpdates 等待所有进行中的状态更新完成，用于进程退出前确保更新已写入数据库。
+func (p *KeyProvider) WaitForUpdates() {
+	p.pendingUpdates.Wait()
+}
+
 // executeTransactionWithRetry wraps a database transaction with a retry mechanism.
 func (p *KeyProvider) executeTransactionWithRetry(operation func(tx *gorm.DB) error) error {
 	const maxRetries = 3
@@ -199,8 +212,9 @@ func (p *KeyProvider) handleFailure(apiKey *models.APIKey, group *models.Group,
 
 	// 获取该分组的有效配置
 	blacklistThreshold := group.EffectiveConfig.BlacklistThreshold
+	blacklisted := false
 
-	return p.executeTransactionWithRetry(func(tx *gorm.DB) error {
+	err = p.executeTransactionWithRetry(func(tx *gorm.DB) error {
 		var key models.APIKey
 		if err := tx.Set("gorm:query_option", "FOR UPDATE").First(&key, apiKey.ID).Error; err != nil {
 			return fmt.Errorf("failed to lock key %d for update: %w", apiKey.ID, err)
@@ -232,8 +246,25 @@ func (p *KeyProvider) handleFailure(apiKey *models.APIKey, group *models.Group,
 			}
 		}
 
+		blacklisted = shouldBlacklist
 		return nil
 	})
+	if err != nil {
+		return err
+	}
+
+	if blacklisted {
+		p.alerts.KeyBlacklisted(group, apiKey.ID, utils.MaskAPIKey(apiKey.KeyValue), failureCount+1)
+
+		var activeCount int64
+		if err := p.db.Model(&models.APIKey{}).Where("group_id = ? AND status = ?", group.ID, models.KeyStatusActive).Count(&activeCount).Error; err != nil {
+			logrus.WithError(err).Warn("Failed to count active keys for alerting")
+		} else {
+			p.alerts.CheckActiveKeys(group, activeCount)
+		}
+	}
+
+	return nil
 }
 
 // LoadKeysFromDB 从数据库加载所有分组和密钥，并填充到 Store 中。
@@ -332,10 +363,8 @@ func (p *KeyProvider) RemoveKeys(groupID uint, keyValues []string) (int64, error
 	err := p.db.Transaction(func(tx *gorm.DB) error {
 		var keyHashes []string
 		for _, keyValue := range keyValues {
-			keyHash := p.encryptionSvc.Hash(keyValue)
-			if keyHash != "" {
-				keyHashes = append(keyHashes, keyHash)
-			}
+			// 包含轮换前密钥的哈希，匹配尚未重新加密的密钥
+			keyHashes = append(keyHashes, p.encryptionSvc.Hashes(keyValue)...)
 		}
 
 		if len(keyHashes) == 0 {
@@ -421,10 +450,8 @@ func (p *KeyProvider) RestoreMultipleKeys(groupID uint, keyValues []string) (int
 	err := p.db.Transaction(func(tx *gorm.DB) error {
 		var keyHashes []string
 		for _, keyValue := range keyValues {
-			keyHash := p.encryptionSvc.Hash(keyValue)
-			if keyHash != "" {
-				keyHashes = append(keyHashes, keyHash)
-			}
+			// 包含轮换前密钥的哈希，匹配尚未重新加密的密钥
+			keyHashes = append(keyHashes, p.encryptionSvc.Hashes(keyValue)...)
 		}
 
 		if len(keyHashes) == 0 {
@@ -555,6 +582,36 @@ func (p *KeyProvider) RemoveKeysFromStore(groupID uint, keyIDs []uint) error {
 	return nil
 }
 
+// AddKeysToStore 将已写入数据库的 Key 加入内存存储，用于在外部事务提交后同步缓存
+func (p *KeyProvider) AddKeysToStore(keys []models.APIKey) error {
+	for i := range keys {
+		if err := p.addKeyToStore(&keys[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RemoveKeysByIDFromStore 从内存存储中移除已在数据库中删除的 Key，分组内的其他 Key 保持不变
+func (p *KeyProvider) RemoveKeysByIDFromStore(groupID uint, keyIDs []uint) error {
+	for _, keyID := range keyIDs {
+		if err := p.removeKeyFromStore(keyID, groupID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UpdateKeyValueInStore replaces the encrypted value of a cached key, keys that are not cached are skipped.
+func (p *KeyProvider) UpdateKeyValueInStore(keyID uint, encryptedKeyValue string) error {
+	keyHashKey := fmt.Sprintf("key:%d", keyID)
+	exists, err := p.store.Exists(keyHashKey)
+	if err != nil || !exists {
+		return err
+	}
+	return p.store.HSet(keyHashKey, map[string]any{"key_string": encryptedKeyValue})
+}
+
 // addKeyToStore is a helper to add a single key to the cache.
 func (p *KeyProvider) addKeyToStore(key *models.APIKey) error {
 	// 1. Store key details in HASH
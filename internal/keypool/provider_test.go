@@ -0,0 +1,69 @@
+package keypool
+
+import (
+	"testing"
+
+	"gpt-load/internal/config"
+	"gpt-load/internal/encryption"
+	"gpt-load/internal/models"
+	"gpt-load/internal/store"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newTestProvider(t *testing.T) (*KeyProvider, *gorm.DB) {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	if err := db.AutoMigrate(&models.APIKey{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	encryptionSvc, err := encryption.NewService("")
+	if err != nil {
+		t.Fatalf("failed to create encryption service: %v", err)
+	}
+	return NewProvider(db, store.NewMemoryStore(), config.NewSystemSettingsManager(), encryptionSvc, nil), db
+}
+
+func TestUpdateStatusCountsFailuresFromLoadedPool(t *testing.T) {
+	provider, db := newTestProvider(t)
+
+	key := models.APIKey{GroupID: 1, KeyValue: "sk-test-1", KeyHash: "hash-1", Status: models.KeyStatusActive, FailureCount: 2}
+	if err := db.Create(&key).Error; err != nil {
+		t.Fatalf("failed to create key: %v", err)
+	}
+	if err := provider.LoadKeysFromDB(); err != nil {
+		t.Fatalf("LoadKeysFromDB() error = %v", err)
+	}
+
+	group := &models.Group{ID: 1, Name: "test"}
+	provider.UpdateStatus(&key, group, false, "upstream error")
+	provider.WaitForUpdates()
+	provider.UpdateStatus(&key, group, false, "upstream error")
+	provider.WaitForUpdates()
+
+	var stored models.APIKey
+	if err := db.First(&stored, key.ID).Error; err != nil {
+		t.Fatalf("failed to load key: %v", err)
+	}
+	if stored.FailureCount != 4 {
+		t.Errorf("failure_count = %d, want 4", stored.FailureCount)
+	}
+
+	provider.UpdateStatus(&key, group, true, "")
+	provider.WaitForUpdates()
+	if err := db.First(&stored, key.ID).Error; err != nil {
+		t.Fatalf("failed to load key: %v", err)
+	}
+	if stored.FailureCount != 0 {
+		t.Errorf("failure_count after success = %d, want 0", stored.FailureCount)
+	}
+}
@@ -95,11 +95,7 @@ func (s *KeyValidator) TestMultipleKeys(group *models.Group, keyValues []string)
 	// Generate hashes for all key values
 	var keyHashes []string
 	for _, keyValue := range keyValues {
-		keyHash := s.encryptionSvc.Hash(keyValue)
-		if keyHash == "" {
-			continue
-		}
-		keyHashes = append(keyHashes, keyHash)
+		keyHashes = append(keyHashes, s.encryptionSvc.Hashes(keyValue)...)
 	}
 
 	// Find which of the provided keys actually exist in the database for this group
@@ -117,8 +113,13 @@ func (s *KeyValidator) TestMultipleKeys(group *models.Group, keyValues []string)
 	}
 
 	for i, kv := range keyValues {
-		keyHash := s.encryptionSvc.Hash(kv)
-		apiKey, exists := existingKeyMap[keyHash]
+		var apiKey models.APIKey
+		exists := false
+		for _, keyHash := range s.encryptionSvc.Hashes(kv) {
+			if apiKey, exists = existingKeyMap[keyHash]; exists {
+				break
+			}
+		}
 		if !exists {
 			results[i] = KeyTestResult{
 				KeyValue: kv,
@@ -0,0 +1,158 @@
+package logsink
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gpt-load/internal/types"
+
+	"github.com/sirupsen/logrus"
+)
+
+// fileSink appends entries as JSON lines and rotates the file when it exceeds the size limit.
+type fileSink struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+func newFileSink(cfg types.FileSinkConfig) (*fileSink, error) {
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log sink directory: %w", err)
+	}
+
+	s := &fileSink{
+		path:       cfg.Path,
+		maxSize:    int64(cfg.MaxSizeMB) * 1024 * 1024,
+		maxBackups: cfg.MaxBackups,
+	}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *fileSink) Name() string {
+	return "file"
+}
+
+func (s *fileSink) Write(entries []Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return fmt.Errorf("file sink is closed")
+	}
+
+	writer := bufio.NewWriter(s.file)
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		line = append(line, '\n')
+
+		if s.maxSize > 0 && s.size > 0 && s.size+int64(len(line)) > s.maxSize {
+			if err := writer.Flush(); err != nil {
+				return err
+			}
+			if err := s.rotate(); err != nil {
+				return err
+			}
+			writer.Reset(s.file)
+		}
+
+		n, err := writer.Write(line)
+		s.size += int64(n)
+		if err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
+
+func (s *fileSink) Close(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+func (s *fileSink) open() error {
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log sink file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat log sink file: %w", err)
+	}
+	s.file = file
+	s.size = info.Size()
+	return nil
+}
+
+// rotate renames the current file with a timestamp suffix, opens a new one and prunes old backups.
+func (s *fileSink) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return err
+	}
+
+	backup := fmt.Sprintf("%s.%s", s.path, time.Now().Format("20060102-150405.000"))
+	if err := os.Rename(s.path, backup); err != nil {
+		return fmt.Errorf("failed to rotate log sink file: %w", err)
+	}
+	if err := s.open(); err != nil {
+		return err
+	}
+
+	s.pruneBackups()
+	return nil
+}
+
+func (s *fileSink) pruneBackups() {
+	if s.maxBackups <= 0 {
+		return
+	}
+
+	matches, err := filepath.Glob(s.path + ".*")
+	if err != nil {
+		return
+	}
+	backups := make([]string, 0, len(matches))
+	prefix := s.path + "."
+	for _, match := range matches {
+		if strings.HasPrefix(match, prefix) {
+			backups = append(backups, match)
+		}
+	}
+	if len(backups) <= s.maxBackups {
+		return
+	}
+
+	// 时间戳后缀按字典序即为时间顺序
+	sort.Strings(backups)
+	for _, old := range backups[:len(backups)-s.maxBackups] {
+		if err := os.Remove(old); err != nil {
+			logrus.WithError(err).Warnf("Failed to remove old log sink file %s", old)
+		}
+	}
+}
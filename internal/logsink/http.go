@@ -0,0 +1,207 @@
+package logsink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"gpt-load/internal/types"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	httpSinkQueueSize      = 10000
+	httpSinkInitialBackoff = time.Second
+	httpSinkMaxBackoff     = 30 * time.Second
+)
+
+// httpSink buffers entries and POSTs them as JSON arrays in batches, retrying with exponential backoff.
+type httpSink struct {
+	url           string
+	headers       http.Header
+	batchSize     int
+	flushInterval time.Duration
+	maxRetries    int
+	client        *http.Client
+
+	queue    chan Entry
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+}
+
+func newHTTPSink(cfg types.HTTPSinkConfig) *httpSink {
+	headers := make(http.Header)
+	for _, header := range cfg.Headers {
+		name, value, ok := strings.Cut(header, ":")
+		if !ok {
+			logrus.Warnf("Ignoring malformed LOG_SINK_HTTP_HEADERS entry '%s'", header)
+			continue
+		}
+		headers.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+	headers.Set("Content-Type", "application/json")
+
+	batchSize := cfg.BatchSize
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+	flushInterval := time.Duration(cfg.FlushIntervalSeconds) * time.Second
+	if flushInterval <= 0 {
+		flushInterval = 5 * time.Second
+	}
+
+	s := &httpSink{
+		url:           cfg.URL,
+		headers:       headers,
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+		maxRetries:    cfg.MaxRetries,
+		client:        &http.Client{Timeout: time.Duration(cfg.TimeoutSeconds) * time.Second},
+		queue:         make(chan Entry, httpSinkQueueSize),
+		stopChan:      make(chan struct{}),
+	}
+
+	s.wg.Add(1)
+	go s.run()
+	return s
+}
+
+func (s *httpSink) Name() string {
+	return "http"
+}
+
+// Write enqueues entries without blocking. Entries are dropped when the queue is full.
+func (s *httpSink) Write(entries []Entry) error {
+	dropped := 0
+	for _, entry := range entries {
+		select {
+		case s.queue <- entry:
+		default:
+			dropped++
+		}
+	}
+	if dropped > 0 {
+		return fmt.Errorf("queue full, dropped %d entries", dropped)
+	}
+	return nil
+}
+
+func (s *httpSink) Close(ctx context.Context) error {
+	close(s.stopChan)
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("timed out flushing pending entries: %w", ctx.Err())
+	}
+}
+
+func (s *httpSink) run() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]Entry, 0, s.batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		s.send(batch)
+		batch = make([]Entry, 0, s.batchSize)
+	}
+
+	for {
+		select {
+		case entry := <-s.queue:
+			batch = append(batch, entry)
+			if len(batch) >= s.batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-s.stopChan:
+			// 关闭前发送队列中剩余的日志
+			for {
+				select {
+				case entry := <-s.queue:
+					batch = append(batch, entry)
+					if len(batch) >= s.batchSize {
+						flush()
+					}
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// send posts a batch, retrying on network errors, 429 and 5xx responses.
+func (s *httpSink) send(batch []Entry) {
+	body, err := json.Marshal(batch)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to marshal request logs for HTTP sink")
+		return
+	}
+
+	backoff := httpSinkInitialBackoff
+	for attempt := 0; ; attempt++ {
+		retryable, err := s.post(body)
+		if err == nil {
+			return
+		}
+		if !retryable || attempt >= s.maxRetries {
+			logrus.WithError(err).Errorf("Failed to deliver %d request logs to HTTP sink after %d attempts", len(batch), attempt+1)
+			return
+		}
+
+		logrus.WithError(err).Debugf("HTTP sink delivery failed (attempt %d), retrying in %v", attempt+1, backoff)
+		select {
+		case <-time.After(backoff):
+		case <-s.stopChan:
+			// 关闭期间不再等待退避，直接做最后一次尝试
+			if _, err := s.post(body); err != nil {
+				logrus.WithError(err).Errorf("Failed to deliver %d request logs to HTTP sink during shutdown", len(batch))
+			}
+			return
+		}
+		backoff = min(backoff*2, httpSinkMaxBackoff)
+	}
+}
+
+func (s *httpSink) post(body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header = s.headers.Clone()
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retryable, fmt.Errorf("unexpected status %d", resp.StatusCode)
+}
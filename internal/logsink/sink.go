@@ -0,0 +1,237 @@
+// Package logsink delivers request logs to external destinations besides the database.
+package logsink
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/types"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	redactedValue = "[REDACTED]"
+	keyValueField = "key_value"
+
+	// managerQueueSize bounds the batches waiting to be delivered to the sinks.
+	managerQueueSize = 1000
+)
+
+// Entry is a request log converted to a field map, after field selection and redaction.
+type Entry map[string]any
+
+// Sink is a destination for request logs.
+type Sink interface {
+	// Name returns the sink name used in logs.
+	Name() string
+	// Write delivers a batch of entries.
+	Write(entries []Entry) error
+	// Close flushes pending entries and releases resources.
+	Close(ctx context.Context) error
+}
+
+// sinkWithFields couples a sink with its field selection and redaction settings.
+type sinkWithFields struct {
+	sink   Sink
+	fields map[string]bool
+	redact map[string]bool
+}
+
+// Manager fans request logs out to all enabled sinks.
+// Batches are delivered by a background goroutine, so slow sinks never block the request path.
+type Manager struct {
+	sinks           []sinkWithFields
+	databaseEnabled bool
+
+	queue     chan []*models.RequestLog
+	stopChan  chan struct{}
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+}
+
+// NewManager creates the enabled sinks from the configuration.
+func NewManager(configManager types.ConfigManager) (*Manager, error) {
+	cfg := configManager.GetLogSinkConfig()
+	m := &Manager{databaseEnabled: cfg.DatabaseEnabled}
+
+	if cfg.File.Enabled {
+		sink, err := newFileSink(cfg.File)
+		if err != nil {
+			return nil, err
+		}
+		m.add(sink, cfg.File.SinkFieldConfig)
+	}
+
+	if cfg.Syslog.Enabled {
+		m.add(newSyslogSink(cfg.Syslog), cfg.Syslog.SinkFieldConfig)
+	}
+
+	if cfg.HTTP.Enabled {
+		m.add(newHTTPSink(cfg.HTTP), cfg.HTTP.SinkFieldConfig)
+	}
+
+	if len(m.sinks) > 0 {
+		m.start()
+	}
+
+	return m, nil
+}
+
+// start launches the goroutine delivering queued logs to the sinks.
+func (m *Manager) start() {
+	m.queue = make(chan []*models.RequestLog, managerQueueSize)
+	m.stopChan = make(chan struct{})
+	m.wg.Add(1)
+	go m.run()
+}
+
+func (m *Manager) add(sink Sink, fieldConfig types.SinkFieldConfig) {
+	redact := toSet(fieldConfig.Redact)
+	// 上游密钥只在明确开启后才发送给外部系统
+	if !fieldConfig.IncludeKeyValue {
+		redact[keyValueField] = true
+	}
+	m.sinks = append(m.sinks, sinkWithFields{
+		sink:   sink,
+		fields: toSet(fieldConfig.Fields),
+		redact: redact,
+	})
+}
+
+// DatabaseEnabled reports whether request logs should also be written to the database.
+func (m *Manager) DatabaseEnabled() bool {
+	return m.databaseEnabled
+}
+
+// HasSinks reports whether any external sink is enabled.
+func (m *Manager) HasSinks() bool {
+	return len(m.sinks) > 0
+}
+
+// Write queues the logs for delivery to every sink without blocking.
+// Batches are dropped when the queue is full; sink failures never affect the database write.
+func (m *Manager) Write(logs []*models.RequestLog) {
+	if len(m.sinks) == 0 || len(logs) == 0 {
+		return
+	}
+
+	select {
+	case m.queue <- logs:
+	default:
+		logrus.Warnf("Log sink queue full, dropped %d request logs", len(logs))
+	}
+}
+
+func (m *Manager) run() {
+	defer m.wg.Done()
+
+	for {
+		select {
+		case logs := <-m.queue:
+			m.deliver(logs)
+		case <-m.stopChan:
+			// 退出前投递已入队的日志
+			for {
+				select {
+				case logs := <-m.queue:
+					m.deliver(logs)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// deliver sends a batch of logs to every sink.
+func (m *Manager) deliver(logs []*models.RequestLog) {
+	base := make([]Entry, 0, len(logs))
+	for _, log := range logs {
+		entry, err := toEntry(log)
+		if err != nil {
+			logrus.WithError(err).Warn("Failed to convert request log for sinks")
+			continue
+		}
+		base = append(base, entry)
+	}
+
+	for _, s := range m.sinks {
+		entries := make([]Entry, len(base))
+		for i, entry := range base {
+			entries[i] = s.filter(entry)
+		}
+		if err := s.sink.Write(entries); err != nil {
+			logrus.WithError(err).Warnf("Failed to write %d request logs to %s sink", len(entries), s.sink.Name())
+		}
+	}
+}
+
+// Close delivers the queued logs and closes all sinks. It is safe to call more than once.
+func (m *Manager) Close(ctx context.Context) {
+	m.closeOnce.Do(func() {
+		if m.stopChan == nil {
+			return
+		}
+		close(m.stopChan)
+
+		done := make(chan struct{})
+		go func() {
+			m.wg.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-ctx.Done():
+			logrus.Warn("Timed out delivering queued request logs to sinks")
+		}
+
+		for _, s := range m.sinks {
+			if err := s.sink.Close(ctx); err != nil {
+				logrus.WithError(err).Warnf("Failed to close %s sink", s.sink.Name())
+			}
+		}
+	})
+}
+
+// filter applies the sink's field selection and redaction to an entry.
+func (s sinkWithFields) filter(entry Entry) Entry {
+	filtered := make(Entry, len(entry))
+	for field, value := range entry {
+		if len(s.fields) > 0 && !s.fields[field] {
+			continue
+		}
+		if s.redact[field] {
+			if str, ok := value.(string); ok && str == "" {
+				filtered[field] = value
+			} else {
+				filtered[field] = redactedValue
+			}
+			continue
+		}
+		filtered[field] = value
+	}
+	return filtered
+}
+
+func toEntry(log *models.RequestLog) (Entry, error) {
+	data, err := json.Marshal(log)
+	if err != nil {
+		return nil, err
+	}
+	var entry Entry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[value] = true
+	}
+	return set
+}
@@ -0,0 +1,129 @@
+package logsink
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/types"
+)
+
+// recordingSink records the entries it receives and can block until released.
+type recordingSink struct {
+	mu      sync.Mutex
+	entries []Entry
+	block   chan struct{}
+	closed  bool
+}
+
+func (s *recordingSink) Name() string { return "recording" }
+
+func (s *recordingSink) Write(entries []Entry) error {
+	if s.block != nil {
+		<-s.block
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = append(s.entries, entries...)
+	return nil
+}
+
+func (s *recordingSink) Close(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return nil
+}
+
+func newTestManager(sink Sink, fieldConfig types.SinkFieldConfig) *Manager {
+	m := &Manager{}
+	m.add(sink, fieldConfig)
+	m.start()
+	return m
+}
+
+func testLog() *models.RequestLog {
+	return &models.RequestLog{
+		ID:          "log-1",
+		GroupName:   "g",
+		KeyValue:    "sk-upstream-secret",
+		RequestBody: `{"model":"gpt-4o"}`,
+		IsSuccess:   true,
+	}
+}
+
+func TestManagerRedactsKeyValueByDefault(t *testing.T) {
+	tests := []struct {
+		name   string
+		config types.SinkFieldConfig
+		want   string
+	}{
+		{"default", types.SinkFieldConfig{}, redactedValue},
+		{"custom redaction", types.SinkFieldConfig{Redact: []string{"request_body"}}, redactedValue},
+		{"opt in", types.SinkFieldConfig{IncludeKeyValue: true}, "sk-upstream-secret"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := &recordingSink{}
+			m := newTestManager(sink, tt.config)
+			m.Write([]*models.RequestLog{testLog()})
+			m.Close(context.Background())
+
+			if len(sink.entries) != 1 {
+				t.Fatalf("got %d entries, want 1", len(sink.entries))
+			}
+			if got := sink.entries[0]["key_value"]; got != tt.want {
+				t.Errorf("key_value = %v, want %v", got, tt.want)
+			}
+			if !sink.closed {
+				t.Error("sink not closed")
+			}
+		})
+	}
+}
+
+func TestManagerFieldSelection(t *testing.T) {
+	sink := &recordingSink{}
+	m := newTestManager(sink, types.SinkFieldConfig{Fields: []string{"id", "group_name"}, Redact: []string{"group_name"}})
+	m.Write([]*models.RequestLog{testLog()})
+	m.Close(context.Background())
+
+	entry := sink.entries[0]
+	if len(entry) != 2 || entry["id"] != "log-1" || entry["group_name"] != redactedValue {
+		t.Errorf("entry = %v", entry)
+	}
+}
+
+func TestManagerWriteDoesNotBlock(t *testing.T) {
+	sink := &recordingSink{block: make(chan struct{})}
+	m := newTestManager(sink, types.SinkFieldConfig{})
+
+	done := make(chan struct{})
+	go func() {
+		for range 3 {
+			m.Write([]*models.RequestLog{testLog()})
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Write blocked on a slow sink")
+	}
+
+	close(sink.block)
+	m.Close(context.Background())
+	if len(sink.entries) != 3 {
+		t.Errorf("got %d entries after close, want the 3 queued entries", len(sink.entries))
+	}
+}
+
+func TestManagerWithoutSinks(t *testing.T) {
+	m := &Manager{}
+	m.Write([]*models.RequestLog{testLog()})
+	m.Close(context.Background())
+	m.Close(context.Background())
+}
@@ -0,0 +1,190 @@
+package logsink
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gpt-load/internal/types"
+)
+
+const (
+	syslogFacilityLocal0  = 16
+	syslogSeverityWarning = 4
+	syslogSeverityInfo    = 6
+	syslogDialTimeout     = 5 * time.Second
+	syslogWriteTimeout    = 5 * time.Second
+	syslogMessageID       = "request"
+	syslogTruncatedSuffix = "...[truncated]"
+)
+
+// syslogSink sends each entry as an RFC 5424 message over UDP or TCP.
+type syslogSink struct {
+	network  string
+	address  string
+	appName  string
+	hostname string
+	procID   string
+	maxBytes int
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+func newSyslogSink(cfg types.SyslogSinkConfig) *syslogSink {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+	return &syslogSink{
+		network:  cfg.Network,
+		address:  cfg.Address,
+		appName:  cfg.AppName,
+		hostname: hostname,
+		procID:   strconv.Itoa(os.Getpid()),
+		maxBytes: cfg.MaxMessageBytes,
+	}
+}
+
+func (s *syslogSink) Name() string {
+	return "syslog"
+}
+
+// Write sends the entries one message each. A failed entry is skipped, only an unreachable
+// server aborts the rest of the batch.
+func (s *syslogSink) Write(entries []Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	failed := 0
+	var lastErr error
+	for i, entry := range entries {
+		message, err := s.format(entry)
+		if err != nil {
+			failed++
+			lastErr = err
+			continue
+		}
+		if err := s.send(message); err != nil {
+			// 连接可能已失效，重连后重试一次
+			s.closeConn()
+			if err = s.send(message); err != nil {
+				s.closeConn()
+				failed++
+				lastErr = err
+				var opErr *net.OpError
+				if errors.As(err, &opErr) && opErr.Op == "dial" {
+					failed += len(entries) - i - 1
+					break
+				}
+			}
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to send %d of %d entries: %w", failed, len(entries), lastErr)
+	}
+	return nil
+}
+
+func (s *syslogSink) Close(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closeConn()
+	return nil
+}
+
+// format builds an RFC 5424 message with the JSON entry as the message body. Messages longer than
+// the limit are shortened by truncating the longest string fields, so the body stays valid JSON.
+func (s *syslogSink) format(entry Entry) ([]byte, error) {
+	severity := syslogSeverityInfo
+	if success, ok := entry["is_success"].(bool); ok && !success {
+		severity = syslogSeverityWarning
+	}
+	priority := syslogFacilityLocal0*8 + severity
+
+	header := fmt.Sprintf("<%d>1 %s %s %s %s %s - ",
+		priority,
+		time.Now().UTC().Format(time.RFC3339Nano),
+		s.hostname,
+		s.appName,
+		s.procID,
+		syslogMessageID,
+	)
+
+	body, err := fitJSON(entry, s.maxBytes-len(header))
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(header), body...), nil
+}
+
+// fitJSON encodes the entry in at most limit bytes by truncating its longest string fields.
+// A limit of 0 or less disables truncation.
+func fitJSON(entry Entry, limit int) ([]byte, error) {
+	body, err := json.Marshal(entry)
+	if err != nil || limit <= 0 || len(body) <= limit {
+		return body, err
+	}
+
+	fitted := make(Entry, len(entry))
+	maps.Copy(fitted, entry)
+	for len(body) > limit {
+		field, value := longestStringField(fitted)
+		if len(value) <= len(syslogTruncatedSuffix) {
+			return nil, fmt.Errorf("entry exceeds the maximum message size of %d bytes", limit)
+		}
+		// JSON 转义会让编码后的长度大于原文，因此至少多截掉超出的部分
+		keep := max(len(value)-(len(body)-limit)-len(syslogTruncatedSuffix), 0)
+		fitted[field] = strings.ToValidUTF8(value[:keep], "") + syslogTruncatedSuffix
+		if body, err = json.Marshal(fitted); err != nil {
+			return nil, err
+		}
+	}
+	return body, nil
+}
+
+func longestStringField(entry Entry) (string, string) {
+	var field, value string
+	for k, v := range entry {
+		if str, ok := v.(string); ok && (len(str) > len(value) || len(str) == len(value) && k < field) {
+			field, value = k, str
+		}
+	}
+	return field, value
+}
+
+func (s *syslogSink) send(message []byte) error {
+	if s.conn == nil {
+		conn, err := net.DialTimeout(s.network, s.address, syslogDialTimeout)
+		if err != nil {
+			return fmt.Errorf("failed to connect to syslog server: %w", err)
+		}
+		s.conn = conn
+	}
+
+	if err := s.conn.SetWriteDeadline(time.Now().Add(syslogWriteTimeout)); err != nil {
+		return err
+	}
+
+	if s.network == "tcp" {
+		// RFC 6587 octet counting framing
+		message = append([]byte(strconv.Itoa(len(message))+" "), message...)
+	}
+	_, err := s.conn.Write(message)
+	return err
+}
+
+func (s *syslogSink) closeConn() {
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn = nil
+	}
+}
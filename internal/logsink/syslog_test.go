@@ -0,0 +1,112 @@
+package logsink
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"gpt-load/internal/types"
+)
+
+func TestFitJSON(t *testing.T) {
+	entry := Entry{
+		"id":            "log-1",
+		"request_body":  strings.Repeat("a", 5000),
+		"response_body": strings.Repeat("é", 3000),
+		"status_code":   200,
+	}
+
+	body, err := fitJSON(entry, 2048)
+	if err != nil {
+		t.Fatalf("fitJSON() error = %v", err)
+	}
+	if len(body) > 2048 {
+		t.Errorf("len(body) = %d, want <= 2048", len(body))
+	}
+
+	var decoded Entry
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		t.Fatalf("fitted body is not valid JSON: %v", err)
+	}
+	if decoded["id"] != "log-1" || decoded["status_code"] != float64(200) {
+		t.Errorf("short fields changed: %v", decoded)
+	}
+	for _, field := range []string{"request_body", "response_body"} {
+		if value := decoded[field].(string); !strings.HasSuffix(value, syslogTruncatedSuffix) {
+			t.Errorf("%s not marked as truncated", field)
+		}
+	}
+	if entry["request_body"] != strings.Repeat("a", 5000) {
+		t.Error("fitJSON modified the entry")
+	}
+}
+
+func TestFitJSONSmallEntry(t *testing.T) {
+	entry := Entry{"id": "log-1"}
+	body, err := fitJSON(entry, 1024)
+	if err != nil || string(body) != `{"id":"log-1"}` {
+		t.Errorf("fitJSON() = %s, %v", body, err)
+	}
+
+	if _, err := fitJSON(Entry{"a": 1, "b": 2, "c": 3}, 5); err == nil {
+		t.Error("fitJSON() for an entry that cannot fit = nil error, want an error")
+	}
+}
+
+func TestSyslogSinkUDPTruncatesLargeEntries(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("udp not available: %v", err)
+	}
+	defer conn.Close()
+
+	sink := newSyslogSink(types.SyslogSinkConfig{
+		Network:         "udp",
+		Address:         conn.LocalAddr().String(),
+		AppName:         "gpt-load",
+		MaxMessageBytes: 8192,
+	})
+	defer sink.Close(context.Background())
+
+	entries := []Entry{
+		{"id": "large", "request_body": strings.Repeat("x", 200000), "is_success": true},
+		{"id": "small", "is_success": false},
+	}
+	if err := sink.Write(entries); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	buf := make([]byte, 65536)
+	for _, want := range []string{`"id":"large"`, `"id":"small"`} {
+		_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("ReadFrom() error = %v", err)
+		}
+		message := string(buf[:n])
+		if n > 8192 {
+			t.Errorf("message size = %d, want <= 8192", n)
+		}
+		if !strings.Contains(message, want) {
+			t.Errorf("message %q does not contain %s", message[:min(len(message), 120)], want)
+		}
+	}
+}
+
+func TestSyslogSinkUnreachableServer(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("tcp not available: %v", err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+
+	sink := newSyslogSink(types.SyslogSinkConfig{Network: "tcp", Address: address, AppName: "gpt-load", MaxMessageBytes: 8192})
+	err = sink.Write([]Entry{{"id": "1"}, {"id": "2"}, {"id": "3"}})
+	if err == nil || !strings.Contains(err.Error(), "3 of 3") {
+		t.Errorf("Write() error = %v, want all entries reported as failed", err)
+	}
+}
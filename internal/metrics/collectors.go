@@ -0,0 +1,113 @@
+package metrics
+
+import (
+	"gpt-load/internal/httpclient"
+	"gpt-load/internal/models"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// keyCollector reports the number of keys per group and status, read from the database on every scrape.
+type keyCollector struct {
+	db   *gorm.DB
+	desc *prometheus.Desc
+}
+
+func newKeyCollector(db *gorm.DB) *keyCollector {
+	return &keyCollector{
+		db: db,
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "keys"),
+			"Number of API keys per group and status.",
+			[]string{"group", "status"}, nil,
+		),
+	}
+}
+
+func (c *keyCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *keyCollector) Collect(ch chan<- prometheus.Metric) {
+	var rows []struct {
+		GroupID uint
+		Status  string
+		Count   int64
+	}
+	if err := c.db.Model(&models.APIKey{}).
+		Select("group_id, status, COUNT(*) as count").
+		Group("group_id, status").
+		Scan(&rows).Error; err != nil {
+		logrus.WithError(err).Warn("Failed to collect key metrics")
+		return
+	}
+
+	var groups []models.Group
+	if err := c.db.Select("id, name").Find(&groups).Error; err != nil {
+		logrus.WithError(err).Warn("Failed to collect group names for key metrics")
+		return
+	}
+
+	// 确保每个分组都输出所有状态，便于告警规则直接使用。密钥池只有 active 和 invalid 两种状态，没有冷却状态
+	counts := make(map[uint]map[string]int64, len(groups))
+	for _, group := range groups {
+		counts[group.ID] = map[string]int64{
+			models.KeyStatusActive:  0,
+			models.KeyStatusInvalid: 0,
+		}
+	}
+	for _, row := range rows {
+		if _, ok := counts[row.GroupID]; ok {
+			counts[row.GroupID][row.Status] = row.Count
+		}
+	}
+
+	for _, group := range groups {
+		for status, count := range counts[group.ID] {
+			ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(count), group.Name, status)
+		}
+	}
+}
+
+// httpClientCollector reports connection pool statistics of the shared HTTP clients.
+type httpClientCollector struct {
+	manager          *httpclient.HTTPClientManager
+	clients          *prometheus.Desc
+	openConnections  *prometheus.Desc
+	dialsTotal       *prometheus.Desc
+	dialErrorsTotal  *prometheus.Desc
+	inFlightRequests *prometheus.Desc
+}
+
+func newHTTPClientCollector(manager *httpclient.HTTPClientManager) *httpClientCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "http_client", name), help, nil, nil)
+	}
+	return &httpClientCollector{
+		manager:          manager,
+		clients:          desc("clients", "Number of distinct upstream HTTP clients."),
+		openConnections:  desc("open_connections", "Number of open upstream connections."),
+		dialsTotal:       desc("dials_total", "Total number of upstream connection attempts."),
+		dialErrorsTotal:  desc("dial_errors_total", "Total number of failed upstream connection attempts."),
+		inFlightRequests: desc("in_flight_requests", "Number of upstream requests waiting for response headers."),
+	}
+}
+
+func (c *httpClientCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.clients
+	ch <- c.openConnections
+	ch <- c.dialsTotal
+	ch <- c.dialErrorsTotal
+	ch <- c.inFlightRequests
+}
+
+func (c *httpClientCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.manager.Stats()
+	ch <- prometheus.MustNewConstMetric(c.clients, prometheus.GaugeValue, float64(stats.Clients))
+	ch <- prometheus.MustNewConstMetric(c.openConnections, prometheus.GaugeValue, float64(stats.OpenConnections))
+	ch <- prometheus.MustNewConstMetric(c.dialsTotal, prometheus.CounterValue, float64(stats.DialsTotal))
+	ch <- prometheus.MustNewConstMetric(c.dialErrorsTotal, prometheus.CounterValue, float64(stats.DialErrorsTotal))
+	ch <- prometheus.MustNewConstMetric(c.inFlightRequests, prometheus.GaugeValue, float64(stats.InFlightRequests))
+}
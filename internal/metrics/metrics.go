@@ -0,0 +1,189 @@
+// Package metrics exposes Prometheus metrics for the proxy, key pool, store and HTTP clients.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"gpt-load/internal/httpclient"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"gorm.io/gorm"
+)
+
+const namespace = "gptload"
+
+// maxModelsPerGroup bounds the distinct values of the model label per group. The model comes
+// from the client request, so further models are reported as otherModelLabel.
+const maxModelsPerGroup = 50
+
+// maxModelLabelLength bounds the length of a model label value.
+const maxModelLabelLength = 128
+
+const otherModelLabel = "other"
+
+// Metrics holds the Prometheus registry and all collectors of the application.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requestsTotal          *prometheus.CounterVec
+	requestDuration        *prometheus.HistogramVec
+	retriesTotal           *prometheus.CounterVec
+	keySelectionFailures   *prometheus.CounterVec
+	storeOperationDuration *prometheus.HistogramVec
+	storeOperationErrors   *prometheus.CounterVec
+
+	requestLogQueueDepth func() (int64, error)
+
+	modelsMu sync.Mutex
+	models   map[string]map[string]struct{} // 每个分组已作为标签使用的模型
+}
+
+// NewMetrics creates the registry and registers all collectors.
+func NewMetrics(db *gorm.DB, httpClientManager *httpclient.HTTPClientManager) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		models:   make(map[string]map[string]struct{}),
+		requestsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "requests_total",
+			Help:      "Total number of proxied client requests.",
+		}, []string{"group", "model", "status_class", "stream"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "request_duration_seconds",
+			Help:      "End-to-end latency of proxied client requests, including retries.",
+			Buckets:   []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 20, 30, 60, 120, 300},
+		}, []string{"group", "model", "status_class", "stream"}),
+		retriesTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "upstream_retries_total",
+			Help:      "Total number of failed upstream attempts that were retried with another key.",
+		}, []string{"group"}),
+		keySelectionFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "key_selection_failures_total",
+			Help:      "Total number of requests for which no key could be selected.",
+		}, []string{"group"}),
+		storeOperationDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "store_operation_duration_seconds",
+			Help:      "Latency of store (Redis or memory) operations.",
+			Buckets:   []float64{0.0001, 0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 1},
+		}, []string{"operation"}),
+		storeOperationErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "store_operation_errors_total",
+			Help:      "Total number of failed store operations.",
+		}, []string{"operation"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requestsTotal,
+		m.requestDuration,
+		m.retriesTotal,
+		m.keySelectionFailures,
+		m.storeOperationDuration,
+		m.storeOperationErrors,
+		newKeyCollector(db),
+		newHTTPClientCollector(httpClientManager),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "request_log_queue_depth",
+			Help:      "Number of request logs buffered in the store waiting to be flushed to the database.",
+		}, m.collectRequestLogQueueDepth),
+	)
+
+	return m
+}
+
+// Handler returns the HTTP handler serving the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// SetRequestLogQueueSource sets the function used to read the request log queue depth on scrape.
+func (m *Metrics) SetRequestLogQueueSource(source func() (int64, error)) {
+	m.requestLogQueueDepth = source
+}
+
+// ObserveRequest records a finished client request.
+func (m *Metrics) ObserveRequest(group, model string, statusCode int, isStream bool, duration time.Duration) {
+	labels := prometheus.Labels{
+		"group":        group,
+		"model":        m.modelLabel(group, model, statusCode),
+		"status_class": statusClass(statusCode),
+		"stream":       strconv.FormatBool(isStream),
+	}
+	m.requestsTotal.With(labels).Inc()
+	m.requestDuration.With(labels).Observe(duration.Seconds())
+}
+
+// modelLabel returns the model label value. A model becomes a label value only after the
+// upstream served it successfully, so invalid model names sent by clients cannot take up the
+// limited label values of the group.
+func (m *Metrics) modelLabel(group, model string, statusCode int) string {
+	if len(model) > maxModelLabelLength {
+		return otherModelLabel
+	}
+
+	m.modelsMu.Lock()
+	defer m.modelsMu.Unlock()
+
+	seen := m.models[group]
+	if _, ok := seen[model]; ok {
+		return model
+	}
+	if statusCode < 200 || statusCode >= 400 || len(seen) >= maxModelsPerGroup {
+		return otherModelLabel
+	}
+	if seen == nil {
+		seen = make(map[string]struct{})
+		m.models[group] = seen
+	}
+	seen[model] = struct{}{}
+	return model
+}
+
+// IncRetry records a failed upstream attempt that is retried.
+func (m *Metrics) IncRetry(group string) {
+	m.retriesTotal.WithLabelValues(group).Inc()
+}
+
+// IncKeySelectionFailure records a request for which no key could be selected.
+func (m *Metrics) IncKeySelectionFailure(group string) {
+	m.keySelectionFailures.WithLabelValues(group).Inc()
+}
+
+// ObserveStoreOperation records the latency of a store operation. It matches store.OperationObserver.
+func (m *Metrics) ObserveStoreOperation(operation string, duration time.Duration, err error) {
+	m.storeOperationDuration.WithLabelValues(operation).Observe(duration.Seconds())
+	if err != nil {
+		m.storeOperationErrors.WithLabelValues(operation).Inc()
+	}
+}
+
+func (m *Metrics) collectRequestLogQueueDepth() float64 {
+	if m.requestLogQueueDepth == nil {
+		return 0
+	}
+	depth, err := m.requestLogQueueDepth()
+	if err != nil {
+		return 0
+	}
+	return float64(depth)
+}
+
+// statusClass maps a status code to its class, e.g. 429 -> "4xx".
+func statusClass(statusCode int) string {
+	if statusCode < 100 || statusCode > 599 {
+		return "unknown"
+	}
+	return strconv.Itoa(statusCode/100) + "xx"
+}
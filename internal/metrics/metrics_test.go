@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func newTestMetrics() *Metrics {
+	return &Metrics{models: make(map[string]map[string]struct{})}
+}
+
+func TestModelLabel(t *testing.T) {
+	m := newTestMetrics()
+
+	// 上游拒绝的模型不会成为标签值
+	if got := m.modelLabel("g", "made-up-model", 400); got != otherModelLabel {
+		t.Errorf("modelLabel() for a failed request = %q, want %q", got, otherModelLabel)
+	}
+	if got := m.modelLabel("g", "gpt-4o", 200); got != "gpt-4o" {
+		t.Errorf("modelLabel() = %q, want gpt-4o", got)
+	}
+	// 已接受的模型在失败的请求中继续使用自己的标签值
+	if got := m.modelLabel("g", "gpt-4o", 500); got != "gpt-4o" {
+		t.Errorf("modelLabel() for a known model = %q, want gpt-4o", got)
+	}
+	if got := m.modelLabel("g", strings.Repeat("x", maxModelLabelLength+1), 200); got != otherModelLabel {
+		t.Errorf("modelLabel() for a long model = %q, want %q", got, otherModelLabel)
+	}
+}
+
+func TestModelLabelLimit(t *testing.T) {
+	m := newTestMetrics()
+
+	for i := range maxModelsPerGroup {
+		model := fmt.Sprintf("model-%d", i)
+		if got := m.modelLabel("g", model, 200); got != model {
+			t.Fatalf("modelLabel(%q) = %q before the limit", model, got)
+		}
+	}
+	if got := m.modelLabel("g", "one-too-many", 200); got != otherModelLabel {
+		t.Errorf("modelLabel() over the limit = %q, want %q", got, otherModelLabel)
+	}
+	if got := m.modelLabel("g", "model-0", 200); got != "model-0" {
+		t.Errorf("modelLabel() for a known model = %q, want model-0", got)
+	}
+
+	// 限制按分组计算
+	if got := m.modelLabel("other-group", "one-too-many", 200); got != "one-too-many" {
+		t.Errorf("modelLabel() in another group = %q, want one-too-many", got)
+	}
+}
+
+func TestStatusClass(t *testing.T) {
+	tests := map[int]string{200: "2xx", 299: "2xx", 404: "4xx", 429: "4xx", 499: "4xx", 502: "5xx", 0: "unknown", 600: "unknown"}
+	for code, want := range tests {
+		if got := statusClass(code); got != want {
+			t.Errorf("statusClass(%d) = %q, want %q", code, got, want)
+		}
+	}
+}
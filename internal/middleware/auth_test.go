@@ -0,0 +1,241 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/services"
+	"gpt-load/internal/store"
+	"gpt-load/internal/types"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const (
+	testAuthKey      = "sk-test-admin-key-0123456789"
+	testMetricsToken = "metrics-token-0123456789"
+)
+
+type testConfigManager struct {
+	types.ConfigManager
+	auth types.AuthConfig
+}
+
+func (m *testConfigManager) GetAuthConfig() types.AuthConfig {
+	return m.auth
+}
+
+type authTestEnv struct {
+	router    *gin.Engine
+	sessions  *services.AdminSessionService
+	apiTokens *services.APITokenService
+}
+
+func newAuthTestEnv(t *testing.T) *authTestEnv {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	if err := db.AutoMigrate(&models.AdminUser{}, &models.APIToken{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	config := &testConfigManager{auth: types.AuthConfig{
+		Key:                     testAuthKey,
+		MetricsToken:            testMetricsToken,
+		SessionTTLMinutes:       60,
+		SessionMaxLifetimeHours: 24,
+		LoginMaxAttempts:        3,
+		LoginLockoutMinutes:     15,
+	}}
+	adminUsers := services.NewAdminUserService(db)
+	sessions := services.NewAdminSessionService(store.NewMemoryStore(), config, adminUsers)
+	apiTokens := services.NewAPITokenService(db)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	if err := router.SetTrustedProxies(nil); err != nil {
+		t.Fatalf("SetTrustedProxies() error = %v", err)
+	}
+	router.GET("/api/groups", Auth(config.auth, adminUsers, sessions, apiTokens), func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/metrics", MetricsAuth(config.auth, sessions), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	return &authTestEnv{router: router, sessions: sessions, apiTokens: apiTokens}
+}
+
+// do sends an admin API request from the client IP with the bearer credential and returns the status code.
+func (e *authTestEnv) do(clientIP, credential string, header ...string) int {
+	return e.get("/api/groups", clientIP, credential, header...)
+}
+
+// get sends a GET request to the path from the client IP with the bearer credential and returns the status code.
+func (e *authTestEnv) get(path, clientIP, credential string, header ...string) int {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.RemoteAddr = clientIP + ":40000"
+	if credential != "" {
+		req.Header.Set("Authorization", "Bearer "+credential)
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	w := httptest.NewRecorder()
+	e.router.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestAuthRepeatedStaleKeyDoesNotLockOut(t *testing.T) {
+	env := newAuthTestEnv(t)
+
+	for range 10 {
+		if code := env.do("10.0.0.1", "sk-outdated-key"); code != http.StatusUnauthorized {
+			t.Fatalf("status = %d, want %d", code, http.StatusUnauthorized)
+		}
+	}
+	if code := env.do("10.0.0.1", testAuthKey); code != http.StatusOK {
+		t.Errorf("valid key after repeated stale key: status = %d, want %d", code, http.StatusOK)
+	}
+}
+
+func TestAuthLockoutSparesTokens(t *testing.T) {
+	env := newAuthTestEnv(t)
+
+	session, err := env.sessions.Create(services.NewOwnerPrincipal())
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	apiToken, hash, prefix, err := env.apiTokens.GenerateToken()
+	if err != nil {
+		t.Fatalf("GenerateToken() error = %v", err)
+	}
+	if err := env.apiTokens.DB.Create(&models.APIToken{Name: "ci", TokenHash: hash, TokenPrefix: prefix, Permissions: []string{services.PermGroupsRead}}).Error; err != nil {
+		t.Fatalf("failed to create API token: %v", err)
+	}
+
+	for i := range 3 {
+		if code := env.do("10.0.0.2", fmt.Sprintf("sk-guess-%d", i)); code != http.StatusUnauthorized {
+			t.Fatalf("guess %d: status = %d, want %d", i, code, http.StatusUnauthorized)
+		}
+	}
+
+	if code := env.do("10.0.0.2", "sk-guess-next"); code != http.StatusTooManyRequests {
+		t.Errorf("guess after lockout: status = %d, want %d", code, http.StatusTooManyRequests)
+	}
+	if code := env.do("10.0.0.2", testAuthKey); code != http.StatusTooManyRequests {
+		t.Errorf("AUTH_KEY after lockout: status = %d, want %d", code, http.StatusTooManyRequests)
+	}
+	if code := env.do("10.0.0.2", session.Token); code != http.StatusOK {
+		t.Errorf("session token after lockout: status = %d, want %d", code, http.StatusOK)
+	}
+	if code := env.do("10.0.0.2", apiToken); code != http.StatusOK {
+		t.Errorf("API token after lockout: status = %d, want %d", code, http.StatusOK)
+	}
+	if code := env.do("10.0.0.3", testAuthKey); code != http.StatusOK {
+		t.Errorf("other IP: status = %d, want %d", code, http.StatusOK)
+	}
+}
+
+func TestAuthIgnoresForwardedForFromUntrustedClients(t *testing.T) {
+	env := newAuthTestEnv(t)
+
+	// 伪造 X-Forwarded-For 不能为每次猜测换一个 IP
+	for i := range 3 {
+		env.do("10.0.0.4", fmt.Sprintf("sk-guess-%d", i), "X-Forwarded-For", fmt.Sprintf("192.0.2.%d", i))
+	}
+	if code := env.do("10.0.0.4", testAuthKey, "X-Forwarded-For", "192.0.2.200"); code != http.StatusTooManyRequests {
+		t.Errorf("status = %d, want the lockout of the connection address", code)
+	}
+
+	// 也不能伪造其他客户端的 IP 将其锁定
+	for i := range 3 {
+		env.do("10.0.0.5", fmt.Sprintf("sk-guess-%d", i), "X-Forwarded-For", "10.0.0.6")
+	}
+	if code := env.do("10.0.0.6", testAuthKey); code != http.StatusOK {
+		t.Errorf("spoofed victim: status = %d, want %d", code, http.StatusOK)
+	}
+}
+
+func TestAuthInvalidTokensDoNotCount(t *testing.T) {
+	env := newAuthTestEnv(t)
+
+	for i := range 5 {
+		env.do("10.0.0.7", fmt.Sprintf("%sinvalid-%d", services.AdminSessionTokenPrefix, i))
+		env.do("10.0.0.7", fmt.Sprintf("%sinvalid-%d", services.APITokenPrefix, i))
+	}
+	if code := env.do("10.0.0.7", testAuthKey); code != http.StatusOK {
+		t.Errorf("status = %d, want %d", code, http.StatusOK)
+	}
+}
+
+func TestMetricsAuthLockout(t *testing.T) {
+	env := newAuthTestEnv(t)
+
+	if code := env.get("/metrics", "10.0.0.8", testMetricsToken); code != http.StatusOK {
+		t.Errorf("metrics token: status = %d, want %d", code, http.StatusOK)
+	}
+	for i := range 3 {
+		if code := env.get("/metrics", "10.0.0.8", fmt.Sprintf("sk-guess-%d", i)); code != http.StatusUnauthorized {
+			t.Fatalf("guess %d: status = %d, want %d", i, code, http.StatusUnauthorized)
+		}
+	}
+
+	// 猜测 /metrics 与管理接口共用同一个锁定
+	if code := env.get("/metrics", "10.0.0.8", testAuthKey); code != http.StatusTooManyRequests {
+		t.Errorf("AUTH_KEY after lockout: status = %d, want %d", code, http.StatusTooManyRequests)
+	}
+	if code := env.do("10.0.0.8", testAuthKey); code != http.StatusTooManyRequests {
+		t.Errorf("admin API after metrics lockout: status = %d, want %d", code, http.StatusTooManyRequests)
+	}
+	if code := env.get("/metrics", "10.0.0.9", testAuthKey); code != http.StatusOK {
+		t.Errorf("other IP: status = %d, want %d", code, http.StatusOK)
+	}
+}
+
+func TestRequirePermissionAndGroupAccess(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	var principal *services.AdminPrincipal
+	router.Use(func(c *gin.Context) {
+		if principal != nil {
+			services.SetAdminPrincipal(c, principal)
+		}
+	})
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.GET("/api/groups/:id", RequirePermission(services.PermGroupsRead), RequireGroupAccess(), ok)
+	router.PUT("/api/groups/:id", RequirePermission(services.PermGroupsWrite), RequireGroupAccess(), ok)
+	router.DELETE("/api/groups/:id", RequirePermission(services.PermGroupsManage), RequireGroupAccess(), ok)
+
+	tests := []struct {
+		name      string
+		principal *services.AdminPrincipal
+		method    string
+		path      string
+		want      int
+	}{
+		{"no principal", nil, http.MethodGet, "/api/groups/1", http.StatusForbidden},
+		{"owner", services.NewOwnerPrincipal(), http.MethodDelete, "/api/groups/1", http.StatusOK},
+		{"viewer reads", &services.AdminPrincipal{Role: models.AdminRoleViewer}, http.MethodGet, "/api/groups/1", http.StatusOK},
+		{"viewer writes", &services.AdminPrincipal{Role: models.AdminRoleViewer}, http.MethodPut, "/api/groups/1", http.StatusForbidden},
+		{"group operator in scope", &services.AdminPrincipal{Role: models.AdminRoleGroupOperator, GroupIDs: []uint{1}}, http.MethodPut, "/api/groups/1", http.StatusOK},
+		{"group operator out of scope", &services.AdminPrincipal{Role: models.AdminRoleGroupOperator, GroupIDs: []uint{1}}, http.MethodGet, "/api/groups/2", http.StatusForbidden},
+		{"group operator deletes", &services.AdminPrincipal{Role: models.AdminRoleGroupOperator, GroupIDs: []uint{1}}, http.MethodDelete, "/api/groups/1", http.StatusForbidden},
+		{"token scope", &services.AdminPrincipal{TokenID: 1, Role: models.AdminRoleOwner, Scopes: []string{services.PermGroupsRead}}, http.MethodPut, "/api/groups/1", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		principal = tt.principal
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+		if w.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, w.Code, tt.want)
+		}
+	}
+}
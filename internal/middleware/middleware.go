@@ -4,6 +4,7 @@ package middleware
 import (
 	"crypto/subtle"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
@@ -13,6 +14,7 @@ import (
 	"gpt-load/internal/types"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 )
 
@@ -116,7 +118,7 @@ func CORS(config types.CORSConfig) gin.HandlerFunc {
 }
 
 // Auth creates an authentication middleware
-func Auth(authConfig types.AuthConfig) gin.HandlerFunc {
+func Auth(authConfig types.AuthConfig, adminUsers *services.AdminUserService, sessions *services.AdminSessionService, apiTokens *services.APITokenService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		path := c.Request.URL.Path
 
@@ -125,11 +127,135 @@ func Auth(authConfig types.AuthConfig) gin.HandlerFunc {
 			return
 		}
 
+		principal, lockedFor := authenticateAdmin(c, authConfig, adminUsers, sessions, apiTokens)
+		if lockedFor > 0 {
+			c.Header("Retry-After", strconv.Itoa(int(lockedFor.Seconds())+1))
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrTooManyRequests, "Too many failed login attempts, please try again later"))
+			c.Abort()
+			return
+		}
+		if principal == nil {
+			response.Error(c, app_errors.ErrUnauthorized)
+			c.Abort()
+			return
+		}
+
+		services.SetAdminPrincipal(c, principal)
+		c.Next()
+	}
+}
+
+// authenticateAdmin resolves the admin of a request from HTTP Basic credentials, a session token, an API token or the AUTH_KEY.
+// Session and API tokens are never affected by the login lockout. Passwords and the AUTH_KEY are rejected while the
+// client IP is locked out, and each distinct wrong one counts as a single failed login, so a client that keeps
+// sending an outdated credential cannot lock the IP out on its own.
+func authenticateAdmin(c *gin.Context, authConfig types.AuthConfig, adminUsers *services.AdminUserService, sessions *services.AdminSessionService, apiTokens *services.APITokenService) (*services.AdminPrincipal, time.Duration) {
+	clientIP := c.ClientIP()
+
+	if username, password, ok := c.Request.BasicAuth(); ok {
+		if lockedFor := sessions.LoginLockedFor(clientIP); lockedFor > 0 {
+			return nil, lockedFor
+		}
+		principal, err := adminUsers.Authenticate(username, password)
+		if err != nil {
+			if err == services.ErrInvalidCredentials {
+				sessions.RecordCredentialFailure(clientIP, username+":"+password)
+			} else {
+				logrus.WithError(err).Error("Failed to authenticate admin user")
+			}
+			return nil, 0
+		}
+		return principal, 0
+	}
+
+	key := extractAuthKey(c)
+	if key == "" {
+		return nil, 0
+	}
+
+	if services.IsSessionToken(key) {
+		principal, err := sessions.Resolve(key)
+		if err != nil {
+			if err != services.ErrSessionInvalid {
+				logrus.WithError(err).Error("Failed to resolve admin session")
+			}
+			return nil, 0
+		}
+		c.Set("adminSessionToken", key)
+		return principal, 0
+	}
+
+	if services.IsAPIToken(key) {
+		principal, err := apiTokens.Authenticate(key, clientIP)
+		if err != nil {
+			if err != services.ErrAPITokenInvalid {
+				logrus.WithError(err).Error("Failed to authenticate API token")
+			}
+			return nil, 0
+		}
+		return principal, 0
+	}
+
+	if lockedFor := sessions.LoginLockedFor(clientIP); lockedFor > 0 {
+		return nil, lockedFor
+	}
+	if subtle.ConstantTimeCompare([]byte(key), []byte(authConfig.Key)) == 1 {
+		return services.NewOwnerPrincipal(), 0
+	}
+	sessions.RecordCredentialFailure(clientIP, key)
+	return nil, 0
+}
+
+// RequirePermission rejects admins whose role lacks the permission
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal := services.AdminPrincipalFromContext(c)
+		if principal == nil || !principal.Can(permission) {
+			response.Error(c, app_errors.ErrForbidden)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireGroupAccess rejects group-scoped admins accessing a group outside their scope via the :id parameter
+func RequireGroupAccess() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal := services.AdminPrincipalFromContext(c)
+		if groupID, err := strconv.Atoi(c.Param("id")); err == nil && principal != nil && !principal.CanAccessGroup(uint(groupID)) {
+			response.Error(c, app_errors.ErrForbidden)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// MetricsAuth authenticates Prometheus scrapes with either the admin key or the dedicated metrics token.
+// Wrong keys count towards the admin login lockout of the client IP, like on the admin API.
+func MetricsAuth(authConfig types.AuthConfig, sessions *services.AdminSessionService) gin.HandlerFunc {
+	return func(c *gin.Context) {
 		key := extractAuthKey(c)
+		if key == "" {
+			response.Error(c, app_errors.ErrUnauthorized)
+			c.Abort()
+			return
+		}
+
+		clientIP := c.ClientIP()
+		if lockedFor := sessions.LoginLockedFor(clientIP); lockedFor > 0 {
+			c.Header("Retry-After", strconv.Itoa(int(lockedFor.Seconds())+1))
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrTooManyRequests, "Too many failed login attempts, please try again later"))
+			c.Abort()
+			return
+		}
 
-		isValid := key != "" && subtle.ConstantTimeCompare([]byte(key), []byte(authConfig.Key)) == 1
+		isValid := subtle.ConstantTimeCompare([]byte(key), []byte(authConfig.Key)) == 1 ||
+			(authConfig.MetricsToken != "" && subtle.ConstantTimeCompare([]byte(key), []byte(authConfig.MetricsToken)) == 1)
 
 		if !isValid {
+			sessions.RecordCredentialFailure(clientIP, key)
 			response.Error(c, app_errors.ErrUnauthorized)
 			c.Abort()
 			return
@@ -162,6 +288,7 @@ func ProxyAuth(gm *services.GroupManager) gin.HandlerFunc {
 		_, existsInGroup := group.ProxyKeysMap[key]
 
 		if existsInEffective || existsInGroup {
+			c.Set("proxyKey", key)
 			c.Next()
 			return
 		}
@@ -171,6 +298,120 @@ func ProxyAuth(gm *services.GroupManager) gin.HandlerFunc {
 	}
 }
 
+// rateLimitCheck is a single limiter key together with its limit.
+type rateLimitCheck struct {
+	key   string
+	limit int
+}
+
+// ProxyRateLimit enforces the per-group and per-proxy-key rate limits.
+// It must run after ProxyAuth, which stores the authenticated proxy key in the context.
+func ProxyRateLimit(gm *services.GroupManager, limiter *services.RateLimitService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		group, err := gm.GetGroupByName(c.Param("group_name"))
+		if err != nil {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, "Failed to retrieve proxy group"))
+			c.Abort()
+			return
+		}
+
+		cfg := group.EffectiveConfig
+		if cfg.GroupRateLimit <= 0 && cfg.ProxyKeyRateLimit <= 0 {
+			c.Next()
+			return
+		}
+		window := time.Duration(cfg.RateLimitWindowSeconds) * time.Second
+
+		// 任一限制拒绝请求时，退还之前已通过的检查计入的配额
+		var checks []rateLimitCheck
+		if cfg.ProxyKeyRateLimit > 0 {
+			checks = append(checks, rateLimitCheck{limiter.ProxyKeyKey(group.ID, c.GetString("proxyKey")), cfg.ProxyKeyRateLimit})
+		}
+		if cfg.GroupRateLimit > 0 {
+			checks = append(checks, rateLimitCheck{limiter.GroupKey(group.ID), cfg.GroupRateLimit})
+		}
+
+		// 响应头展示剩余配额最少的那个限制
+		var tightest *services.RateLimitResult
+		var charged []*services.RateLimitResult
+		for _, check := range checks {
+			result, err := limiter.Allow(check.key, check.limit, window)
+			if err != nil {
+				// 存储异常时放行，避免限流组件故障导致代理整体不可用
+				logrus.WithError(err).WithField("group", group.Name).Warn("Rate limit check failed, allowing request")
+				continue
+			}
+
+			if !result.Allowed {
+				for _, previous := range charged {
+					if err := limiter.Release(previous); err != nil {
+						logrus.WithError(err).WithField("group", group.Name).Warn("Failed to release rate limit quota")
+					}
+				}
+				setRateLimitHeaders(c, result)
+				c.Header("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+				response.Error(c, app_errors.ErrTooManyRequests)
+				c.Abort()
+				return
+			}
+
+			charged = append(charged, result)
+			if tightest == nil || result.Remaining < tightest.Remaining {
+				tightest = result
+			}
+		}
+
+		if tightest != nil {
+			setRateLimitHeaders(c, tightest)
+		}
+		c.Next()
+	}
+}
+
+// setRateLimitHeaders writes the X-RateLimit-* headers for a rate limit result.
+func setRateLimitHeaders(c *gin.Context, result *services.RateLimitResult) {
+	c.Header("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+	c.Header("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	c.Header("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(result.ResetAfter)))
+}
+
+// ceilSeconds rounds a duration up to whole seconds, with a minimum of 1.
+func ceilSeconds(d time.Duration) int {
+	seconds := int((d + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		return 1
+	}
+	return seconds
+}
+
+// RequestID assigns a request ID to every request. An incoming X-Request-ID header is honored
+// if it is well-formed, otherwise a new ID is generated. The ID is returned to the client.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := strings.TrimSpace(c.GetHeader("X-Request-ID"))
+		if !isValidRequestID(requestID) {
+			requestID = uuid.NewString()
+		}
+
+		c.Set("requestID", requestID)
+		c.Header("X-Request-ID", requestID)
+		c.Next()
+	}
+}
+
+// isValidRequestID checks that a client supplied request ID is safe to log and forward.
+func isValidRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > 64 {
+		return false
+	}
+	for _, r := range requestID {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.' || r == ':') {
+			return false
+		}
+	}
+	return true
+}
+
 // Recovery creates a recovery middleware with custom error handling
 func Recovery() gin.HandlerFunc {
 	return gin.CustomRecovery(func(c *gin.Context, recovered any) {
@@ -190,10 +431,29 @@ func RateLimiter(config types.PerformanceConfig) gin.HandlerFunc {
 		case semaphore <- struct{}{}:
 			defer func() { <-semaphore }()
 			c.Next()
+			return
 		default:
-			response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, "Too many concurrent requests"))
-			c.Abort()
 		}
+
+		// 并发已满时，按配置等待空闲槽位
+		if config.MaxConcurrentWait > 0 {
+			timer := time.NewTimer(time.Duration(config.MaxConcurrentWait) * time.Second)
+			defer timer.Stop()
+
+			select {
+			case semaphore <- struct{}{}:
+				defer func() { <-semaphore }()
+				c.Next()
+				return
+			case <-timer.C:
+			case <-c.Request.Context().Done():
+				c.Abort()
+				return
+			}
+		}
+
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrTooManyRequests, "Too many concurrent requests"))
+		c.Abort()
 	}
 }
 
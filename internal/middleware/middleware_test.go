@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func TestRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestID())
+	router.GET("/", func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString("requestID"))
+	})
+
+	tests := []struct {
+		name     string
+		incoming string
+		honored  bool
+	}{
+		{"client id", "req-2024_01.a:b", true},
+		{"uuid", "3f1c9b2e-8d4a-4f6e-9a7b-1c2d3e4f5a6b", true},
+		{"missing", "", false},
+		{"too long", strings.Repeat("a", 65), false},
+		{"header injection", "abc\r\nX-Evil: 1", false},
+		{"spaces", "a b", false},
+		{"non ascii", "请求", false},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tt.incoming != "" {
+			req.Header["X-Request-Id"] = []string{tt.incoming}
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		got := w.Header().Get("X-Request-ID")
+		if got != w.Body.String() {
+			t.Errorf("%s: header %q differs from the context value %q", tt.name, got, w.Body.String())
+		}
+		if tt.honored {
+			if got != tt.incoming {
+				t.Errorf("%s: request ID = %q, want %q", tt.name, got, tt.incoming)
+			}
+			continue
+		}
+		if _, err := uuid.Parse(got); err != nil {
+			t.Errorf("%s: request ID = %q, want a generated UUID", tt.name, got)
+		}
+	}
+}
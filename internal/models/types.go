@@ -25,53 +25,105 @@ type SystemSetting struct {
 
 // GroupConfig 存储特定于分组的配置
 type GroupConfig struct {
-	RequestTimeout               *int    `json:"request_timeout,omitempty"`
-	IdleConnTimeout              *int    `json:"idle_conn_timeout,omitempty"`
-	ConnectTimeout               *int    `json:"connect_timeout,omitempty"`
-	MaxIdleConns                 *int    `json:"max_idle_conns,omitempty"`
-	MaxIdleConnsPerHost          *int    `json:"max_idle_conns_per_host,omitempty"`
-	ResponseHeaderTimeout        *int    `json:"response_header_timeout,omitempty"`
-	ProxyURL                     *string `json:"proxy_url,omitempty"`
-	MaxRetries                   *int    `json:"max_retries,omitempty"`
-	BlacklistThreshold           *int    `json:"blacklist_threshold,omitempty"`
-	KeyValidationIntervalMinutes *int    `json:"key_validation_interval_minutes,omitempty"`
-	KeyValidationConcurrency     *int    `json:"key_validation_concurrency,omitempty"`
-	KeyValidationTimeoutSeconds  *int    `json:"key_validation_timeout_seconds,omitempty"`
-	EnableRequestBodyLogging     *bool   `json:"enable_request_body_logging,omitempty"`
+	RequestTimeout                *int    `json:"request_timeout,omitempty"`
+	IdleConnTimeout               *int    `json:"idle_conn_timeout,omitempty"`
+	ConnectTimeout                *int    `json:"connect_timeout,omitempty"`
+	MaxIdleConns                  *int    `json:"max_idle_conns,omitempty"`
+	MaxIdleConnsPerHost           *int    `json:"max_idle_conns_per_host,omitempty"`
+	ResponseHeaderTimeout         *int    `json:"response_header_timeout,omitempty"`
+	ProxyURL                      *string `json:"proxy_url,omitempty"`
+	MaxRetries                    *int    `json:"max_retries,omitempty"`
+	BlacklistThreshold            *int    `json:"blacklist_threshold,omitempty"`
+	KeyValidationIntervalMinutes  *int    `json:"key_validation_interval_minutes,omitempty"`
+	KeyValidationConcurrency      *int    `json:"key_validation_concurrency,omitempty"`
+	KeyValidationTimeoutSeconds   *int    `json:"key_validation_timeout_seconds,omitempty"`
+	EnableRequestBodyLogging      *bool   `json:"enable_request_body_logging,omitempty"`
+	EnableResponseBodyLogging     *bool   `json:"enable_response_body_logging,omitempty"`
+	ResponseBodyLogMaxBytes       *int    `json:"response_body_log_max_bytes,omitempty"`
+	RateLimitWindowSeconds        *int    `json:"rate_limit_window_seconds,omitempty"`
+	GroupRateLimit                *int    `json:"group_rate_limit,omitempty"`
+	ProxyKeyRateLimit             *int    `json:"proxy_key_rate_limit,omitempty"`
+	QueueMaxDepth                 *int    `json:"queue_max_depth,omitempty"`
+	QueueMaxWaitSeconds           *int    `json:"queue_max_wait_seconds,omitempty"`
+	EnableResponseCache           *bool   `json:"enable_response_cache,omitempty"`
+	ResponseCacheTTLSeconds       *int    `json:"response_cache_ttl_seconds,omitempty"`
+	ResponseCacheMaxBytes         *int    `json:"response_cache_max_bytes,omitempty"`
+	ResponseCacheIncludeClientKey *bool   `json:"response_cache_include_client_key,omitempty"`
+	EnableRequestCoalescing       *bool   `json:"enable_request_coalescing,omitempty"`
+	AlertLowActiveKeysThreshold   *int    `json:"alert_low_active_keys_threshold,omitempty"`
+	AlertErrorRateThreshold       *int    `json:"alert_error_rate_threshold,omitempty"`
+	AlertErrorRateWindowMinutes   *int    `json:"alert_error_rate_window_minutes,omitempty"`
+	AlertErrorRateMinRequests     *int    `json:"alert_error_rate_min_requests,omitempty"`
+	LogRedactionEnabled           *bool   `json:"log_redaction_enabled,omitempty"`
+	LogRedactionDetectors         *string `json:"log_redaction_detectors,omitempty"`
+	LogRedactionMode              *string `json:"log_redaction_mode,omitempty"`
+	LogRedactionFields            *string `json:"log_redaction_fields,omitempty"`
 }
 
 // HeaderRule defines a single rule for header manipulation.
 type HeaderRule struct {
-	Key    string `json:"key"`
-	Value  string `json:"value"`
-	Action string `json:"action"` // "set" or "remove"
+	Key    string   `json:"key"`
+	Value  string   `json:"value"`
+	Action string   `json:"action"`           // "set" or "remove"
+	Paths  []string `json:"paths,omitempty"`  // Optional glob patterns matched against the request path
+	Models []string `json:"models,omitempty"` // Optional glob patterns matched against the model
+}
+
+// BodyRule defines a single JSON-path based request body transformation rule.
+type BodyRule struct {
+	Action string             `json:"action"` // "set", "set_if_absent", "remove", "rename", "clamp", "append" or "prepend"
+	Path   string             `json:"path"`   // e.g. "generationConfig.maxOutputTokens" or "messages[0].content"
+	Value  any                `json:"value,omitempty"`
+	To     string             `json:"to,omitempty"` // Target path for "rename"
+	Min    *float64           `json:"min,omitempty"`
+	Max    *float64           `json:"max,omitempty"`
+	When   *BodyRuleCondition `json:"when,omitempty"`
+}
+
+// BodyRuleCondition restricts a body rule to matching requests. Empty lists match everything.
+type BodyRuleCondition struct {
+	Models     []string `json:"models,omitempty"`      // Glob patterns, e.g. "gpt-4o*"
+	Paths      []string `json:"paths,omitempty"`       // Glob patterns matched against the path after the group name
+	ClientKeys []string `json:"client_keys,omitempty"` // Proxy keys used by the client
+}
+
+// RedactionRule defines a custom pattern that is redacted from logged request bodies and errors.
+type RedactionRule struct {
+	Name    string `json:"name"`    // Shown in the replacement, e.g. [REDACTED:employee_id]
+	Pattern string `json:"pattern"` // Go regular expression
 }
 
 // Group 对应 groups 表
 type Group struct {
-	ID                 uint                 `gorm:"primaryKey;autoIncrement" json:"id"`
-	EffectiveConfig    types.SystemSettings `gorm:"-" json:"effective_config,omitempty"`
-	Name               string               `gorm:"type:varchar(255);not null;unique" json:"name"`
-	Endpoint           string               `gorm:"-" json:"endpoint"`
-	DisplayName        string               `gorm:"type:varchar(255)" json:"display_name"`
-	ProxyKeys          string               `gorm:"type:text" json:"proxy_keys"`
-	Description        string               `gorm:"type:varchar(512)" json:"description"`
-	Upstreams          datatypes.JSON       `gorm:"type:json;not null" json:"upstreams"`
-	ValidationEndpoint string               `gorm:"type:varchar(255)" json:"validation_endpoint"`
-	ChannelType        string               `gorm:"type:varchar(50);not null" json:"channel_type"`
-	Sort               int                  `gorm:"default:0" json:"sort"`
-	TestModel          string               `gorm:"type:varchar(255);not null" json:"test_model"`
-	ParamOverrides     datatypes.JSONMap    `gorm:"type:json" json:"param_overrides"`
-	Config             datatypes.JSONMap    `gorm:"type:json" json:"config"`
-	HeaderRules        datatypes.JSON       `gorm:"type:json" json:"header_rules"`
-	APIKeys            []APIKey             `gorm:"foreignKey:GroupID" json:"api_keys"`
-	LastValidatedAt    *time.Time           `json:"last_validated_at"`
-	CreatedAt          time.Time            `json:"created_at"`
-	UpdatedAt          time.Time            `json:"updated_at"`
+	ID                  uint                 `gorm:"primaryKey;autoIncrement" json:"id"`
+	EffectiveConfig     types.SystemSettings `gorm:"-" json:"effective_config,omitempty"`
+	Name                string               `gorm:"type:varchar(255);not null;unique" json:"name"`
+	Endpoint            string               `gorm:"-" json:"endpoint"`
+	DisplayName         string               `gorm:"type:varchar(255)" json:"display_name"`
+	ProxyKeys           string               `gorm:"type:text" json:"proxy_keys"`
+	Description         string               `gorm:"type:varchar(512)" json:"description"`
+	Upstreams           datatypes.JSON       `gorm:"type:json;not null" json:"upstreams"`
+	ValidationEndpoint  string               `gorm:"type:varchar(255)" json:"validation_endpoint"`
+	ChannelType         string               `gorm:"type:varchar(50);not null" json:"channel_type"`
+	Sort                int                  `gorm:"default:0" json:"sort"`
+	TestModel           string               `gorm:"type:varchar(255);not null" json:"test_model"`
+	ParamOverrides      datatypes.JSONMap    `gorm:"type:json" json:"param_overrides"`
+	Config              datatypes.JSONMap    `gorm:"type:json" json:"config"`
+	HeaderRules         datatypes.JSON       `gorm:"type:json" json:"header_rules"`
+	BodyRules           datatypes.JSON       `gorm:"type:json" json:"body_rules"`
+	ResponseHeaderRules datatypes.JSON       `gorm:"type:json" json:"response_header_rules"`
+	RedactionRules      datatypes.JSON       `gorm:"type:json" json:"redaction_rules"`
+	APIKeys             []APIKey             `gorm:"foreignKey:GroupID" json:"api_keys"`
+	LastValidatedAt     *time.Time           `json:"last_validated_at"`
+	CreatedAt           time.Time            `json:"created_at"`
+	UpdatedAt           time.Time            `json:"updated_at"`
 
 	// For cache
-	ProxyKeysMap   map[string]struct{} `gorm:"-" json:"-"`
-	HeaderRuleList []HeaderRule        `gorm:"-" json:"-"`
+	ProxyKeysMap           map[string]struct{} `gorm:"-" json:"-"`
+	HeaderRuleList         []HeaderRule        `gorm:"-" json:"-"`
+	BodyRuleList           []BodyRule          `gorm:"-" json:"-"`
+	ResponseHeaderRuleList []HeaderRule        `gorm:"-" json:"-"`
+	RedactionRuleList      []RedactionRule     `gorm:"-" json:"-"`
 }
 
 // APIKey 对应 api_keys 表
@@ -90,30 +142,121 @@ type APIKey struct {
 
 // RequestType 请求类型常量
 const (
-	RequestTypeRetry = "retry"
-	RequestTypeFinal = "final"
+	RequestTypeRetry     = "retry"
+	RequestTypeFinal     = "final"
+	RequestTypeCached    = "cached"
+	RequestTypeCoalesced = "coalesced"
 )
 
 // RequestLog 对应 request_logs 表
 type RequestLog struct {
-	ID           string    `gorm:"type:varchar(36);primaryKey" json:"id"`
-	Timestamp    time.Time `gorm:"not null;index" json:"timestamp"`
-	GroupID      uint      `gorm:"not null;index" json:"group_id"`
-	GroupName    string    `gorm:"type:varchar(255);index" json:"group_name"`
-	KeyValue     string    `gorm:"type:text" json:"key_value"`
-	KeyHash      string    `gorm:"type:varchar(128);index" json:"key_hash"`
-	Model        string    `gorm:"type:varchar(255);index" json:"model"`
-	IsSuccess    bool      `gorm:"not null" json:"is_success"`
-	SourceIP     string    `gorm:"type:varchar(64)" json:"source_ip"`
-	StatusCode   int       `gorm:"not null" json:"status_code"`
-	RequestPath  string    `gorm:"type:varchar(500)" json:"request_path"`
-	Duration     int64     `gorm:"not null" json:"duration_ms"`
-	ErrorMessage string    `gorm:"type:text" json:"error_message"`
-	UserAgent    string    `gorm:"type:varchar(512)" json:"user_agent"`
-	RequestType  string    `gorm:"type:varchar(20);not null;default:'final';index" json:"request_type"`
-	UpstreamAddr string    `gorm:"type:varchar(500)" json:"upstream_addr"`
-	IsStream     bool      `gorm:"not null" json:"is_stream"`
-	RequestBody  string    `gorm:"type:text" json:"request_body"`
+	ID                string    `gorm:"type:varchar(36);primaryKey" json:"id"`
+	Timestamp         time.Time `gorm:"not null;index" json:"timestamp"`
+	GroupID           uint      `gorm:"not null;index" json:"group_id"`
+	GroupName         string    `gorm:"type:varchar(255);index" json:"group_name"`
+	KeyValue          string    `gorm:"type:text" json:"key_value"`
+	KeyHash           string    `gorm:"type:varchar(128);index" json:"key_hash"`
+	Model             string    `gorm:"type:varchar(255);index" json:"model"`
+	IsSuccess         bool      `gorm:"not null" json:"is_success"`
+	SourceIP          string    `gorm:"type:varchar(64)" json:"source_ip"`
+	StatusCode        int       `gorm:"not null" json:"status_code"`
+	RequestPath       string    `gorm:"type:varchar(500)" json:"request_path"`
+	Duration          int64     `gorm:"not null" json:"duration_ms"`
+	ErrorMessage      string    `gorm:"type:text" json:"error_message"`
+	UserAgent         string    `gorm:"type:varchar(512)" json:"user_agent"`
+	RequestType       string    `gorm:"type:varchar(20);not null;default:'final';index" json:"request_type"`
+	UpstreamAddr      string    `gorm:"type:varchar(500)" json:"upstream_addr"`
+	IsStream          bool      `gorm:"not null" json:"is_stream"`
+	RequestBody       string    `gorm:"type:text" json:"request_body"`
+	ResponseBody      string    `gorm:"type:text" json:"response_body"`
+	RequestID         string    `gorm:"type:varchar(64);index" json:"request_id"`
+	Attempt           int       `gorm:"not null;default:0" json:"attempt"`
+	UpstreamRequestID string    `gorm:"type:varchar(255)" json:"upstream_request_id"`
+}
+
+// 审计操作类型
+const (
+	AuditActionGroupCreate        = "group.create"
+	AuditActionGroupUpdate        = "group.update"
+	AuditActionGroupDelete        = "group.delete"
+	AuditActionGroupCopy          = "group.copy"
+	AuditActionKeysAdd            = "keys.add"
+	AuditActionKeysDelete         = "keys.delete"
+	AuditActionKeysRestore        = "keys.restore"
+	AuditActionKeysRestoreInvalid = "keys.restore_all_invalid"
+	AuditActionKeysClearInvalid   = "keys.clear_all_invalid"
+	AuditActionKeysClearAll       = "keys.clear_all"
+	AuditActionKeysValidate       = "keys.validate"
+	AuditActionSettingsUpdate     = "settings.update"
+	AuditActionUserCreate         = "user.create"
+	AuditActionUserUpdate         = "user.update"
+	AuditActionUserDelete         = "user.delete"
+	AuditActionTokenCreate        = "token.create"
+	AuditActionTokenRevoke        = "token.revoke"
+	AuditActionLogsPurge          = "logs.purge"
+	AuditActionKeysReencrypt      = "keys.reencrypt"
+)
+
+// 审计目标类型
+const (
+	AuditTargetGroup    = "group"
+	AuditTargetSettings = "settings"
+	AuditTargetUser     = "user"
+	AuditTargetAPIToken = "api_token"
+	AuditTargetLogs     = "request_logs"
+	AuditTargetKeys     = "api_keys"
+)
+
+// AuditLog 对应 audit_logs 表，记录管理接口的变更操作
+type AuditLog struct {
+	ID         uint           `gorm:"primaryKey;autoIncrement" json:"id"`
+	Timestamp  time.Time      `gorm:"not null;index" json:"timestamp"`
+	Actor      string         `gorm:"type:varchar(255);index" json:"actor"`
+	SourceIP   string         `gorm:"type:varchar(64)" json:"source_ip"`
+	Action     string         `gorm:"type:varchar(64);not null;index" json:"action"`
+	TargetType string         `gorm:"type:varchar(32);index" json:"target_type"`
+	TargetID   uint           `gorm:"index" json:"target_id"`
+	TargetName string         `gorm:"type:varchar(255)" json:"target_name"`
+	Changes    datatypes.JSON `gorm:"type:json" json:"changes"`
+	Details    datatypes.JSON `gorm:"type:json" json:"details"`
+}
+
+// 管理员角色
+const (
+	AdminRoleOwner         = "owner"          // 全部权限，包括用户管理和系统设置
+	AdminRoleOperator      = "operator"       // 管理分组和密钥
+	AdminRoleGroupOperator = "group_operator" // 仅能管理指定分组的密钥和配置
+	AdminRoleViewer        = "viewer"         // 只读查看仪表盘和日志
+)
+
+// AdminUser 对应 admin_users 表
+type AdminUser struct {
+	ID           uint                      `gorm:"primaryKey;autoIncrement" json:"id"`
+	Username     string                    `gorm:"type:varchar(255);not null;unique" json:"username"`
+	PasswordHash string                    `gorm:"type:varchar(255);not null" json:"-"`
+	Role         string                    `gorm:"type:varchar(32);not null" json:"role"`
+	GroupIDs     datatypes.JSONSlice[uint] `gorm:"type:json" json:"group_ids"`
+	Disabled     bool                      `gorm:"not null;default:false" json:"disabled"`
+	ExternalID   string                    `gorm:"type:varchar(255);index" json:"external_id,omitempty"` // OIDC 账号的 issuer#subject，本地账号为空
+	LastLoginAt  *time.Time                `json:"last_login_at"`
+	CreatedAt    time.Time                 `json:"created_at"`
+	UpdatedAt    time.Time                 `json:"updated_at"`
+}
+
+// APIToken 对应 api_tokens 表，供自动化脚本调用管理接口
+type APIToken struct {
+	ID          uint                        `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name        string                      `gorm:"type:varchar(255);not null;unique" json:"name"`
+	TokenHash   string                      `gorm:"type:varchar(64);not null;uniqueIndex" json:"-"`
+	TokenPrefix string                      `gorm:"type:varchar(16);not null" json:"token_prefix"` // 令牌前几位，便于识别
+	Permissions datatypes.JSONSlice[string] `gorm:"type:json" json:"permissions"`
+	GroupIDs    datatypes.JSONSlice[uint]   `gorm:"type:json" json:"group_ids"` // 为空表示不限分组
+	ExpiresAt   *time.Time                  `json:"expires_at"`
+	LastUsedAt  *time.Time                  `json:"last_used_at"`
+	LastUsedIP  string                      `gorm:"type:varchar(64)" json:"last_used_ip"`
+	CreatedBy   string                      `gorm:"type:varchar(255)" json:"created_by"`
+	CreatedAt   time.Time                   `json:"created_at"`
+	UpdatedAt   time.Time                   `json:"updated_at"`
 }
 
 // StatCard 用于仪表盘的单个统计卡片数据
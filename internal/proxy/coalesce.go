@@ -0,0 +1,118 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"gpt-load/internal/channel"
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/response"
+	"gpt-load/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// captureWriter tees everything written to the client into a buffer.
+type captureWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *captureWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *captureWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// handleCoalescedRequest sends identical concurrent non-streaming requests upstream only once.
+// The first request (leader) is executed normally, the others wait for and replay its response.
+func (ps *ProxyServer) handleCoalescedRequest(
+	c *gin.Context,
+	channelHandler channel.ChannelProxy,
+	group *models.Group,
+	bodyBytes []byte,
+	startTime time.Time,
+) {
+	key := ps.buildResponseCacheKey(c, group, bodyBytes)
+
+	result, coalesced := ps.requestCoalescer.Do(group.ID, key, func() *services.CoalescedResponse {
+		// 记录执行前已有的响应头（如限流头），这些头属于当前请求，不共享给其他请求
+		initialHeader := c.Writer.Header().Clone()
+
+		// 上游请求由所有合并的请求共享，不能因领头请求的客户端断开而取消
+		cfg := group.EffectiveConfig
+		timeout := time.Duration(cfg.RequestTimeout) * time.Second * time.Duration(cfg.MaxRetries+1)
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(c.Request.Context()), timeout)
+		defer cancel()
+		clientRequest := c.Request
+		c.Request = c.Request.WithContext(ctx)
+
+		writer := &captureWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		ps.executeRequestWithRetry(c, channelHandler, group, bodyBytes, false, startTime, 0)
+		c.Writer = writer.ResponseWriter
+		c.Request = clientRequest
+
+		return writer.coalescedResponse(initialHeader)
+	})
+
+	if !coalesced {
+		return
+	}
+
+	if result == nil {
+		// 领头请求没有得到可共享的响应，跟随的请求不能返回空的成功响应
+		err := errors.New("coalesced request produced no response")
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrBadGateway, err.Error()))
+		ps.logRequest(c, group, nil, startTime, http.StatusBadGateway, err, false, "", channelHandler, bodyBytes, models.RequestTypeCoalesced, 0, "", nil)
+		return
+	}
+
+	copyResponseHeaders(c, result.Header)
+	c.Header("X-Coalesced", "true")
+	c.Status(result.StatusCode)
+	if _, err := c.Writer.Write(result.Body); err != nil {
+		logUpstreamError("writing coalesced response to client", err)
+	}
+
+	capture := newResponseCapture(group, result.Header, false)
+	capture.Write(result.Body)
+	ps.logRequest(c, group, nil, startTime, result.StatusCode, nil, false, "", channelHandler, bodyBytes, models.RequestTypeCoalesced, 0, "", capture)
+}
+
+// coalescedResponse returns the response to share with the followers, or nil when nothing was
+// written, e.g. because the request was aborted.
+func (w *captureWriter) coalescedResponse(initialHeader http.Header) *services.CoalescedResponse {
+	if !w.Written() || w.body.Len() == 0 {
+		return nil
+	}
+	header := diffHeader(w.Header(), initialHeader)
+	// 请求 ID 属于领头请求，跟随的请求使用自己的 ID
+	header.Del("X-Request-Id")
+	return &services.CoalescedResponse{
+		StatusCode: w.Status(),
+		Header:     header,
+		Body:       w.body.Bytes(),
+	}
+}
+
+// diffHeader returns the headers in current that were added or changed compared to initial.
+func diffHeader(current, initial http.Header) http.Header {
+	diff := make(http.Header)
+	for key, values := range current {
+		if slices.Equal(values, initial[key]) {
+			continue
+		}
+		diff[key] = slices.Clone(values)
+	}
+	return diff
+}
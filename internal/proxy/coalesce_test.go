@@ -0,0 +1,63 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newCaptureWriter() (*captureWriter, *gin.Context) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	return &captureWriter{ResponseWriter: c.Writer}, c
+}
+
+func TestCoalescedResponseNothingWritten(t *testing.T) {
+	writer, c := newCaptureWriter()
+	initial := c.Writer.Header().Clone()
+
+	if res := writer.coalescedResponse(initial); res != nil {
+		t.Errorf("coalescedResponse() = %+v, want nil for an unwritten response", res)
+	}
+
+	// 只有状态码没有内容（例如客户端断开的 499）同样不能共享
+	writer.WriteHeader(499)
+	writer.WriteHeaderNow()
+	if res := writer.coalescedResponse(initial); res != nil {
+		t.Errorf("coalescedResponse() = %+v, want nil for an empty body", res)
+	}
+}
+
+func TestCoalescedResponseSharesNewHeaders(t *testing.T) {
+	writer, c := newCaptureWriter()
+	c.Writer.Header().Set("X-RateLimit-Remaining", "4")
+	initial := c.Writer.Header().Clone()
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.Header().Set("X-Request-Id", "req-leader")
+	writer.WriteHeader(http.StatusCreated)
+	if _, err := writer.Write([]byte(`{"id":1}`)); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	res := writer.coalescedResponse(initial)
+	if res == nil {
+		t.Fatal("coalescedResponse() = nil, want a response")
+	}
+	if res.StatusCode != http.StatusCreated {
+		t.Errorf("StatusCode = %d, want %d", res.StatusCode, http.StatusCreated)
+	}
+	if string(res.Body) != `{"id":1}` {
+		t.Errorf("Body = %q", res.Body)
+	}
+	if res.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("Content-Type not shared: %v", res.Header)
+	}
+	for _, name := range []string{"X-Ratelimit-Remaining", "X-Request-Id"} {
+		if _, ok := res.Header[name]; ok {
+			t.Errorf("header %s of the leader request shared: %v", name, res.Header)
+		}
+	}
+}
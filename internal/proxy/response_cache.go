@@ -0,0 +1,112 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// cacheBypassHeader lets clients skip the response cache for a single request.
+	cacheBypassHeader          = "X-Cache-Bypass"
+	responseCacheKeyContextKey = "responseCacheKey"
+)
+
+// isResponseCacheEnabled reports whether the response cache applies to the current request.
+func (ps *ProxyServer) isResponseCacheEnabled(c *gin.Context, group *models.Group) bool {
+	if !group.EffectiveConfig.EnableResponseCache {
+		return false
+	}
+
+	switch strings.ToLower(strings.TrimSpace(c.GetHeader(cacheBypassHeader))) {
+	case "1", "true", "yes":
+		return false
+	}
+	return true
+}
+
+// buildResponseCacheKey builds the cache key for the current request.
+func (ps *ProxyServer) buildResponseCacheKey(c *gin.Context, group *models.Group, bodyBytes []byte) string {
+	path := c.Request.URL.Path
+	if c.Request.URL.RawQuery != "" {
+		path += "?" + c.Request.URL.RawQuery
+	}
+
+	var clientKey string
+	if group.EffectiveConfig.ResponseCacheIncludeClientKey {
+		clientKey = c.GetString("proxyKey")
+	}
+
+	return ps.responseCache.BuildKey(group.ID, c.Request.Method, path, normalizeAcceptEncoding(c.GetHeader("Accept-Encoding")), bodyBytes, clientKey)
+}
+
+// normalizeAcceptEncoding returns the encodings the client accepts, lowercased and sorted, so that
+// equivalent Accept-Encoding headers share a cache entry. Encodings refused with q=0 are dropped.
+func normalizeAcceptEncoding(header string) string {
+	var encodings []string
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding == "" {
+			continue
+		}
+		if q, ok := strings.CutPrefix(strings.ReplaceAll(strings.ToLower(params), " ", ""), "q="); ok {
+			if value, err := strconv.ParseFloat(q, 64); err == nil && value == 0 {
+				continue
+			}
+		}
+		encodings = append(encodings, coding)
+	}
+	slices.Sort(encodings)
+	return strings.Join(slices.Compact(encodings), ",")
+}
+
+// writeCachedResponse replays a cached response to the client.
+func (ps *ProxyServer) writeCachedResponse(c *gin.Context, cached *services.CachedResponse) {
+	copyResponseHeaders(c, cached.Header)
+	c.Header("X-Cache", "HIT")
+	c.Status(cached.StatusCode)
+	if _, err := c.Writer.Write(cached.Body); err != nil {
+		logUpstreamError("writing cached response to client", err)
+	}
+}
+
+// handleCacheableResponse writes a non-streaming response to the client and stores successful ones in the cache.
+func (ps *ProxyServer) handleCacheableResponse(c *gin.Context, resp *http.Response, group *models.Group, cacheKey string) {
+	cfg := group.EffectiveConfig
+
+	// 超过缓存上限的响应直接透传，避免整体读入内存
+	if resp.ContentLength > int64(cfg.ResponseCacheMaxBytes) {
+		ps.handleNormalResponse(c, resp)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, int64(cfg.ResponseCacheMaxBytes)+1))
+	if _, writeErr := c.Writer.Write(body); writeErr != nil {
+		logUpstreamError("writing response body", writeErr)
+		return
+	}
+	if err != nil {
+		logUpstreamError("reading response body", err)
+		return
+	}
+
+	if len(body) > cfg.ResponseCacheMaxBytes {
+		// 剩余部分继续透传给客户端，不写入缓存
+		ps.handleNormalResponse(c, resp)
+		return
+	}
+
+	if resp.StatusCode == http.StatusOK {
+		ttl := time.Duration(cfg.ResponseCacheTTLSeconds) * time.Second
+		ps.responseCache.Set(cacheKey, resp.StatusCode, resp.Header, body, ttl, cfg.ResponseCacheMaxBytes)
+	}
+}
@@ -0,0 +1,158 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/services"
+	"gpt-load/internal/store"
+	"gpt-load/internal/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newCacheTestContext(header http.Header) (*httptest.ResponseRecorder, *gin.Context) {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/proxy/openai/v1/chat/completions?x=1", nil)
+	for name, values := range header {
+		c.Request.Header[name] = values
+	}
+	return recorder, c
+}
+
+func upstreamResponse(status int, body string) *http.Response {
+	return &http.Response{
+		StatusCode:    status,
+		Header:        http.Header{"Content-Type": {"application/json"}},
+		Body:          io.NopCloser(strings.NewReader(body)),
+		ContentLength: -1,
+	}
+}
+
+func TestIsResponseCacheEnabled(t *testing.T) {
+	ps := &ProxyServer{}
+	enabled := &models.Group{EffectiveConfig: types.SystemSettings{EnableResponseCache: true}}
+
+	tests := []struct {
+		group  *models.Group
+		bypass string
+		want   bool
+	}{
+		{enabled, "", true},
+		{enabled, "0", true},
+		{enabled, "true", false},
+		{enabled, " YES ", false},
+		{enabled, "1", false},
+		{&models.Group{}, "", false},
+	}
+	for _, tt := range tests {
+		_, c := newCacheTestContext(http.Header{cacheBypassHeader: {tt.bypass}})
+		if got := ps.isResponseCacheEnabled(c, tt.group); got != tt.want {
+			t.Errorf("isResponseCacheEnabled(enabled=%t, bypass=%q) = %t, want %t", tt.group.EffectiveConfig.EnableResponseCache, tt.bypass, got, tt.want)
+		}
+	}
+}
+
+func TestBuildResponseCacheKeyAcceptEncoding(t *testing.T) {
+	ps := &ProxyServer{responseCache: services.NewResponseCacheService(store.NewMemoryStore())}
+	group := &models.Group{ID: 1}
+	body := []byte(`{"model":"gpt-4o"}`)
+	keyFor := func(acceptEncoding string) string {
+		_, c := newCacheTestContext(http.Header{"Accept-Encoding": {acceptEncoding}})
+		return ps.buildResponseCacheKey(c, group, body)
+	}
+
+	// gzip 响应不能回放给没有声明支持 gzip 的客户端
+	identity := keyFor("")
+	gzipKey := keyFor("gzip")
+	if gzipKey == identity {
+		t.Error("gzip and identity clients share a cache key")
+	}
+	if keyFor("identity, gzip;q=0") != keyFor("identity") {
+		t.Error("refused encoding changed the cache key")
+	}
+	if keyFor(" GZIP, br;q=0.5 ") != keyFor("br, gzip") {
+		t.Error("equivalent Accept-Encoding headers have different cache keys")
+	}
+}
+
+func TestNormalizeAcceptEncoding(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", ""},
+		{"gzip", "gzip"},
+		{"gzip, deflate, br", "br,deflate,gzip"},
+		{"br;q=1.0, GZIP;q=0.8, *;q=0.1", "*,br,gzip"},
+		{"gzip;q=0, identity", "identity"},
+		{"gzip, gzip , ", "gzip"},
+	}
+	for _, tt := range tests {
+		if got := normalizeAcceptEncoding(tt.header); got != tt.want {
+			t.Errorf("normalizeAcceptEncoding(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestHandleCacheableResponse(t *testing.T) {
+	cache := services.NewResponseCacheService(store.NewMemoryStore())
+	ps := &ProxyServer{responseCache: cache}
+	group := &models.Group{ID: 1, EffectiveConfig: types.SystemSettings{
+		EnableResponseCache:           true,
+		ResponseCacheTTLSeconds:       60,
+		ResponseCacheMaxBytes:         16,
+		ResponseCacheIncludeClientKey: true,
+	}}
+
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		cached bool
+	}{
+		{"success", http.StatusOK, `{"id":"1"}`, true},
+		{"error response", http.StatusTooManyRequests, `{"error":"rate"}`, false},
+		{"over the size limit", http.StatusOK, `{"id":"` + strings.Repeat("x", 32) + `"}`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder, c := newCacheTestContext(nil)
+			c.Set("proxyKey", "sk-"+tt.name)
+			key := ps.buildResponseCacheKey(c, group, []byte(`{"model":"m"}`))
+
+			ps.handleCacheableResponse(c, upstreamResponse(tt.status, tt.body), group, key)
+			// 无论是否缓存，客户端都收到完整的响应
+			if recorder.Body.String() != tt.body {
+				t.Errorf("client body = %q, want %q", recorder.Body.String(), tt.body)
+			}
+
+			cached, ok := cache.Get(key)
+			if ok != tt.cached {
+				t.Fatalf("cached = %t, want %t", ok, tt.cached)
+			}
+			if !ok {
+				return
+			}
+
+			replay, replayContext := newCacheTestContext(nil)
+			ps.writeCachedResponse(replayContext, cached)
+			if replay.Code != tt.status || replay.Body.String() != tt.body || replay.Header().Get("X-Cache") != "HIT" {
+				t.Errorf("replayed %d %q, headers %v", replay.Code, replay.Body.String(), replay.Header())
+			}
+		})
+	}
+
+	// 启用按客户端密钥区分时，不同密钥不共享缓存
+	_, c := newCacheTestContext(nil)
+	c.Set("proxyKey", "sk-other")
+	if _, ok := cache.Get(ps.buildResponseCacheKey(c, group, []byte(`{"model":"m"}`))); ok {
+		t.Error("response cached for another client key")
+	}
+}
@@ -0,0 +1,243 @@
+package proxy
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"gpt-load/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// maxSSELineBytes bounds an incomplete SSE line kept between two reads.
+const maxSSELineBytes = 1 << 20
+
+// gzipStreamFactor bounds the decompressed size of a gzip encoded stream relative to the limit,
+// the SSE framing around the generated text is much larger than the text itself.
+const gzipStreamFactor = 16
+
+// responseCapture records the upstream response body for the request log.
+//
+// Bodies are kept up to the configured limit. Streaming responses are additionally reassembled
+// into the generated text while the chunks pass through, so the log shows the final completion
+// rather than the raw SSE events.
+type responseCapture struct {
+	limit    int
+	encoding string
+	stream   bool
+
+	raw bytes.Buffer
+
+	line   []byte // 尚未读到换行的 SSE 行
+	text   strings.Builder
+	parsed bool // 至少识别出一个流式分片
+}
+
+// streamChunk covers the streaming formats of the supported channels: OpenAI chat and
+// completions, the OpenAI Responses API, Anthropic messages and Gemini.
+type streamChunk struct {
+	Type    string `json:"type"`
+	Choices []struct {
+		Index int    `json:"index"`
+		Text  string `json:"text"`
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+	} `json:"choices"`
+	Delta      json.RawMessage `json:"delta"`
+	Candidates []struct {
+		Content struct {
+			Parts []struct {
+				Text string `json:"text"`
+			} `json:"parts"`
+		} `json:"content"`
+	} `json:"candidates"`
+}
+
+// newResponseCapture returns nil when response body logging is disabled for the group or the
+// response is binary, such as generated audio or images.
+func newResponseCapture(group *models.Group, header http.Header, stream bool) *responseCapture {
+	cfg := group.EffectiveConfig
+	if !cfg.EnableResponseBodyLogging {
+		return nil
+	}
+
+	contentType := strings.ToLower(header.Get("Content-Type"))
+	for _, prefix := range []string{"audio/", "image/", "video/", "application/octet-stream"} {
+		if strings.HasPrefix(contentType, prefix) {
+			return nil
+		}
+	}
+
+	return &responseCapture{
+		limit:    cfg.ResponseBodyLogMaxBytes,
+		encoding: strings.ToLower(strings.TrimSpace(header.Get("Content-Encoding"))),
+		stream:   stream,
+	}
+}
+
+// Write records a chunk of the body. It never fails, so a slow or broken log capture cannot
+// affect the response delivered to the client.
+func (rc *responseCapture) Write(p []byte) (int, error) {
+	if rc == nil {
+		return len(p), nil
+	}
+
+	if remaining := rc.limit - rc.raw.Len(); remaining > 0 {
+		rc.raw.Write(p[:min(len(p), remaining)])
+	}
+
+	// 压缩的流在结束后整体解压再拼接
+	if rc.stream && rc.encoding == "" {
+		rc.consumeSSE(p)
+	}
+	return len(p), nil
+}
+
+// tee returns a body that records everything read from it.
+func (rc *responseCapture) tee(body io.ReadCloser) io.ReadCloser {
+	if rc == nil {
+		return body
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.TeeReader(body, rc), body}
+}
+
+// Body returns the captured body decoded for the log. Call it once after the response completed.
+func (rc *responseCapture) Body() string {
+	if rc == nil {
+		return ""
+	}
+
+	body := rc.raw.Bytes()
+	if rc.encoding == "gzip" {
+		decodeLimit := rc.limit
+		if rc.stream {
+			decodeLimit *= gzipStreamFactor
+		}
+		body = decodeGzip(body, decodeLimit)
+		if rc.stream {
+			rc.consumeSSE(body)
+		}
+	}
+
+	if rc.stream {
+		if len(rc.line) > 0 {
+			rc.handleSSELine(rc.line)
+			rc.line = nil
+		}
+		// 无法识别的流式格式保留原始内容
+		if rc.parsed {
+			return sanitizeLogText(rc.text.String())
+		}
+	}
+	return sanitizeLogText(string(body))
+}
+
+func (rc *responseCapture) consumeSSE(p []byte) {
+	data := append(rc.line, p...)
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		rc.handleSSELine(data[:i])
+		data = data[i+1:]
+	}
+	if len(data) > maxSSELineBytes {
+		data = nil
+	}
+	rc.line = append(rc.line[:0], data...)
+}
+
+func (rc *responseCapture) handleSSELine(line []byte) {
+	line = bytes.TrimRight(line, "\r")
+	payload, ok := bytes.CutPrefix(line, []byte("data:"))
+	if !ok {
+		return
+	}
+	payload = bytes.TrimSpace(payload)
+	if len(payload) == 0 || string(payload) == "[DONE]" {
+		return
+	}
+
+	text, recognized := extractStreamText(payload)
+	if !recognized {
+		return
+	}
+	rc.parsed = true
+
+	if remaining := rc.limit - rc.text.Len(); remaining > 0 {
+		rc.text.WriteString(text[:min(len(text), remaining)])
+	}
+}
+
+// extractStreamText returns the generated text of a streaming chunk and whether the chunk
+// has one of the known formats.
+func extractStreamText(payload []byte) (string, bool) {
+	var chunk streamChunk
+	if err := json.Unmarshal(payload, &chunk); err != nil {
+		return "", false
+	}
+
+	switch {
+	case chunk.Type == "response.output_text.delta":
+		var delta string
+		_ = json.Unmarshal(chunk.Delta, &delta)
+		return delta, true
+	case chunk.Type == "content_block_delta":
+		var delta struct {
+			Text string `json:"text"`
+		}
+		_ = json.Unmarshal(chunk.Delta, &delta)
+		return delta.Text, true
+	case chunk.Type != "":
+		// Anthropic 和 Responses API 的其他事件不含生成文本
+		return "", true
+	case len(chunk.Choices) > 0:
+		var b strings.Builder
+		for _, choice := range chunk.Choices {
+			if choice.Index == 0 {
+				b.WriteString(choice.Delta.Content)
+				b.WriteString(choice.Text)
+			}
+		}
+		return b.String(), true
+	case len(chunk.Candidates) > 0:
+		var b strings.Builder
+		for _, part := range chunk.Candidates[0].Content.Parts {
+			b.WriteString(part.Text)
+		}
+		return b.String(), true
+	}
+	return "", false
+}
+
+// decodeGzip decompresses at most limit bytes. A body cut off by the capture limit still yields
+// the part that could be decompressed.
+func decodeGzip(body []byte, limit int) []byte {
+	reader, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		logrus.Debugf("Failed to decode gzip response body for logging: %v", err)
+		return nil
+	}
+	defer reader.Close()
+
+	decoded, err := io.ReadAll(io.LimitReader(reader, int64(limit)))
+	if err != nil && err != io.ErrUnexpectedEOF {
+		logrus.Debugf("Failed to decode gzip response body for logging: %v", err)
+	}
+	return decoded
+}
+
+// sanitizeLogText drops invalid UTF-8, e.g. a character cut in half by the limit, and NUL
+// bytes, which some databases reject in text columns.
+func sanitizeLogText(s string) string {
+	return strings.ReplaceAll(strings.ToValidUTF8(s, ""), "\x00", "")
+}
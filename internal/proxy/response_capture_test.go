@@ -0,0 +1,169 @@
+package proxy
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/types"
+)
+
+func captureGroup(limit int) *models.Group {
+	return &models.Group{EffectiveConfig: types.SystemSettings{EnableResponseBodyLogging: true, ResponseBodyLogMaxBytes: limit}}
+}
+
+func gzipBytes(data string) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	w.Write([]byte(data))
+	w.Close()
+	return buf.Bytes()
+}
+
+// writeInChunks writes the data in small pieces, so SSE lines are split across writes.
+func writeInChunks(rc *responseCapture, data []byte, size int) {
+	for len(data) > 0 {
+		n := min(size, len(data))
+		rc.Write(data[:n])
+		data = data[n:]
+	}
+}
+
+func TestResponseCaptureReassemblesStreams(t *testing.T) {
+	tests := []struct {
+		name   string
+		stream string
+		want   string
+	}{
+		{
+			name: "openai chat",
+			stream: "data: {\"choices\":[{\"index\":0,\"delta\":{\"role\":\"assistant\"}}]}\n\n" +
+				"data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"Hel\"}}]}\n\n" +
+				"data: {\"choices\":[{\"index\":1,\"delta\":{\"content\":\"other\"}}]}\n\n" +
+				"data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"lo 你好\"}}]}\r\n\r\n" +
+				"data: [DONE]\n\n",
+			want: "Hello 你好",
+		},
+		{
+			name:   "openai completions",
+			stream: "data: {\"choices\":[{\"index\":0,\"text\":\"a\"}]}\n\ndata: {\"choices\":[{\"index\":0,\"text\":\"b\"}]}\n\n",
+			want:   "ab",
+		},
+		{
+			name: "responses api",
+			stream: "event: response.created\ndata: {\"type\":\"response.created\",\"response\":{}}\n\n" +
+				"event: response.output_text.delta\ndata: {\"type\":\"response.output_text.delta\",\"delta\":\"Hi\"}\n\n" +
+				"data: {\"type\":\"response.output_text.delta\",\"delta\":\" there\"}\n\n",
+			want: "Hi there",
+		},
+		{
+			name: "anthropic",
+			stream: "event: message_start\ndata: {\"type\":\"message_start\",\"message\":{}}\n\n" +
+				"data: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"Bon\"}}\n\n" +
+				"data: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"jour\"}}\n\n" +
+				"data: {\"type\":\"message_stop\"}\n\n",
+			want: "Bonjour",
+		},
+		{
+			name: "gemini without trailing newline",
+			stream: "data: {\"candidates\":[{\"content\":{\"parts\":[{\"text\":\"x\"},{\"text\":\"y\"}]}}]}\r\n\r\n" +
+				"data: {\"candidates\":[{\"content\":{\"parts\":[{\"text\":\"z\"}]}}]}",
+			want: "xyz",
+		},
+		{
+			name:   "unknown format keeps the raw stream",
+			stream: "data: {\"foo\":1}\n\n",
+			want:   "data: {\"foo\":1}\n\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc := newResponseCapture(captureGroup(1024), http.Header{"Content-Type": {"text/event-stream"}}, true)
+			writeInChunks(rc, []byte(tt.stream), 7)
+			if got := rc.Body(); got != tt.want {
+				t.Errorf("Body() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResponseCaptureLimits(t *testing.T) {
+	rc := newResponseCapture(captureGroup(8), nil, false)
+	n, err := rc.Write([]byte("0123456789"))
+	if n != 10 || err != nil {
+		t.Errorf("Write() = %d, %v, want the full length", n, err)
+	}
+	if got := rc.Body(); got != "01234567" {
+		t.Errorf("Body() = %q", got)
+	}
+
+	// 截断的多字节字符和 NUL 不写入日志
+	rc = newResponseCapture(captureGroup(4), nil, false)
+	rc.Write([]byte("a\x00你好"))
+	if got := rc.Body(); got != "a" {
+		t.Errorf("Body() = %q, want the cut character dropped", got)
+	}
+
+	// 拼接后的文本同样受限制
+	rc = newResponseCapture(captureGroup(64), nil, true)
+	rc.Write([]byte(strings.Repeat("data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"abcd\"}}]}\n\n", 3)))
+	if got := rc.Body(); got != "abcdabcdabcd" {
+		t.Errorf("Body() = %q", got)
+	}
+	rc = newResponseCapture(captureGroup(6), nil, true)
+	rc.Write([]byte(strings.Repeat("data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"abcd\"}}]}\n\n", 3)))
+	if got := rc.Body(); got != "abcdab" {
+		t.Errorf("Body() of a stream over the limit = %q", got)
+	}
+}
+
+func TestResponseCaptureGzip(t *testing.T) {
+	header := http.Header{"Content-Encoding": {"gzip"}}
+
+	rc := newResponseCapture(captureGroup(1024), header, false)
+	rc.Write(gzipBytes(`{"id":"1"}`))
+	if got := rc.Body(); got != `{"id":"1"}` {
+		t.Errorf("Body() = %q", got)
+	}
+
+	stream := "data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"zip\"}}]}\n\ndata: [DONE]\n\n"
+	rc = newResponseCapture(captureGroup(1024), header, true)
+	writeInChunks(rc, gzipBytes(stream), 5)
+	if got := rc.Body(); got != "zip" {
+		t.Errorf("Body() of a gzip stream = %q", got)
+	}
+
+	rc = newResponseCapture(captureGroup(1024), header, false)
+	rc.Write([]byte("not gzip"))
+	if got := rc.Body(); got != "" {
+		t.Errorf("Body() of an invalid gzip body = %q", got)
+	}
+}
+
+func TestNewResponseCapture(t *testing.T) {
+	if rc := newResponseCapture(&models.Group{}, nil, false); rc != nil {
+		t.Error("capture created with response body logging disabled")
+	}
+	for _, contentType := range []string{"audio/mpeg", "image/png", "Video/MP4", "application/octet-stream"} {
+		if rc := newResponseCapture(captureGroup(10), http.Header{"Content-Type": {contentType}}, false); rc != nil {
+			t.Errorf("capture created for %s", contentType)
+		}
+	}
+
+	// 未启用时的 nil capture 可以直接使用
+	var rc *responseCapture
+	body := io.NopCloser(strings.NewReader("x"))
+	if n, _ := rc.Write([]byte("abc")); n != 3 || rc.Body() != "" || rc.tee(body) != body {
+		t.Error("nil capture is not a no-op")
+	}
+
+	rc = newResponseCapture(captureGroup(10), http.Header{"Content-Type": {"application/json"}}, false)
+	data, _ := io.ReadAll(rc.tee(io.NopCloser(strings.NewReader(`{"ok":1}`))))
+	if string(data) != `{"ok":1}` || rc.Body() != `{"ok":1}` {
+		t.Errorf("tee read %q, captured %q", data, rc.Body())
+	}
+}
@@ -8,7 +8,7 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-func (ps *ProxyServer) handleStreamingResponse(c *gin.Context, resp *http.Response) {
+func (ps *ProxyServer) handleStreamingResponse(c *gin.Context, resp *http.Response, capture *responseCapture) {
 	c.Header("Content-Type", "text/event-stream")
 	c.Header("Cache-Control", "no-cache")
 	c.Header("Connection", "keep-alive")
@@ -17,6 +17,7 @@ func (ps *ProxyServer) handleStreamingResponse(c *gin.Context, resp *http.Respon
 	flusher, ok := c.Writer.(http.Flusher)
 	if !ok {
 		logrus.Error("Streaming unsupported by the writer, falling back to normal response")
+		resp.Body = capture.tee(resp.Body)
 		ps.handleNormalResponse(c, resp)
 		return
 	}
@@ -30,6 +31,8 @@ func (ps *ProxyServer) handleStreamingResponse(c *gin.Context, resp *http.Respon
 				return
 			}
 			flusher.Flush()
+			// 先发送给客户端再记录，日志采集不影响流式响应的延迟
+			capture.Write(buf[:n])
 		}
 		if err == io.EOF {
 			break
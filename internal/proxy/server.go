@@ -11,18 +11,24 @@ import (
 	"net/http"
 	"time"
 
+	"gpt-load/internal/alert"
 	"gpt-load/internal/channel"
 	"gpt-load/internal/config"
 	"gpt-load/internal/encryption"
 	app_errors "gpt-load/internal/errors"
 	"gpt-load/internal/keypool"
+	"gpt-load/internal/metrics"
 	"gpt-load/internal/models"
+	"gpt-load/internal/redact"
 	"gpt-load/internal/response"
 	"gpt-load/internal/services"
+	"gpt-load/internal/tracing"
 	"gpt-load/internal/utils"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 )
 
 // ProxyServer represents the proxy server
@@ -32,7 +38,14 @@ type ProxyServer struct {
 	settingsManager   *config.SystemSettingsManager
 	channelFactory    *channel.Factory
 	requestLogService *services.RequestLogService
+	requestQueue      *services.RequestQueueService
+	responseCache     *services.ResponseCacheService
+	requestCoalescer  *services.RequestCoalescer
 	encryptionSvc     encryption.Service
+	tracer            *tracing.Tracer
+	metrics           *metrics.Metrics
+	alerts            *alert.Service
+	redactors         *redact.Cache
 }
 
 // NewProxyServer creates a new proxy server
@@ -42,7 +55,13 @@ func NewProxyServer(
 	settingsManager *config.SystemSettingsManager,
 	channelFactory *channel.Factory,
 	requestLogService *services.RequestLogService,
+	requestQueue *services.RequestQueueService,
+	responseCache *services.ResponseCacheService,
+	requestCoalescer *services.RequestCoalescer,
 	encryptionSvc encryption.Service,
+	tracer *tracing.Tracer,
+	appMetrics *metrics.Metrics,
+	alerts *alert.Service,
 ) (*ProxyServer, error) {
 	return &ProxyServer{
 		keyProvider:       keyProvider,
@@ -50,7 +69,14 @@ func NewProxyServer(
 		settingsManager:   settingsManager,
 		channelFactory:    channelFactory,
 		requestLogService: requestLogService,
+		requestQueue:      requestQueue,
+		responseCache:     responseCache,
+		requestCoalescer:  requestCoalescer,
 		encryptionSvc:     encryptionSvc,
+		tracer:            tracer,
+		metrics:           appMetrics,
+		alerts:            alerts,
+		redactors:         redact.NewCache(encryptionSvc.Hash),
 	}, nil
 }
 
@@ -59,6 +85,23 @@ func (ps *ProxyServer) HandleProxy(c *gin.Context) {
 	startTime := time.Now()
 	groupName := c.Param("group_name")
 
+	// 根 span，延续客户端传入的 W3C trace context
+	ctx, span := ps.tracer.StartServer(c.Request.Context(), c.Request.Header, "gpt-load.proxy",
+		attribute.String("gpt_load.group", groupName),
+		attribute.String("gpt_load.request_id", c.GetString("requestID")),
+		attribute.String("http.request.method", c.Request.Method),
+		attribute.String("url.path", c.Param("path")),
+	)
+	defer func() {
+		status := c.Writer.Status()
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if status >= 400 {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+		span.End()
+	}()
+	c.Request = c.Request.WithContext(ctx)
+
 	group, err := ps.groupManager.GetGroupByName(groupName)
 	if err != nil {
 		response.Error(c, app_errors.ParseDBError(err))
@@ -85,7 +128,43 @@ func (ps *ProxyServer) HandleProxy(c *gin.Context) {
 		return
 	}
 
+	model := channelHandler.ExtractModel(c, finalBodyBytes)
+	c.Set("model", model)
+	span.SetAttributes(attribute.String("gen_ai.request.model", model))
+
+	if len(group.BodyRuleList) > 0 {
+		ruleCtx := &utils.BodyRuleContext{
+			Model:     model,
+			Path:      c.Param("path"),
+			ClientKey: c.GetString("proxyKey"),
+		}
+		finalBodyBytes, err = utils.ApplyBodyRules(finalBodyBytes, group.BodyRuleList, ruleCtx)
+		if err != nil {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, fmt.Sprintf("Failed to apply body rules: %v", err)))
+			return
+		}
+	}
+
 	isStream := channelHandler.IsStreamRequest(c, bodyBytes)
+	span.SetAttributes(attribute.Bool("gpt_load.stream", isStream))
+
+	if !isStream && ps.isResponseCacheEnabled(c, group) {
+		cacheKey := ps.buildResponseCacheKey(c, group, finalBodyBytes)
+		if cached, ok := ps.responseCache.Get(cacheKey); ok {
+			ps.writeCachedResponse(c, cached)
+			cachedCapture := newResponseCapture(group, cached.Header, false)
+			cachedCapture.Write(cached.Body)
+			ps.logRequest(c, group, nil, startTime, cached.StatusCode, nil, false, "", channelHandler, finalBodyBytes, models.RequestTypeCached, 0, "", cachedCapture)
+			return
+		}
+		c.Set(responseCacheKeyContextKey, cacheKey)
+		c.Header("X-Cache", "MISS")
+	}
+
+	if !isStream && group.EffectiveConfig.EnableRequestCoalescing {
+		ps.handleCoalescedRequest(c, channelHandler, group, finalBodyBytes, startTime)
+		return
+	}
 
 	ps.executeRequestWithRetry(c, channelHandler, group, finalBodyBytes, isStream, startTime, 0)
 }
@@ -102,11 +181,22 @@ func (ps *ProxyServer) executeRequestWithRetry(
 ) {
 	cfg := group.EffectiveConfig
 
-	apiKey, err := ps.keyProvider.SelectKey(group.ID)
+	_, selectSpan := ps.tracer.Start(c.Request.Context(), "gpt-load.select_key",
+		attribute.Int("gpt_load.attempt", retryCount+1),
+	)
+	apiKey, err := ps.selectKey(c, group)
+	if err != nil {
+		selectSpan.RecordError(err)
+		selectSpan.SetStatus(codes.Error, err.Error())
+	} else {
+		selectSpan.SetAttributes(attribute.String("gpt_load.key_hash", ps.encryptionSvc.Hash(apiKey.KeyValue)))
+	}
+	selectSpan.End()
 	if err != nil {
 		logrus.Errorf("Failed to select a key for group %s on attempt %d: %v", group.Name, retryCount+1, err)
+		ps.metrics.IncKeySelectionFailure(group.Name)
 		response.Error(c, app_errors.NewAPIError(app_errors.ErrNoKeysAvailable, err.Error()))
-		ps.logRequest(c, group, nil, startTime, http.StatusServiceUnavailable, err, isStream, "", channelHandler, bodyBytes, models.RequestTypeFinal)
+		ps.logRequest(c, group, nil, startTime, http.StatusServiceUnavailable, err, isStream, "", channelHandler, bodyBytes, models.RequestTypeFinal, retryCount+1, "", nil)
 		return
 	}
 
@@ -116,13 +206,20 @@ func (ps *ProxyServer) executeRequestWithRetry(
 		return
 	}
 
+	// 每次上游尝试一个 span，重试之间互为兄弟节点
+	attemptCtx, attemptSpan := ps.tracer.StartClient(c.Request.Context(), "gpt-load.upstream_attempt",
+		attribute.Int("gpt_load.attempt", retryCount+1),
+		attribute.String("gpt_load.key_hash", ps.encryptionSvc.Hash(apiKey.KeyValue)),
+	)
+	defer attemptSpan.End()
+
 	var ctx context.Context
 	var cancel context.CancelFunc
 	if isStream {
-		ctx, cancel = context.WithCancel(c.Request.Context())
+		ctx, cancel = context.WithCancel(attemptCtx)
 	} else {
 		timeout := time.Duration(cfg.RequestTimeout) * time.Second
-		ctx, cancel = context.WithTimeout(c.Request.Context(), timeout)
+		ctx, cancel = context.WithTimeout(attemptCtx, timeout)
 	}
 	defer cancel()
 
@@ -140,12 +237,21 @@ func (ps *ProxyServer) executeRequestWithRetry(
 	req.Header.Del("Authorization")
 	req.Header.Del("X-Api-Key")
 	req.Header.Del("X-Goog-Api-Key")
+	req.Header.Del(cacheBypassHeader)
+	req.Header.Del("Traceparent")
+	req.Header.Del("Tracestate")
+
+	if requestID := c.GetString("requestID"); requestID != "" {
+		req.Header.Set("X-Request-ID", requestID)
+	}
 
 	channelHandler.ModifyRequest(req, apiKey, group)
+	attemptSpan.SetAttributes(attribute.String("server.address", req.URL.Host))
 
 	// Apply custom header rules
+	headerCtx := utils.NewHeaderVariableContextFromGin(c, group, apiKey)
+	headerCtx.UpstreamHost = req.URL.Host
 	if len(group.HeaderRuleList) > 0 {
-		headerCtx := utils.NewHeaderVariableContextFromGin(c, group, apiKey)
 		utils.ApplyHeaderRules(req, group.HeaderRuleList, headerCtx)
 	}
 
@@ -157,22 +263,26 @@ func (ps *ProxyServer) executeRequestWithRetry(
 		client = channelHandler.GetHTTPClient()
 	}
 
+	ps.tracer.Inject(ctx, req.Header)
+
 	resp, err := client.Do(req)
 	if resp != nil {
 		defer resp.Body.Close()
+		attemptSpan.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
 	}
 
 	// Unified error handling for retries. Exclude 404 from being a retryable error.
 	if err != nil || (resp != nil && resp.StatusCode >= 400 && resp.StatusCode != http.StatusNotFound) {
 		if err != nil && app_errors.IsIgnorableError(err) {
 			logrus.Debugf("Client-side ignorable error for key %s, aborting retries: %v", utils.MaskAPIKey(apiKey.KeyValue), err)
-			ps.logRequest(c, group, apiKey, startTime, 499, err, isStream, upstreamURL, channelHandler, bodyBytes, models.RequestTypeFinal)
+			ps.logRequest(c, group, apiKey, startTime, 499, err, isStream, upstreamURL, channelHandler, bodyBytes, models.RequestTypeFinal, retryCount+1, "", nil)
 			return
 		}
 
 		var statusCode int
 		var errorMessage string
 		var parsedError string
+		var errorCapture *responseCapture
 
 		if err != nil {
 			statusCode = 500
@@ -189,6 +299,9 @@ func (ps *ProxyServer) executeRequestWithRetry(
 			}
 
 			errorBody = handleGzipCompression(resp, errorBody)
+			// 错误响应体已解压，不再按 Content-Encoding 处理
+			errorCapture = newResponseCapture(group, nil, false)
+			errorCapture.Write(errorBody)
 			errorMessage = string(errorBody)
 			parsedError = app_errors.ParseUpstreamError(errorBody)
 			logrus.Debugf("Request failed with status %d (attempt %d/%d) for key %s. Parsed Error: %s", statusCode, retryCount+1, cfg.MaxRetries, utils.MaskAPIKey(apiKey.KeyValue), parsedError)
@@ -197,6 +310,9 @@ func (ps *ProxyServer) executeRequestWithRetry(
 		// 使用解析后的错误信息更新密钥状态
 		ps.keyProvider.UpdateStatus(apiKey, group, false, parsedError)
 
+		attemptSpan.SetStatus(codes.Error, utils.TruncateString(parsedError, 500))
+		attemptSpan.SetAttributes(attribute.String("gpt_load.retry_reason", utils.TruncateString(parsedError, 500)))
+
 		// 判断是否为最后一次尝试
 		isLastAttempt := retryCount >= cfg.MaxRetries
 		requestType := models.RequestTypeRetry
@@ -204,7 +320,7 @@ func (ps *ProxyServer) executeRequestWithRetry(
 			requestType = models.RequestTypeFinal
 		}
 
-		ps.logRequest(c, group, apiKey, startTime, statusCode, errors.New(parsedError), isStream, upstreamURL, channelHandler, bodyBytes, requestType)
+		ps.logRequest(c, group, apiKey, startTime, statusCode, errors.New(parsedError), isStream, upstreamURL, channelHandler, bodyBytes, requestType, retryCount+1, upstreamRequestID(resp), errorCapture)
 
 		// 如果是最后一次尝试，直接返回错误，不再递归
 		if isLastAttempt {
@@ -217,6 +333,7 @@ func (ps *ProxyServer) executeRequestWithRetry(
 			return
 		}
 
+		attemptSpan.End()
 		ps.executeRequestWithRetry(c, channelHandler, group, bodyBytes, isStream, startTime, retryCount+1)
 		return
 	}
@@ -224,20 +341,102 @@ func (ps *ProxyServer) executeRequestWithRetry(
 	// ps.keyProvider.UpdateStatus(apiKey, group, true) // 请求成功不再重置成功次数，减少IO消耗
 	logrus.Debugf("Request for group %s succeeded on attempt %d with key %s", group.Name, retryCount+1, utils.MaskAPIKey(apiKey.KeyValue))
 
-	for key, values := range resp.Header {
-		for _, value := range values {
-			c.Header(key, value)
-		}
+	// Capture the provider's request ID before response header rules may strip it
+	upstreamID := upstreamRequestID(resp)
+	capture := newResponseCapture(group, resp.Header, isStream)
+
+	// Apply response header rules before the headers reach the client or the cache
+	if len(group.ResponseHeaderRuleList) > 0 {
+		utils.ApplyResponseHeaderRules(resp.Header, group.ResponseHeaderRuleList, headerCtx)
 	}
+
+	copyResponseHeaders(c, resp.Header)
 	c.Status(resp.StatusCode)
 
 	if isStream {
-		ps.handleStreamingResponse(c, resp)
+		_, streamSpan := ps.tracer.Start(attemptCtx, "gpt-load.stream_response")
+		ps.handleStreamingResponse(c, resp, capture)
+		streamSpan.End()
 	} else {
-		ps.handleNormalResponse(c, resp)
+		resp.Body = capture.tee(resp.Body)
+		if cacheKey := c.GetString(responseCacheKeyContextKey); cacheKey != "" {
+			ps.handleCacheableResponse(c, resp, group, cacheKey)
+		} else {
+			ps.handleNormalResponse(c, resp)
+		}
+	}
+
+	ps.logRequest(c, group, apiKey, startTime, resp.StatusCode, nil, isStream, upstreamURL, channelHandler, bodyBytes, models.RequestTypeFinal, retryCount+1, upstreamID, capture)
+}
+
+// selectKey selects a key for the group. When no key is available and queueing is enabled
+// for the group, the request waits in the group's queue until a key becomes available.
+func (ps *ProxyServer) selectKey(c *gin.Context, group *models.Group) (*models.APIKey, error) {
+	apiKey, err := ps.keyProvider.SelectKey(group.ID)
+	cfg := group.EffectiveConfig
+	if err == nil || !errors.Is(err, app_errors.ErrNoActiveKeys) || cfg.QueueMaxDepth <= 0 {
+		return apiKey, err
+	}
+
+	maxWait := time.Duration(cfg.QueueMaxWaitSeconds) * time.Second
+	queueErr := ps.requestQueue.Wait(c.Request.Context(), group.ID, cfg.QueueMaxDepth, maxWait, func() (bool, error) {
+		apiKey, err = ps.keyProvider.SelectKey(group.ID)
+		if err == nil {
+			return true, nil
+		}
+		if errors.Is(err, app_errors.ErrNoActiveKeys) {
+			return false, nil
+		}
+		return false, err
+	})
+	if queueErr != nil {
+		if errors.Is(queueErr, services.ErrQueueFull) || errors.Is(queueErr, services.ErrQueueTimeout) {
+			return nil, fmt.Errorf("%w: %v", app_errors.ErrNoActiveKeys, queueErr)
+		}
+		return nil, queueErr
+	}
+
+	return apiKey, nil
+}
+
+// upstreamRequestID extracts the provider's own request ID from the upstream response headers.
+func upstreamRequestID(resp *http.Response) string {
+	if resp == nil {
+		return ""
+	}
+	for _, header := range []string{"X-Request-Id", "Request-Id", "X-Goog-Request-Id"} {
+		if value := resp.Header.Get(header); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// copyResponseHeaders writes the upstream headers to the client, keeping gpt-load's own X-Request-ID.
+func copyResponseHeaders(c *gin.Context, header http.Header) {
+	for key, values := range header {
+		for _, value := range values {
+			c.Header(key, value)
+		}
 	}
+	// 上游的 X-Request-Id 会覆盖 RequestID 中间件设置的值，上游的 ID 已单独记录在日志中
+	if requestID := c.GetString("requestID"); requestID != "" {
+		c.Header("X-Request-ID", requestID)
+	}
+}
 
-	ps.logRequest(c, group, apiKey, startTime, resp.StatusCode, nil, isStream, upstreamURL, channelHandler, bodyBytes, models.RequestTypeFinal)
+// logRedactor returns the redactor of the group's log redaction settings, nil when disabled.
+func (ps *ProxyServer) logRedactor(group *models.Group) *redact.Redactor {
+	cfg := group.EffectiveConfig
+	if !cfg.LogRedactionEnabled {
+		return nil
+	}
+	return ps.redactors.Get(redact.Options{
+		Detectors: utils.ParseArray(cfg.LogRedactionDetectors, nil),
+		Mode:      cfg.LogRedactionMode,
+		Fields:    utils.ParseArray(cfg.LogRedactionFields, nil),
+		Rules:     group.RedactionRuleList,
+	})
 }
 
 // logRequest is a helper function to create and record a request log.
@@ -253,33 +452,53 @@ func (ps *ProxyServer) logRequest(
 	channelHandler channel.ChannelProxy,
 	bodyBytes []byte,
 	requestType string,
+	attempt int,
+	upstreamRequestID string,
+	capture *responseCapture,
 ) {
+	if requestType == models.RequestTypeRetry {
+		ps.metrics.IncRetry(group.Name)
+	} else {
+		ps.metrics.ObserveRequest(group.Name, c.GetString("model"), statusCode, isStream, time.Since(startTime))
+		ps.alerts.RecordRequestResult(group, finalError == nil && statusCode < 400)
+	}
+
 	if ps.requestLogService == nil {
 		return
 	}
 
 	var requestBodyToLog, userAgent string
 
+	redactor := ps.logRedactor(group)
+
 	if group.EffectiveConfig.EnableRequestBodyLogging {
-		requestBodyToLog = utils.TruncateString(string(bodyBytes), 65000)
+		// 先脱敏再截断，避免截断后的半个敏感值逃过检测
+		if redactor != nil {
+			requestBodyToLog = utils.TruncateString(redactor.Body(bodyBytes), 65000)
+		} else {
+			requestBodyToLog = utils.TruncateString(string(bodyBytes), 65000)
+		}
 		userAgent = c.Request.UserAgent()
 	}
 
 	duration := time.Since(startTime).Milliseconds()
 
 	logEntry := &models.RequestLog{
-		GroupID:      group.ID,
-		GroupName:    group.Name,
-		IsSuccess:    finalError == nil && statusCode < 400,
-		SourceIP:     c.ClientIP(),
-		StatusCode:   statusCode,
-		RequestPath:  utils.TruncateString(c.Request.URL.String(), 500),
-		Duration:     duration,
-		UserAgent:    userAgent,
-		RequestType:  requestType,
-		IsStream:     isStream,
-		UpstreamAddr: utils.TruncateString(upstreamAddr, 500),
-		RequestBody:  requestBodyToLog,
+		GroupID:           group.ID,
+		GroupName:         group.Name,
+		IsSuccess:         finalError == nil && statusCode < 400,
+		SourceIP:          c.ClientIP(),
+		StatusCode:        statusCode,
+		RequestPath:       utils.TruncateString(c.Request.URL.String(), 500),
+		Duration:          duration,
+		UserAgent:         userAgent,
+		RequestType:       requestType,
+		IsStream:          isStream,
+		UpstreamAddr:      utils.TruncateString(upstreamAddr, 500),
+		RequestBody:       requestBodyToLog,
+		RequestID:         c.GetString("requestID"),
+		Attempt:           attempt,
+		UpstreamRequestID: utils.TruncateString(upstreamRequestID, 255),
 	}
 
 	if channelHandler != nil && bodyBytes != nil {
@@ -299,8 +518,20 @@ func (ps *ProxyServer) logRequest(
 		logEntry.KeyHash = ps.encryptionSvc.Hash(apiKey.KeyValue)
 	}
 
+	if capture != nil {
+		// 响应内容与请求体一样经过脱敏后再截断
+		responseBody := capture.Body()
+		if redactor != nil {
+			responseBody = redactor.Body([]byte(responseBody))
+		}
+		logEntry.ResponseBody = utils.TruncateString(responseBody, 65000)
+	}
+
 	if finalError != nil {
 		logEntry.ErrorMessage = finalError.Error()
+		if redactor != nil {
+			logEntry.ErrorMessage = redactor.Text(logEntry.ErrorMessage)
+		}
 	}
 
 	if err := ps.requestLogService.Record(logEntry); err != nil {
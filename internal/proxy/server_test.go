@@ -0,0 +1,52 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestUpstreamRequestID(t *testing.T) {
+	tests := []struct {
+		name   string
+		header http.Header
+		want   string
+	}{
+		{"openai", http.Header{"X-Request-Id": {"req_abc"}}, "req_abc"},
+		{"anthropic", http.Header{"Request-Id": {"req_011"}}, "req_011"},
+		{"gemini", http.Header{"X-Goog-Request-Id": {"g-1"}}, "g-1"},
+		{"first match wins", http.Header{"Request-Id": {"second"}, "X-Request-Id": {"first"}}, "first"},
+		{"none", http.Header{"Content-Type": {"application/json"}}, ""},
+	}
+	for _, tt := range tests {
+		if got := upstreamRequestID(&http.Response{Header: tt.header}); got != tt.want {
+			t.Errorf("%s: upstreamRequestID() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+	if got := upstreamRequestID(nil); got != "" {
+		t.Errorf("upstreamRequestID(nil) = %q", got)
+	}
+}
+
+func TestCopyResponseHeadersKeepsRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Set("requestID", "req-client")
+	c.Header("X-Request-ID", "req-client")
+
+	copyResponseHeaders(c, http.Header{
+		"X-Request-Id": {"req_upstream"},
+		"Content-Type": {"application/json"},
+	})
+	c.Status(http.StatusOK)
+
+	if got := recorder.Header().Get("X-Request-ID"); got != "req-client" {
+		t.Errorf("X-Request-ID = %q, want the client's request ID", got)
+	}
+	if got := recorder.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q, want the upstream header", got)
+	}
+}
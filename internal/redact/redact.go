@@ -0,0 +1,417 @@
+// Package redact removes secrets and personal data from request bodies and error messages
+// before they are written to the request logs.
+package redact
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Redaction modes
+const (
+	ModeMask = "mask" // 替换为 [REDACTED:<类型>]
+	ModeHash = "hash" // 替换为 [<类型>:<哈希>]，相同的值得到相同的哈希，便于关联
+)
+
+// Built-in detectors
+const (
+	DetectorAPIKey = "api_key"
+	DetectorEmail  = "email"
+	DetectorPhone  = "phone"
+	DetectorCard   = "card"
+)
+
+// fieldDetector names the values masked by JSON path.
+const fieldDetector = "field"
+
+// hashLength is the number of hash characters kept in hash mode.
+const hashLength = 12
+
+var ruleNamePattern = regexp.MustCompile(`^[A-Za-z0-9_\-]+$`)
+
+type detector struct {
+	name    string
+	pattern string
+	// valid filters out false positives the pattern cannot express, nil accepts every match
+	valid func(match string) bool
+}
+
+// builtinDetectors are tried in this order, earlier detectors win when matches overlap.
+// When a detector rejects its match, the later detectors are tried at the same position.
+var builtinDetectors = []detector{
+	{
+		name: DetectorAPIKey,
+		pattern: `(?:sk-(?:proj-|ant-)?[A-Za-z0-9_\-]{20,}|AIza[0-9A-Za-z_\-]{35}|AKIA[0-9A-Z]{16}|gh[pousr]_[A-Za-z0-9]{36,}` +
+			`|github_pat_[A-Za-z0-9_]{40,}|xox[abprs]-[A-Za-z0-9\-]{10,}|gsk_[A-Za-z0-9]{20,}|xai-[A-Za-z0-9]{20,}` +
+			`|Bearer\s+[A-Za-z0-9._~+/\-]{20,}=*)`,
+	},
+	{
+		name:    DetectorEmail,
+		pattern: `[A-Za-z0-9._%+\-]+@[A-Za-z0-9\-]+(?:\.[A-Za-z0-9\-]+)*\.[A-Za-z]{2,}`,
+	},
+	{
+		name:    DetectorCard,
+		pattern: `\b\d(?:[ \-]?\d){12,18}\b`,
+		valid:   luhnValid,
+	},
+	{
+		// 国际格式或带分隔符的号码，以及 11 位中国大陆手机号；不带分隔符的长数字（如时间戳）不视为电话
+		name:    DetectorPhone,
+		pattern: `(?:\+\d{1,3}[ .\-]?)?(?:\(\d{1,4}\)[ .\-]?)?\b\d{2,5}(?:[ .\-]\d{2,5}){1,4}\b|\b1[3-9]\d{9}\b`,
+		valid: func(match string) bool {
+			digits := countDigits(match)
+			return digits >= 10 && digits <= 15
+		},
+	},
+}
+
+// Options configure a Redactor.
+type Options struct {
+	Detectors []string               // built-in detectors to enable
+	Mode      string                 // mask or hash
+	Fields    []string               // JSON paths whose values are replaced entirely, "*" matches any key or index
+	Rules     []models.RedactionRule // custom patterns, tried before the built-in detectors
+}
+
+// Redactor applies one redaction configuration. It is safe for concurrent use.
+type Redactor struct {
+	pattern   *regexp.Regexp
+	detectors []detector
+	groups    []int // 每个检测器在合并后正则中的分组序号
+	// fallbacks[i] 合并了第 i 个及之后的检测器，仅为带 valid 的检测器之后的位置编译，
+	// 用于在前面的检测器否决匹配时继续尝试后面的检测器
+	fallbacks map[int]*matcher
+	fields    [][]string
+	hash      bool
+	hasher    func(string) string
+}
+
+// matcher is the merged pattern of the detectors starting at first.
+type matcher struct {
+	pattern *regexp.Regexp
+	first   int
+	groups  []int
+}
+
+// New compiles the options into a Redactor. Invalid rules, paths and unknown detectors are
+// skipped with a warning, they are rejected when the settings are saved.
+func New(opts Options, hasher func(string) string) *Redactor {
+	r := &Redactor{hash: opts.Mode == ModeHash, hasher: hasher}
+
+	for _, rule := range opts.Rules {
+		if err := ValidateRule(rule); err != nil {
+			logrus.Warnf("Skipping redaction rule: %v", err)
+			continue
+		}
+		r.detectors = append(r.detectors, detector{name: rule.Name, pattern: rule.Pattern})
+	}
+	// 内置检测器始终按固定顺序合并，与配置中的书写顺序无关
+	for _, d := range builtinDetectors {
+		if slices.Contains(opts.Detectors, d.name) {
+			r.detectors = append(r.detectors, d)
+		}
+	}
+	if err := ValidateDetectors(opts.Detectors); err != nil {
+		logrus.Warnf("Skipping redaction detectors: %v", err)
+	}
+
+	for _, field := range opts.Fields {
+		segments, err := utils.ParseJSONPath(field)
+		if err != nil {
+			logrus.Warnf("Skipping redaction field: %v", err)
+			continue
+		}
+		r.fields = append(r.fields, segments)
+	}
+
+	// 所有检测器合并为一个正则，一次扫描完成替换，已替换的内容不会被其他检测器再次匹配
+	if len(r.detectors) > 0 {
+		main, err := r.compile(0)
+		if err != nil {
+			logrus.Errorf("Failed to compile redaction patterns, only fields are redacted: %v", err)
+			return r
+		}
+		r.pattern, r.groups = main.pattern, main.groups
+
+		r.fallbacks = make(map[int]*matcher)
+		for i, d := range r.detectors[:len(r.detectors)-1] {
+			if d.valid == nil {
+				continue
+			}
+			// 子集中的模式已随完整正则编译成功
+			r.fallbacks[i+1], _ = r.compile(i + 1)
+		}
+	}
+	return r
+}
+
+// compile merges the patterns of the detectors starting at first into one regular expression.
+func (r *Redactor) compile(first int) (*matcher, error) {
+	alternatives := make([]string, 0, len(r.detectors)-first)
+	for i := first; i < len(r.detectors); i++ {
+		alternatives = append(alternatives, fmt.Sprintf("(?P<redact%d>%s)", i, r.detectors[i].pattern))
+	}
+	pattern, err := regexp.Compile(strings.Join(alternatives, "|"))
+	if err != nil {
+		return nil, err
+	}
+	m := &matcher{pattern: pattern, first: first, groups: make([]int, len(alternatives))}
+	for i := range m.groups {
+		m.groups[i] = pattern.SubexpIndex(fmt.Sprintf("redact%d", first+i))
+	}
+	return m, nil
+}
+
+// ValidateRule checks that a custom redaction rule has a usable name and a valid pattern.
+func ValidateRule(rule models.RedactionRule) error {
+	if !ruleNamePattern.MatchString(rule.Name) {
+		return fmt.Errorf("rule name '%s' may only contain letters, digits, '_' and '-'", rule.Name)
+	}
+	if rule.Pattern == "" {
+		return fmt.Errorf("rule '%s' has no pattern", rule.Name)
+	}
+	re, err := regexp.Compile(rule.Pattern)
+	if err != nil {
+		return fmt.Errorf("rule '%s' has an invalid pattern: %w", rule.Name, err)
+	}
+	if re.MatchString("") {
+		return fmt.Errorf("rule '%s' matches empty text", rule.Name)
+	}
+	return nil
+}
+
+// ValidateDetectors checks the names of built-in detectors.
+func ValidateDetectors(names []string) error {
+	for _, name := range names {
+		known := false
+		for _, d := range builtinDetectors {
+			known = known || d.name == name
+		}
+		if !known {
+			return fmt.Errorf("unknown redaction detector '%s', expected api_key, email, phone or card", name)
+		}
+	}
+	return nil
+}
+
+// Body redacts a request or response body. The configured fields of JSON bodies are replaced
+// first, then the detectors run over the whole text.
+func (r *Redactor) Body(body []byte) string {
+	text := string(body)
+	if len(r.fields) > 0 {
+		if masked, ok := r.maskFields(body); ok {
+			text = masked
+		}
+	}
+	return r.Text(text)
+}
+
+// Text redacts the detector matches in free text such as error messages.
+func (r *Redactor) Text(text string) string {
+	if r.pattern == nil || text == "" {
+		return text
+	}
+
+	matches := r.pattern.FindAllStringSubmatchIndex(text, -1)
+	if len(matches) == 0 {
+		return text
+	}
+
+	var b strings.Builder
+	b.Grow(len(text))
+	last := 0
+	main := &matcher{pattern: r.pattern, groups: r.groups}
+	fallbackMatches := make(map[int][][]int)
+	for _, m := range matches {
+		// 后备检测器的匹配可能比被否决的匹配更长，跳过已被替换的部分
+		if m[0] == m[1] || m[0] < last {
+			continue
+		}
+		name, end, ok := r.resolve(text, main, m, fallbackMatches)
+		if !ok {
+			continue
+		}
+		b.WriteString(text[last:m[0]])
+		b.WriteString(r.replacement(name, text[m[0]:end]))
+		last = end
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// resolve finds the detector that accepts the match m of the matcher. When a detector rejects
+// its match, the later detectors are tried at the same position; their matches over the whole
+// text are computed once per call of Text and kept in found.
+func (r *Redactor) resolve(text string, mt *matcher, m []int, found map[int][][]int) (name string, end int, ok bool) {
+	for i, group := range mt.groups {
+		if m[2*group] < 0 {
+			continue
+		}
+		d := r.detectors[mt.first+i]
+		if d.valid == nil || d.valid(text[m[0]:m[1]]) {
+			return d.name, m[1], true
+		}
+
+		next := r.fallbacks[mt.first+i+1]
+		if next == nil {
+			return "", 0, false
+		}
+		all, cached := found[next.first]
+		if !cached {
+			all = next.pattern.FindAllStringSubmatchIndex(text, -1)
+			found[next.first] = all
+		}
+		idx, exists := slices.BinarySearchFunc(all, m[0], func(match []int, start int) int {
+			return cmp.Compare(match[0], start)
+		})
+		if !exists || all[idx][0] == all[idx][1] {
+			return "", 0, false
+		}
+		return r.resolve(text, next, all[idx], found)
+	}
+	return "", 0, false
+}
+
+func (r *Redactor) replacement(name, value string) string {
+	if !r.hash || r.hasher == nil {
+		return "[REDACTED:" + name + "]"
+	}
+	hash := r.hasher(value)
+	if len(hash) > hashLength {
+		hash = hash[:hashLength]
+	}
+	return "[" + name + ":" + hash + "]"
+}
+
+// maskFields replaces the values at the configured JSON paths. It reports false when the
+// body is not JSON or no path matched, so the original formatting is kept.
+func (r *Redactor) maskFields(body []byte) (string, bool) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var data any
+	if err := decoder.Decode(&data); err != nil {
+		return "", false
+	}
+
+	changed := false
+	for _, segments := range r.fields {
+		var masked bool
+		data, masked = r.maskPath(data, segments)
+		changed = changed || masked
+	}
+	if !changed {
+		return "", false
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(data); err != nil {
+		return "", false
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), true
+}
+
+func (r *Redactor) maskPath(node any, segments []string) (any, bool) {
+	if len(segments) == 0 {
+		value, ok := node.(string)
+		if !ok {
+			encoded, _ := json.Marshal(node)
+			value = string(encoded)
+		}
+		return r.replacement(fieldDetector, value), true
+	}
+
+	segment, rest := segments[0], segments[1:]
+	changed := false
+	switch typed := node.(type) {
+	case map[string]any:
+		for key, child := range typed {
+			if segment != "*" && segment != key {
+				continue
+			}
+			if masked, ok := r.maskPath(child, rest); ok {
+				typed[key] = masked
+				changed = true
+			}
+		}
+	case []any:
+		for i, child := range typed {
+			if segment != "*" && segment != fmt.Sprint(i) {
+				continue
+			}
+			if masked, ok := r.maskPath(child, rest); ok {
+				typed[i] = masked
+				changed = true
+			}
+		}
+	}
+	return node, changed
+}
+
+// Cache reuses the compiled Redactor of identical options, compiling the patterns for
+// every logged request would be expensive.
+type Cache struct {
+	hasher    func(string) string
+	redactors sync.Map // options key -> *Redactor
+}
+
+// NewCache creates a cache whose redactors hash values with hasher in hash mode.
+func NewCache(hasher func(string) string) *Cache {
+	return &Cache{hasher: hasher}
+}
+
+// Get returns the Redactor of the options, compiling it on first use.
+func (c *Cache) Get(opts Options) *Redactor {
+	key, err := json.Marshal(opts)
+	if err != nil {
+		return New(opts, c.hasher)
+	}
+	if cached, ok := c.redactors.Load(string(key)); ok {
+		return cached.(*Redactor)
+	}
+	redactor, _ := c.redactors.LoadOrStore(string(key), New(opts, c.hasher))
+	return redactor.(*Redactor)
+}
+
+func countDigits(s string) int {
+	count := 0
+	for _, ch := range s {
+		if ch >= '0' && ch <= '9' {
+			count++
+		}
+	}
+	return count
+}
+
+// luhnValid reports whether the digits of s pass the Luhn checksum of card numbers.
+func luhnValid(s string) bool {
+	sum, double := 0, false
+	for i := len(s) - 1; i >= 0; i-- {
+		ch := s[i]
+		if ch < '0' || ch > '9' {
+			continue
+		}
+		digit := int(ch - '0')
+		if double {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		double = !double
+	}
+	return sum%10 == 0
+}
@@ -3,6 +3,7 @@ package router
 import (
 	"embed"
 	"gpt-load/internal/handler"
+	"gpt-load/internal/metrics"
 	"gpt-load/internal/middleware"
 	"gpt-load/internal/proxy"
 	"gpt-load/internal/services"
@@ -16,6 +17,7 @@ import (
 	"github.com/gin-contrib/static"
 
 	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
 )
 
 type embedFileSystem struct {
@@ -42,6 +44,11 @@ func NewRouter(
 	proxyServer *proxy.ProxyServer,
 	configManager types.ConfigManager,
 	groupManager *services.GroupManager,
+	rateLimitService *services.RateLimitService,
+	adminUserService *services.AdminUserService,
+	adminSessionService *services.AdminSessionService,
+	apiTokenService *services.APITokenService,
+	appMetrics *metrics.Metrics,
 	buildFS embed.FS,
 	indexPage []byte,
 ) *gin.Engine {
@@ -49,6 +56,12 @@ func NewRouter(
 
 	router := gin.New()
 
+	// 只信任配置的反向代理转发的客户端 IP，防止伪造 X-Forwarded-For 绕过登录锁定和 IP 限制
+	if err := router.SetTrustedProxies(configManager.GetEffectiveServerConfig().TrustedProxies); err != nil {
+		logrus.WithError(err).Error("Invalid trusted proxies, not trusting any proxy")
+		_ = router.SetTrustedProxies(nil)
+	}
+
 	// 注册全局中间件
 	router.Use(middleware.Recovery())
 	router.Use(middleware.ErrorHandler())
@@ -63,17 +76,24 @@ func NewRouter(
 	})
 
 	// 注册路由
-	registerSystemRoutes(router, serverHandler)
-	registerAPIRoutes(router, serverHandler, configManager)
-	registerProxyRoutes(router, proxyServer, groupManager)
+	registerSystemRoutes(router, serverHandler, configManager, adminSessionService, appMetrics)
+	registerAPIRoutes(router, serverHandler, configManager, adminUserService, adminSessionService, apiTokenService)
+	registerProxyRoutes(router, proxyServer, groupManager, rateLimitService)
 	registerFrontendRoutes(router, buildFS, indexPage)
 
 	return router
 }
 
 // registerSystemRoutes 注册系统级路由
-func registerSystemRoutes(router *gin.Engine, serverHandler *handler.Server) {
+func registerSystemRoutes(
+	router *gin.Engine,
+	serverHandler *handler.Server,
+	configManager types.ConfigManager,
+	adminSessionService *services.AdminSessionService,
+	appMetrics *metrics.Metrics,
+) {
 	router.GET("/health", serverHandler.Health)
+	router.GET("/metrics", middleware.MetricsAuth(configManager.GetAuthConfig(), adminSessionService), gin.WrapH(appMetrics.Handler()))
 }
 
 // registerAPIRoutes 注册API路由
@@ -81,6 +101,9 @@ func registerAPIRoutes(
 	router *gin.Engine,
 	serverHandler *handler.Server,
 	configManager types.ConfigManager,
+	adminUserService *services.AdminUserService,
+	adminSessionService *services.AdminSessionService,
+	apiTokenService *services.APITokenService,
 ) {
 	api := router.Group("/api")
 	authConfig := configManager.GetAuthConfig()
@@ -90,53 +113,62 @@ func registerAPIRoutes(
 
 	// 认证
 	protectedAPI := api.Group("")
-	protectedAPI.Use(middleware.Auth(authConfig))
+	protectedAPI.Use(middleware.Auth(authConfig, adminUserService, adminSessionService, apiTokenService))
 	registerProtectedAPIRoutes(protectedAPI, serverHandler)
 }
 
 // registerPublicAPIRoutes 公开API路由
 func registerPublicAPIRoutes(api *gin.RouterGroup, serverHandler *handler.Server) {
 	api.POST("/auth/login", serverHandler.Login)
+	api.GET("/auth/oidc/config", serverHandler.GetOIDCConfig)
+	api.GET("/auth/oidc/login", serverHandler.OIDCLogin)
+	api.GET("/auth/oidc/callback", serverHandler.OIDCCallback)
 }
 
-// registerProtectedAPIRoutes 认证API路由
+// registerProtectedAPIRoutes 认证API路由，每个路由按角色权限校验
 func registerProtectedAPIRoutes(api *gin.RouterGroup, serverHandler *handler.Server) {
+	can := middleware.RequirePermission
+
 	api.GET("/channel-types", serverHandler.CommonHandler.GetChannelTypes)
+	api.GET("/auth/me", serverHandler.GetCurrentAdmin)
+	api.POST("/auth/refresh", serverHandler.RefreshSession)
+	api.POST("/auth/logout", serverHandler.Logout)
 
 	groups := api.Group("/groups")
+	groups.Use(middleware.RequireGroupAccess())
 	{
-		groups.POST("", serverHandler.CreateGroup)
-		groups.GET("", serverHandler.ListGroups)
-		groups.GET("/list", serverHandler.List)
-		groups.GET("/config-options", serverHandler.GetGroupConfigOptions)
-		groups.PUT("/:id", serverHandler.UpdateGroup)
-		groups.DELETE("/:id", serverHandler.DeleteGroup)
-		groups.GET("/:id/stats", serverHandler.GetGroupStats)
-		groups.POST("/:id/copy", serverHandler.CopyGroup)
+		groups.POST("", can(services.PermGroupsManage), serverHandler.CreateGroup)
+		groups.GET("", can(services.PermGroupsRead), serverHandler.ListGroups)
+		groups.GET("/list", can(services.PermGroupsRead), serverHandler.List)
+		groups.GET("/config-options", can(services.PermGroupsRead), serverHandler.GetGroupConfigOptions)
+		groups.PUT("/:id", can(services.PermGroupsWrite), serverHandler.UpdateGroup)
+		groups.DELETE("/:id", can(services.PermGroupsManage), serverHandler.DeleteGroup)
+		groups.GET("/:id/stats", can(services.PermGroupsRead), serverHandler.GetGroupStats)
+		groups.POST("/:id/copy", can(services.PermGroupsManage), serverHandler.CopyGroup)
 	}
 
-	// Key Management Routes
+	// Key Management Routes，分组范围在 handler 中按 group_id 校验
 	keys := api.Group("/keys")
 	{
-		keys.GET("", serverHandler.ListKeysInGroup)
-		keys.GET("/export", serverHandler.ExportKeys)
-		keys.POST("/add-multiple", serverHandler.AddMultipleKeys)
-		keys.POST("/add-async", serverHandler.AddMultipleKeysAsync)
-		keys.POST("/delete-multiple", serverHandler.DeleteMultipleKeys)
-		keys.POST("/delete-async", serverHandler.DeleteMultipleKeysAsync)
-		keys.POST("/restore-multiple", serverHandler.RestoreMultipleKeys)
-		keys.POST("/restore-all-invalid", serverHandler.RestoreAllInvalidKeys)
-		keys.POST("/clear-all-invalid", serverHandler.ClearAllInvalidKeys)
-		keys.POST("/clear-all", serverHandler.ClearAllKeys)
-		keys.POST("/validate-group", serverHandler.ValidateGroupKeys)
-		keys.POST("/test-multiple", serverHandler.TestMultipleKeys)
+		keys.GET("", can(services.PermKeysRead), serverHandler.ListKeysInGroup)
+		keys.GET("/export", can(services.PermKeysRead), serverHandler.ExportKeys)
+		keys.POST("/add-multiple", can(services.PermKeysWrite), serverHandler.AddMultipleKeys)
+		keys.POST("/add-async", can(services.PermKeysWrite), serverHandler.AddMultipleKeysAsync)
+		keys.POST("/delete-multiple", can(services.PermKeysWrite), serverHandler.DeleteMultipleKeys)
+		keys.POST("/delete-async", can(services.PermKeysWrite), serverHandler.DeleteMultipleKeysAsync)
+		keys.POST("/restore-multiple", can(services.PermKeysWrite), serverHandler.RestoreMultipleKeys)
+		keys.POST("/restore-all-invalid", can(services.PermKeysWrite), serverHandler.RestoreAllInvalidKeys)
+		keys.POST("/clear-all-invalid", can(services.PermKeysWrite), serverHandler.ClearAllInvalidKeys)
+		keys.POST("/clear-all", can(services.PermKeysWrite), serverHandler.ClearAllKeys)
+		keys.POST("/validate-group", can(services.PermKeysWrite), serverHandler.ValidateGroupKeys)
+		keys.POST("/test-multiple", can(services.PermKeysWrite), serverHandler.TestMultipleKeys)
 	}
 
-	// Tasks
-	api.GET("/tasks/status", serverHandler.GetTaskStatus)
+	// Tasks，分组范围在 handler 中按任务所属分组过滤
+	api.GET("/tasks/status", can(services.PermKeysRead), serverHandler.GetTaskStatus)
 
 	// 仪表板和日志
-	dashboard := api.Group("/dashboard")
+	dashboard := api.Group("/dashboard", can(services.PermDashboardRead))
 	{
 		dashboard.GET("/stats", serverHandler.Stats)
 		dashboard.GET("/chart", serverHandler.Chart)
@@ -144,17 +176,58 @@ func registerProtectedAPIRoutes(api *gin.RouterGroup, serverHandler *handler.Ser
 	}
 
 	// 日志
-	logs := api.Group("/logs")
+	logs := api.Group("/logs", can(services.PermLogsRead))
 	{
 		logs.GET("", serverHandler.GetLogs)
-		logs.GET("/export", serverHandler.ExportLogs)
+		logs.GET("/export", can(services.PermKeysRead), serverHandler.ExportLogs)
+		logs.GET("/archives", serverHandler.GetLogArchives)
+		logs.GET("/:id", serverHandler.GetLog)
+	}
+
+	// 审计日志
+	audit := api.Group("/audit", can(services.PermAuditRead))
+	{
+		audit.GET("", serverHandler.GetAuditLogs)
+		audit.GET("/export", serverHandler.ExportAuditLogs)
 	}
 
 	// 设置
 	settings := api.Group("/settings")
 	{
-		settings.GET("", serverHandler.GetSettings)
-		settings.PUT("", serverHandler.UpdateSettings)
+		settings.GET("", can(services.PermSettingsRead), serverHandler.GetSettings)
+		settings.PUT("", can(services.PermSettingsWrite), serverHandler.UpdateSettings)
+	}
+
+	// 管理员账号
+	users := api.Group("/users", can(services.PermUsersManage))
+	{
+		users.GET("", serverHandler.ListAdminUsers)
+		users.POST("", serverHandler.CreateAdminUser)
+		users.PUT("/:id", serverHandler.UpdateAdminUser)
+		users.DELETE("/:id", serverHandler.DeleteAdminUser)
+	}
+
+	// API 令牌
+	tokens := api.Group("/tokens", can(services.PermTokensManage))
+	{
+		tokens.GET("", serverHandler.ListAPITokens)
+		tokens.POST("", serverHandler.CreateAPIToken)
+		tokens.DELETE("/:id", serverHandler.DeleteAPIToken)
+	}
+
+	// 声明式配置
+	configDocument := api.Group("/config", can(services.PermConfigManage))
+	{
+		configDocument.GET("/export", serverHandler.ExportConfig)
+		configDocument.POST("/diff", serverHandler.DiffConfig)
+		configDocument.POST("/apply", serverHandler.ApplyConfig)
+	}
+
+	// 加密密钥轮换
+	encryption := api.Group("/encryption", can(services.PermEncryptionManage))
+	{
+		encryption.GET("", serverHandler.GetEncryptionStatus)
+		encryption.POST("/reencrypt", serverHandler.ReencryptKeys)
 	}
 }
 
@@ -163,10 +236,13 @@ func registerProxyRoutes(
 	router *gin.Engine,
 	proxyServer *proxy.ProxyServer,
 	groupManager *services.GroupManager,
+	rateLimitService *services.RateLimitService,
 ) {
 	proxyGroup := router.Group("/proxy")
 
+	proxyGroup.Use(middleware.RequestID())
 	proxyGroup.Use(middleware.ProxyAuth(groupManager))
+	proxyGroup.Use(middleware.ProxyRateLimit(groupManager, rateLimitService))
 
 	proxyGroup.Any("/:group_name/*path", proxyServer.HandleProxy)
 }
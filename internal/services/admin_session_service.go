@@ -0,0 +1,286 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gpt-load/internal/store"
+	"gpt-load/internal/types"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// AdminSessionTokenPrefix marks admin session tokens, so they are not confused with the AUTH_KEY.
+	AdminSessionTokenPrefix = "gls_"
+
+	adminSessionKeyPrefix  = "admin_session:"
+	loginAttemptsKeyPrefix = "login_attempts:"
+	loginLockoutKeyPrefix  = "login_lockout:"
+	loginFailedKeyPrefix   = "login_failed_credential:"
+)
+
+// ErrSessionInvalid is returned for unknown, expired or revoked session tokens.
+var ErrSessionInvalid = errors.New("session is invalid or expired")
+
+// adminSession is the session record kept in the store.
+type adminSession struct {
+	UserID uint `json:"user_id"` // 0 表示使用 AUTH_KEY 登录
+	// Fingerprint 由密码哈希或 AUTH_KEY 计算，修改密码或更换 AUTH_KEY 后会话自动失效
+	Fingerprint string    `json:"fingerprint"`
+	IssuedAt    time.Time `json:"issued_at"` // 首次登录时间，刷新不会改变
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// AdminSession is a session token issued to an admin.
+type AdminSession struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// AdminSessionService issues, refreshes and revokes admin session tokens and limits login attempts.
+// Sessions live in the store, so they are shared by all nodes.
+type AdminSessionService struct {
+	store         store.Store
+	configManager types.ConfigManager
+	adminUsers    *AdminUserService
+}
+
+// NewAdminSessionService creates a new AdminSessionService.
+func NewAdminSessionService(store store.Store, configManager types.ConfigManager, adminUsers *AdminUserService) *AdminSessionService {
+	return &AdminSessionService{
+		store:         store,
+		configManager: configManager,
+		adminUsers:    adminUsers,
+	}
+}
+
+// IsSessionToken reports whether the credential looks like a session token.
+func IsSessionToken(token string) bool {
+	return strings.HasPrefix(token, AdminSessionTokenPrefix)
+}
+
+// Create issues a new session for an authenticated admin.
+func (s *AdminSessionService) Create(principal *AdminPrincipal) (*AdminSession, error) {
+	fingerprint, err := s.fingerprint(principal.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return s.issue(adminSession{
+		UserID:      principal.UserID,
+		Fingerprint: fingerprint,
+		IssuedAt:    time.Now(),
+	})
+}
+
+// Resolve returns the admin of a valid session token.
+func (s *AdminSessionService) Resolve(token string) (*AdminPrincipal, error) {
+	session, err := s.load(token)
+	if err != nil {
+		return nil, err
+	}
+	return s.principalOf(session)
+}
+
+// Refresh replaces a valid session token with a new one. The new session never outlives
+// the maximum lifetime counted from the original login.
+func (s *AdminSessionService) Refresh(token string) (*AdminSession, error) {
+	session, err := s.load(token)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.principalOf(session); err != nil {
+		return nil, err
+	}
+
+	maxLifetime := time.Duration(s.configManager.GetAuthConfig().SessionMaxLifetimeHours) * time.Hour
+	if time.Since(session.IssuedAt) >= maxLifetime {
+		return nil, ErrSessionInvalid
+	}
+
+	newSession, err := s.issue(*session)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Revoke(token); err != nil {
+		logrus.WithError(err).Warn("Failed to revoke refreshed admin session")
+	}
+	return newSession, nil
+}
+
+// Revoke invalidates a session token immediately.
+func (s *AdminSessionService) Revoke(token string) error {
+	return s.store.Delete(sessionStoreKey(token))
+}
+
+// LoginLockedFor returns how long logins from the IP are still locked out, or zero.
+func (s *AdminSessionService) LoginLockedFor(ip string) time.Duration {
+	value, err := s.store.Get(loginLockoutKeyPrefix + ip)
+	if err != nil {
+		return 0
+	}
+	lockedUntil, err := strconv.ParseInt(string(value), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return max(time.Until(time.Unix(lockedUntil, 0)), 0)
+}
+
+// RecordLoginFailure counts a failed login from the IP and locks the IP out once
+// the attempt limit is reached. It returns the lockout duration when a lockout starts.
+func (s *AdminSessionService) RecordLoginFailure(ip string) time.Duration {
+	authConfig := s.configManager.GetAuthConfig()
+	if authConfig.LoginMaxAttempts <= 0 {
+		return 0
+	}
+	lockout := time.Duration(authConfig.LoginLockoutMinutes) * time.Minute
+
+	attemptsKey := loginAttemptsKeyPrefix + ip
+	count, err := s.store.IncrBy(attemptsKey, 1)
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to record failed login attempt")
+		return 0
+	}
+	if count == 1 {
+		if err := s.store.Expire(attemptsKey, lockout); err != nil {
+			logrus.WithError(err).Warn("Failed to set expiry on login attempt counter")
+		}
+	}
+	if count < int64(authConfig.LoginMaxAttempts) {
+		return 0
+	}
+
+	lockedUntil := time.Now().Add(lockout).Unix()
+	if err := s.store.Set(loginLockoutKeyPrefix+ip, []byte(strconv.FormatInt(lockedUntil, 10)), lockout); err != nil {
+		logrus.WithError(err).Warn("Failed to lock out login source")
+		return 0
+	}
+	if err := s.store.Delete(attemptsKey); err != nil {
+		logrus.WithError(err).Debug("Failed to reset login attempt counter")
+	}
+	logrus.WithField("source_ip", ip).Warnf("Too many failed admin logins, locked out for %v", lockout)
+	return lockout
+}
+
+// RecordCredentialFailure counts a rejected credential sent to the admin API. Each distinct credential
+// counts once per lockout window, repeating the same wrong credential does not bring the IP closer to a lockout.
+func (s *AdminSessionService) RecordCredentialFailure(ip, credential string) time.Duration {
+	authConfig := s.configManager.GetAuthConfig()
+	if authConfig.LoginMaxAttempts <= 0 {
+		return 0
+	}
+
+	sum := sha256.Sum256([]byte(credential))
+	key := loginFailedKeyPrefix + ip + ":" + hex.EncodeToString(sum[:16])
+	isNew, err := s.store.SetNX(key, []byte("1"), time.Duration(authConfig.LoginLockoutMinutes)*time.Minute)
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to record rejected credential")
+	} else if !isNew {
+		return 0
+	}
+	return s.RecordLoginFailure(ip)
+}
+
+// ResetLoginFailures clears the failed login counter of the IP after a successful login.
+func (s *AdminSessionService) ResetLoginFailures(ip string) {
+	if err := s.store.Delete(loginAttemptsKeyPrefix + ip); err != nil {
+		logrus.WithError(err).Debug("Failed to reset login attempt counter")
+	}
+}
+
+// issue stores a session under a new random token.
+func (s *AdminSessionService) issue(session adminSession) (*AdminSession, error) {
+	authConfig := s.configManager.GetAuthConfig()
+	now := time.Now()
+	expiresAt := now.Add(time.Duration(authConfig.SessionTTLMinutes) * time.Minute)
+	if maxExpiresAt := session.IssuedAt.Add(time.Duration(authConfig.SessionMaxLifetimeHours) * time.Hour); expiresAt.After(maxExpiresAt) {
+		expiresAt = maxExpiresAt
+	}
+	session.ExpiresAt = expiresAt
+
+	randomBytes := make([]byte, 32)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return nil, fmt.Errorf("failed to generate session token: %w", err)
+	}
+	token := AdminSessionTokenPrefix + base64.RawURLEncoding.EncodeToString(randomBytes)
+
+	data, err := json.Marshal(session)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.Set(sessionStoreKey(token), data, time.Until(expiresAt)); err != nil {
+		return nil, fmt.Errorf("failed to store session: %w", err)
+	}
+
+	return &AdminSession{Token: token, ExpiresAt: expiresAt}, nil
+}
+
+func (s *AdminSessionService) load(token string) (*adminSession, error) {
+	data, err := s.store.Get(sessionStoreKey(token))
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, ErrSessionInvalid
+		}
+		return nil, err
+	}
+
+	var session adminSession
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, ErrSessionInvalid
+	}
+	if time.Now().After(session.ExpiresAt) {
+		return nil, ErrSessionInvalid
+	}
+	return &session, nil
+}
+
+func (s *AdminSessionService) fingerprint(userID uint) (string, error) {
+	if userID == 0 {
+		return credentialFingerprint(s.configManager.GetAuthConfig().Key), nil
+	}
+	user, err := s.adminUsers.findActiveUser(userID)
+	if err != nil {
+		return "", err
+	}
+	return credentialFingerprint(user.PasswordHash), nil
+}
+
+// principalOf checks that the session's credentials are still current and returns its admin.
+func (s *AdminSessionService) principalOf(session *adminSession) (*AdminPrincipal, error) {
+	if session.UserID == 0 {
+		if session.Fingerprint != credentialFingerprint(s.configManager.GetAuthConfig().Key) {
+			return nil, ErrSessionInvalid
+		}
+		return NewOwnerPrincipal(), nil
+	}
+
+	user, err := s.adminUsers.findActiveUser(session.UserID)
+	if err != nil {
+		if errors.Is(err, ErrInvalidCredentials) {
+			return nil, ErrSessionInvalid
+		}
+		return nil, err
+	}
+	if session.Fingerprint != credentialFingerprint(user.PasswordHash) {
+		return nil, ErrSessionInvalid
+	}
+	return newAdminPrincipal(user), nil
+}
+
+// sessionStoreKey hashes the token, so raw tokens are never written to the store.
+func sessionStoreKey(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return adminSessionKeyPrefix + hex.EncodeToString(hash[:])
+}
+
+func credentialFingerprint(secret string) string {
+	hash := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(hash[:8])
+}
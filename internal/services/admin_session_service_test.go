@@ -0,0 +1,147 @@
+package services
+
+import (
+	"testing"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/store"
+	"gpt-load/internal/types"
+)
+
+// testConfigManager serves a fixed configuration; methods not overridden panic when called.
+type testConfigManager struct {
+	types.ConfigManager
+	auth types.AuthConfig
+	oidc types.OIDCConfig
+}
+
+func (m *testConfigManager) GetAuthConfig() types.AuthConfig {
+	return m.auth
+}
+
+func (m *testConfigManager) GetOIDCConfig() types.OIDCConfig {
+	return m.oidc
+}
+
+func newTestSessionService(maxAttempts int) (*AdminSessionService, *testConfigManager) {
+	config := &testConfigManager{auth: types.AuthConfig{
+		Key:                     "sk-test-admin-key",
+		SessionTTLMinutes:       60,
+		SessionMaxLifetimeHours: 24,
+		LoginMaxAttempts:        maxAttempts,
+		LoginLockoutMinutes:     15,
+	}}
+	return NewAdminSessionService(store.NewMemoryStore(), config, nil), config
+}
+
+func TestAdminSessionLifecycle(t *testing.T) {
+	sessions, config := newTestSessionService(5)
+
+	session, err := sessions.Create(NewOwnerPrincipal())
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if !IsSessionToken(session.Token) {
+		t.Errorf("token %q does not have the session prefix", session.Token)
+	}
+	if principal, err := sessions.Resolve(session.Token); err != nil || principal.Role != models.AdminRoleOwner {
+		t.Fatalf("Resolve() = %v, %v", principal, err)
+	}
+
+	refreshed, err := sessions.Refresh(session.Token)
+	if err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if _, err := sessions.Resolve(session.Token); err != ErrSessionInvalid {
+		t.Errorf("Resolve() of the refreshed token error = %v, want ErrSessionInvalid", err)
+	}
+
+	// 更换 AUTH_KEY 后旧会话失效
+	config.auth.Key = "sk-rotated-admin-key"
+	if _, err := sessions.Resolve(refreshed.Token); err != ErrSessionInvalid {
+		t.Errorf("Resolve() after changing AUTH_KEY error = %v, want ErrSessionInvalid", err)
+	}
+
+	if _, err := sessions.Resolve(AdminSessionTokenPrefix + "unknown"); err != ErrSessionInvalid {
+		t.Errorf("Resolve() of an unknown token error = %v, want ErrSessionInvalid", err)
+	}
+}
+
+func TestAdminSessionRevoke(t *testing.T) {
+	sessions, _ := newTestSessionService(5)
+
+	session, err := sessions.Create(NewOwnerPrincipal())
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := sessions.Revoke(session.Token); err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+	if _, err := sessions.Resolve(session.Token); err != ErrSessionInvalid {
+		t.Errorf("Resolve() after Revoke() error = %v, want ErrSessionInvalid", err)
+	}
+}
+
+func TestLoginLockout(t *testing.T) {
+	sessions, _ := newTestSessionService(3)
+	const ip = "192.0.2.1"
+
+	for i := range 2 {
+		if lockout := sessions.RecordLoginFailure(ip); lockout != 0 {
+			t.Fatalf("failure %d started a lockout", i+1)
+		}
+	}
+	if sessions.LoginLockedFor(ip) != 0 {
+		t.Fatal("locked out before reaching the limit")
+	}
+	if lockout := sessions.RecordLoginFailure(ip); lockout == 0 {
+		t.Fatal("third failure did not start a lockout")
+	}
+	if sessions.LoginLockedFor(ip) <= 0 {
+		t.Error("LoginLockedFor() = 0 after the lockout started")
+	}
+	if sessions.LoginLockedFor("192.0.2.2") != 0 {
+		t.Error("lockout applied to another IP")
+	}
+}
+
+func TestLoginFailuresReset(t *testing.T) {
+	sessions, _ := newTestSessionService(3)
+	const ip = "192.0.2.1"
+
+	sessions.RecordLoginFailure(ip)
+	sessions.RecordLoginFailure(ip)
+	sessions.ResetLoginFailures(ip)
+	sessions.RecordLoginFailure(ip)
+	sessions.RecordLoginFailure(ip)
+	if sessions.LoginLockedFor(ip) != 0 {
+		t.Error("failures before a successful login counted towards the lockout")
+	}
+}
+
+func TestRecordCredentialFailureCountsDistinctCredentials(t *testing.T) {
+	sessions, _ := newTestSessionService(3)
+	const ip = "192.0.2.1"
+
+	for range 10 {
+		sessions.RecordCredentialFailure(ip, "sk-outdated")
+	}
+	if sessions.LoginLockedFor(ip) != 0 {
+		t.Fatal("repeating one credential locked the IP out")
+	}
+
+	sessions.RecordCredentialFailure(ip, "sk-guess-1")
+	if lockout := sessions.RecordCredentialFailure(ip, "sk-guess-2"); lockout == 0 {
+		t.Error("third distinct credential did not start a lockout")
+	}
+}
+
+func TestLoginLockoutDisabled(t *testing.T) {
+	sessions, _ := newTestSessionService(0)
+	for range 10 {
+		sessions.RecordLoginFailure("192.0.2.1")
+	}
+	if sessions.LoginLockedFor("192.0.2.1") != 0 {
+		t.Error("lockout started although LOGIN_MAX_ATTEMPTS is 0")
+	}
+}
@@ -0,0 +1,232 @@
+package services
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"gpt-load/internal/models"
+	"slices"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// 管理接口权限
+const (
+	PermGroupsRead       = "groups:read"
+	PermGroupsWrite      = "groups:write"  // 修改分组配置
+	PermGroupsManage     = "groups:manage" // 创建、删除、复制分组
+	PermKeysRead         = "keys:read"
+	PermKeysWrite        = "keys:write"
+	PermLogsRead         = "logs:read"
+	PermDashboardRead    = "dashboard:read"
+	PermSettingsRead     = "settings:read"
+	PermSettingsWrite    = "settings:write"
+	PermAuditRead        = "audit:read"
+	PermUsersManage      = "users:manage"
+	PermTokensManage     = "tokens:manage"
+	PermConfigManage     = "config:manage"     // 导出和应用整个实例的配置
+	PermEncryptionManage = "encryption:manage" // 轮换加密密钥后重新加密数据
+)
+
+// allPermissions lists every permission, in display order.
+var allPermissions = []string{
+	PermGroupsRead, PermGroupsWrite, PermGroupsManage,
+	PermKeysRead, PermKeysWrite,
+	PermLogsRead, PermDashboardRead,
+	PermSettingsRead, PermSettingsWrite,
+	PermAuditRead, PermUsersManage, PermTokensManage,
+	PermConfigManage, PermEncryptionManage,
+}
+
+// rolePermissions maps each admin role to its permissions. The owner role has every permission.
+var rolePermissions = map[string][]string{
+	models.AdminRoleOperator: {
+		PermGroupsRead, PermGroupsWrite, PermGroupsManage,
+		PermKeysRead, PermKeysWrite,
+		PermLogsRead, PermDashboardRead, PermSettingsRead,
+	},
+	models.AdminRoleGroupOperator: {
+		PermGroupsRead, PermGroupsWrite,
+		PermKeysRead, PermKeysWrite,
+		PermLogsRead, PermDashboardRead,
+	},
+	models.AdminRoleViewer: {
+		PermGroupsRead, PermLogsRead, PermDashboardRead,
+	},
+}
+
+// AuthKeyActor is the actor name of requests authenticated with the AUTH_KEY. It cannot be used as a username.
+const AuthKeyActor = "admin"
+
+// adminPrincipalContextKey is the gin context key of the authenticated admin.
+const adminPrincipalContextKey = "adminPrincipal"
+
+// ErrInvalidCredentials is returned when the username or password is wrong or the user is disabled.
+var ErrInvalidCredentials = errors.New("invalid username or password")
+
+// IsValidAdminRole reports whether the role is a known admin role.
+func IsValidAdminRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok || role == models.AdminRoleOwner
+}
+
+// AdminPrincipal is the authenticated identity of an admin API request.
+type AdminPrincipal struct {
+	UserID   uint // 0 表示使用 AUTH_KEY 登录
+	TokenID  uint // 使用 API 令牌认证时的令牌 ID
+	Username string
+	Role     string
+	GroupIDs []uint
+	Scopes   []string // API 令牌的权限范围，替代角色权限
+}
+
+// Can reports whether the principal has the given permission.
+func (p *AdminPrincipal) Can(permission string) bool {
+	if p.TokenID != 0 {
+		return slices.Contains(p.Scopes, permission)
+	}
+	if p.Role == models.AdminRoleOwner {
+		return true
+	}
+	return slices.Contains(rolePermissions[p.Role], permission)
+}
+
+// Permissions returns all permissions of the principal.
+func (p *AdminPrincipal) Permissions() []string {
+	if p.TokenID != 0 {
+		return slices.Clone(p.Scopes)
+	}
+	if p.Role == models.AdminRoleOwner {
+		return slices.Clone(allPermissions)
+	}
+	return slices.Clone(rolePermissions[p.Role])
+}
+
+// IsGroupScoped reports whether the principal is limited to specific groups.
+func (p *AdminPrincipal) IsGroupScoped() bool {
+	if p.TokenID != 0 {
+		return len(p.GroupIDs) > 0
+	}
+	return p.Role == models.AdminRoleGroupOperator
+}
+
+// CanAccessGroup reports whether the principal may access the given group.
+func (p *AdminPrincipal) CanAccessGroup(groupID uint) bool {
+	return !p.IsGroupScoped() || slices.Contains(p.GroupIDs, groupID)
+}
+
+// NewOwnerPrincipal returns the principal of requests authenticated with the AUTH_KEY.
+func NewOwnerPrincipal() *AdminPrincipal {
+	return &AdminPrincipal{Username: AuthKeyActor, Role: models.AdminRoleOwner}
+}
+
+// SetAdminPrincipal stores the authenticated admin in the request context.
+func SetAdminPrincipal(c *gin.Context, principal *AdminPrincipal) {
+	c.Set(adminPrincipalContextKey, principal)
+	c.Set("actor", principal.Username)
+}
+
+// AdminPrincipalFromContext returns the authenticated admin of the request, or nil.
+func AdminPrincipalFromContext(c *gin.Context) *AdminPrincipal {
+	value, exists := c.Get(adminPrincipalContextKey)
+	if !exists {
+		return nil
+	}
+	principal, _ := value.(*AdminPrincipal)
+	return principal
+}
+
+// AdminGroupScope returns a GORM scope that limits a query to the groups of a group-scoped admin.
+// column is the group ID column, e.g. "id" for groups or "group_id" for logs.
+func AdminGroupScope(c *gin.Context, column string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		principal := AdminPrincipalFromContext(c)
+		if principal == nil || !principal.IsGroupScoped() {
+			return db
+		}
+		return db.Where(column+" IN ?", principal.GroupIDs)
+	}
+}
+
+// verifiedCredential caches a successful bcrypt check, so repeated requests skip the expensive comparison.
+type verifiedCredential struct {
+	passwordHash string
+	digest       [32]byte
+}
+
+// AdminUserService authenticates admin users.
+type AdminUserService struct {
+	DB       *gorm.DB
+	verified sync.Map // username -> verifiedCredential
+}
+
+// NewAdminUserService creates a new AdminUserService.
+func NewAdminUserService(db *gorm.DB) *AdminUserService {
+	return &AdminUserService{DB: db}
+}
+
+// HashPassword hashes a password for storage.
+func (s *AdminUserService) HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// Authenticate verifies a username and password and returns the principal.
+func (s *AdminUserService) Authenticate(username, password string) (*AdminPrincipal, error) {
+	var user models.AdminUser
+	if err := s.DB.Where("username = ?", username).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+	if user.Disabled {
+		return nil, ErrInvalidCredentials
+	}
+
+	digest := sha256.Sum256([]byte(password))
+	if cached, ok := s.verified.Load(username); ok {
+		credential := cached.(verifiedCredential)
+		// 密码哈希变化（修改密码）后缓存自动失效
+		if credential.passwordHash == user.PasswordHash && subtle.ConstantTimeCompare(credential.digest[:], digest[:]) == 1 {
+			return newAdminPrincipal(&user), nil
+		}
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	s.verified.Store(username, verifiedCredential{passwordHash: user.PasswordHash, digest: digest})
+
+	return newAdminPrincipal(&user), nil
+}
+
+// findActiveUser loads an enabled admin user by ID.
+func (s *AdminUserService) findActiveUser(userID uint) (*models.AdminUser, error) {
+	var user models.AdminUser
+	if err := s.DB.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+	if user.Disabled {
+		return nil, ErrInvalidCredentials
+	}
+	return &user, nil
+}
+
+func newAdminPrincipal(user *models.AdminUser) *AdminPrincipal {
+	return &AdminPrincipal{
+		UserID:   user.ID,
+		Username: user.Username,
+		Role:     user.Role,
+		GroupIDs: user.GroupIDs,
+	}
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"testing"
+
+	"gpt-load/internal/models"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestDB opens an in-memory SQLite database and migrates the tables.
+func newTestDB(t *testing.T, tables ...any) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	if err := db.AutoMigrate(tables...); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	return db
+}
+
+func TestAdminPrincipalPermissions(t *testing.T) {
+	tests := []struct {
+		role    string
+		allowed []string
+		denied  []string
+	}{
+		{models.AdminRoleOwner, []string{PermUsersManage, PermEncryptionManage, PermConfigManage}, nil},
+		{models.AdminRoleOperator, []string{PermGroupsManage, PermKeysWrite, PermSettingsRead}, []string{PermSettingsWrite, PermUsersManage, PermAuditRead}},
+		{models.AdminRoleGroupOperator, []string{PermGroupsWrite, PermKeysWrite}, []string{PermGroupsManage, PermSettingsRead}},
+		{models.AdminRoleViewer, []string{PermGroupsRead, PermLogsRead}, []string{PermKeysRead, PermGroupsWrite}},
+		{"unknown", nil, []string{PermGroupsRead}},
+	}
+	for _, tt := range tests {
+		principal := &AdminPrincipal{Role: tt.role}
+		for _, permission := range tt.allowed {
+			if !principal.Can(permission) {
+				t.Errorf("%s cannot %s", tt.role, permission)
+			}
+		}
+		for _, permission := range tt.denied {
+			if principal.Can(permission) {
+				t.Errorf("%s can %s", tt.role, permission)
+			}
+		}
+	}
+
+	if got := NewOwnerPrincipal().Permissions(); len(got) != len(allPermissions) {
+		t.Errorf("owner permissions = %v, want all", got)
+	}
+}
+
+func TestAdminPrincipalTokenScopes(t *testing.T) {
+	// API 令牌只拥有其权限范围，与角色无关
+	token := &AdminPrincipal{TokenID: 1, Role: models.AdminRoleOwner, Scopes: []string{PermGroupsRead}}
+	if !token.Can(PermGroupsRead) || token.Can(PermGroupsWrite) {
+		t.Errorf("token permissions = %v", token.Permissions())
+	}
+	if token.IsGroupScoped() {
+		t.Error("token without groups is group scoped")
+	}
+
+	token.GroupIDs = []uint{2}
+	if !token.CanAccessGroup(2) || token.CanAccessGroup(3) {
+		t.Error("token group scope not applied")
+	}
+}
+
+func TestAdminPrincipalGroupScope(t *testing.T) {
+	operator := &AdminPrincipal{Role: models.AdminRoleGroupOperator, GroupIDs: []uint{1, 3}}
+	if !operator.IsGroupScoped() || !operator.CanAccessGroup(3) || operator.CanAccessGroup(2) {
+		t.Error("group operator scope not applied")
+	}
+
+	// 没有分组的分组操作员不能访问任何分组
+	empty := &AdminPrincipal{Role: models.AdminRoleGroupOperator}
+	if empty.CanAccessGroup(1) {
+		t.Error("group operator without groups can access a group")
+	}
+
+	// 其他角色的 group_ids 不生效
+	viewer := &AdminPrincipal{Role: models.AdminRoleViewer, GroupIDs: []uint{1}}
+	if viewer.IsGroupScoped() || !viewer.CanAccessGroup(2) {
+		t.Error("viewer limited to groups")
+	}
+}
+
+func TestIsValidAdminRole(t *testing.T) {
+	for _, role := range []string{models.AdminRoleOwner, models.AdminRoleOperator, models.AdminRoleGroupOperator, models.AdminRoleViewer} {
+		if !IsValidAdminRole(role) {
+			t.Errorf("IsValidAdminRole(%q) = false", role)
+		}
+	}
+	if IsValidAdminRole("root") {
+		t.Error("IsValidAdminRole(\"root\") = true")
+	}
+}
+
+func TestAdminUserAuthenticate(t *testing.T) {
+	db := newTestDB(t, &models.AdminUser{})
+
+	users := NewAdminUserService(db)
+	hash, err := users.HashPassword("correct horse")
+	if err != nil {
+		t.Fatalf("HashPassword() error = %v", err)
+	}
+	user := models.AdminUser{Username: "alice", PasswordHash: hash, Role: models.AdminRoleGroupOperator, GroupIDs: []uint{4}}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	principal, err := users.Authenticate("alice", "correct horse")
+	if err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+	if principal.UserID != user.ID || principal.Role != models.AdminRoleGroupOperator || !principal.CanAccessGroup(4) {
+		t.Errorf("principal = %+v", principal)
+	}
+
+	for _, tt := range []struct{ username, password string }{
+		{"alice", "wrong"},
+		{"bob", "correct horse"},
+	} {
+		if _, err := users.Authenticate(tt.username, tt.password); err != ErrInvalidCredentials {
+			t.Errorf("Authenticate(%q, %q) error = %v, want ErrInvalidCredentials", tt.username, tt.password, err)
+		}
+	}
+
+	// 修改密码后缓存的校验结果失效
+	newHash, _ := users.HashPassword("battery staple")
+	db.Model(&user).Update("password_hash", newHash)
+	if _, err := users.Authenticate("alice", "correct horse"); err != ErrInvalidCredentials {
+		t.Errorf("old password after change error = %v, want ErrInvalidCredentials", err)
+	}
+	if _, err := users.Authenticate("alice", "battery staple"); err != nil {
+		t.Errorf("new password error = %v", err)
+	}
+
+	db.Model(&user).Update("disabled", true)
+	if _, err := users.Authenticate("alice", "battery staple"); err != ErrInvalidCredentials {
+		t.Errorf("disabled user error = %v, want ErrInvalidCredentials", err)
+	}
+}
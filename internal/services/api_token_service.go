@@ -0,0 +1,134 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gpt-load/internal/models"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	// APITokenPrefix marks machine API tokens.
+	APITokenPrefix = "glt_"
+	// APITokenRole is the role reported for requests authenticated with an API token.
+	APITokenRole = "api_token"
+	// APITokenActorPrefix prefixes the token name in the audit trail, e.g. "token:ci-deploy".
+	APITokenActorPrefix = "token:"
+
+	// 最近使用时间的写入间隔，避免每个请求都写数据库
+	apiTokenLastUsedInterval = time.Minute
+)
+
+// ErrAPITokenInvalid is returned for unknown, revoked or expired API tokens.
+var ErrAPITokenInvalid = errors.New("API token is invalid or expired")
+
+// APITokenScopes lists the permissions that can be granted to API tokens.
+// Account and token management stay with interactive owners.
+var APITokenScopes = slices.DeleteFunc(slices.Clone(allPermissions), func(permission string) bool {
+	return permission == PermUsersManage || permission == PermTokensManage
+})
+
+// IsAPIToken reports whether the credential looks like an API token.
+func IsAPIToken(token string) bool {
+	return strings.HasPrefix(token, APITokenPrefix)
+}
+
+// APITokenService issues and authenticates scoped API tokens for automation.
+type APITokenService struct {
+	DB       *gorm.DB
+	lastUsed sync.Map // token ID -> time.Time
+}
+
+// NewAPITokenService creates a new APITokenService.
+func NewAPITokenService(db *gorm.DB) *APITokenService {
+	return &APITokenService{DB: db}
+}
+
+// GenerateToken returns a new raw token, its hash for storage and a display prefix.
+func (s *APITokenService) GenerateToken() (token, hash, prefix string, err error) {
+	random, err := randomURLToken()
+	if err != nil {
+		return "", "", "", err
+	}
+	token = APITokenPrefix + random
+	return token, hashAPIToken(token), token[:len(APITokenPrefix)+6], nil
+}
+
+// ValidateScopes checks the permissions requested for a token.
+func (s *APITokenService) ValidateScopes(permissions []string, groupIDs []uint) error {
+	if len(permissions) == 0 {
+		return errors.New("at least one permission is required")
+	}
+	for _, permission := range permissions {
+		if !slices.Contains(APITokenScopes, permission) {
+			return fmt.Errorf("permission '%s' cannot be granted to API tokens", permission)
+		}
+	}
+	if len(groupIDs) > 0 {
+		for _, permission := range []string{PermGroupsManage, PermConfigManage, PermEncryptionManage} {
+			if slices.Contains(permissions, permission) {
+				return fmt.Errorf("group-scoped tokens cannot have the %s permission", permission)
+			}
+		}
+	}
+	return nil
+}
+
+// Authenticate resolves an API token and records its use.
+func (s *APITokenService) Authenticate(token, clientIP string) (*AdminPrincipal, error) {
+	var apiToken models.APIToken
+	if err := s.DB.Where("token_hash = ?", hashAPIToken(token)).First(&apiToken).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAPITokenInvalid
+		}
+		return nil, err
+	}
+	if apiToken.ExpiresAt != nil && time.Now().After(*apiToken.ExpiresAt) {
+		return nil, ErrAPITokenInvalid
+	}
+
+	s.touch(&apiToken, clientIP)
+
+	return &AdminPrincipal{
+		TokenID:  apiToken.ID,
+		Username: APITokenActorPrefix + apiToken.Name,
+		Role:     APITokenRole,
+		GroupIDs: apiToken.GroupIDs,
+		Scopes:   apiToken.Permissions,
+	}, nil
+}
+
+// Forget drops the cached last-used time of a revoked token.
+func (s *APITokenService) Forget(tokenID uint) {
+	s.lastUsed.Delete(tokenID)
+}
+
+// touch updates the last-used time and IP at most once per interval.
+func (s *APITokenService) touch(apiToken *models.APIToken, clientIP string) {
+	now := time.Now()
+	if last, ok := s.lastUsed.Load(apiToken.ID); ok && now.Sub(last.(time.Time)) < apiTokenLastUsedInterval && apiToken.LastUsedIP == clientIP {
+		return
+	}
+	s.lastUsed.Store(apiToken.ID, now)
+
+	err := s.DB.Model(&models.APIToken{}).Where("id = ?", apiToken.ID).UpdateColumns(map[string]any{
+		"last_used_at": now,
+		"last_used_ip": clientIP,
+	}).Error
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to update API token last used time")
+	}
+}
+
+func hashAPIToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gpt-load/internal/models"
+)
+
+func newTestAPITokenService(t *testing.T) *APITokenService {
+	t.Helper()
+
+	db := newTestDB(t, &models.APIToken{})
+	return NewAPITokenService(db)
+}
+
+// createToken stores a new token and returns its raw value.
+func createToken(t *testing.T, s *APITokenService, apiToken models.APIToken) (string, *models.APIToken) {
+	t.Helper()
+
+	token, hash, prefix, err := s.GenerateToken()
+	if err != nil {
+		t.Fatalf("GenerateToken() error = %v", err)
+	}
+	apiToken.TokenHash, apiToken.TokenPrefix = hash, prefix
+	if err := s.DB.Create(&apiToken).Error; err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+	return token, &apiToken
+}
+
+func TestGenerateAPIToken(t *testing.T) {
+	s := newTestAPITokenService(t)
+
+	token, hash, prefix, err := s.GenerateToken()
+	if err != nil {
+		t.Fatalf("GenerateToken() error = %v", err)
+	}
+	if !IsAPIToken(token) || !strings.HasPrefix(token, prefix) || len(prefix) != len(APITokenPrefix)+6 {
+		t.Errorf("token %q, prefix %q", token, prefix)
+	}
+	if hash == token || strings.Contains(hash, token) {
+		t.Error("hash contains the raw token")
+	}
+	if other, _, _, _ := s.GenerateToken(); other == token {
+		t.Error("GenerateToken() returned the same token twice")
+	}
+}
+
+func TestAPITokenAuthenticate(t *testing.T) {
+	s := newTestAPITokenService(t)
+
+	token, stored := createToken(t, s, models.APIToken{Name: "ci", Permissions: []string{PermKeysWrite}, GroupIDs: []uint{7}})
+	principal, err := s.Authenticate(token, "10.0.0.1")
+	if err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+	if principal.TokenID != stored.ID || principal.Username != "token:ci" || principal.Role != APITokenRole {
+		t.Errorf("principal = %+v", principal)
+	}
+	if !principal.Can(PermKeysWrite) || principal.Can(PermKeysRead) || !principal.CanAccessGroup(7) || principal.CanAccessGroup(8) {
+		t.Errorf("principal scopes not applied: %+v", principal)
+	}
+
+	var reloaded models.APIToken
+	s.DB.First(&reloaded, stored.ID)
+	if reloaded.LastUsedAt == nil || reloaded.LastUsedIP != "10.0.0.1" {
+		t.Errorf("last used not recorded: %v, %q", reloaded.LastUsedAt, reloaded.LastUsedIP)
+	}
+
+	expired := time.Now().Add(-time.Minute)
+	expiredToken, _ := createToken(t, s, models.APIToken{Name: "old", Permissions: []string{PermGroupsRead}, ExpiresAt: &expired})
+	for _, candidate := range []string{expiredToken, APITokenPrefix + "unknown", token + "x"} {
+		if _, err := s.Authenticate(candidate, "10.0.0.1"); err != ErrAPITokenInvalid {
+			t.Errorf("Authenticate(%q) error = %v, want ErrAPITokenInvalid", candidate, err)
+		}
+	}
+
+	// 吊销令牌即删除记录
+	s.DB.Delete(&models.APIToken{}, stored.ID)
+	s.Forget(stored.ID)
+	if _, err := s.Authenticate(token, "10.0.0.1"); err != ErrAPITokenInvalid {
+		t.Errorf("Authenticate() of a revoked token error = %v, want ErrAPITokenInvalid", err)
+	}
+}
+
+func TestValidateAPITokenScopes(t *testing.T) {
+	s := &APITokenService{}
+
+	tests := []struct {
+		name        string
+		permissions []string
+		groupIDs    []uint
+		want        string
+	}{
+		{"read only", []string{PermGroupsRead, PermLogsRead}, nil, ""},
+		{"group scoped writes", []string{PermKeysWrite, PermGroupsWrite}, []uint{1}, ""},
+		{"instance wide management", []string{PermGroupsManage, PermConfigManage}, nil, ""},
+		{"no permission", nil, nil, "at least one permission"},
+		{"unknown permission", []string{"keys:delete"}, nil, "cannot be granted"},
+		{"user management", []string{PermUsersManage}, nil, "cannot be granted"},
+		{"token management", []string{PermTokensManage}, nil, "cannot be granted"},
+		{"group scoped group management", []string{PermGroupsManage}, []uint{1}, "group-scoped tokens cannot"},
+		{"group scoped config", []string{PermConfigManage}, []uint{1}, "group-scoped tokens cannot"},
+		{"group scoped encryption", []string{PermEncryptionManage}, []uint{1}, "group-scoped tokens cannot"},
+	}
+	for _, tt := range tests {
+		err := s.ValidateScopes(tt.permissions, tt.groupIDs)
+		if tt.want == "" {
+			if err != nil {
+				t.Errorf("%s: ValidateScopes() error = %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: ValidateScopes() error = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,332 @@
+package services
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"gpt-load/internal/models"
+	"io"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// auditIgnoredFields are bookkeeping or derived fields that never show up in diffs.
+var auditIgnoredFields = map[string]bool{
+	"id":                true,
+	"api_keys":          true,
+	"effective_config":  true,
+	"endpoint":          true,
+	"created_at":        true,
+	"updated_at":        true,
+	"last_validated_at": true,
+	"last_login_at":     true,
+}
+
+// auditSecretFields hold credentials and are masked in diffs.
+var auditSecretFields = map[string]bool{
+	"proxy_keys":     true,
+	"auth_key":       true,
+	"key_value":      true,
+	"alert_webhooks": true,
+}
+
+// AuditEntry describes a single administrative change.
+type AuditEntry struct {
+	Action     string
+	TargetType string
+	TargetID   uint
+	TargetName string
+	Before     any // 变更前的状态，创建操作为 nil
+	After      any // 变更后的状态，删除操作为 nil
+	Details    any // 额外信息，例如影响的密钥数量
+}
+
+// FieldChange is the before/after value of a single changed field.
+type FieldChange struct {
+	Before any `json:"before"`
+	After  any `json:"after"`
+}
+
+// AuditService records and queries administrative changes.
+type AuditService struct {
+	DB *gorm.DB
+}
+
+// NewAuditService creates a new AuditService.
+func NewAuditService(db *gorm.DB) *AuditService {
+	return &AuditService{DB: db}
+}
+
+// Record writes an audit entry for the current request.
+// Failures are logged and never affect the operation being audited.
+func (s *AuditService) Record(c *gin.Context, entry AuditEntry) {
+	actor := c.GetString("actor")
+	if actor == "" {
+		actor = AuthKeyActor
+	}
+	s.RecordAs(actor, c.ClientIP(), entry)
+}
+
+// RecordAs writes an audit entry on behalf of the given actor, e.g. for changes made from the command line.
+func (s *AuditService) RecordAs(actor, sourceIP string, entry AuditEntry) {
+	auditLog := models.AuditLog{
+		Timestamp:  time.Now(),
+		Actor:      actor,
+		SourceIP:   sourceIP,
+		Action:     entry.Action,
+		TargetType: entry.TargetType,
+		TargetID:   entry.TargetID,
+		TargetName: entry.TargetName,
+	}
+
+	changes, err := diffStates(entry.Before, entry.After)
+	if err != nil {
+		logrus.WithError(err).WithField("action", entry.Action).Error("Failed to compute audit diff")
+	} else if len(changes) > 0 {
+		auditLog.Changes = mustJSON(changes)
+	}
+	if entry.Details != nil {
+		auditLog.Details = mustJSON(entry.Details)
+	}
+
+	if err := s.DB.Create(&auditLog).Error; err != nil {
+		logrus.WithError(err).WithField("action", entry.Action).Error("Failed to write audit log")
+	}
+}
+
+// auditFiltersScope returns a GORM scope function that applies filters from the Gin context.
+func (s *AuditService) auditFiltersScope(c *gin.Context) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if action := c.Query("action"); action != "" {
+			db = db.Where("action = ?", action)
+		}
+		if actor := c.Query("actor"); actor != "" {
+			db = db.Where("actor = ?", actor)
+		}
+		if targetType := c.Query("target_type"); targetType != "" {
+			db = db.Where("target_type = ?", targetType)
+		}
+		if targetIDStr := c.Query("target_id"); targetIDStr != "" {
+			if targetID, err := strconv.Atoi(targetIDStr); err == nil {
+				db = db.Where("target_id = ?", targetID)
+			}
+		}
+		if targetName := c.Query("target_name"); targetName != "" {
+			db = db.Where("target_name LIKE ?", "%"+targetName+"%")
+		}
+		if sourceIP := c.Query("source_ip"); sourceIP != "" {
+			db = db.Where("source_ip = ?", sourceIP)
+		}
+		if startTimeStr := c.Query("start_time"); startTimeStr != "" {
+			if startTime, err := time.Parse(time.RFC3339, startTimeStr); err == nil {
+				db = db.Where("timestamp >= ?", startTime)
+			}
+		}
+		if endTimeStr := c.Query("end_time"); endTimeStr != "" {
+			if endTime, err := time.Parse(time.RFC3339, endTimeStr); err == nil {
+				db = db.Where("timestamp <= ?", endTime)
+			}
+		}
+		return db
+	}
+}
+
+// GetAuditLogsQuery returns a GORM query for fetching audit logs with filters.
+func (s *AuditService) GetAuditLogsQuery(c *gin.Context) *gorm.DB {
+	return s.DB.Model(&models.AuditLog{}).Scopes(s.auditFiltersScope(c))
+}
+
+// StreamAuditLogsToCSV streams the filtered audit logs as a CSV.
+func (s *AuditService) StreamAuditLogsToCSV(c *gin.Context, writer io.Writer) error {
+	csvWriter := csv.NewWriter(writer)
+	defer csvWriter.Flush()
+
+	header := []string{"timestamp", "actor", "source_ip", "action", "target_type", "target_id", "target_name", "changes", "details"}
+	if err := csvWriter.Write(header); err != nil {
+		return fmt.Errorf("failed to write CSV header: %w", err)
+	}
+
+	var batch []models.AuditLog
+	err := s.GetAuditLogsQuery(c).Order("timestamp desc").FindInBatches(&batch, 500, func(tx *gorm.DB, _ int) error {
+		for _, entry := range batch {
+			record := []string{
+				entry.Timestamp.Format(time.RFC3339),
+				entry.Actor,
+				entry.SourceIP,
+				entry.Action,
+				entry.TargetType,
+				strconv.FormatUint(uint64(entry.TargetID), 10),
+				entry.TargetName,
+				string(entry.Changes),
+				string(entry.Details),
+			}
+			if err := csvWriter.Write(record); err != nil {
+				return fmt.Errorf("failed to write CSV record: %w", err)
+			}
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return fmt.Errorf("failed to fetch audit logs: %w", err)
+	}
+
+	return nil
+}
+
+// diffStates compares two states field by field and returns the masked changes.
+func diffStates(before, after any) (map[string]FieldChange, error) {
+	beforeMap, err := toFieldMap(before)
+	if err != nil {
+		return nil, err
+	}
+	afterMap, err := toFieldMap(after)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string]struct{}, len(beforeMap)+len(afterMap))
+	for field := range beforeMap {
+		fields[field] = struct{}{}
+	}
+	for field := range afterMap {
+		fields[field] = struct{}{}
+	}
+
+	changes := make(map[string]FieldChange)
+	for field := range fields {
+		if auditIgnoredFields[field] {
+			continue
+		}
+		oldValue, newValue := beforeMap[field], afterMap[field]
+		if reflect.DeepEqual(oldValue, newValue) {
+			continue
+		}
+		changes[field] = FieldChange{
+			Before: maskAuditValue(field, oldValue),
+			After:  maskAuditValue(field, newValue),
+		}
+	}
+	return changes, nil
+}
+
+// toFieldMap converts a struct or map into a generic JSON field map.
+func toFieldMap(state any) (map[string]any, error) {
+	if state == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// maskAuditValue hides credentials so that the audit log never stores secrets in clear text.
+func maskAuditValue(field string, value any) any {
+	switch {
+	case value == nil:
+		return nil
+	case auditSecretFields[field] || isSecretName(field):
+		str, ok := value.(string)
+		if !ok {
+			return "****"
+		}
+		var masked []string
+		for _, part := range strings.Split(str, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				masked = append(masked, maskSecret(part))
+			}
+		}
+		return strings.Join(masked, ",")
+	case field == "proxy_url":
+		if str, ok := value.(string); ok {
+			if u, err := url.Parse(str); err == nil && u.User != nil {
+				return u.Redacted()
+			}
+		}
+		return value
+	case field == "header_rules" || field == "response_header_rules":
+		return maskHeaderRules(value)
+	}
+
+	// 分组配置等嵌套对象逐字段处理
+	if nested, ok := value.(map[string]any); ok {
+		masked := make(map[string]any, len(nested))
+		for key, nestedValue := range nested {
+			masked[key] = maskAuditValue(key, nestedValue)
+		}
+		return masked
+	}
+	return value
+}
+
+// maskHeaderRules masks values of header rules that carry credentials, e.g. Authorization.
+func maskHeaderRules(value any) any {
+	rules, ok := value.([]any)
+	if !ok {
+		return value
+	}
+	masked := make([]any, 0, len(rules))
+	for _, rule := range rules {
+		ruleMap, ok := rule.(map[string]any)
+		if !ok {
+			masked = append(masked, rule)
+			continue
+		}
+		key, _ := ruleMap["key"].(string)
+		val, _ := ruleMap["value"].(string)
+		if val != "" && isSecretHeader(key) {
+			copied := make(map[string]any, len(ruleMap))
+			for k, v := range ruleMap {
+				copied[k] = v
+			}
+			copied["value"] = maskSecret(val)
+			ruleMap = copied
+		}
+		masked = append(masked, ruleMap)
+	}
+	return masked
+}
+
+func isSecretName(name string) bool {
+	name = strings.ToLower(name)
+	return strings.HasSuffix(name, "_token") || strings.HasSuffix(name, "_secret") || strings.Contains(name, "password")
+}
+
+func isSecretHeader(name string) bool {
+	name = strings.ToLower(name)
+	for _, marker := range []string{"authorization", "key", "token", "secret", "cookie"} {
+		if strings.Contains(name, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+func maskSecret(secret string) string {
+	if len(secret) <= 8 {
+		return "****"
+	}
+	return fmt.Sprintf("%s****%s", secret[:4], secret[len(secret)-4:])
+}
+
+func mustJSON(value any) datatypes.JSON {
+	data, err := json.Marshal(value)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to marshal audit data")
+		return nil
+	}
+	return datatypes.JSON(data)
+}
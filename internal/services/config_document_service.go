@@ -0,0 +1,779 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gpt-load/internal/alert"
+	"gpt-load/internal/config"
+	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+)
+
+// ConfigDocumentVersion is the version of the configuration document format.
+const ConfigDocumentVersion = 1
+
+// 配置文档格式
+const (
+	ConfigFormatYAML = "yaml"
+	ConfigFormatJSON = "json"
+)
+
+// 配置变更类型
+const (
+	ConfigActionCreate = "create"
+	ConfigActionUpdate = "update"
+	ConfigActionDelete = "delete"
+
+	ConfigKindSettings = "settings"
+	ConfigKindGroup    = "group"
+	ConfigKindKeys     = "keys"
+)
+
+// ConfigDocument is the declarative configuration of an instance.
+type ConfigDocument struct {
+	Version  int             `json:"version"`
+	Settings map[string]any  `json:"settings,omitempty"` // 省略时不修改系统设置
+	Groups   []GroupDocument `json:"groups"`
+}
+
+// GroupDocument is the declarative configuration of a group.
+type GroupDocument struct {
+	Name                string                 `json:"name"`
+	DisplayName         string                 `json:"display_name,omitempty"`
+	Description         string                 `json:"description,omitempty"`
+	ChannelType         string                 `json:"channel_type"`
+	Sort                int                    `json:"sort"`
+	TestModel           string                 `json:"test_model"`
+	ValidationEndpoint  string                 `json:"validation_endpoint,omitempty"`
+	Upstreams           []UpstreamDefinition   `json:"upstreams"`
+	ParamOverrides      map[string]any         `json:"param_overrides,omitempty"`
+	Config              map[string]any         `json:"config,omitempty"`
+	HeaderRules         []models.HeaderRule    `json:"header_rules,omitempty"`
+	ResponseHeaderRules []models.HeaderRule    `json:"response_header_rules,omitempty"`
+	BodyRules           []models.BodyRule      `json:"body_rules,omitempty"`
+	RedactionRules      []models.RedactionRule `json:"redaction_rules,omitempty"`
+	ProxyKeys           string                 `json:"proxy_keys,omitempty"`
+	Keys                []string               `json:"keys,omitempty"` // 省略时不管理该分组的密钥
+}
+
+// ConfigChange is a single change of a configuration plan. Key values are never included.
+type ConfigChange struct {
+	Action  string                 `json:"action"`
+	Kind    string                 `json:"kind"`
+	Name    string                 `json:"name"`
+	Changes map[string]FieldChange `json:"changes,omitempty"`
+	Count   int                    `json:"count,omitempty"` // 增加或删除的密钥数量
+}
+
+// ConfigPlan lists the changes needed to bring the instance in line with a document.
+type ConfigPlan struct {
+	Changes []ConfigChange `json:"changes"`
+	Applied bool           `json:"applied"`
+}
+
+// ConfigApplyOptions controls how a document is applied.
+type ConfigApplyOptions struct {
+	DryRun   bool
+	Prune    bool // 删除文档中不存在的分组，以及受管分组中多余的密钥
+	Actor    string
+	SourceIP string
+}
+
+// plannedGroup is a group create or update together with its key changes.
+type plannedGroup struct {
+	before     *models.Group // nil 表示新建
+	group      models.Group
+	changed    bool
+	addKeys    []string
+	removeKeys []string
+}
+
+// configPlan is the internal form of a plan, carrying everything needed to apply it.
+type configPlan struct {
+	ConfigPlan
+	settings       map[string]any
+	settingsBefore map[string]any
+	groups         []*plannedGroup
+	deletes        []models.Group
+}
+
+// ConfigDocumentService exports configuration documents and applies them to the instance.
+type ConfigDocumentService struct {
+	DB              *gorm.DB
+	SettingsManager *config.SystemSettingsManager
+	GroupManager    *GroupManager
+	KeyService      *KeyService
+	AuditService    *AuditService
+}
+
+// NewConfigDocumentService creates a new ConfigDocumentService.
+func NewConfigDocumentService(
+	db *gorm.DB,
+	settingsManager *config.SystemSettingsManager,
+	groupManager *GroupManager,
+	keyService *KeyService,
+	auditService *AuditService,
+) *ConfigDocumentService {
+	return &ConfigDocumentService{
+		DB:              db,
+		SettingsManager: settingsManager,
+		GroupManager:    groupManager,
+		KeyService:      keyService,
+		AuditService:    auditService,
+	}
+}
+
+// Export builds the configuration document of the instance. Keys are only included on request.
+func (s *ConfigDocumentService) Export(includeKeys bool) (*ConfigDocument, error) {
+	settings, err := toFieldMap(s.SettingsManager.GetSettings())
+	if err != nil {
+		return nil, fmt.Errorf("failed to export settings: %w", err)
+	}
+
+	var groups []models.Group
+	if err := s.DB.Order("sort asc, id asc").Find(&groups).Error; err != nil {
+		return nil, fmt.Errorf("failed to load groups: %w", err)
+	}
+
+	doc := &ConfigDocument{
+		Version:  ConfigDocumentVersion,
+		Settings: settings,
+		Groups:   make([]GroupDocument, 0, len(groups)),
+	}
+	for i := range groups {
+		groupDoc, err := NewGroupDocument(&groups[i])
+		if err != nil {
+			return nil, err
+		}
+		if includeKeys {
+			keys, err := s.loadKeys(groups[i].ID)
+			if err != nil {
+				return nil, err
+			}
+			for _, key := range keys {
+				groupDoc.Keys = append(groupDoc.Keys, key.value)
+			}
+		}
+		doc.Groups = append(doc.Groups, *groupDoc)
+	}
+	return doc, nil
+}
+
+// Plan compares a document with the instance and returns the changes, without applying them.
+func (s *ConfigDocumentService) Plan(doc *ConfigDocument, prune bool) (*ConfigPlan, error) {
+	plan, err := s.buildPlan(doc, prune)
+	if err != nil {
+		return nil, err
+	}
+	return &plan.ConfigPlan, nil
+}
+
+// Apply brings the instance in line with a document. The whole document is validated
+// before anything is written, and all database writes run in one transaction, so a
+// failure leaves the instance unchanged. Caches are updated once the transaction commits.
+func (s *ConfigDocumentService) Apply(doc *ConfigDocument, opts ConfigApplyOptions) (*ConfigPlan, error) {
+	plan, err := s.buildPlan(doc, opts.Prune)
+	if err != nil {
+		return nil, err
+	}
+	if opts.DryRun || len(plan.Changes) == 0 {
+		return &plan.ConfigPlan, nil
+	}
+
+	applied := &appliedConfig{
+		removedKeys:   make(map[uint][]uint),
+		deletedGroups: make(map[uint][]uint),
+	}
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if len(plan.settings) > 0 {
+			if err := s.SettingsManager.SaveSettings(tx, plan.settings); err != nil {
+				return fmt.Errorf("failed to update settings: %w", err)
+			}
+			after := make(map[string]any, len(plan.settingsBefore))
+			for key, value := range plan.settingsBefore {
+				after[key] = value
+			}
+			for key, value := range plan.settings {
+				after[key] = value
+			}
+			applied.audits = append(applied.audits, AuditEntry{
+				Action:     models.AuditActionSettingsUpdate,
+				TargetType: models.AuditTargetSettings,
+				TargetName: "system",
+				Before:     plan.settingsBefore,
+				After:      after,
+				Details:    map[string]any{"source": "config"},
+			})
+		}
+
+		for _, planned := range plan.groups {
+			if err := s.applyGroup(tx, planned, applied); err != nil {
+				return err
+			}
+		}
+
+		for i := range plan.deletes {
+			if err := s.deleteGroup(tx, &plan.deletes[i], applied); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.syncApplied(plan, applied, opts)
+	plan.Applied = true
+	return &plan.ConfigPlan, nil
+}
+
+// appliedConfig collects what a committed apply has to sync to the caches and the audit log.
+type appliedConfig struct {
+	audits        []AuditEntry
+	addedKeys     []models.APIKey
+	removedKeys   map[uint][]uint // 分组 ID -> 删除的密钥 ID
+	deletedGroups map[uint][]uint // 删除的分组 ID -> 其密钥 ID
+}
+
+// syncApplied updates the caches and records the audit entries after the transaction committed.
+// The database is already consistent, so failures here are logged rather than returned.
+func (s *ConfigDocumentService) syncApplied(plan *configPlan, applied *appliedConfig, opts ConfigApplyOptions) {
+	if len(plan.settings) > 0 {
+		if err := s.SettingsManager.Invalidate(); err != nil {
+			logrus.WithError(err).Error("failed to reload system settings")
+		}
+	}
+
+	provider := s.KeyService.KeyProvider
+	if err := provider.AddKeysToStore(applied.addedKeys); err != nil {
+		logrus.WithError(err).Error("failed to add keys to the key pool")
+	}
+	for groupID, keyIDs := range applied.removedKeys {
+		if err := provider.RemoveKeysByIDFromStore(groupID, keyIDs); err != nil {
+			logrus.WithError(err).WithField("groupID", groupID).Error("failed to remove keys from the key pool")
+		}
+	}
+	for groupID, keyIDs := range applied.deletedGroups {
+		if len(keyIDs) == 0 {
+			continue
+		}
+		if err := provider.RemoveKeysFromStore(groupID, keyIDs); err != nil {
+			logrus.WithError(err).WithField("groupID", groupID).Error("failed to remove keys of a deleted group from the key pool")
+		}
+	}
+
+	groupsChanged := len(plan.deletes) > 0
+	for _, planned := range plan.groups {
+		groupsChanged = groupsChanged || planned.changed
+	}
+	if groupsChanged {
+		if err := s.GroupManager.Invalidate(); err != nil {
+			logrus.WithError(err).Error("failed to invalidate group cache")
+		}
+	}
+
+	for _, entry := range applied.audits {
+		s.AuditService.RecordAs(opts.Actor, opts.SourceIP, entry)
+	}
+}
+
+// applyGroup writes a planned group and its key changes within the transaction.
+func (s *ConfigDocumentService) applyGroup(tx *gorm.DB, planned *plannedGroup, applied *appliedConfig) error {
+	group := &planned.group
+	if planned.changed {
+		if planned.before == nil {
+			if err := tx.Create(group).Error; err != nil {
+				return fmt.Errorf("failed to create group '%s': %w", group.Name, err)
+			}
+			applied.audits = append(applied.audits, AuditEntry{
+				Action:     models.AuditActionGroupCreate,
+				TargetType: models.AuditTargetGroup,
+				TargetID:   group.ID,
+				TargetName: group.Name,
+				After:      group,
+				Details:    map[string]any{"source": "config"},
+			})
+		} else {
+			if err := tx.Save(group).Error; err != nil {
+				return fmt.Errorf("failed to update group '%s': %w", group.Name, err)
+			}
+			applied.audits = append(applied.audits, AuditEntry{
+				Action:     models.AuditActionGroupUpdate,
+				TargetType: models.AuditTargetGroup,
+				TargetID:   group.ID,
+				TargetName: group.Name,
+				Before:     planned.before,
+				After:      group,
+				Details:    map[string]any{"source": "config"},
+			})
+		}
+	}
+
+	if len(planned.addKeys) > 0 {
+		newKeys, err := s.KeyService.prepareNewKeys(tx, group.ID, planned.addKeys)
+		if err != nil {
+			return fmt.Errorf("failed to add keys to group '%s': %w", group.Name, err)
+		}
+		if len(newKeys) > 0 {
+			if err := tx.CreateInBatches(&newKeys, chunkSize).Error; err != nil {
+				return fmt.Errorf("failed to add keys to group '%s': %w", group.Name, err)
+			}
+			applied.addedKeys = append(applied.addedKeys, newKeys...)
+		}
+		applied.audits = append(applied.audits, AuditEntry{
+			Action:     models.AuditActionKeysAdd,
+			TargetType: models.AuditTargetGroup,
+			TargetID:   group.ID,
+			TargetName: group.Name,
+			Details:    map[string]any{"added_count": len(newKeys), "ignored_count": len(planned.addKeys) - len(newKeys), "source": "config"},
+		})
+	}
+
+	if len(planned.removeKeys) > 0 {
+		var keyHashes []string
+		for _, keyValue := range planned.removeKeys {
+			// 包含轮换前密钥的哈希，匹配尚未重新加密的密钥
+			keyHashes = append(keyHashes, s.KeyService.EncryptionSvc.Hashes(keyValue)...)
+		}
+		var keyIDs []uint
+		if err := tx.Model(&models.APIKey{}).Where("group_id = ? AND key_hash IN ?", group.ID, keyHashes).Pluck("id", &keyIDs).Error; err != nil {
+			return fmt.Errorf("failed to remove keys from group '%s': %w", group.Name, err)
+		}
+		if len(keyIDs) > 0 {
+			if err := tx.Where("id IN ?", keyIDs).Delete(&models.APIKey{}).Error; err != nil {
+				return fmt.Errorf("failed to remove keys from group '%s': %w", group.Name, err)
+			}
+			applied.removedKeys[group.ID] = keyIDs
+		}
+		applied.audits = append(applied.audits, AuditEntry{
+			Action:     models.AuditActionKeysDelete,
+			TargetType: models.AuditTargetGroup,
+			TargetID:   group.ID,
+			TargetName: group.Name,
+			Details:    map[string]any{"deleted_count": len(keyIDs), "source": "config"},
+		})
+	}
+	return nil
+}
+
+// deleteGroup deletes a pruned group together with its keys within the transaction.
+func (s *ConfigDocumentService) deleteGroup(tx *gorm.DB, group *models.Group, applied *appliedConfig) error {
+	var keyIDs []uint
+	if err := tx.Model(&models.APIKey{}).Where("group_id = ?", group.ID).Pluck("id", &keyIDs).Error; err != nil {
+		return fmt.Errorf("failed to delete group '%s': %w", group.Name, err)
+	}
+	if err := tx.Where("group_id = ?", group.ID).Delete(&models.APIKey{}).Error; err != nil {
+		return fmt.Errorf("failed to delete group '%s': %w", group.Name, err)
+	}
+	if err := tx.Delete(&models.Group{}, group.ID).Error; err != nil {
+		return fmt.Errorf("failed to delete group '%s': %w", group.Name, err)
+	}
+
+	applied.deletedGroups[group.ID] = keyIDs
+	applied.audits = append(applied.audits, AuditEntry{
+		Action:     models.AuditActionGroupDelete,
+		TargetType: models.AuditTargetGroup,
+		TargetID:   group.ID,
+		TargetName: group.Name,
+		Before:     group,
+		Details:    map[string]any{"deleted_keys": len(keyIDs), "source": "config"},
+	})
+	return nil
+}
+
+// buildPlan validates the document and works out every change.
+func (s *ConfigDocumentService) buildPlan(doc *ConfigDocument, prune bool) (*configPlan, error) {
+	if doc.Version != ConfigDocumentVersion {
+		return nil, fmt.Errorf("unsupported config document version %d, expected %d", doc.Version, ConfigDocumentVersion)
+	}
+
+	plan := &configPlan{ConfigPlan: ConfigPlan{Changes: []ConfigChange{}}}
+	if err := s.planSettings(doc.Settings, plan); err != nil {
+		return nil, err
+	}
+
+	var existing []models.Group
+	if err := s.DB.Order("sort asc, id asc").Find(&existing).Error; err != nil {
+		return nil, fmt.Errorf("failed to load groups: %w", err)
+	}
+	existingByName := make(map[string]*models.Group, len(existing))
+	for i := range existing {
+		existingByName[existing[i].Name] = &existing[i]
+	}
+
+	seen := make(map[string]bool, len(doc.Groups))
+	for i := range doc.Groups {
+		groupDoc := &doc.Groups[i]
+		groupDoc.Name = strings.TrimSpace(groupDoc.Name)
+		if seen[groupDoc.Name] {
+			return nil, fmt.Errorf("group '%s' is defined more than once", groupDoc.Name)
+		}
+		seen[groupDoc.Name] = true
+
+		if err := s.planGroup(groupDoc, existingByName[groupDoc.Name], prune, plan); err != nil {
+			return nil, fmt.Errorf("group '%s': %w", groupDoc.Name, err)
+		}
+	}
+
+	if prune {
+		for _, group := range existing {
+			if seen[group.Name] {
+				continue
+			}
+			plan.deletes = append(plan.deletes, group)
+			plan.Changes = append(plan.Changes, ConfigChange{Action: ConfigActionDelete, Kind: ConfigKindGroup, Name: group.Name})
+		}
+	}
+	return plan, nil
+}
+
+// planSettings collects the settings whose value differs from the current one.
+// Settings missing from the document are left unchanged.
+func (s *ConfigDocumentService) planSettings(settings map[string]any, plan *configPlan) error {
+	if len(settings) == 0 {
+		return nil
+	}
+
+	// 与设置接口一致：清理代理密钥并校验告警地址
+	if proxyKeys, ok := settings["proxy_keys"].(string); ok {
+		settings["proxy_keys"] = strings.Join(utils.SplitAndTrim(proxyKeys, ","), ",")
+	}
+	if webhooks, ok := settings["alert_webhooks"].(string); ok {
+		if err := alert.ValidateWebhooks(webhooks); err != nil {
+			return fmt.Errorf("settings: %w", err)
+		}
+	}
+	if err := s.SettingsManager.ValidateSettings(settings); err != nil {
+		return fmt.Errorf("settings: %w", err)
+	}
+
+	current, err := toFieldMap(s.SettingsManager.GetSettings())
+	if err != nil {
+		return err
+	}
+
+	changed := make(map[string]any)
+	for key, value := range settings {
+		if !reflect.DeepEqual(current[key], value) {
+			changed[key] = value
+		}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+
+	after := make(map[string]any, len(changed))
+	for key, value := range changed {
+		after[key] = value
+	}
+	before := make(map[string]any, len(changed))
+	for key := range changed {
+		before[key] = current[key]
+	}
+	changes, err := diffStates(before, after)
+	if err != nil {
+		return err
+	}
+
+	plan.settings = changed
+	plan.settingsBefore = current
+	plan.Changes = append(plan.Changes, ConfigChange{Action: ConfigActionUpdate, Kind: ConfigKindSettings, Name: "system", Changes: changes})
+	return nil
+}
+
+// planGroup validates a group document against the existing group, if any, and records its changes.
+func (s *ConfigDocumentService) planGroup(groupDoc *GroupDocument, existing *models.Group, prune bool, plan *configPlan) error {
+	planned := &plannedGroup{}
+	if existing != nil {
+		before := *existing
+		planned.before = &before
+		planned.group = *existing
+	}
+	if err := s.applyGroupDocument(&planned.group, groupDoc); err != nil {
+		return err
+	}
+
+	var beforeDoc *GroupDocument
+	if existing != nil {
+		var err error
+		if beforeDoc, err = NewGroupDocument(existing); err != nil {
+			return err
+		}
+	}
+	afterDoc, err := NewGroupDocument(&planned.group)
+	if err != nil {
+		return err
+	}
+	changes, err := diffStates(beforeDoc, afterDoc)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case existing == nil:
+		planned.changed = true
+		plan.Changes = append(plan.Changes, ConfigChange{Action: ConfigActionCreate, Kind: ConfigKindGroup, Name: groupDoc.Name, Changes: changes})
+	case len(changes) > 0:
+		planned.changed = true
+		plan.Changes = append(plan.Changes, ConfigChange{Action: ConfigActionUpdate, Kind: ConfigKindGroup, Name: groupDoc.Name, Changes: changes})
+	}
+
+	if groupDoc.Keys != nil {
+		if err := s.planKeys(planned, groupDoc.Keys, prune); err != nil {
+			return err
+		}
+		if len(planned.addKeys) > 0 {
+			plan.Changes = append(plan.Changes, ConfigChange{Action: ConfigActionCreate, Kind: ConfigKindKeys, Name: groupDoc.Name, Count: len(planned.addKeys)})
+		}
+		if len(planned.removeKeys) > 0 {
+			plan.Changes = append(plan.Changes, ConfigChange{Action: ConfigActionDelete, Kind: ConfigKindKeys, Name: groupDoc.Name, Count: len(planned.removeKeys)})
+		}
+	}
+
+	plan.groups = append(plan.groups, planned)
+	return nil
+}
+
+// planKeys works out which keys of a managed group must be added or, with prune, removed.
+func (s *ConfigDocumentService) planKeys(planned *plannedGroup, keys []string, prune bool) error {
+	wanted := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+		hash := s.KeyService.EncryptionSvc.Hash(key)
+		if wanted[hash] {
+			continue
+		}
+		wanted[hash] = true
+		planned.addKeys = append(planned.addKeys, key)
+	}
+	if planned.before == nil {
+		return nil
+	}
+
+	existingKeys, err := s.loadKeys(planned.group.ID)
+	if err != nil {
+		return err
+	}
+	existingHashes := make(map[string]bool, len(existingKeys))
+	for _, key := range existingKeys {
+		existingHashes[key.hash] = true
+		if prune && !wanted[key.hash] {
+			planned.removeKeys = append(planned.removeKeys, key.value)
+		}
+	}
+	planned.addKeys = slices.DeleteFunc(planned.addKeys, func(key string) bool {
+		return existingHashes[s.KeyService.EncryptionSvc.Hash(key)]
+	})
+	return nil
+}
+
+// applyGroupDocument validates a group document and copies it onto the group,
+// following the same rules as the group API.
+func (s *ConfigDocumentService) applyGroupDocument(group *models.Group, doc *GroupDocument) error {
+	if !IsValidGroupName(doc.Name) {
+		return errors.New("invalid group name, only lowercase letters, numbers, hyphens and underscores are allowed (1-100 characters)")
+	}
+
+	channelType := strings.TrimSpace(doc.ChannelType)
+	if !IsValidChannelType(channelType) {
+		return fmt.Errorf("invalid channel type '%s'", doc.ChannelType)
+	}
+
+	testModel := strings.TrimSpace(doc.TestModel)
+	if testModel == "" {
+		return errors.New("test_model is required")
+	}
+
+	upstreamsJSON, err := json.Marshal(doc.Upstreams)
+	if err != nil {
+		return err
+	}
+	if doc.Upstreams == nil {
+		upstreamsJSON = nil
+	}
+	upstreams, err := ValidateAndCleanUpstreams(upstreamsJSON)
+	if err != nil {
+		return err
+	}
+
+	groupConfig, err := ValidateAndCleanGroupConfig(s.SettingsManager, doc.Config)
+	if err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+
+	validationEndpoint := strings.TrimSpace(doc.ValidationEndpoint)
+	if !IsValidValidationEndpoint(validationEndpoint) {
+		return fmt.Errorf("invalid validation_endpoint '%s', it must be a path starting with /", doc.ValidationEndpoint)
+	}
+
+	headerRules, err := NormalizeHeaderRules(doc.HeaderRules)
+	if err != nil {
+		return fmt.Errorf("invalid header rules: %w", err)
+	}
+	responseHeaderRules, err := NormalizeHeaderRules(doc.ResponseHeaderRules)
+	if err != nil {
+		return fmt.Errorf("invalid response header rules: %w", err)
+	}
+	bodyRules, err := ValidateAndCleanBodyRules(doc.BodyRules)
+	if err != nil {
+		return fmt.Errorf("invalid body rules: %w", err)
+	}
+	redactionRules, err := ValidateAndCleanRedactionRules(doc.RedactionRules)
+	if err != nil {
+		return fmt.Errorf("invalid redaction rules: %w", err)
+	}
+
+	group.Name = doc.Name
+	group.DisplayName = strings.TrimSpace(doc.DisplayName)
+	group.Description = strings.TrimSpace(doc.Description)
+	group.ChannelType = channelType
+	group.Sort = doc.Sort
+	group.TestModel = testModel
+	group.ValidationEndpoint = validationEndpoint
+	group.Upstreams = upstreams
+	group.ParamOverrides = doc.ParamOverrides
+	group.Config = groupConfig
+	group.HeaderRules = headerRules
+	group.ResponseHeaderRules = responseHeaderRules
+	group.BodyRules = bodyRules
+	group.RedactionRules = redactionRules
+	group.ProxyKeys = strings.TrimSpace(doc.ProxyKeys)
+	return nil
+}
+
+// groupKey is a decrypted key of a group.
+type groupKey struct {
+	value string
+	hash  string
+}
+
+func (s *ConfigDocumentService) loadKeys(groupID uint) ([]groupKey, error) {
+	var apiKeys []models.APIKey
+	if err := s.DB.Select("id, key_value").Where("group_id = ?", groupID).Order("id asc").Find(&apiKeys).Error; err != nil {
+		return nil, fmt.Errorf("failed to load keys: %w", err)
+	}
+
+	keys := make([]groupKey, 0, len(apiKeys))
+	for _, apiKey := range apiKeys {
+		value, err := s.KeyService.EncryptionSvc.Decrypt(apiKey.KeyValue)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt key %d: %w", apiKey.ID, err)
+		}
+		// 按主密钥重新计算哈希，兼容尚未重新加密的密钥
+		keys = append(keys, groupKey{value: value, hash: s.KeyService.EncryptionSvc.Hash(value)})
+	}
+	return keys, nil
+}
+
+// NewGroupDocument converts a stored group into its document form, without keys.
+func NewGroupDocument(group *models.Group) (*GroupDocument, error) {
+	doc := &GroupDocument{
+		Name:               group.Name,
+		DisplayName:        group.DisplayName,
+		Description:        group.Description,
+		ChannelType:        group.ChannelType,
+		Sort:               group.Sort,
+		TestModel:          group.TestModel,
+		ValidationEndpoint: group.ValidationEndpoint,
+		ParamOverrides:     group.ParamOverrides,
+		Config:             group.Config,
+		ProxyKeys:          group.ProxyKeys,
+	}
+
+	fields := []struct {
+		name   string
+		data   []byte
+		target any
+	}{
+		{"upstreams", group.Upstreams, &doc.Upstreams},
+		{"header_rules", group.HeaderRules, &doc.HeaderRules},
+		{"response_header_rules", group.ResponseHeaderRules, &doc.ResponseHeaderRules},
+		{"body_rules", group.BodyRules, &doc.BodyRules},
+		{"redaction_rules", group.RedactionRules, &doc.RedactionRules},
+	}
+	for _, field := range fields {
+		if len(field.data) == 0 {
+			continue
+		}
+		if err := json.Unmarshal(field.data, field.target); err != nil {
+			return nil, fmt.Errorf("group '%s': failed to parse %s: %w", group.Name, field.name, err)
+		}
+	}
+	return doc, nil
+}
+
+// ParseConfigDocument decodes a YAML or JSON configuration document. Unknown fields are rejected.
+func ParseConfigDocument(data []byte, format string) (*ConfigDocument, error) {
+	var doc ConfigDocument
+	if err := ParseDocument(data, format, &doc); err != nil {
+		return nil, fmt.Errorf("invalid config document: %w", err)
+	}
+	return &doc, nil
+}
+
+// ParseDocument decodes YAML or JSON into target using the JSON field names. Unknown fields are rejected.
+func ParseDocument(data []byte, format string, target any) error {
+	if format == ConfigFormatYAML {
+		var value any
+		if err := yaml.Unmarshal(data, &value); err != nil {
+			return fmt.Errorf("invalid YAML: %w", err)
+		}
+		var err error
+		if data, err = json.Marshal(value); err != nil {
+			return fmt.Errorf("invalid YAML: %w", err)
+		}
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(target)
+}
+
+// MarshalDocument encodes a value as YAML or JSON using its JSON field names and order.
+func MarshalDocument(value any, format string) ([]byte, error) {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if format != ConfigFormatYAML {
+		return append(data, '\n'), nil
+	}
+
+	// JSON 也是合法的 YAML，解析为节点后改为块格式输出，保留字段顺序
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, err
+	}
+	resetYAMLStyle(&node)
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&node); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func resetYAMLStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		resetYAMLStyle(child)
+	}
+}
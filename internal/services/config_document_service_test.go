@@ -0,0 +1,203 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"gpt-load/internal/config"
+	"gpt-load/internal/encryption"
+	"gpt-load/internal/keypool"
+	"gpt-load/internal/models"
+	"gpt-load/internal/store"
+
+	"gorm.io/gorm"
+)
+
+type configDocumentTestEnv struct {
+	db    *gorm.DB
+	store store.Store
+	svc   *ConfigDocumentService
+}
+
+func newConfigDocumentTestEnv(t *testing.T) *configDocumentTestEnv {
+	t.Helper()
+
+	db := newTestDB(t, &models.Group{}, &models.APIKey{}, &models.AuditLog{})
+
+	encryptionSvc, err := encryption.NewService("")
+	if err != nil {
+		t.Fatalf("failed to create encryption service: %v", err)
+	}
+	memoryStore := store.NewMemoryStore()
+	settingsManager := config.NewSystemSettingsManager()
+	provider := keypool.NewProvider(db, memoryStore, settingsManager, encryptionSvc, nil)
+	keyService := NewKeyService(db, provider, nil, encryptionSvc)
+	svc := NewConfigDocumentService(db, settingsManager, &GroupManager{}, keyService, NewAuditService(db))
+	return &configDocumentTestEnv{db: db, store: memoryStore, svc: svc}
+}
+
+func testGroupDocument(name string, sort int, keys ...string) GroupDocument {
+	return GroupDocument{
+		Name:        name,
+		ChannelType: "openai",
+		Sort:        sort,
+		TestModel:   "gpt-4o-mini",
+		Upstreams:   []UpstreamDefinition{{URL: "https://api.openai.com", Weight: 1}},
+		Keys:        keys,
+	}
+}
+
+// groupKeys returns the sorted key values of a group, reporting keys missing from the key pool.
+func (e *configDocumentTestEnv) groupKeys(t *testing.T, name string) []string {
+	t.Helper()
+
+	var group models.Group
+	if err := e.db.Where("name = ?", name).First(&group).Error; err != nil {
+		t.Fatalf("failed to load group '%s': %v", name, err)
+	}
+	var keys []models.APIKey
+	if err := e.db.Where("group_id = ?", group.ID).Find(&keys).Error; err != nil {
+		t.Fatalf("failed to load keys: %v", err)
+	}
+	values := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if cached, _ := e.store.Exists(fmt.Sprintf("key:%d", key.ID)); !cached {
+			t.Errorf("key %s of group '%s' is not in the key pool", key.KeyValue, name)
+		}
+		values = append(values, key.KeyValue)
+	}
+	slices.Sort(values)
+	return values
+}
+
+func TestConfigApplyRollsBackOnFailure(t *testing.T) {
+	env := newConfigDocumentTestEnv(t)
+
+	initial := &ConfigDocument{Version: ConfigDocumentVersion, Groups: []GroupDocument{
+		testGroupDocument("main", 1, "sk-key-1", "sk-key-2"),
+		testGroupDocument("legacy", 2, "sk-legacy-1"),
+	}}
+	if _, err := env.svc.Apply(initial, ConfigApplyOptions{Actor: "test"}); err != nil {
+		t.Fatalf("initial Apply() error = %v", err)
+	}
+
+	// 新分组写入失败时，之前的分组更新、密钥变更和删除都必须回滚
+	failCreate := errors.New("simulated write failure")
+	if err := env.db.Callback().Create().Before("gorm:create").Register("test:fail_group", func(tx *gorm.DB) {
+		if group, ok := tx.Statement.Dest.(*models.Group); ok && group.Name == "fresh" {
+			_ = tx.AddError(failCreate)
+		}
+	}); err != nil {
+		t.Fatalf("failed to register callback: %v", err)
+	}
+
+	doc := &ConfigDocument{Version: ConfigDocumentVersion, Groups: []GroupDocument{
+		testGroupDocument("main", 5, "sk-key-2", "sk-key-3"),
+		testGroupDocument("fresh", 3, "sk-fresh-1"),
+	}}
+	if _, err := env.svc.Apply(doc, ConfigApplyOptions{Prune: true, Actor: "test"}); !errors.Is(err, failCreate) {
+		t.Fatalf("Apply() error = %v, want the write failure", err)
+	}
+
+	var main models.Group
+	env.db.Where("name = ?", "main").First(&main)
+	if main.Sort != 1 {
+		t.Errorf("group update was not rolled back, sort = %d", main.Sort)
+	}
+	if keys := env.groupKeys(t, "main"); !slices.Equal(keys, []string{"sk-key-1", "sk-key-2"}) {
+		t.Errorf("keys of 'main' = %v, want the keys before the failed apply", keys)
+	}
+	if keys := env.groupKeys(t, "legacy"); !slices.Equal(keys, []string{"sk-legacy-1"}) {
+		t.Errorf("pruned group 'legacy' keys = %v, want it unchanged", keys)
+	}
+	if exists, _ := env.store.Exists("key:4"); exists {
+		t.Error("a key of the failed apply was added to the key pool")
+	}
+
+	if err := env.db.Callback().Create().Remove("test:fail_group"); err != nil {
+		t.Fatalf("failed to remove callback: %v", err)
+	}
+	plan, err := env.svc.Apply(doc, ConfigApplyOptions{Prune: true, Actor: "test"})
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if !plan.Applied {
+		t.Error("plan not marked as applied")
+	}
+
+	if keys := env.groupKeys(t, "main"); !slices.Equal(keys, []string{"sk-key-2", "sk-key-3"}) {
+		t.Errorf("keys of 'main' = %v", keys)
+	}
+	if keys := env.groupKeys(t, "fresh"); !slices.Equal(keys, []string{"sk-fresh-1"}) {
+		t.Errorf("keys of 'fresh' = %v", keys)
+	}
+	var remaining int64
+	env.db.Model(&models.Group{}).Where("name = ?", "legacy").Count(&remaining)
+	if remaining != 0 {
+		t.Error("pruned group 'legacy' was not deleted")
+	}
+	if exists, _ := env.store.Exists("key:3"); exists {
+		t.Error("key of the pruned group is still in the key pool")
+	}
+	if exists, _ := env.store.Exists("key:1"); exists {
+		t.Error("pruned key is still in the key pool")
+	}
+
+	var audits []models.AuditLog
+	env.db.Order("id asc").Find(&audits)
+	var actions []string
+	for _, audit := range audits {
+		actions = append(actions, audit.Action)
+	}
+	for _, want := range []string{models.AuditActionGroupUpdate, models.AuditActionGroupCreate, models.AuditActionKeysAdd, models.AuditActionKeysDelete, models.AuditActionGroupDelete} {
+		if !slices.Contains(actions, want) {
+			t.Errorf("audit actions %v do not contain %s", actions, want)
+		}
+	}
+}
+
+func TestConfigApplyValidatesBeforeWriting(t *testing.T) {
+	env := newConfigDocumentTestEnv(t)
+
+	tests := []struct {
+		name string
+		doc  *ConfigDocument
+		want string
+	}{
+		{
+			name: "unsupported version",
+			doc:  &ConfigDocument{Version: 2},
+			want: "unsupported config document version",
+		},
+		{
+			name: "duplicate group",
+			doc: &ConfigDocument{Version: ConfigDocumentVersion, Groups: []GroupDocument{
+				testGroupDocument("dup", 1), testGroupDocument("dup", 2),
+			}},
+			want: "defined more than once",
+		},
+		{
+			name: "invalid last group",
+			doc: &ConfigDocument{Version: ConfigDocumentVersion, Groups: []GroupDocument{
+				testGroupDocument("valid", 1, "sk-valid-1"),
+				{Name: "broken", ChannelType: "unknown", TestModel: "m"},
+			}},
+			want: "invalid channel type",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := env.svc.Apply(tt.doc, ConfigApplyOptions{}); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Apply() error = %v, want %q", err, tt.want)
+			}
+			var groups int64
+			env.db.Model(&models.Group{}).Count(&groups)
+			if groups != 0 {
+				t.Errorf("%d groups written by an invalid document", groups)
+			}
+		})
+	}
+}
@@ -61,11 +61,42 @@ func (gm *GroupManager) Initialize() error {
 				g.HeaderRuleList = []models.HeaderRule{}
 			}
 
+			// Parse response header rules with error handling
+			if len(group.ResponseHeaderRules) > 0 {
+				if err := json.Unmarshal(group.ResponseHeaderRules, &g.ResponseHeaderRuleList); err != nil {
+					logrus.WithError(err).WithField("group_name", g.Name).Warn("Failed to parse response header rules for group")
+					g.ResponseHeaderRuleList = []models.HeaderRule{}
+				}
+			} else {
+				g.ResponseHeaderRuleList = []models.HeaderRule{}
+			}
+
+			// Parse body rules with error handling
+			if len(group.BodyRules) > 0 {
+				if err := json.Unmarshal(group.BodyRules, &g.BodyRuleList); err != nil {
+					logrus.WithError(err).WithField("group_name", g.Name).Warn("Failed to parse body rules for group")
+					g.BodyRuleList = []models.BodyRule{}
+				}
+			} else {
+				g.BodyRuleList = []models.BodyRule{}
+			}
+
+			// Parse log redaction rules with error handling
+			if len(group.RedactionRules) > 0 {
+				if err := json.Unmarshal(group.RedactionRules, &g.RedactionRuleList); err != nil {
+					logrus.WithError(err).WithField("group_name", g.Name).Warn("Failed to parse redaction rules for group")
+					g.RedactionRuleList = []models.RedactionRule{}
+				}
+			} else {
+				g.RedactionRuleList = []models.RedactionRule{}
+			}
+
 			groupMap[g.Name] = &g
 			logrus.WithFields(logrus.Fields{
 				"group_name":         g.Name,
 				"effective_config":   g.EffectiveConfig,
 				"header_rules_count": len(g.HeaderRuleList),
+				"body_rules_count":   len(g.BodyRuleList),
 			}).Debug("Loaded group with effective config")
 		}
 
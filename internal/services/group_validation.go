@@ -0,0 +1,242 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"gpt-load/internal/channel"
+	"gpt-load/internal/config"
+	"gpt-load/internal/models"
+	"gpt-load/internal/redact"
+	"gpt-load/internal/utils"
+	"net/http"
+	"path"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"gorm.io/datatypes"
+)
+
+// IsValidChannelType checks if the channel type is valid by checking against the registered channels.
+func IsValidChannelType(channelType string) bool {
+	channels := channel.GetChannels()
+	for _, t := range channels {
+		if t == channelType {
+			return true
+		}
+	}
+	return false
+}
+
+// UpstreamDefinition defines the structure for an upstream in the request.
+type UpstreamDefinition struct {
+	URL    string `json:"url"`
+	Weight int    `json:"weight"`
+}
+
+// ValidateAndCleanUpstreams validates and cleans the upstreams JSON.
+func ValidateAndCleanUpstreams(upstreams json.RawMessage) (datatypes.JSON, error) {
+	if len(upstreams) == 0 {
+		return nil, fmt.Errorf("upstreams field is required")
+	}
+
+	var defs []UpstreamDefinition
+	if err := json.Unmarshal(upstreams, &defs); err != nil {
+		return nil, fmt.Errorf("invalid format for upstreams: %w", err)
+	}
+
+	if len(defs) == 0 {
+		return nil, fmt.Errorf("at least one upstream is required")
+	}
+
+	for i := range defs {
+		defs[i].URL = strings.TrimSpace(defs[i].URL)
+		if defs[i].URL == "" {
+			return nil, fmt.Errorf("upstream URL cannot be empty")
+		}
+		// Basic URL format validation
+		if !strings.HasPrefix(defs[i].URL, "http://") && !strings.HasPrefix(defs[i].URL, "https://") {
+			return nil, fmt.Errorf("invalid URL format for upstream: %s", defs[i].URL)
+		}
+		if defs[i].Weight <= 0 {
+			return nil, fmt.Errorf("upstream weight must be a positive integer")
+		}
+	}
+
+	cleanedUpstreams, err := json.Marshal(defs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal cleaned upstreams: %w", err)
+	}
+
+	return cleanedUpstreams, nil
+}
+
+// IsValidGroupName checks if the group name is valid.
+func IsValidGroupName(name string) bool {
+	if name == "" {
+		return false
+	}
+	// 允许使用小写字母、数字、下划线和中划线，长度在 1 到 100 个字符之间
+	match, _ := regexp.MatchString("^[a-z0-9_-]{1,100}$", name)
+	return match
+}
+
+// IsValidValidationEndpoint checks if the validation endpoint is a valid path.
+func IsValidValidationEndpoint(endpoint string) bool {
+	if endpoint == "" {
+		return true
+	}
+	if !strings.HasPrefix(endpoint, "/") {
+		return false
+	}
+	if strings.Contains(endpoint, "://") {
+		return false
+	}
+	return true
+}
+
+// ValidateAndCleanGroupConfig validates the group config against the GroupConfig struct and system-defined rules.
+func ValidateAndCleanGroupConfig(settingsManager *config.SystemSettingsManager, configMap map[string]any) (map[string]any, error) {
+	if configMap == nil {
+		return nil, nil
+	}
+
+	// 1. Check for unknown fields by comparing against the GroupConfig struct definition.
+	var tempGroupConfig models.GroupConfig
+	groupConfigType := reflect.TypeOf(tempGroupConfig)
+	validFields := make(map[string]bool)
+	for i := 0; i < groupConfigType.NumField(); i++ {
+		jsonTag := groupConfigType.Field(i).Tag.Get("json")
+		fieldName := strings.Split(jsonTag, ",")[0]
+		if fieldName != "" && fieldName != "-" {
+			validFields[fieldName] = true
+		}
+	}
+
+	for key := range configMap {
+		if !validFields[key] {
+			return nil, fmt.Errorf("unknown config field: '%s'", key)
+		}
+	}
+
+	// 2. Validate the values of the provided fields using the central system settings validator.
+	if err := settingsManager.ValidateGroupConfigOverrides(configMap); err != nil {
+		return nil, err
+	}
+
+	// 3. Unmarshal and marshal back to clean the map and ensure correct types.
+	configBytes, err := json.Marshal(configMap)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config map: %w", err)
+	}
+
+	var validatedConfig models.GroupConfig
+	if err := json.Unmarshal(configBytes, &validatedConfig); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal into validated config: %w", err)
+	}
+
+	validatedBytes, err := json.Marshal(validatedConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal validated config: %w", err)
+	}
+	var finalMap map[string]any
+	if err := json.Unmarshal(validatedBytes, &finalMap); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal into final map: %w", err)
+	}
+
+	return finalMap, nil
+}
+
+// NormalizeHeaderRules validates header rules, normalizes keys to canonical form and serializes them for storage.
+func NormalizeHeaderRules(rules []models.HeaderRule) (datatypes.JSON, error) {
+	normalizedHeaderRules := make([]models.HeaderRule, 0, len(rules))
+	seenKeys := make(map[string]bool)
+
+	for _, rule := range rules {
+		key := strings.TrimSpace(rule.Key)
+		if key == "" {
+			continue
+		}
+
+		// Normalize to canonical form
+		canonicalKey := http.CanonicalHeaderKey(key)
+
+		if rule.Action != "set" && rule.Action != "remove" {
+			return nil, fmt.Errorf("invalid action '%s' for header %s", rule.Action, canonicalKey)
+		}
+
+		if err := utils.ValidateHeaderValue(rule.Value); err != nil {
+			return nil, fmt.Errorf("invalid value for header %s: %w", canonicalKey, err)
+		}
+
+		for _, pattern := range append(append([]string{}, rule.Paths...), rule.Models...) {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("invalid condition pattern '%s' for header %s", pattern, canonicalKey)
+			}
+		}
+
+		// Check for duplicate keys under the same conditions
+		seenKey := canonicalKey + "|" + strings.Join(rule.Paths, ",") + "|" + strings.Join(rule.Models, ",")
+		if seenKeys[seenKey] {
+			return nil, fmt.Errorf("Duplicate header key: %s", canonicalKey)
+		}
+		seenKeys[seenKey] = true
+
+		normalizedHeaderRules = append(normalizedHeaderRules, models.HeaderRule{
+			Key:    canonicalKey,
+			Value:  rule.Value,
+			Action: rule.Action,
+			Paths:  rule.Paths,
+			Models: rule.Models,
+		})
+	}
+
+	headerRulesBytes, err := json.Marshal(normalizedHeaderRules)
+	if err != nil {
+		return nil, fmt.Errorf("failed to process header rules: %w", err)
+	}
+	return headerRulesBytes, nil
+}
+
+// ValidateAndCleanBodyRules validates the body rules and serializes them for storage.
+func ValidateAndCleanBodyRules(rules []models.BodyRule) (datatypes.JSON, error) {
+	cleaned := make([]models.BodyRule, 0, len(rules))
+	for i, rule := range rules {
+		rule.Action = strings.TrimSpace(rule.Action)
+		rule.Path = strings.TrimSpace(rule.Path)
+		rule.To = strings.TrimSpace(rule.To)
+		if err := utils.ValidateBodyRule(rule); err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i+1, err)
+		}
+		cleaned = append(cleaned, rule)
+	}
+
+	bodyRulesBytes, err := json.Marshal(cleaned)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal body rules: %w", err)
+	}
+	return bodyRulesBytes, nil
+}
+
+// ValidateAndCleanRedactionRules validates the custom log redaction rules and serializes them for storage.
+func ValidateAndCleanRedactionRules(rules []models.RedactionRule) (datatypes.JSON, error) {
+	cleaned := make([]models.RedactionRule, 0, len(rules))
+	seen := make(map[string]bool, len(rules))
+	for i, rule := range rules {
+		rule.Name = strings.TrimSpace(rule.Name)
+		if err := redact.ValidateRule(rule); err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i+1, err)
+		}
+		if seen[rule.Name] {
+			return nil, fmt.Errorf("rule %d: duplicate rule name '%s'", i+1, rule.Name)
+		}
+		seen[rule.Name] = true
+		cleaned = append(cleaned, rule)
+	}
+
+	redactionRulesBytes, err := json.Marshal(cleaned)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal redaction rules: %w", err)
+	}
+	return redactionRulesBytes, nil
+}
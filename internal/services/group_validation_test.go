@@ -0,0 +1,78 @@
+package services
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"gpt-load/internal/models"
+)
+
+func TestNormalizeHeaderRules(t *testing.T) {
+	data, err := NormalizeHeaderRules([]models.HeaderRule{
+		{Key: "x-tenant", Value: "${ENV:GPT_LOAD_HEADER_TENANT}", Action: "set"},
+		{Key: "  ", Value: "ignored", Action: "set"},
+		{Key: "x-drop", Action: "remove"},
+	})
+	if err != nil {
+		t.Fatalf("NormalizeHeaderRules() error = %v", err)
+	}
+
+	var rules []models.HeaderRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		t.Fatalf("failed to decode rules: %v", err)
+	}
+	if len(rules) != 2 || rules[0].Key != "X-Tenant" || rules[1].Key != "X-Drop" {
+		t.Errorf("normalized rules = %+v", rules)
+	}
+}
+
+func TestNormalizeHeaderRulesErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []models.HeaderRule
+		want  string
+	}{
+		{
+			name:  "secret environment variable",
+			rules: []models.HeaderRule{{Key: "X-Leak", Value: "${ENV:AUTH_KEY}", Action: "set"}},
+			want:  "not allowed",
+		},
+		{
+			name:  "invalid action",
+			rules: []models.HeaderRule{{Key: "X-A", Action: "append"}},
+			want:  "invalid action",
+		},
+		{
+			name:  "invalid pattern",
+			rules: []models.HeaderRule{{Key: "X-A", Action: "set", Paths: []string{"/v1/["}}},
+			want:  "invalid condition pattern",
+		},
+		{
+			name: "duplicate key",
+			rules: []models.HeaderRule{
+				{Key: "x-a", Value: "1", Action: "set"},
+				{Key: "X-A", Value: "2", Action: "set"},
+			},
+			want: "Duplicate header key",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NormalizeHeaderRules(tt.rules)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("NormalizeHeaderRules() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeHeaderRulesConditionsAllowSameKey(t *testing.T) {
+	_, err := NormalizeHeaderRules([]models.HeaderRule{
+		{Key: "X-A", Value: "1", Action: "set", Models: []string{"gpt-4*"}},
+		{Key: "X-A", Value: "2", Action: "set", Models: []string{"claude-*"}},
+	})
+	if err != nil {
+		t.Errorf("NormalizeHeaderRules() error = %v, want rules with different conditions to be accepted", err)
+	}
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"fmt"
+	"gpt-load/internal/alert"
 	"gpt-load/internal/config"
 	"gpt-load/internal/encryption"
 	"gpt-load/internal/keypool"
@@ -29,10 +30,17 @@ type KeyManualValidationService struct {
 	SettingsManager *config.SystemSettingsManager
 	ConfigManager   types.ConfigManager
 	EncryptionSvc   encryption.Service
+	Alerts          *alert.Service
+}
+
+// validationOutcome is the result of validating a single key.
+type validationOutcome struct {
+	isValid   bool
+	wasActive bool
 }
 
 // NewKeyManualValidationService creates a new KeyManualValidationService.
-func NewKeyManualValidationService(db *gorm.DB, validator *keypool.KeyValidator, taskService *TaskService, settingsManager *config.SystemSettingsManager, configManager types.ConfigManager, encryptionSvc encryption.Service) *KeyManualValidationService {
+func NewKeyManualValidationService(db *gorm.DB, validator *keypool.KeyValidator, taskService *TaskService, settingsManager *config.SystemSettingsManager, configManager types.ConfigManager, encryptionSvc encryption.Service, alerts *alert.Service) *KeyManualValidationService {
 	return &KeyManualValidationService{
 		DB:              db,
 		Validator:       validator,
@@ -40,6 +48,7 @@ func NewKeyManualValidationService(db *gorm.DB, validator *keypool.KeyValidator,
 		SettingsManager: settingsManager,
 		ConfigManager:   configManager,
 		EncryptionSvc:   encryptionSvc,
+		Alerts:          alerts,
 	}
 }
 
@@ -82,7 +91,7 @@ func (s *KeyManualValidationService) runValidation(group *models.Group, keys []m
 	logrus.WithFields(logFields).Info("Starting manual validation")
 
 	jobs := make(chan models.APIKey, len(keys))
-	results := make(chan bool, len(keys))
+	results := make(chan validationOutcome, len(keys))
 
 	concurrency := group.EffectiveConfig.KeyValidationConcurrency
 
@@ -103,13 +112,16 @@ func (s *KeyManualValidationService) runValidation(group *models.Group, keys []m
 	}()
 
 	validCount := 0
+	newlyInvalidCount := 0
 	processedCount := 0
 	lastUpdateTime := time.Now()
 
-	for isValid := range results {
+	for outcome := range results {
 		processedCount++
-		if isValid {
+		if outcome.isValid {
 			validCount++
+		} else if outcome.wasActive {
+			newlyInvalidCount++
 		}
 
 		// Throttle progress updates to once per second
@@ -137,17 +149,19 @@ func (s *KeyManualValidationService) runValidation(group *models.Group, keys []m
 		logrus.Errorf("Failed to end task for group %s: %v", group.Name, err)
 	}
 	logrus.Infof("Manual validation finished for group %s: %+v", group.Name, result)
+
+	s.Alerts.ValidationFinished(group, result.TotalKeys, result.InvalidKeys, newlyInvalidCount)
 }
 
 // validationResult 包含验证结果信息
-func (s *KeyManualValidationService) validationWorker(wg *sync.WaitGroup, group *models.Group, jobs <-chan models.APIKey, results chan<- bool) {
+func (s *KeyManualValidationService) validationWorker(wg *sync.WaitGroup, group *models.Group, jobs <-chan models.APIKey, results chan<- validationOutcome) {
 	defer wg.Done()
 	for key := range jobs {
 		// Decrypt the key before validation
 		decryptedKey, err := s.EncryptionSvc.Decrypt(key.KeyValue)
 		if err != nil {
 			logrus.WithError(err).WithField("key_id", key.ID).Error("Manual validation: Failed to decrypt key for validation, marking as invalid")
-			results <- false
+			results <- validationOutcome{wasActive: key.Status == models.KeyStatusActive}
 			continue
 		}
 
@@ -156,6 +170,6 @@ func (s *KeyManualValidationService) validationWorker(wg *sync.WaitGroup, group
 		keyForValidation.KeyValue = decryptedKey
 
 		isValid, _ := s.Validator.ValidateSingleKey(&keyForValidation, group)
-		results <- isValid
+		results <- validationOutcome{isValid: isValid, wasActive: key.Status == models.KeyStatusActive}
 	}
 }
@@ -0,0 +1,198 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"gpt-load/internal/encryption"
+	"gpt-load/internal/keypool"
+	"gpt-load/internal/models"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	reencryptTimeout   = 30 * time.Minute
+	reencryptBatchSize = 500
+)
+
+// ErrEncryptionDisabled is returned when re-encryption is requested without ENCRYPTION_KEY.
+var ErrEncryptionDisabled = errors.New("encryption is disabled, configure ENCRYPTION_KEY first")
+
+// EncryptionStatus shows how many stored values are not yet encrypted with the primary key.
+type EncryptionStatus struct {
+	Enabled     bool   `json:"enabled"`
+	KeyID       string `json:"key_id,omitempty"`
+	PendingKeys int64  `json:"pending_keys"`
+	PendingLogs int64  `json:"pending_logs"`
+}
+
+// KeyReencryptResult holds the result of a re-encryption task.
+type KeyReencryptResult struct {
+	KeyID           string `json:"key_id"`
+	ReencryptedKeys int    `json:"reencrypted_keys"`
+	ReencryptedLogs int    `json:"reencrypted_logs"`
+	FailedKeys      int    `json:"failed_keys"`
+	FailedLogs      int    `json:"failed_logs"`
+}
+
+// KeyReencryptService rewrites the stored keys with the primary encryption key after a key rotation.
+// It works in small batches, so the proxy keeps serving while it runs.
+type KeyReencryptService struct {
+	DB            *gorm.DB
+	TaskService   *TaskService
+	KeyProvider   *keypool.KeyProvider
+	EncryptionSvc encryption.Service
+}
+
+// NewKeyReencryptService creates a new KeyReencryptService.
+func NewKeyReencryptService(db *gorm.DB, taskService *TaskService, keyProvider *keypool.KeyProvider, encryptionSvc encryption.Service) *KeyReencryptService {
+	return &KeyReencryptService{
+		DB:            db,
+		TaskService:   taskService,
+		KeyProvider:   keyProvider,
+		EncryptionSvc: encryptionSvc,
+	}
+}
+
+// GetStatus counts the keys and request logs that still use a previous key.
+func (s *KeyReencryptService) GetStatus() (*EncryptionStatus, error) {
+	status := &EncryptionStatus{KeyID: s.EncryptionSvc.KeyID()}
+	if status.KeyID == "" {
+		return status, nil
+	}
+	status.Enabled = true
+
+	if err := s.pendingQuery(&models.APIKey{}).Count(&status.PendingKeys).Error; err != nil {
+		return nil, fmt.Errorf("failed to count keys: %w", err)
+	}
+	if err := s.pendingQuery(&models.RequestLog{}).Count(&status.PendingLogs).Error; err != nil {
+		return nil, fmt.Errorf("failed to count request logs: %w", err)
+	}
+	return status, nil
+}
+
+// StartReencryptTask starts re-encrypting every value that is not encrypted with the primary key.
+func (s *KeyReencryptService) StartReencryptTask() (*TaskStatus, error) {
+	if s.EncryptionSvc.KeyID() == "" {
+		return nil, ErrEncryptionDisabled
+	}
+	status, err := s.GetStatus()
+	if err != nil {
+		return nil, err
+	}
+
+	initialStatus, err := s.TaskService.StartTask(TaskTypeKeyReencrypt, "", int(status.PendingKeys+status.PendingLogs), reencryptTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	go s.runReencrypt()
+
+	return initialStatus, nil
+}
+
+func (s *KeyReencryptService) runReencrypt() {
+	result := KeyReencryptResult{KeyID: s.EncryptionSvc.KeyID()}
+	processed := 0
+	progressCallback := func(count int) {
+		processed += count
+		if err := s.TaskService.UpdateProgress(processed); err != nil {
+			logrus.Warnf("Failed to update re-encryption progress: %v", err)
+		}
+	}
+
+	var err error
+	// 先处理密钥，代理选取密钥时读取的缓存也随之更新
+	result.ReencryptedKeys, result.FailedKeys, err = reencryptTable[uint](s, &models.APIKey{}, progressCallback, func(id uint, keyValue string) {
+		if err := s.KeyProvider.UpdateKeyValueInStore(id, keyValue); err != nil {
+			logrus.WithError(err).Warnf("Failed to update key %d in store", id)
+		}
+	})
+	if err == nil {
+		result.ReencryptedLogs, result.FailedLogs, err = reencryptTable[string](s, &models.RequestLog{}, progressCallback, nil)
+	}
+
+	if err != nil {
+		logrus.WithError(err).Error("Key re-encryption failed")
+		if endErr := s.TaskService.EndTask(nil, err); endErr != nil {
+			logrus.Errorf("Failed to end re-encryption task: %v (original error: %v)", endErr, err)
+		}
+		return
+	}
+
+	logrus.Infof("Re-encrypted %d keys and %d request logs with key %s, %d keys and %d logs failed",
+		result.ReencryptedKeys, result.ReencryptedLogs, result.KeyID, result.FailedKeys, result.FailedLogs)
+	if endErr := s.TaskService.EndTask(result, nil); endErr != nil {
+		logrus.Errorf("Failed to end re-encryption task: %v", endErr)
+	}
+}
+
+// pendingQuery selects the rows whose key value is not encrypted with the primary key.
+func (s *KeyReencryptService) pendingQuery(model any) *gorm.DB {
+	return s.DB.Model(model).Where("key_value <> '' AND key_value NOT LIKE ?", s.EncryptionSvc.KeyID()+":%")
+}
+
+// encryptedRow is a row of a table holding an encrypted key value.
+type encryptedRow[T uint | string] struct {
+	ID       T
+	KeyValue string
+}
+
+// reencryptTable rewrites the pending rows of a table in batches and returns the number of
+// re-encrypted and failed rows. Rows changed concurrently are left to the writer that changed them.
+func reencryptTable[T uint | string](s *KeyReencryptService, model any, progress func(int), onUpdated func(id T, keyValue string)) (int, int, error) {
+	reencrypted, failed := 0, 0
+	var lastID T
+	for {
+		var rows []encryptedRow[T]
+		if err := s.pendingQuery(model).Select("id, key_value").Where("id > ?", lastID).
+			Order("id asc").Limit(reencryptBatchSize).Find(&rows).Error; err != nil {
+			return reencrypted, failed, fmt.Errorf("failed to load rows: %w", err)
+		}
+		if len(rows) == 0 {
+			return reencrypted, failed, nil
+		}
+
+		updated := make([]encryptedRow[T], 0, len(rows))
+		err := s.DB.Transaction(func(tx *gorm.DB) error {
+			for _, row := range rows {
+				plaintext, err := s.EncryptionSvc.Decrypt(row.KeyValue)
+				if err != nil {
+					logrus.WithError(err).Warnf("Failed to decrypt row %v, skipping", row.ID)
+					failed++
+					continue
+				}
+				ciphertext, err := s.EncryptionSvc.Encrypt(plaintext)
+				if err != nil {
+					return err
+				}
+
+				result := tx.Model(model).Where("id = ? AND key_value = ?", row.ID, row.KeyValue).UpdateColumns(map[string]any{
+					"key_value": ciphertext,
+					"key_hash":  s.EncryptionSvc.Hash(plaintext),
+				})
+				if result.Error != nil {
+					return result.Error
+				}
+				if result.RowsAffected > 0 {
+					updated = append(updated, encryptedRow[T]{ID: row.ID, KeyValue: ciphertext})
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return reencrypted, failed, err
+		}
+
+		reencrypted += len(updated)
+		if onUpdated != nil {
+			for _, row := range updated {
+				onUpdated(row.ID, row.KeyValue)
+			}
+		}
+		lastID = rows[len(rows)-1].ID
+		progress(len(rows))
+	}
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"gpt-load/internal/config"
+	"gpt-load/internal/encryption"
+	"gpt-load/internal/keypool"
+	"gpt-load/internal/models"
+	"gpt-load/internal/store"
+)
+
+func TestKeyReencryptAfterRotation(t *testing.T) {
+	db := newTestDB(t, &models.APIKey{}, &models.RequestLog{})
+
+	old, _ := encryption.NewService("old-encryption-key-Aa1!")
+	rotated, err := encryption.NewService("new-encryption-key-Bb2@", "old-encryption-key-Aa1!")
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	encrypt := func(svc encryption.Service, plaintext string) string {
+		ciphertext, err := svc.Encrypt(plaintext)
+		if err != nil {
+			t.Fatalf("Encrypt() error = %v", err)
+		}
+		return ciphertext
+	}
+
+	keys := []models.APIKey{
+		{GroupID: 1, KeyValue: encrypt(old, "sk-old"), KeyHash: old.Hash("sk-old"), Status: models.KeyStatusActive},
+		{GroupID: 1, KeyValue: encrypt(rotated, "sk-new"), KeyHash: rotated.Hash("sk-new"), Status: models.KeyStatusActive},
+		{GroupID: 1, KeyValue: "ffffffff:00", KeyHash: "unreadable", Status: models.KeyStatusActive},
+	}
+	if err := db.Create(&keys).Error; err != nil {
+		t.Fatalf("failed to create keys: %v", err)
+	}
+	logs := []models.RequestLog{
+		{ID: "log-old", KeyValue: encrypt(old, "sk-old"), KeyHash: old.Hash("sk-old")},
+		{ID: "log-empty"},
+	}
+	if err := db.Create(&logs).Error; err != nil {
+		t.Fatalf("failed to create request logs: %v", err)
+	}
+
+	memoryStore := store.NewMemoryStore()
+	provider := keypool.NewProvider(db, memoryStore, config.NewSystemSettingsManager(), rotated, nil)
+	if err := provider.LoadKeysFromDB(); err != nil {
+		t.Fatalf("LoadKeysFromDB() error = %v", err)
+	}
+	taskService := NewTaskService(memoryStore)
+	s := NewKeyReencryptService(db, taskService, provider, rotated)
+
+	status, err := s.GetStatus()
+	if err != nil {
+		t.Fatalf("GetStatus() error = %v", err)
+	}
+	if !status.Enabled || status.KeyID != rotated.KeyID() || status.PendingKeys != 2 || status.PendingLogs != 1 {
+		t.Errorf("status before re-encryption = %+v", status)
+	}
+
+	if _, err := s.StartReencryptTask(); err != nil {
+		t.Fatalf("StartReencryptTask() error = %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	task, _ := taskService.GetTaskStatus()
+	for task.IsRunning && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		task, _ = taskService.GetTaskStatus()
+	}
+	if task.IsRunning || task.Error != "" {
+		t.Fatalf("task = %+v", task)
+	}
+
+	var key models.APIKey
+	db.First(&key, keys[0].ID)
+	if plaintext, err := rotated.Decrypt(key.KeyValue); err != nil || plaintext != "sk-old" || !rotated.IsCurrent(key.KeyValue) {
+		t.Errorf("re-encrypted key = %q, %q, %v", key.KeyValue, plaintext, err)
+	}
+	if key.KeyHash != rotated.Hash("sk-old") {
+		t.Error("key hash not rewritten with the primary key")
+	}
+	// 代理读取的缓存同步更新
+	details, _ := memoryStore.HGetAll("key:1")
+	if details["key_string"] != key.KeyValue {
+		t.Errorf("store key_string = %q, want %q", details["key_string"], key.KeyValue)
+	}
+
+	var log models.RequestLog
+	db.First(&log, "id = ?", "log-old")
+	if !rotated.IsCurrent(log.KeyValue) || log.KeyHash != rotated.Hash("sk-old") {
+		t.Errorf("re-encrypted log = %+v", log)
+	}
+
+	// 无法解密的值保留原样，仍计为待处理
+	status, _ = s.GetStatus()
+	if status.PendingKeys != 1 || status.PendingLogs != 0 {
+		t.Errorf("status after re-encryption = %+v", status)
+	}
+
+	noop, _ := encryption.NewService("")
+	if _, err := NewKeyReencryptService(db, taskService, provider, noop).StartReencryptTask(); err != ErrEncryptionDisabled {
+		t.Errorf("StartReencryptTask() without encryption error = %v, want ErrEncryptionDisabled", err)
+	}
+}
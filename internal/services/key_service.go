@@ -8,6 +8,7 @@ import (
 	"gpt-load/internal/models"
 	"io"
 	"regexp"
+	"slices"
 	"strings"
 
 	"github.com/sirupsen/logrus"
@@ -92,10 +93,42 @@ func (s *KeyService) processAndCreateKeys(
 	keys []string,
 	progressCallback func(processed int),
 ) (addedCount int, ignoredCount int, err error) {
+	newKeysToCreate, err := s.prepareNewKeys(s.DB, groupID, keys)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if len(newKeysToCreate) == 0 {
+		return 0, len(keys), nil
+	}
+
+	// Use KeyProvider to add keys in chunks
+	for i := 0; i < len(newKeysToCreate); i += chunkSize {
+		end := i + chunkSize
+		if end > len(newKeysToCreate) {
+			end = len(newKeysToCreate)
+		}
+		chunk := newKeysToCreate[i:end]
+		if err := s.KeyProvider.AddKeys(groupID, chunk); err != nil {
+			return addedCount, len(keys) - addedCount, err
+		}
+		addedCount += len(chunk)
+
+		if progressCallback != nil {
+			progressCallback(i + len(chunk))
+		}
+	}
+
+	return addedCount, len(keys) - addedCount, nil
+}
+
+// prepareNewKeys encrypts the keys that are not yet in the group, skipping duplicates and invalid formats.
+// The returned keys are not saved.
+func (s *KeyService) prepareNewKeys(db *gorm.DB, groupID uint, keys []string) ([]models.APIKey, error) {
 	// 1. Get existing key hashes in the group for deduplication
 	var existingHashes []string
-	if err := s.DB.Model(&models.APIKey{}).Where("group_id = ?", groupID).Pluck("key_hash", &existingHashes).Error; err != nil {
-		return 0, 0, err
+	if err := db.Model(&models.APIKey{}).Where("group_id = ?", groupID).Pluck("key_hash", &existingHashes).Error; err != nil {
+		return nil, err
 	}
 	existingHashMap := make(map[string]bool)
 	for _, h := range existingHashes {
@@ -112,11 +145,11 @@ func (s *KeyService) processAndCreateKeys(
 			continue
 		}
 
-		// Generate hash for deduplication check
-		keyHash := s.EncryptionSvc.Hash(trimmedKey)
-		if existingHashMap[keyHash] {
+		// Generate hash for deduplication check, keys not yet re-encrypted carry the hash of a previous key
+		if slices.ContainsFunc(s.EncryptionSvc.Hashes(trimmedKey), func(hash string) bool { return existingHashMap[hash] }) {
 			continue
 		}
+		keyHash := s.EncryptionSvc.Hash(trimmedKey)
 
 		encryptedKey, err := s.EncryptionSvc.Encrypt(trimmedKey)
 		if err != nil {
@@ -132,29 +165,30 @@ func (s *KeyService) processAndCreateKeys(
 			Status:   models.KeyStatusActive,
 		})
 	}
+	return newKeysToCreate, nil
+}
 
-	if len(newKeysToCreate) == 0 {
-		return 0, len(keys), nil
-	}
-
-	// 3. Use KeyProvider to add keys in chunks
-	for i := 0; i < len(newKeysToCreate); i += chunkSize {
-		end := i + chunkSize
-		if end > len(newKeysToCreate) {
-			end = len(newKeysToCreate)
+// DeleteGroup deletes a group together with its keys, and removes the keys from the key pool.
+// It returns the number of deleted keys.
+func (s *KeyService) DeleteGroup(groupID uint) (int, error) {
+	var keyIDs []uint
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.APIKey{}).Where("group_id = ?", groupID).Pluck("id", &keyIDs).Error; err != nil {
+			return err
 		}
-		chunk := newKeysToCreate[i:end]
-		if err := s.KeyProvider.AddKeys(groupID, chunk); err != nil {
-			return addedCount, len(keys) - addedCount, err
+		if err := tx.Where("group_id = ?", groupID).Delete(&models.APIKey{}).Error; err != nil {
+			return err
 		}
-		addedCount += len(chunk)
-
-		if progressCallback != nil {
-			progressCallback(i + len(chunk))
+		if err := tx.Delete(&models.Group{}, groupID).Error; err != nil {
+			return err
 		}
-	}
-
-	return addedCount, len(keys) - addedCount, nil
+		// 缓存清理失败时回滚事务
+		if len(keyIDs) > 0 {
+			return s.KeyProvider.RemoveKeysFromStore(groupID, keyIDs)
+		}
+		return nil
+	})
+	return len(keyIDs), err
 }
 
 // ParseKeysFromText parses a string of keys from various formats into a string slice.
@@ -296,15 +330,15 @@ func (s *KeyService) DeleteMultipleKeys(groupID uint, keysText string) (*DeleteK
 }
 
 // ListKeysInGroupQuery builds a query to list all keys within a specific group, filtered by status.
-func (s *KeyService) ListKeysInGroupQuery(groupID uint, statusFilter string, searchHash string) *gorm.DB {
+func (s *KeyService) ListKeysInGroupQuery(groupID uint, statusFilter string, searchHashes []string) *gorm.DB {
 	query := s.DB.Model(&models.APIKey{}).Where("group_id = ?", groupID)
 
 	if statusFilter != "" {
 		query = query.Where("status = ?", statusFilter)
 	}
 
-	if searchHash != "" {
-		query = query.Where("key_hash = ?", searchHash)
+	if len(searchHashes) > 0 {
+		query = query.Where("key_hash IN ?", searchHashes)
 	}
 
 	query = query.Order("last_used_at desc, updated_at desc")
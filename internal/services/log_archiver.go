@@ -0,0 +1,309 @@
+package services
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gpt-load/internal/models"
+	"gpt-load/internal/types"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Archive formats
+const (
+	LogArchiveFormatJSONL = "jsonl"
+	LogArchiveFormatCSV   = "csv"
+)
+
+const logArchiveManifestName = "manifest.json"
+
+// LogArchive describes one daily archive file in the manifest.
+type LogArchive struct {
+	File           string    `json:"file"` // 相对于归档目录的路径
+	Date           string    `json:"date"` // 日志所属的 UTC 日期
+	Format         string    `json:"format"`
+	Rows           int64     `json:"rows"`
+	Size           int64     `json:"size"`
+	SHA256         string    `json:"sha256"`
+	FirstTimestamp time.Time `json:"first_timestamp"`
+	LastTimestamp  time.Time `json:"last_timestamp"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+type logArchiveManifest struct {
+	Archives []LogArchive `json:"archives"`
+}
+
+// LogArchiver writes expired request logs to daily gzip compressed files.
+//
+// Each cleanup run appends a new gzip member to the file of the day, which standard tools and
+// readers decompress as one stream. The manifest records the row count and checksum of every
+// file so archives can be verified before they are moved to cold storage.
+type LogArchiver struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewLogArchiver creates a new LogArchiver.
+func NewLogArchiver(configManager types.ConfigManager) *LogArchiver {
+	return &LogArchiver{dir: configManager.GetLogSinkConfig().ArchiveDir}
+}
+
+// Dir returns the archive directory.
+func (a *LogArchiver) Dir() string {
+	return a.dir
+}
+
+// Append writes the logs to the archive files of their days and updates the manifest. When it
+// returns an error the files are left as they were, so the logs must not be deleted.
+func (a *LogArchiver) Append(logs []models.RequestLog, format string) error {
+	if format != LogArchiveFormatJSONL && format != LogArchiveFormatCSV {
+		return fmt.Errorf("unsupported archive format '%s'", format)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	manifest, err := a.loadManifest()
+	if err != nil {
+		return err
+	}
+
+	byDay := make(map[string][]models.RequestLog)
+	var days []string
+	for _, log := range logs {
+		day := log.Timestamp.UTC().Format("2006-01-02")
+		if _, ok := byDay[day]; !ok {
+			days = append(days, day)
+		}
+		byDay[day] = append(byDay[day], log)
+	}
+	sort.Strings(days)
+
+	for _, day := range days {
+		entry, err := a.appendDay(day, byDay[day], format)
+		if err != nil {
+			return fmt.Errorf("failed to archive logs of %s: %w", day, err)
+		}
+		manifest.merge(entry)
+	}
+	return a.saveManifest(manifest)
+}
+
+// List returns the archives recorded in the manifest, newest first.
+func (a *LogArchiver) List() ([]LogArchive, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	manifest, err := a.loadManifest()
+	if err != nil {
+		return nil, err
+	}
+	archives := manifest.Archives
+	sort.Slice(archives, func(i, j int) bool {
+		if archives[i].Date != archives[j].Date {
+			return archives[i].Date > archives[j].Date
+		}
+		return archives[i].File < archives[j].File
+	})
+	return archives, nil
+}
+
+// appendDay appends the logs of one day to its archive file and returns the updated file details.
+func (a *LogArchiver) appendDay(day string, logs []models.RequestLog, format string) (LogArchive, error) {
+	relPath := filepath.Join(day[:4], day[5:7], fmt.Sprintf("request_logs-%s.%s.gz", day, format))
+	path := filepath.Join(a.dir, relPath)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return LogArchive{}, err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return LogArchive{}, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return LogArchive{}, err
+	}
+	originalSize := info.Size()
+
+	writeErr := writeArchiveMember(file, logs, format, originalSize == 0)
+	if writeErr == nil {
+		writeErr = file.Sync()
+	}
+	if writeErr != nil {
+		// 回退到写入前的大小，避免留下不完整的 gzip 数据
+		_ = file.Truncate(originalSize)
+		file.Close()
+		return LogArchive{}, writeErr
+	}
+	if err := file.Close(); err != nil {
+		return LogArchive{}, err
+	}
+
+	size, checksum, err := fileChecksum(path)
+	if err != nil {
+		return LogArchive{}, err
+	}
+	return LogArchive{
+		File:           filepath.ToSlash(relPath),
+		Date:           day,
+		Format:         format,
+		Rows:           int64(len(logs)),
+		Size:           size,
+		SHA256:         checksum,
+		FirstTimestamp: logs[0].Timestamp.UTC(),
+		LastTimestamp:  logs[len(logs)-1].Timestamp.UTC(),
+		UpdatedAt:      time.Now().UTC(),
+	}, nil
+}
+
+// writeArchiveMember writes the logs as one gzip member. CSV files get a header row only when
+// the file is new.
+func writeArchiveMember(w io.Writer, logs []models.RequestLog, format string, newFile bool) error {
+	gz := gzip.NewWriter(w)
+
+	var err error
+	if format == LogArchiveFormatCSV {
+		err = writeLogsCSV(gz, logs, newFile)
+	} else {
+		encoder := json.NewEncoder(gz)
+		for i := range logs {
+			if err = encoder.Encode(&logs[i]); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func writeLogsCSV(w io.Writer, logs []models.RequestLog, header bool) error {
+	csvWriter := csv.NewWriter(w)
+	logType := reflect.TypeOf(models.RequestLog{})
+
+	if header {
+		columns := make([]string, logType.NumField())
+		for i := range logType.NumField() {
+			columns[i] = strings.Split(logType.Field(i).Tag.Get("json"), ",")[0]
+		}
+		if err := csvWriter.Write(columns); err != nil {
+			return err
+		}
+	}
+
+	record := make([]string, logType.NumField())
+	for i := range logs {
+		value := reflect.ValueOf(logs[i])
+		for j := range value.NumField() {
+			switch field := value.Field(j).Interface().(type) {
+			case time.Time:
+				record[j] = field.UTC().Format(time.RFC3339Nano)
+			default:
+				record[j] = fmt.Sprint(field)
+			}
+		}
+		if err := csvWriter.Write(record); err != nil {
+			return err
+		}
+	}
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+func fileChecksum(path string) (int64, string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return 0, "", err
+	}
+	return size, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func (a *LogArchiver) loadManifest() (*logArchiveManifest, error) {
+	manifest := &logArchiveManifest{}
+	data, err := os.ReadFile(filepath.Join(a.dir, logArchiveManifestName))
+	if errors.Is(err, os.ErrNotExist) {
+		return manifest, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive manifest: %w", err)
+	}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse archive manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+// saveManifest replaces the manifest atomically, a crash never leaves a partial manifest.
+func (a *LogArchiver) saveManifest(manifest *logArchiveManifest) error {
+	if err := os.MkdirAll(a.dir, 0o700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(a.dir, logArchiveManifestName)
+	tmp, err := os.CreateTemp(a.dir, logArchiveManifestName+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// merge adds the rows of an append to the manifest entry of the file.
+func (m *logArchiveManifest) merge(entry LogArchive) {
+	for i := range m.Archives {
+		existing := &m.Archives[i]
+		if existing.File != entry.File {
+			continue
+		}
+		existing.Rows += entry.Rows
+		existing.Size = entry.Size
+		existing.SHA256 = entry.SHA256
+		if entry.FirstTimestamp.Before(existing.FirstTimestamp) {
+			existing.FirstTimestamp = entry.FirstTimestamp
+		}
+		if entry.LastTimestamp.After(existing.LastTimestamp) {
+			existing.LastTimestamp = entry.LastTimestamp
+		}
+		existing.UpdatedAt = entry.UpdatedAt
+		return
+	}
+	m.Archives = append(m.Archives, entry)
+}
@@ -0,0 +1,180 @@
+package services
+
+import (
+	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gpt-load/internal/models"
+)
+
+func archiveTestLogs(day string, count int, start int) []models.RequestLog {
+	base, _ := time.Parse("2006-01-02", day)
+	logs := make([]models.RequestLog, count)
+	for i := range logs {
+		logs[i] = models.RequestLog{
+			ID:        fmt.Sprintf("%s-%d", day, start+i),
+			Timestamp: base.Add(time.Duration(start+i) * time.Hour),
+			GroupName: "openai",
+			IsSuccess: true,
+		}
+	}
+	return logs
+}
+
+// readArchive decompresses every gzip member of an archive file.
+func readArchive(t *testing.T, path string) []string {
+	t.Helper()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open archive: %v", err)
+	}
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatalf("failed to read archive: %v", err)
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(gz)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("failed to read archive: %v", err)
+	}
+	return lines
+}
+
+func TestLogArchiverAppendJSONL(t *testing.T) {
+	archiver := &LogArchiver{dir: t.TempDir()}
+
+	logs := append(archiveTestLogs("2026-03-01", 2, 0), archiveTestLogs("2026-03-02", 1, 0)...)
+	if err := archiver.Append(logs, LogArchiveFormatJSONL); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	// 同一天再次归档时追加新的 gzip 成员
+	if err := archiver.Append(archiveTestLogs("2026-03-01", 1, 5), LogArchiveFormatJSONL); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+
+	archives, err := archiver.List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(archives) != 2 || archives[0].Date != "2026-03-02" || archives[1].Date != "2026-03-01" {
+		t.Fatalf("List() = %+v, want newest first", archives)
+	}
+
+	first := archives[1]
+	if first.File != "2026/03/request_logs-2026-03-01.jsonl.gz" || first.Rows != 3 {
+		t.Errorf("archive = %+v", first)
+	}
+	if !first.FirstTimestamp.Equal(logs[0].Timestamp) || first.LastTimestamp.Hour() != 5 {
+		t.Errorf("archive time range = %v - %v", first.FirstTimestamp, first.LastTimestamp)
+	}
+
+	path := filepath.Join(archiver.Dir(), filepath.FromSlash(first.File))
+	lines := readArchive(t, path)
+	if len(lines) != 3 {
+		t.Fatalf("archive has %d rows, want 3", len(lines))
+	}
+	var log models.RequestLog
+	if err := json.Unmarshal([]byte(lines[2]), &log); err != nil || log.ID != "2026-03-01-5" {
+		t.Errorf("last archived log = %+v, %v", log, err)
+	}
+
+	// 清单中的校验和与文件一致
+	data, _ := os.ReadFile(path)
+	sum := sha256.Sum256(data)
+	if first.SHA256 != hex.EncodeToString(sum[:]) || first.Size != int64(len(data)) {
+		t.Errorf("manifest checksum %s (%d bytes) does not match the file", first.SHA256, first.Size)
+	}
+}
+
+func TestLogArchiverAppendCSV(t *testing.T) {
+	archiver := &LogArchiver{dir: t.TempDir()}
+
+	for start := range 2 {
+		if err := archiver.Append(archiveTestLogs("2026-03-01", 1, start), LogArchiveFormatCSV); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+
+	lines := readArchive(t, filepath.Join(archiver.Dir(), "2026", "03", "request_logs-2026-03-01.csv.gz"))
+	records, err := csv.NewReader(strings.NewReader(strings.Join(lines, "\n"))).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV: %v", err)
+	}
+	// 表头只在新文件中写入一次
+	if len(records) != 3 || records[0][0] != "id" || records[1][0] != "2026-03-01-0" || records[2][0] != "2026-03-01-1" {
+		t.Errorf("records = %v", records)
+	}
+}
+
+func TestLogArchiverAppendErrors(t *testing.T) {
+	archiver := &LogArchiver{dir: t.TempDir()}
+
+	if err := archiver.Append(archiveTestLogs("2026-03-01", 1, 0), "parquet"); err == nil || !strings.Contains(err.Error(), "unsupported archive format") {
+		t.Errorf("Append() error = %v, want unsupported format", err)
+	}
+
+	// 归档文件无法写入时返回错误，清单保持不变
+	if err := os.MkdirAll(filepath.Join(archiver.Dir(), "2026", "03", "request_logs-2026-03-02.jsonl.gz"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	logs := append(archiveTestLogs("2026-03-01", 1, 0), archiveTestLogs("2026-03-02", 1, 0)...)
+	if err := archiver.Append(logs, LogArchiveFormatJSONL); err == nil || !strings.Contains(err.Error(), "failed to archive logs of 2026-03-02") {
+		t.Errorf("Append() error = %v", err)
+	}
+	if archives, _ := archiver.List(); len(archives) != 0 {
+		t.Errorf("List() after a failed append = %+v", archives)
+	}
+}
+
+func TestArchiveAndPurge(t *testing.T) {
+	db := newTestDB(t, &models.RequestLog{})
+
+	logs := append(archiveTestLogs("2026-03-01", 3, 0), archiveTestLogs("2026-03-10", 2, 0)...)
+	if err := db.Create(&logs).Error; err != nil {
+		t.Fatalf("failed to create logs: %v", err)
+	}
+	cutoff := time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC)
+
+	// 归档失败时不删除日志
+	blocked := filepath.Join(t.TempDir(), "file")
+	os.WriteFile(blocked, nil, 0o600)
+	failing := NewLogCleanupService(db, nil, &LogArchiver{dir: blocked})
+	if _, err := failing.archiveAndPurge(LogArchiveFormatJSONL, "timestamp < ?", cutoff); err == nil {
+		t.Error("archiveAndPurge() with an unwritable directory succeeded")
+	}
+	var count int64
+	db.Model(&models.RequestLog{}).Count(&count)
+	if count != 5 {
+		t.Errorf("%d logs left after a failed archive, want 5", count)
+	}
+
+	archiver := &LogArchiver{dir: t.TempDir()}
+	s := NewLogCleanupService(db, nil, archiver)
+	deleted, err := s.archiveAndPurge(LogArchiveFormatJSONL, "timestamp < ?", cutoff)
+	if err != nil || deleted != 3 {
+		t.Fatalf("archiveAndPurge() = %d, %v, want 3", deleted, err)
+	}
+	db.Model(&models.RequestLog{}).Count(&count)
+	if count != 2 {
+		t.Errorf("%d logs left, want 2", count)
+	}
+	if archives, _ := archiver.List(); len(archives) != 1 || archives[0].Rows != 3 {
+		t.Errorf("List() = %+v", archives)
+	}
+}
@@ -11,19 +11,24 @@ import (
 	"gorm.io/gorm"
 )
 
+// logArchiveBatchSize is the number of logs archived and deleted per batch.
+const logArchiveBatchSize = 1000
+
 // LogCleanupService 负责清理过期的请求日志
 type LogCleanupService struct {
 	db              *gorm.DB
 	settingsManager *config.SystemSettingsManager
+	archiver        *LogArchiver
 	stopCh          chan struct{}
 	wg              sync.WaitGroup
 }
 
 // NewLogCleanupService 创建新的日志清理服务
-func NewLogCleanupService(db *gorm.DB, settingsManager *config.SystemSettingsManager) *LogCleanupService {
+func NewLogCleanupService(db *gorm.DB, settingsManager *config.SystemSettingsManager, archiver *LogArchiver) *LogCleanupService {
 	return &LogCleanupService{
 		db:              db,
 		settingsManager: settingsManager,
+		archiver:        archiver,
 		stopCh:          make(chan struct{}),
 	}
 }
@@ -72,34 +77,107 @@ func (s *LogCleanupService) run() {
 	}
 }
 
-// cleanupExpiredLogs 清理过期的请求日志
+// cleanupExpiredLogs 清理过期的请求日志，成功和失败的请求分别按各自的保留天数清理
 func (s *LogCleanupService) cleanupExpiredLogs() {
-	// 获取日志保留天数配置
 	settings := s.settingsManager.GetSettings()
-	retentionDays := settings.RequestLogRetentionDays
+	failedRetentionDays := settings.RequestLogFailedRetentionDays
+	if failedRetentionDays <= 0 {
+		failedRetentionDays = settings.RequestLogRetentionDays
+	}
 
-	if retentionDays <= 0 {
-		logrus.Debug("Log retention is disabled (retention_days <= 0)")
-		return
+	classes := []struct {
+		success       bool
+		retentionDays int
+	}{
+		{true, settings.RequestLogRetentionDays},
+		{false, failedRetentionDays},
 	}
+	for _, class := range classes {
+		if class.retentionDays <= 0 {
+			logrus.Debugf("Log retention is disabled for is_success=%t (retention_days <= 0)", class.success)
+			continue
+		}
 
-	// 计算过期时间点
-	cutoffTime := time.Now().AddDate(0, 0, -retentionDays).UTC()
+		// 计算过期时间点
+		cutoffTime := time.Now().AddDate(0, 0, -class.retentionDays).UTC()
 
-	// 执行删除操作
-	result := s.db.Where("timestamp < ?", cutoffTime).Delete(&models.RequestLog{})
-	if result.Error != nil {
-		logrus.WithError(result.Error).Error("Failed to cleanup expired request logs")
-		return
+		var deletedCount int64
+		var err error
+		if settings.RequestLogArchiveEnabled {
+			deletedCount, err = s.archiveAndPurge(settings.RequestLogArchiveFormat, "timestamp < ? AND is_success = ?", cutoffTime, class.success)
+		} else {
+			deletedCount, err = s.purgeLogs(cutoffTime, class.success)
+		}
+		if err != nil {
+			logrus.WithError(err).WithField("is_success", class.success).Error("Failed to cleanup expired request logs")
+			continue
+		}
+
+		if deletedCount > 0 {
+			logrus.WithFields(logrus.Fields{
+				"deleted_count":  deletedCount,
+				"cutoff_time":    cutoffTime.Format(time.RFC3339),
+				"retention_days": class.retentionDays,
+				"is_success":     class.success,
+				"archived":       settings.RequestLogArchiveEnabled,
+			}).Info("Successfully cleaned up expired request logs")
+		} else {
+			logrus.Debugf("No expired request logs found to cleanup for is_success=%t", class.success)
+		}
 	}
+}
+
+// purgeLogs 删除指定时间之前成功或失败的请求日志
+func (s *LogCleanupService) purgeLogs(cutoffTime time.Time, success bool) (int64, error) {
+	result := s.db.Where("timestamp < ? AND is_success = ?", cutoffTime, success).Delete(&models.RequestLog{})
+	return result.RowsAffected, result.Error
+}
 
-	if result.RowsAffected > 0 {
-		logrus.WithFields(logrus.Fields{
-			"deleted_count":  result.RowsAffected,
-			"cutoff_time":    cutoffTime.Format(time.RFC3339),
-			"retention_days": retentionDays,
-		}).Info("Successfully cleaned up expired request logs")
-	} else {
-		logrus.Debug("No expired request logs found to cleanup")
+// archiveAndPurge 分批将符合条件的日志写入归档文件，每批写入成功后才删除对应的记录。
+// 写入后、删除前进程中断时，下次清理会再次归档这批日志，可按 id 去重。
+func (s *LogCleanupService) archiveAndPurge(format string, query string, args ...any) (int64, error) {
+	var deletedCount int64
+	for {
+		select {
+		case <-s.stopCh:
+			return deletedCount, nil
+		default:
+		}
+
+		var logs []models.RequestLog
+		if err := s.db.Where(query, args...).
+			Order("timestamp asc").Limit(logArchiveBatchSize).Find(&logs).Error; err != nil {
+			return deletedCount, err
+		}
+		if len(logs) == 0 {
+			return deletedCount, nil
+		}
+
+		if err := s.archiver.Append(logs, format); err != nil {
+			return deletedCount, err
+		}
+
+		ids := make([]string, len(logs))
+		for i := range logs {
+			ids[i] = logs[i].ID
+		}
+		result := s.db.Where("id IN ?", ids).Delete(&models.RequestLog{})
+		if result.Error != nil {
+			return deletedCount, result.Error
+		}
+		deletedCount += result.RowsAffected
+
+		if len(logs) < logArchiveBatchSize {
+			return deletedCount, nil
+		}
 	}
 }
+
+// PurgeLogsBefore 删除指定时间之前的请求日志，返回删除的数量。启用日志归档时先归档再删除
+func (s *LogCleanupService) PurgeLogsBefore(cutoffTime time.Time) (int64, error) {
+	if settings := s.settingsManager.GetSettings(); settings.RequestLogArchiveEnabled {
+		return s.archiveAndPurge(settings.RequestLogArchiveFormat, "timestamp < ?", cutoffTime)
+	}
+	result := s.db.Where("timestamp < ?", cutoffTime).Delete(&models.RequestLog{})
+	return result.RowsAffected, result.Error
+}
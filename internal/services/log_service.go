@@ -38,12 +38,12 @@ func NewLogService(db *gorm.DB, encryptionSvc encryption.Service) *LogService {
 // logFiltersScope returns a GORM scope function that applies filters from the Gin context.
 func (s *LogService) logFiltersScope(c *gin.Context) func(db *gorm.DB) *gorm.DB {
 	return func(db *gorm.DB) *gorm.DB {
+		db = db.Scopes(AdminGroupScope(c, "group_id"))
 		if groupName := c.Query("group_name"); groupName != "" {
 			db = db.Where("group_name LIKE ?", "%"+groupName+"%")
 		}
 		if keyValue := c.Query("key_value"); keyValue != "" {
-			keyHash := s.EncryptionSvc.Hash(keyValue)
-			db = db.Where("key_hash = ?", keyHash)
+			db = db.Where("key_hash IN ?", s.EncryptionSvc.Hashes(keyValue))
 		}
 		if model := c.Query("model"); model != "" {
 			db = db.Where("model LIKE ?", "%"+model+"%")
@@ -53,6 +53,9 @@ func (s *LogService) logFiltersScope(c *gin.Context) func(db *gorm.DB) *gorm.DB
 				db = db.Where("is_success = ?", isSuccess)
 			}
 		}
+		if requestID := c.Query("request_id"); requestID != "" {
+			db = db.Where("request_id = ?", requestID)
+		}
 		if requestType := c.Query("request_type"); requestType != "" {
 			db = db.Where("request_type = ?", requestType)
 		}
@@ -0,0 +1,726 @@
+package services
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gpt-load/internal/config"
+	"gpt-load/internal/models"
+	"gpt-load/internal/store"
+	"gpt-load/internal/types"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	oidcStateKeyPrefix = "oidc_state:"
+	oidcStateTTL       = 10 * time.Minute
+	oidcClockSkew      = time.Minute
+	// 未知 kid 触发重新拉取 JWKS 的最小间隔
+	oidcJWKSRefreshInterval = time.Minute
+)
+
+var (
+	// ErrOIDCDisabled is returned when single sign-on is not configured.
+	ErrOIDCDisabled = errors.New("OIDC single sign-on is not enabled")
+	// ErrOIDCAccessDenied is returned when no admin role is mapped to the identity.
+	ErrOIDCAccessDenied = errors.New("no admin role is mapped to this account")
+	// ErrOIDCUsernameTaken is returned when a local account already uses the username.
+	ErrOIDCUsernameTaken = errors.New("the username is already used by another admin account")
+)
+
+// oidcRoleRank orders roles by privilege, so the highest mapped role wins.
+var oidcRoleRank = map[string]int{
+	models.AdminRoleViewer:        1,
+	models.AdminRoleGroupOperator: 2,
+	models.AdminRoleOperator:      3,
+	models.AdminRoleOwner:         4,
+}
+
+// oidcProviderMetadata is the subset of the discovery document that is used.
+type oidcProviderMetadata struct {
+	Issuer                   string   `json:"issuer"`
+	AuthorizationEndpoint    string   `json:"authorization_endpoint"`
+	TokenEndpoint            string   `json:"token_endpoint"`
+	UserinfoEndpoint         string   `json:"userinfo_endpoint"`
+	JWKSURI                  string   `json:"jwks_uri"`
+	TokenEndpointAuthMethods []string `json:"token_endpoint_auth_methods_supported"`
+}
+
+// oidcAuthState is kept in the store between the redirect to the provider and the callback.
+type oidcAuthState struct {
+	CodeVerifier string `json:"code_verifier"`
+	Nonce        string `json:"nonce"`
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// OIDCLoginResult is the outcome of a successful single sign-on.
+type OIDCLoginResult struct {
+	Principal *AdminPrincipal
+	User      *models.AdminUser
+	Previous  *models.AdminUser // 登录前的账号状态，首次登录自动创建账号时为 nil
+}
+
+// OIDCService implements OpenID Connect single sign-on with the authorization code flow and PKCE.
+// Identities are linked to admin accounts, which are created on first login and whose role is
+// synchronized from the role claim on every login.
+type OIDCService struct {
+	DB              *gorm.DB
+	configManager   types.ConfigManager
+	settingsManager *config.SystemSettingsManager
+	store           store.Store
+	httpClient      *http.Client
+
+	mu            sync.RWMutex
+	provider      *oidcProviderMetadata
+	keys          map[string]crypto.PublicKey
+	keysFetchedAt time.Time
+}
+
+// NewOIDCService creates a new OIDCService.
+func NewOIDCService(db *gorm.DB, configManager types.ConfigManager, settingsManager *config.SystemSettingsManager, store store.Store) *OIDCService {
+	return &OIDCService{
+		DB:              db,
+		configManager:   configManager,
+		settingsManager: settingsManager,
+		store:           store,
+		httpClient:      &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// Enabled reports whether single sign-on is configured.
+func (s *OIDCService) Enabled() bool {
+	return s.configManager.GetOIDCConfig().Enabled
+}
+
+// ProviderName returns the display name of the identity provider.
+func (s *OIDCService) ProviderName() string {
+	return s.configManager.GetOIDCConfig().ProviderName
+}
+
+// AuthorizationURL starts a login and returns the provider URL the browser is redirected to.
+func (s *OIDCService) AuthorizationURL(ctx context.Context) (string, error) {
+	oidcConfig := s.configManager.GetOIDCConfig()
+	if !oidcConfig.Enabled {
+		return "", ErrOIDCDisabled
+	}
+	provider, err := s.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	state, err := randomURLToken()
+	if err != nil {
+		return "", err
+	}
+	authState := oidcAuthState{}
+	if authState.CodeVerifier, err = randomURLToken(); err != nil {
+		return "", err
+	}
+	if authState.Nonce, err = randomURLToken(); err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(authState)
+	if err != nil {
+		return "", err
+	}
+	if err := s.store.Set(oidcStateKeyPrefix+state, data, oidcStateTTL); err != nil {
+		return "", fmt.Errorf("failed to store OIDC state: %w", err)
+	}
+
+	challenge := sha256.Sum256([]byte(authState.CodeVerifier))
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {oidcConfig.ClientID},
+		"redirect_uri":          {s.redirectURL()},
+		"scope":                 {strings.Join(oidcConfig.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {authState.Nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+
+	authURL, err := url.Parse(provider.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid authorization endpoint: %w", err)
+	}
+	// 保留授权端点自带的查询参数
+	existing := authURL.Query()
+	for key, values := range query {
+		existing[key] = values
+	}
+	authURL.RawQuery = existing.Encode()
+	return authURL.String(), nil
+}
+
+// Login completes the authorization code flow, verifies the ID token and returns the linked admin account.
+func (s *OIDCService) Login(ctx context.Context, state, code string) (*OIDCLoginResult, error) {
+	oidcConfig := s.configManager.GetOIDCConfig()
+	if !oidcConfig.Enabled {
+		return nil, ErrOIDCDisabled
+	}
+
+	authState, err := s.consumeState(state)
+	if err != nil {
+		return nil, err
+	}
+	provider, err := s.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	idToken, accessToken, err := s.exchangeCode(ctx, provider, code, authState.CodeVerifier)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := s.verifyIDToken(ctx, provider, idToken, authState.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	// 部分身份提供方只在 userinfo 中返回用户名或分组
+	if (lookupClaim(claims, oidcConfig.UsernameClaim) == nil || lookupClaim(claims, oidcConfig.RoleClaim) == nil) &&
+		provider.UserinfoEndpoint != "" && accessToken != "" {
+		if err := s.mergeUserinfo(ctx, provider, accessToken, claims); err != nil {
+			logrus.WithError(err).Warn("Failed to fetch OIDC userinfo")
+		}
+	}
+
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return nil, errors.New("ID token has no subject")
+	}
+	username := oidcUsername(claims, oidcConfig.UsernameClaim)
+	if username == "" || username == AuthKeyActor {
+		return nil, fmt.Errorf("invalid username '%s' in OIDC claims", username)
+	}
+
+	role, groupIDs := resolveOIDCRole(oidcConfig, claims)
+	if role == "" {
+		logrus.WithField("username", username).Warn("OIDC login denied: no admin role mapped")
+		return nil, ErrOIDCAccessDenied
+	}
+
+	return s.syncUser(provider.Issuer+"#"+subject, username, role, groupIDs)
+}
+
+// syncUser links the identity to an admin account, creating it on first login.
+func (s *OIDCService) syncUser(externalID, username, role string, groupIDs []uint) (*OIDCLoginResult, error) {
+	now := time.Now()
+	result := &OIDCLoginResult{}
+
+	var user models.AdminUser
+	err := s.DB.Where("external_id = ?", externalID).First(&user).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		var count int64
+		if err := s.DB.Model(&models.AdminUser{}).Where("username = ?", username).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			return nil, ErrOIDCUsernameTaken
+		}
+		user = models.AdminUser{
+			Username:    username,
+			Role:        role,
+			GroupIDs:    groupIDs,
+			ExternalID:  externalID,
+			LastLoginAt: &now,
+		}
+		if err := s.DB.Create(&user).Error; err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, err
+	default:
+		if user.Disabled {
+			return nil, ErrOIDCAccessDenied
+		}
+		previous := user
+		result.Previous = &previous
+		user.Role = role
+		user.GroupIDs = groupIDs
+		user.LastLoginAt = &now
+		if err := s.DB.Save(&user).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	result.User = &user
+	result.Principal = newAdminPrincipal(&user)
+	return result, nil
+}
+
+// redirectURL returns the callback URL registered at the provider.
+func (s *OIDCService) redirectURL() string {
+	if redirectURL := s.configManager.GetOIDCConfig().RedirectURL; redirectURL != "" {
+		return redirectURL
+	}
+	return strings.TrimSuffix(s.settingsManager.GetAppUrl(), "/") + "/api/auth/oidc/callback"
+}
+
+func (s *OIDCService) consumeState(state string) (*oidcAuthState, error) {
+	if state == "" {
+		return nil, errors.New("missing OIDC state")
+	}
+	key := oidcStateKeyPrefix + state
+	data, err := s.store.Get(key)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, errors.New("OIDC login expired or state is invalid, please try again")
+		}
+		return nil, err
+	}
+	if err := s.store.Delete(key); err != nil {
+		logrus.WithError(err).Warn("Failed to delete OIDC state")
+	}
+
+	var authState oidcAuthState
+	if err := json.Unmarshal(data, &authState); err != nil {
+		return nil, fmt.Errorf("invalid OIDC state: %w", err)
+	}
+	return &authState, nil
+}
+
+// discover loads and caches the provider's discovery document.
+func (s *OIDCService) discover(ctx context.Context) (*oidcProviderMetadata, error) {
+	s.mu.RLock()
+	provider := s.provider
+	s.mu.RUnlock()
+	if provider != nil {
+		return provider, nil
+	}
+
+	issuer := s.configManager.GetOIDCConfig().IssuerURL
+	var metadata oidcProviderMetadata
+	if err := s.getJSON(ctx, issuer+"/.well-known/openid-configuration", "", &metadata); err != nil {
+		return nil, fmt.Errorf("OIDC discovery failed: %w", err)
+	}
+	if strings.TrimSuffix(metadata.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("OIDC discovery returned issuer '%s', expected '%s'", metadata.Issuer, issuer)
+	}
+	if metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" || metadata.JWKSURI == "" {
+		return nil, errors.New("OIDC discovery document is missing required endpoints")
+	}
+
+	s.mu.Lock()
+	s.provider = &metadata
+	s.mu.Unlock()
+	return &metadata, nil
+}
+
+// exchangeCode redeems the authorization code and returns the ID token and access token.
+func (s *OIDCService) exchangeCode(ctx context.Context, provider *oidcProviderMetadata, code, codeVerifier string) (string, string, error) {
+	oidcConfig := s.configManager.GetOIDCConfig()
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {s.redirectURL()},
+		"client_id":     {oidcConfig.ClientID},
+		"code_verifier": {codeVerifier},
+	}
+
+	// 默认使用 client_secret_basic，提供方只支持 client_secret_post 时改为表单传参
+	useBasicAuth := len(provider.TokenEndpointAuthMethods) == 0 || slices.Contains(provider.TokenEndpointAuthMethods, "client_secret_basic")
+	if oidcConfig.ClientSecret != "" && !useBasicAuth {
+		form.Set("client_secret", oidcConfig.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, provider.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if oidcConfig.ClientSecret != "" && useBasicAuth {
+		req.SetBasicAuth(url.QueryEscape(oidcConfig.ClientID), url.QueryEscape(oidcConfig.ClientSecret))
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", "", fmt.Errorf("OIDC token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var tokenResponse struct {
+		IDToken          string `json:"id_token"`
+		AccessToken      string `json:"access_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read OIDC token response: %w", err)
+	}
+	if err := json.Unmarshal(body, &tokenResponse); err != nil {
+		return "", "", fmt.Errorf("invalid OIDC token response (status %d)", resp.StatusCode)
+	}
+	if resp.StatusCode != http.StatusOK || tokenResponse.Error != "" {
+		return "", "", fmt.Errorf("OIDC token request rejected: %s %s", tokenResponse.Error, tokenResponse.ErrorDescription)
+	}
+	if tokenResponse.IDToken == "" {
+		return "", "", errors.New("OIDC token response has no id_token")
+	}
+	return tokenResponse.IDToken, tokenResponse.AccessToken, nil
+}
+
+// verifyIDToken checks the signature and the standard claims of an ID token.
+func (s *OIDCService) verifyIDToken(ctx context.Context, provider *oidcProviderMetadata, rawToken, nonce string) (map[string]any, error) {
+	parts := strings.Split(rawToken, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed ID token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeJWTSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("invalid ID token header: %w", err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid ID token signature: %w", err)
+	}
+	key, err := s.signingKey(ctx, provider, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyJWTSignature(header.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	var claims map[string]any
+	if err := decodeJWTSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("invalid ID token claims: %w", err)
+	}
+
+	if iss, _ := claims["iss"].(string); iss != provider.Issuer {
+		return nil, fmt.Errorf("ID token issuer '%s' does not match", iss)
+	}
+	clientID := s.configManager.GetOIDCConfig().ClientID
+	if !slices.Contains(claimStrings(claims["aud"]), clientID) {
+		return nil, errors.New("ID token audience does not match the client ID")
+	}
+	exp, _ := claims["exp"].(float64)
+	if time.Now().Add(-oidcClockSkew).After(time.Unix(int64(exp), 0)) {
+		return nil, errors.New("ID token has expired")
+	}
+	if claimNonce, _ := claims["nonce"].(string); claimNonce != nonce {
+		return nil, errors.New("ID token nonce does not match")
+	}
+	return claims, nil
+}
+
+// signingKey returns the provider key with the given ID, reloading the JWKS when the key is unknown.
+func (s *OIDCService) signingKey(ctx context.Context, provider *oidcProviderMetadata, kid string) (crypto.PublicKey, error) {
+	s.mu.RLock()
+	key := findJWK(s.keys, kid)
+	fetchedAt := s.keysFetchedAt
+	s.mu.RUnlock()
+	if key != nil {
+		return key, nil
+	}
+	if time.Since(fetchedAt) < oidcJWKSRefreshInterval {
+		return nil, fmt.Errorf("unknown ID token signing key '%s'", kid)
+	}
+
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := s.getJSON(ctx, provider.JWKSURI, "", &jwks); err != nil {
+		return nil, fmt.Errorf("failed to fetch OIDC signing keys: %w", err)
+	}
+	keys := make(map[string]crypto.PublicKey, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		publicKey, err := jwk.publicKey()
+		if err != nil {
+			logrus.WithError(err).WithField("kid", jwk.Kid).Warn("Skipping unsupported OIDC signing key")
+			continue
+		}
+		keys[jwk.Kid] = publicKey
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	s.keysFetchedAt = time.Now()
+	s.mu.Unlock()
+
+	if key = findJWK(keys, kid); key == nil {
+		return nil, fmt.Errorf("unknown ID token signing key '%s'", kid)
+	}
+	return key, nil
+}
+
+// mergeUserinfo adds claims from the userinfo endpoint that the ID token does not carry.
+func (s *OIDCService) mergeUserinfo(ctx context.Context, provider *oidcProviderMetadata, accessToken string, claims map[string]any) error {
+	var userinfo map[string]any
+	if err := s.getJSON(ctx, provider.UserinfoEndpoint, accessToken, &userinfo); err != nil {
+		return err
+	}
+	if userinfo["sub"] != claims["sub"] {
+		return errors.New("userinfo subject does not match the ID token")
+	}
+	for key, value := range userinfo {
+		if _, exists := claims[key]; !exists {
+			claims[key] = value
+		}
+	}
+	return nil
+}
+
+func (s *OIDCService) getJSON(ctx context.Context, endpoint, bearerToken string, target any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if bearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+bearerToken)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned status %d", endpoint, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(target)
+}
+
+// resolveOIDCRole maps the role claim to the most privileged matching admin role.
+// Group operator mappings that match together grant the union of their groups.
+func resolveOIDCRole(oidcConfig types.OIDCConfig, claims map[string]any) (string, []uint) {
+	values := claimStrings(lookupClaim(claims, oidcConfig.RoleClaim))
+
+	role := ""
+	var groupIDs []uint
+	for _, mapping := range oidcConfig.RoleMappings {
+		if !slices.Contains(values, mapping.ClaimValue) {
+			continue
+		}
+		if oidcRoleRank[mapping.Role] > oidcRoleRank[role] {
+			role = mapping.Role
+		}
+		if mapping.Role == models.AdminRoleGroupOperator {
+			groupIDs = append(groupIDs, mapping.GroupIDs...)
+		}
+	}
+
+	if role == "" {
+		return oidcConfig.DefaultRole, nil
+	}
+	if role != models.AdminRoleGroupOperator {
+		return role, nil
+	}
+	slices.Sort(groupIDs)
+	return role, slices.Compact(groupIDs)
+}
+
+// lookupClaim returns a claim by name. Dotted names address nested claims, e.g. "realm_access.roles".
+func lookupClaim(claims map[string]any, name string) any {
+	if value, ok := claims[name]; ok {
+		return value
+	}
+	var current any = claims
+	for _, part := range strings.Split(name, ".") {
+		object, ok := current.(map[string]any)
+		if !ok {
+			return nil
+		}
+		current = object[part]
+	}
+	return current
+}
+
+// claimStrings returns a string or string array claim as a slice.
+func claimStrings(value any) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []any:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if str, ok := item.(string); ok {
+				values = append(values, str)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+func oidcUsername(claims map[string]any, usernameClaim string) string {
+	for _, name := range []string{usernameClaim, "email", "sub"} {
+		if username, ok := lookupClaim(claims, name).(string); ok && username != "" {
+			return username
+		}
+	}
+	return ""
+}
+
+func findJWK(keys map[string]crypto.PublicKey, kid string) crypto.PublicKey {
+	if kid != "" {
+		return keys[kid]
+	}
+	// 未指定 kid 时只在唯一密钥的情况下使用
+	if len(keys) == 1 {
+		for _, key := range keys {
+			return key
+		}
+	}
+	return nil
+}
+
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve '%s'", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type '%s'", k.Kty)
+}
+
+// verifyJWTSignature verifies an RS*, PS* or ES* signature. Symmetric and unsigned tokens are rejected.
+func verifyJWTSignature(alg string, key crypto.PublicKey, signingInput string, signature []byte) error {
+	if len(alg) != 5 {
+		return fmt.Errorf("unsupported ID token algorithm '%s'", alg)
+	}
+	family, bits := alg[:2], alg[2:]
+
+	var hash crypto.Hash
+	switch bits {
+	case "256":
+		hash = crypto.SHA256
+	case "384":
+		hash = crypto.SHA384
+	case "512":
+		hash = crypto.SHA512
+	default:
+		return fmt.Errorf("unsupported ID token algorithm '%s'", alg)
+	}
+	hasher := hash.New()
+	hasher.Write([]byte(signingInput))
+	digest := hasher.Sum(nil)
+
+	invalid := errors.New("invalid ID token signature")
+	switch family {
+	case "RS", "PS":
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return invalid
+		}
+		verify := rsa.VerifyPKCS1v15(rsaKey, hash, digest, signature)
+		if family == "PS" {
+			verify = rsa.VerifyPSS(rsaKey, hash, digest, signature, nil)
+		}
+		if verify != nil {
+			return invalid
+		}
+		return nil
+	case "ES":
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return invalid
+		}
+		// 签名为定长的 r || s
+		size := (ecKey.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return invalid
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		sv := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(ecKey, digest, r, sv) {
+			return invalid
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported ID token algorithm '%s'", alg)
+}
+
+func decodeJWTSegment(segment string, target any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, target)
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(data) == 0 {
+		return nil, errors.New("invalid key parameter")
+	}
+	return new(big.Int).SetBytes(data), nil
+}
+
+func randomURLToken() (string, error) {
+	randomBytes := make([]byte, 32)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return "", fmt.Errorf("failed to generate random token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(randomBytes), nil
+}
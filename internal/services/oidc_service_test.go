@@ -0,0 +1,280 @@
+package services
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/types"
+
+	"gorm.io/gorm"
+)
+
+const (
+	testOIDCIssuer   = "https://idp.example.com"
+	testOIDCClientID = "gpt-load"
+	testOIDCNonce    = "nonce-1"
+)
+
+// testIdentityProvider serves the JWKS of an RSA and an EC signing key.
+type testIdentityProvider struct {
+	server       *httptest.Server
+	rsaKey       *rsa.PrivateKey
+	ecKey        *ecdsa.PrivateKey
+	jwksRequests atomic.Int32
+}
+
+func newTestIdentityProvider(t *testing.T) *testIdentityProvider {
+	t.Helper()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate RSA key: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate EC key: %v", err)
+	}
+	idp := &testIdentityProvider{rsaKey: rsaKey, ecKey: ecKey}
+
+	encode := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+	jwks := map[string]any{"keys": []map[string]string{
+		{"kty": "RSA", "kid": "rsa-1", "use": "sig", "n": encode(rsaKey.N.Bytes()), "e": encode(big.NewInt(int64(rsaKey.E)).Bytes())},
+		{"kty": "EC", "kid": "ec-1", "crv": "P-256", "x": encode(ecKey.X.FillBytes(make([]byte, 32))), "y": encode(ecKey.Y.FillBytes(make([]byte, 32)))},
+		{"kty": "RSA", "kid": "enc-1", "use": "enc", "n": encode(rsaKey.N.Bytes()), "e": "AQAB"},
+	}}
+	idp.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idp.jwksRequests.Add(1)
+		_ = json.NewEncoder(w).Encode(jwks)
+	}))
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+func (idp *testIdentityProvider) metadata() *oidcProviderMetadata {
+	return &oidcProviderMetadata{Issuer: testOIDCIssuer, JWKSURI: idp.server.URL}
+}
+
+// sign builds a token with the given header algorithm and key ID, signed with the matching test key.
+func (idp *testIdentityProvider) sign(t *testing.T, alg, kid string, claims map[string]any) string {
+	t.Helper()
+
+	encodeJSON := func(v any) string {
+		data, _ := json.Marshal(v)
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	signingInput := encodeJSON(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"}) + "." + encodeJSON(claims)
+	digest := crypto.SHA256.New()
+	digest.Write([]byte(signingInput))
+	sum := digest.Sum(nil)
+
+	var signature []byte
+	var err error
+	switch alg {
+	case "RS256":
+		signature, err = rsa.SignPKCS1v15(rand.Reader, idp.rsaKey, crypto.SHA256, sum)
+	case "PS256":
+		signature, err = rsa.SignPSS(rand.Reader, idp.rsaKey, crypto.SHA256, sum, nil)
+	case "ES256":
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, idp.ecKey, sum)
+		if err == nil {
+			signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+		}
+	default:
+		signature = []byte("unsigned")
+	}
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func testIDTokenClaims() map[string]any {
+	return map[string]any{
+		"iss":   testOIDCIssuer,
+		"aud":   []string{testOIDCClientID, "other"},
+		"sub":   "user-1",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"nonce": testOIDCNonce,
+	}
+}
+
+func newTestOIDCService(db *gorm.DB, oidc types.OIDCConfig) *OIDCService {
+	oidc.ClientID = testOIDCClientID
+	return NewOIDCService(db, &testConfigManager{oidc: oidc}, nil, nil)
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	idp := newTestIdentityProvider(t)
+	svc := newTestOIDCService(nil, types.OIDCConfig{})
+
+	withClaim := func(key string, value any) map[string]any {
+		claims := testIDTokenClaims()
+		if value == nil {
+			delete(claims, key)
+		} else {
+			claims[key] = value
+		}
+		return claims
+	}
+	valid := idp.sign(t, "RS256", "rsa-1", testIDTokenClaims())
+	parts := strings.Split(valid, ".")
+	tampered := parts[0] + "." + base64.RawURLEncoding.EncodeToString([]byte(`{"iss":"`+testOIDCIssuer+`","sub":"admin"}`)) + "." + parts[2]
+
+	tests := []struct {
+		name  string
+		token string
+		want  string
+	}{
+		{"rs256", valid, ""},
+		{"ps256", idp.sign(t, "PS256", "rsa-1", testIDTokenClaims()), ""},
+		{"es256", idp.sign(t, "ES256", "ec-1", testIDTokenClaims()), ""},
+		{"string audience", idp.sign(t, "RS256", "rsa-1", withClaim("aud", testOIDCClientID)), ""},
+		{"within clock skew", idp.sign(t, "RS256", "rsa-1", withClaim("exp", time.Now().Add(-30*time.Second).Unix())), ""},
+		{"expired", idp.sign(t, "RS256", "rsa-1", withClaim("exp", time.Now().Add(-2*time.Minute).Unix())), "expired"},
+		{"missing expiry", idp.sign(t, "RS256", "rsa-1", withClaim("exp", nil)), "expired"},
+		{"wrong issuer", idp.sign(t, "RS256", "rsa-1", withClaim("iss", "https://evil.example.com")), "issuer"},
+		{"wrong audience", idp.sign(t, "RS256", "rsa-1", withClaim("aud", "other")), "audience"},
+		{"wrong nonce", idp.sign(t, "RS256", "rsa-1", withClaim("nonce", "replayed")), "nonce"},
+		{"tampered claims", tampered, "invalid ID token signature"},
+		{"unsigned", idp.sign(t, "none", "rsa-1", testIDTokenClaims()), "unsupported ID token algorithm"},
+		{"symmetric", idp.sign(t, "HS256", "rsa-1", testIDTokenClaims()), "unsupported ID token algorithm"},
+		{"key type mismatch", idp.sign(t, "ES256", "rsa-1", testIDTokenClaims()), "invalid ID token signature"},
+		{"encryption key", idp.sign(t, "RS256", "enc-1", testIDTokenClaims()), "unknown ID token signing key"},
+		{"malformed", "not-a-token", "malformed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := svc.verifyIDToken(context.Background(), idp.metadata(), tt.token, testOIDCNonce)
+			if tt.want == "" {
+				if err != nil || claims["sub"] != "user-1" {
+					t.Fatalf("verifyIDToken() = %v, %v", claims, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("verifyIDToken() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestOIDCSigningKeyRefresh(t *testing.T) {
+	idp := newTestIdentityProvider(t)
+	svc := newTestOIDCService(nil, types.OIDCConfig{})
+	ctx := context.Background()
+
+	if _, err := svc.signingKey(ctx, idp.metadata(), "rsa-1"); err != nil {
+		t.Fatalf("signingKey() error = %v", err)
+	}
+	if _, err := svc.signingKey(ctx, idp.metadata(), "ec-1"); err != nil {
+		t.Fatalf("signingKey() error = %v", err)
+	}
+	if got := idp.jwksRequests.Load(); got != 1 {
+		t.Errorf("JWKS fetched %d times, want once", got)
+	}
+
+	// 未知 kid 不会在刷新间隔内反复拉取 JWKS
+	for range 3 {
+		if _, err := svc.signingKey(ctx, idp.metadata(), "rotated"); err == nil {
+			t.Fatal("signingKey() of an unknown key = nil error")
+		}
+	}
+	if got := idp.jwksRequests.Load(); got != 1 {
+		t.Errorf("JWKS fetched %d times within the refresh interval, want once", got)
+	}
+
+	svc.keysFetchedAt = time.Now().Add(-2 * oidcJWKSRefreshInterval)
+	_, _ = svc.signingKey(ctx, idp.metadata(), "rotated")
+	if got := idp.jwksRequests.Load(); got != 2 {
+		t.Errorf("JWKS fetched %d times after the refresh interval, want 2", got)
+	}
+}
+
+func TestResolveOIDCRole(t *testing.T) {
+	oidcConfig := types.OIDCConfig{
+		RoleClaim: "realm_access.roles",
+		RoleMappings: []types.OIDCRoleMapping{
+			{ClaimValue: "ops", Role: models.AdminRoleOperator},
+			{ClaimValue: "team-a", Role: models.AdminRoleGroupOperator, GroupIDs: []uint{2, 1}},
+			{ClaimValue: "team-b", Role: models.AdminRoleGroupOperator, GroupIDs: []uint{2, 3}},
+			{ClaimValue: "staff", Role: models.AdminRoleViewer},
+		},
+	}
+	claims := func(roles ...any) map[string]any {
+		return map[string]any{"realm_access": map[string]any{"roles": roles}}
+	}
+
+	tests := []struct {
+		name        string
+		claims      map[string]any
+		defaultRole string
+		wantRole    string
+		wantGroups  []uint
+	}{
+		{"highest role wins", claims("staff", "ops", "team-a"), "", models.AdminRoleOperator, nil},
+		{"group operator groups merged", claims("team-a", "team-b", "staff"), "", models.AdminRoleGroupOperator, []uint{1, 2, 3}},
+		{"no mapping", claims("guest"), "", "", nil},
+		{"default role", claims("guest"), models.AdminRoleViewer, models.AdminRoleViewer, nil},
+		{"missing claim", map[string]any{}, "", "", nil},
+		{"flat claim name", map[string]any{"realm_access.roles": "ops"}, "", models.AdminRoleOperator, nil},
+	}
+	for _, tt := range tests {
+		cfg := oidcConfig
+		cfg.DefaultRole = tt.defaultRole
+		role, groups := resolveOIDCRole(cfg, tt.claims)
+		if role != tt.wantRole || !slices.Equal(groups, tt.wantGroups) {
+			t.Errorf("%s: resolveOIDCRole() = %q, %v, want %q, %v", tt.name, role, groups, tt.wantRole, tt.wantGroups)
+		}
+	}
+}
+
+func TestOIDCSyncUser(t *testing.T) {
+	db := newTestDB(t, &models.AdminUser{})
+	svc := newTestOIDCService(db, types.OIDCConfig{})
+	const externalID = testOIDCIssuer + "#user-1"
+
+	first, err := svc.syncUser(externalID, "alice", models.AdminRoleGroupOperator, []uint{1})
+	if err != nil {
+		t.Fatalf("syncUser() error = %v", err)
+	}
+	if first.Previous != nil || first.Principal.Role != models.AdminRoleGroupOperator || !first.Principal.CanAccessGroup(1) {
+		t.Errorf("first login = %+v", first.Principal)
+	}
+
+	// 每次登录同步角色
+	second, err := svc.syncUser(externalID, "alice", models.AdminRoleViewer, nil)
+	if err != nil {
+		t.Fatalf("syncUser() error = %v", err)
+	}
+	if second.User.ID != first.User.ID || second.Principal.Role != models.AdminRoleViewer || second.Previous.Role != models.AdminRoleGroupOperator {
+		t.Errorf("second login = %+v, previous %+v", second.User, second.Previous)
+	}
+
+	if err := db.Create(&models.AdminUser{Username: "bob", PasswordHash: "x", Role: models.AdminRoleOwner}).Error; err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	if _, err := svc.syncUser(testOIDCIssuer+"#user-2", "bob", models.AdminRoleViewer, nil); err != ErrOIDCUsernameTaken {
+		t.Errorf("syncUser() with a local username error = %v, want ErrOIDCUsernameTaken", err)
+	}
+
+	db.Model(&models.AdminUser{}).Where("id = ?", first.User.ID).Update("disabled", true)
+	if _, err := svc.syncUser(externalID, "alice", models.AdminRoleViewer, nil); err != ErrOIDCAccessDenied {
+		t.Errorf("syncUser() of a disabled account error = %v, want ErrOIDCAccessDenied", err)
+	}
+}
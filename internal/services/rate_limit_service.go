@@ -0,0 +1,133 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gpt-load/internal/store"
+	"math"
+	"strconv"
+	"time"
+)
+
+// RateLimitResult holds the outcome of a single rate limit check.
+type RateLimitResult struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	ResetAfter time.Duration
+	RetryAfter time.Duration
+
+	counterKey string        // 计入的窗口计数器，用于 Release 退还
+	counterTTL time.Duration // 计数器的 TTL，退还时计数器可能已过期并被重新创建
+}
+
+// RateLimitService implements a sliding window rate limiter on top of the shared store,
+// so that limits hold across all master and slave nodes.
+type RateLimitService struct {
+	store store.Store
+}
+
+// NewRateLimitService creates a new RateLimitService.
+func NewRateLimitService(store store.Store) *RateLimitService {
+	return &RateLimitService{store: store}
+}
+
+// GroupKey returns the limiter key for a whole group.
+func (s *RateLimitService) GroupKey(groupID uint) string {
+	return fmt.Sprintf("ratelimit:group:%d", groupID)
+}
+
+// ProxyKeyKey returns the limiter key for a single client proxy key inside a group.
+// The raw key is hashed so it is never written to the store.
+func (s *RateLimitService) ProxyKeyKey(groupID uint, proxyKey string) string {
+	sum := sha256.Sum256([]byte(proxyKey))
+	return fmt.Sprintf("ratelimit:group:%d:key:%s", groupID, hex.EncodeToString(sum[:8]))
+}
+
+// Allow records a request against the given key and reports whether it is within the limit.
+// It uses a sliding window counter: the previous window's count is weighted by how much of it
+// still overlaps the sliding window, and added to the current window's count.
+func (s *RateLimitService) Allow(key string, limit int, window time.Duration) (*RateLimitResult, error) {
+	if limit <= 0 || window <= 0 {
+		return &RateLimitResult{Allowed: true, Limit: limit}, nil
+	}
+
+	now := time.Now()
+	windowNanos := window.Nanoseconds()
+	currentStart := now.UnixNano() / windowNanos
+	elapsed := now.UnixNano() - currentStart*windowNanos
+	weight := float64(windowNanos-elapsed) / float64(windowNanos)
+	resetAfter := time.Duration(windowNanos - elapsed)
+
+	currentKey := fmt.Sprintf("%s:%d", key, currentStart)
+	previousKey := fmt.Sprintf("%s:%d", key, currentStart-1)
+
+	// 保留两个窗口，供下一个窗口计算滑动权重。计数和 TTL 在同一操作中设置，计数器不会丢失 TTL
+	count, err := s.store.IncrByWithTTL(currentKey, 1, 2*window)
+	if err != nil {
+		return nil, fmt.Errorf("failed to increment rate limit counter: %w", err)
+	}
+
+	var previous int64
+	if val, err := s.store.Get(previousKey); err == nil {
+		previous, _ = strconv.ParseInt(string(val), 10, 64)
+	} else if !errors.Is(err, store.ErrNotFound) {
+		return nil, fmt.Errorf("failed to read previous rate limit counter: %w", err)
+	}
+
+	estimated := float64(previous)*weight + float64(count)
+	result := &RateLimitResult{
+		Limit:      limit,
+		ResetAfter: resetAfter,
+		counterKey: currentKey,
+		counterTTL: 2 * window,
+	}
+
+	if estimated > float64(limit) {
+		// 被拒绝的请求不计入窗口
+		if _, err := s.store.IncrByWithTTL(currentKey, -1, 2*window); err != nil {
+			return nil, fmt.Errorf("failed to revert rate limit counter: %w", err)
+		}
+		result.Allowed = false
+		result.Remaining = 0
+		result.RetryAfter = s.retryAfter(previous, count-1, limit, weight, windowNanos, elapsed)
+		return result, nil
+	}
+
+	result.Allowed = true
+	result.Remaining = max(limit-int(math.Ceil(estimated)), 0)
+	return result, nil
+}
+
+// Release returns a request recorded by an allowed check, e.g. when another limit rejected
+// the same request, so that it does not use up quota it was never served with.
+func (s *RateLimitService) Release(result *RateLimitResult) error {
+	if result == nil || !result.Allowed || result.counterKey == "" {
+		return nil
+	}
+	if _, err := s.store.IncrByWithTTL(result.counterKey, -1, result.counterTTL); err != nil {
+		return fmt.Errorf("failed to release rate limit counter: %w", err)
+	}
+	return nil
+}
+
+// retryAfter estimates how long a client must wait until one more request fits into the window.
+func (s *RateLimitService) retryAfter(previous, current int64, limit int, weight float64, windowNanos, elapsed int64) time.Duration {
+	// 当前窗口已满：等到下一个窗口，且当前窗口计数的权重衰减到能容纳一个请求
+	if current >= int64(limit) {
+		targetWeight := float64(limit-1) / float64(current)
+		return time.Duration(windowNanos-elapsed) + time.Duration((1-targetWeight)*float64(windowNanos))
+	}
+
+	// 等待上一个窗口的权重衰减到刚好容纳一个请求
+	if previous > 0 {
+		targetWeight := float64(int64(limit)-current-1) / float64(previous)
+		if targetWeight < weight {
+			return time.Duration((weight - targetWeight) * float64(windowNanos))
+		}
+	}
+
+	return time.Second
+}
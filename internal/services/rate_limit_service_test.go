@@ -0,0 +1,111 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"gpt-load/internal/store"
+)
+
+func TestRateLimitServiceAllow(t *testing.T) {
+	limiter := NewRateLimitService(store.NewMemoryStore())
+	key := limiter.GroupKey(1)
+
+	for i := range 3 {
+		result, err := limiter.Allow(key, 3, time.Hour)
+		if err != nil {
+			t.Fatalf("Allow() error = %v", err)
+		}
+		if !result.Allowed {
+			t.Fatalf("request %d rejected, want allowed", i+1)
+		}
+		if want := 2 - i; result.Remaining != want {
+			t.Errorf("request %d remaining = %d, want %d", i+1, result.Remaining, want)
+		}
+	}
+
+	result, err := limiter.Allow(key, 3, time.Hour)
+	if err != nil {
+		t.Fatalf("Allow() error = %v", err)
+	}
+	if result.Allowed {
+		t.Fatal("request over the limit allowed")
+	}
+	if result.RetryAfter <= 0 {
+		t.Errorf("RetryAfter = %v, want > 0", result.RetryAfter)
+	}
+}
+
+func TestRateLimitServiceRelease(t *testing.T) {
+	limiter := NewRateLimitService(store.NewMemoryStore())
+	key := limiter.ProxyKeyKey(1, "sk-client")
+
+	first, err := limiter.Allow(key, 1, time.Hour)
+	if err != nil || !first.Allowed {
+		t.Fatalf("first request: allowed = %v, error = %v", first != nil && first.Allowed, err)
+	}
+	if err := limiter.Release(first); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+
+	second, err := limiter.Allow(key, 1, time.Hour)
+	if err != nil {
+		t.Fatalf("Allow() error = %v", err)
+	}
+	if !second.Allowed {
+		t.Error("request after release rejected, want the released quota to be available")
+	}
+
+	// 被拒绝的结果不计入窗口，不能重复退还
+	rejected, err := limiter.Allow(key, 1, time.Hour)
+	if err != nil {
+		t.Fatalf("Allow() error = %v", err)
+	}
+	if rejected.Allowed {
+		t.Fatal("request over the limit allowed")
+	}
+	if err := limiter.Release(rejected); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	if result, _ := limiter.Allow(key, 1, time.Hour); result.Allowed {
+		t.Error("releasing a rejected result freed quota")
+	}
+}
+
+func TestRateLimitServiceCountersExpire(t *testing.T) {
+	memoryStore := store.NewMemoryStore()
+	limiter := NewRateLimitService(memoryStore)
+	window := 50 * time.Millisecond
+
+	result, err := limiter.Allow(limiter.GroupKey(1), 5, window)
+	if err != nil || !result.Allowed {
+		t.Fatalf("Allow() allowed = %v, error = %v", result != nil && result.Allowed, err)
+	}
+
+	// 计数器过期后再退还会重新创建计数器，新计数器同样要有 TTL
+	time.Sleep(3 * window)
+	if exists, _ := memoryStore.Exists(result.counterKey); exists {
+		t.Fatal("counter did not expire")
+	}
+	if err := limiter.Release(result); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	time.Sleep(3 * window)
+	if exists, _ := memoryStore.Exists(result.counterKey); exists {
+		t.Error("counter recreated by Release() has no TTL")
+	}
+}
+
+func TestRateLimitServiceKeysDoNotExposeProxyKey(t *testing.T) {
+	limiter := NewRateLimitService(store.NewMemoryStore())
+	key := limiter.ProxyKeyKey(7, "sk-secret-proxy-key")
+	if got, want := key[:len("ratelimit:group:7:key:")], "ratelimit:group:7:key:"; got != want {
+		t.Errorf("ProxyKeyKey() prefix = %q, want %q", got, want)
+	}
+	if len(key) != len("ratelimit:group:7:key:")+16 {
+		t.Errorf("ProxyKeyKey() = %q, want a 16 character hash suffix", key)
+	}
+	if limiter.ProxyKeyKey(7, "sk-other") == key {
+		t.Error("different proxy keys share a limiter key")
+	}
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"fmt"
+	"gpt-load/internal/store"
+	"net/http"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sync/singleflight"
+)
+
+// CoalescedResponse is the response of a leader request shared with its coalesced followers.
+type CoalescedResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// RequestCoalescer merges identical in-flight requests so that only one of them goes upstream.
+// Coalescing is node-local; the coalesced counters are aggregated across nodes in the store.
+type RequestCoalescer struct {
+	group singleflight.Group
+	store store.Store
+}
+
+// NewRequestCoalescer creates a new RequestCoalescer.
+func NewRequestCoalescer(store store.Store) *RequestCoalescer {
+	return &RequestCoalescer{store: store}
+}
+
+// Do runs fn once for all concurrent callers with the same key.
+// The returned bool is true if the caller was a follower and received the leader's response.
+// fn returns nil when the leader has no response to share; followers then receive nil.
+func (rc *RequestCoalescer) Do(groupID uint, key string, fn func() *CoalescedResponse) (*CoalescedResponse, bool) {
+	isLeader := false
+	v, _, _ := rc.group.Do(key, func() (any, error) {
+		isLeader = true
+		return fn(), nil
+	})
+
+	result, _ := v.(*CoalescedResponse)
+	if isLeader || result == nil {
+		return result, !isLeader
+	}
+
+	if _, err := rc.store.HIncrBy(coalesceStatsKey(groupID), "coalesced", 1); err != nil {
+		logrus.WithError(err).Warn("Failed to update coalesced request stat")
+	}
+	return result, true
+}
+
+// GetCoalescedCount returns how many requests of a group have been served by coalescing.
+func (rc *RequestCoalescer) GetCoalescedCount(groupID uint) (int64, error) {
+	values, err := rc.store.HGetAll(coalesceStatsKey(groupID))
+	if err != nil {
+		return 0, fmt.Errorf("failed to get coalesce stats: %w", err)
+	}
+	count, _ := strconv.ParseInt(values["coalesced"], 10, 64)
+	return count, nil
+}
+
+func coalesceStatsKey(groupID uint) string {
+	return fmt.Sprintf("coalesce:group:%d:stats", groupID)
+}
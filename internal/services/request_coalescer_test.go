@@ -0,0 +1,103 @@
+package services
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"gpt-load/internal/store"
+)
+
+// runCoalesced starts a leader that blocks until all followers joined, then returns result.
+func runCoalesced(t *testing.T, rc *RequestCoalescer, followers int, result *CoalescedResponse) (leader *CoalescedResponse, shared []*CoalescedResponse) {
+	t.Helper()
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		res, coalesced := rc.Do(1, "key", func() *CoalescedResponse {
+			close(started)
+			<-release
+			return result
+		})
+		if coalesced {
+			t.Error("leader reported as coalesced")
+		}
+		leader = res
+	}()
+	<-started
+
+	for range followers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, coalesced := rc.Do(1, "key", func() *CoalescedResponse {
+				t.Error("follower executed the request")
+				return nil
+			})
+			if !coalesced {
+				t.Error("follower not reported as coalesced")
+			}
+			mu.Lock()
+			shared = append(shared, res)
+			mu.Unlock()
+		}()
+	}
+
+	// 给跟随的请求加入 singleflight 的时间
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	return leader, shared
+}
+
+func TestRequestCoalescerSharesLeaderResponse(t *testing.T) {
+	rc := NewRequestCoalescer(store.NewMemoryStore())
+	want := &CoalescedResponse{StatusCode: http.StatusOK, Body: []byte(`{"ok":true}`)}
+
+	leader, shared := runCoalesced(t, rc, 3, want)
+	if leader != want {
+		t.Errorf("leader result = %v, want %v", leader, want)
+	}
+	for _, res := range shared {
+		if res != want {
+			t.Errorf("follower result = %v, want %v", res, want)
+		}
+	}
+
+	count, err := rc.GetCoalescedCount(1)
+	if err != nil {
+		t.Fatalf("GetCoalescedCount() error = %v", err)
+	}
+	if count != 3 {
+		t.Errorf("coalesced count = %d, want 3", count)
+	}
+}
+
+func TestRequestCoalescerNilResponse(t *testing.T) {
+	rc := NewRequestCoalescer(store.NewMemoryStore())
+
+	leader, shared := runCoalesced(t, rc, 2, nil)
+	if leader != nil {
+		t.Errorf("leader result = %v, want nil", leader)
+	}
+	if len(shared) != 2 {
+		t.Fatalf("got %d follower results, want 2", len(shared))
+	}
+	for _, res := range shared {
+		if res != nil {
+			t.Errorf("follower result = %v, want nil", res)
+		}
+	}
+
+	// 没有拿到响应的跟随请求不计入合并统计
+	if count, _ := rc.GetCoalescedCount(1); count != 0 {
+		t.Errorf("coalesced count = %d, want 0", count)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"gpt-load/internal/config"
+	"gpt-load/internal/logsink"
 	"gpt-load/internal/models"
 	"gpt-load/internal/store"
 	"strings"
@@ -28,17 +29,19 @@ type RequestLogService struct {
 	db              *gorm.DB
 	store           store.Store
 	settingsManager *config.SystemSettingsManager
+	sinks           *logsink.Manager
 	stopChan        chan struct{}
 	wg              sync.WaitGroup
 	ticker          *time.Ticker
 }
 
 // NewRequestLogService creates a new RequestLogService instance
-func NewRequestLogService(db *gorm.DB, store store.Store, sm *config.SystemSettingsManager) *RequestLogService {
+func NewRequestLogService(db *gorm.DB, store store.Store, sm *config.SystemSettingsManager, sinks *logsink.Manager) *RequestLogService {
 	return &RequestLogService{
 		db:              db,
 		store:           store,
 		settingsManager: sm,
+		sinks:           sinks,
 		stopChan:        make(chan struct{}),
 	}
 }
@@ -94,6 +97,7 @@ func (s *RequestLogService) Stop(ctx context.Context) {
 	select {
 	case <-done:
 		s.flush()
+		s.sinks.Close(ctx)
 		logrus.Info("RequestLogService stopped gracefully.")
 	case <-ctx.Done():
 		logrus.Warn("RequestLogService stop timed out.")
@@ -106,7 +110,7 @@ func (s *RequestLogService) Record(log *models.RequestLog) error {
 	log.Timestamp = time.Now()
 
 	if s.settingsManager.GetSettings().RequestLogWriteIntervalMinutes == 0 {
-		return s.writeLogsToDB([]*models.RequestLog{log})
+		return s.writeLogs([]*models.RequestLog{log})
 	}
 
 	cacheKey := RequestLogCachePrefix + log.ID
@@ -124,6 +128,11 @@ func (s *RequestLogService) Record(log *models.RequestLog) error {
 	return s.store.SAdd(PendingLogKeysSet, cacheKey)
 }
 
+// PendingCount returns the number of request logs buffered in the store and not yet written to the database
+func (s *RequestLogService) PendingCount() (int64, error) {
+	return s.store.SCard(PendingLogKeysSet)
+}
+
 // flush data from cache to database
 func (s *RequestLogService) flush() {
 	if s.settingsManager.GetSettings().RequestLogWriteIntervalMinutes == 0 {
@@ -171,7 +180,7 @@ func (s *RequestLogService) flush() {
 			continue
 		}
 
-		err = s.writeLogsToDB(logs)
+		err = s.writeLogs(logs)
 
 		if err != nil {
 			logrus.Errorf("Failed to flush request logs batch, will retry next time. Error: %v", err)
@@ -196,15 +205,27 @@ func (s *RequestLogService) flush() {
 	}
 }
 
-// writeLogsToDB writes a batch of request logs to the database
+// writeLogs persists a batch of request logs and, once that succeeds, delivers them to the log sinks
+func (s *RequestLogService) writeLogs(logs []*models.RequestLog) error {
+	if err := s.writeLogsToDB(logs); err != nil {
+		return err
+	}
+	s.sinks.Write(logs)
+	return nil
+}
+
+// writeLogsToDB writes a batch of request logs to the database.
+// When the database sink is disabled only the key and hourly statistics are updated.
 func (s *RequestLogService) writeLogsToDB(logs []*models.RequestLog) error {
 	if len(logs) == 0 {
 		return nil
 	}
 
 	return s.db.Transaction(func(tx *gorm.DB) error {
-		if err := tx.CreateInBatches(logs, len(logs)).Error; err != nil {
-			return fmt.Errorf("failed to batch insert request logs: %w", err)
+		if s.sinks.DatabaseEnabled() {
+			if err := tx.CreateInBatches(logs, len(logs)).Error; err != nil {
+				return fmt.Errorf("failed to batch insert request logs: %w", err)
+			}
 		}
 
 		keyStats := make(map[string]int64)
@@ -0,0 +1,194 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gpt-load/internal/store"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const queuePollInterval = 200 * time.Millisecond
+
+var (
+	// ErrQueueFull is returned when the group's wait queue has reached its maximum depth.
+	ErrQueueFull = errors.New("request queue is full")
+	// ErrQueueTimeout is returned when a queued request did not obtain capacity in time.
+	ErrQueueTimeout = errors.New("timed out waiting in request queue")
+)
+
+// QueueStats holds the wait queue statistics of a group, aggregated across all nodes.
+type QueueStats struct {
+	Waiting       int64 `json:"waiting"`
+	TotalQueued   int64 `json:"total_queued"`
+	TotalServed   int64 `json:"total_served"`
+	TotalTimeouts int64 `json:"total_timeouts"`
+	TotalRejected int64 `json:"total_rejected"`
+	AvgWaitMs     int64 `json:"avg_wait_ms"`
+	MaxWaitMs     int64 `json:"max_wait_ms"`
+}
+
+// groupQueue is a node-local FIFO of requests waiting for the same group.
+type groupQueue struct {
+	mu      sync.Mutex
+	waiters []*queueWaiter
+}
+
+type queueWaiter struct {
+	enqueuedAt time.Time
+}
+
+// RequestQueueService lets requests wait in a bounded per-group FIFO queue
+// for capacity instead of failing immediately.
+type RequestQueueService struct {
+	store  store.Store
+	mu     sync.Mutex
+	queues map[uint]*groupQueue
+}
+
+// NewRequestQueueService creates a new RequestQueueService.
+func NewRequestQueueService(store store.Store) *RequestQueueService {
+	return &RequestQueueService{
+		store:  store,
+		queues: make(map[uint]*groupQueue),
+	}
+}
+
+// Wait enqueues the caller and repeatedly invokes try while it is at the head of the queue,
+// until try succeeds, the max wait elapses, or the context is cancelled.
+// try should return (true, nil) on success, (false, nil) to keep waiting, or an error to abort.
+func (s *RequestQueueService) Wait(ctx context.Context, groupID uint, maxDepth int, maxWait time.Duration, try func() (bool, error)) error {
+	q := s.getQueue(groupID)
+	waiter := &queueWaiter{enqueuedAt: time.Now()}
+
+	q.mu.Lock()
+	if len(q.waiters) >= maxDepth {
+		q.mu.Unlock()
+		s.incrStat(groupID, "rejected", 1)
+		return ErrQueueFull
+	}
+	q.waiters = append(q.waiters, waiter)
+	q.mu.Unlock()
+
+	s.incrStat(groupID, "waiting", 1)
+	s.incrStat(groupID, "queued", 1)
+
+	defer func() {
+		q.remove(waiter)
+		s.incrStat(groupID, "waiting", -1)
+	}()
+
+	timer := time.NewTimer(maxWait)
+	defer timer.Stop()
+	ticker := time.NewTicker(queuePollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+			s.incrStat(groupID, "timeouts", 1)
+			return ErrQueueTimeout
+		case <-ticker.C:
+			if !q.isHead(waiter) {
+				continue
+			}
+			ok, err := try()
+			if err != nil {
+				return err
+			}
+			if ok {
+				s.recordServed(groupID, time.Since(waiter.enqueuedAt))
+				return nil
+			}
+		}
+	}
+}
+
+// GetStats returns the queue statistics of a group.
+func (s *RequestQueueService) GetStats(groupID uint) (*QueueStats, error) {
+	values, err := s.store.HGetAll(queueStatsKey(groupID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get queue stats: %w", err)
+	}
+
+	parse := func(field string) int64 {
+		v, _ := strconv.ParseInt(values[field], 10, 64)
+		return v
+	}
+
+	stats := &QueueStats{
+		Waiting:       max(parse("waiting"), 0),
+		TotalQueued:   parse("queued"),
+		TotalServed:   parse("served"),
+		TotalTimeouts: parse("timeouts"),
+		TotalRejected: parse("rejected"),
+		MaxWaitMs:     parse("max_wait_ms"),
+	}
+	if stats.TotalServed > 0 {
+		stats.AvgWaitMs = parse("total_wait_ms") / stats.TotalServed
+	}
+	return stats, nil
+}
+
+func (s *RequestQueueService) getQueue(groupID uint) *groupQueue {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	q, ok := s.queues[groupID]
+	if !ok {
+		q = &groupQueue{}
+		s.queues[groupID] = q
+	}
+	return q
+}
+
+func (s *RequestQueueService) recordServed(groupID uint, wait time.Duration) {
+	waitMs := wait.Milliseconds()
+	s.incrStat(groupID, "served", 1)
+	s.incrStat(groupID, "total_wait_ms", waitMs)
+
+	// 最大等待时间非原子更新，仅用于展示
+	stats, err := s.store.HGetAll(queueStatsKey(groupID))
+	if err != nil {
+		return
+	}
+	currentMax, _ := strconv.ParseInt(stats["max_wait_ms"], 10, 64)
+	if waitMs > currentMax {
+		if err := s.store.HSet(queueStatsKey(groupID), map[string]any{"max_wait_ms": waitMs}); err != nil {
+			logrus.WithError(err).Warn("Failed to update queue max wait stat")
+		}
+	}
+}
+
+func (s *RequestQueueService) incrStat(groupID uint, field string, incr int64) {
+	if _, err := s.store.HIncrBy(queueStatsKey(groupID), field, incr); err != nil {
+		logrus.WithError(err).WithField("field", field).Warn("Failed to update queue stat")
+	}
+}
+
+func queueStatsKey(groupID uint) string {
+	return fmt.Sprintf("queue:group:%d:stats", groupID)
+}
+
+func (q *groupQueue) isHead(w *queueWaiter) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.waiters) > 0 && q.waiters[0] == w
+}
+
+func (q *groupQueue) remove(w *queueWaiter) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for i, waiter := range q.waiters {
+		if waiter == w {
+			q.waiters = append(q.waiters[:i], q.waiters[i+1:]...)
+			return
+		}
+	}
+}
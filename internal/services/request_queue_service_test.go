@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gpt-load/internal/store"
+)
+
+func TestRequestQueueServes(t *testing.T) {
+	s := NewRequestQueueService(store.NewMemoryStore())
+
+	tries := 0
+	err := s.Wait(context.Background(), 1, 5, 5*time.Second, func() (bool, error) {
+		tries++
+		return tries == 2, nil
+	})
+	if err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+
+	stats, _ := s.GetStats(1)
+	if stats.Waiting != 0 || stats.TotalQueued != 1 || stats.TotalServed != 1 || stats.MaxWaitMs < queuePollInterval.Milliseconds() {
+		t.Errorf("stats = %+v", stats)
+	}
+	if stats.AvgWaitMs != stats.MaxWaitMs {
+		t.Errorf("avg wait = %d, want %d for a single request", stats.AvgWaitMs, stats.MaxWaitMs)
+	}
+}
+
+func TestRequestQueueFIFO(t *testing.T) {
+	s := NewRequestQueueService(store.NewMemoryStore())
+
+	var released atomic.Bool
+	var order []int
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := range 2 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := s.Wait(context.Background(), 1, 2, 5*time.Second, func() (bool, error) {
+				// 只有队首的请求会尝试获取容量
+				if !released.Load() {
+					return false, nil
+				}
+				mu.Lock()
+				order = append(order, i)
+				mu.Unlock()
+				return true, nil
+			})
+			if err != nil {
+				t.Errorf("Wait() error = %v", err)
+			}
+		}()
+		// 确保第一个请求先入队
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	// 队列已满时直接拒绝
+	if err := s.Wait(context.Background(), 1, 2, time.Second, func() (bool, error) { return true, nil }); err != ErrQueueFull {
+		t.Errorf("Wait() on a full queue error = %v, want ErrQueueFull", err)
+	}
+	// 各分组的队列相互独立
+	if err := s.Wait(context.Background(), 2, 2, time.Second, func() (bool, error) { return true, nil }); err != nil {
+		t.Errorf("Wait() of another group error = %v", err)
+	}
+
+	if stats, _ := s.GetStats(1); stats.Waiting != 2 {
+		t.Errorf("waiting = %d, want 2", stats.Waiting)
+	}
+	released.Store(true)
+	wg.Wait()
+
+	if len(order) != 2 || order[0] != 0 || order[1] != 1 {
+		t.Errorf("served in order %v, want [0 1]", order)
+	}
+	stats, _ := s.GetStats(1)
+	if stats.Waiting != 0 || stats.TotalQueued != 2 || stats.TotalServed != 2 || stats.TotalRejected != 1 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+func TestRequestQueueFailures(t *testing.T) {
+	s := NewRequestQueueService(store.NewMemoryStore())
+	never := func() (bool, error) { return false, nil }
+
+	if err := s.Wait(context.Background(), 1, 1, 300*time.Millisecond, never); err != ErrQueueTimeout {
+		t.Errorf("Wait() error = %v, want ErrQueueTimeout", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := s.Wait(ctx, 1, 1, 5*time.Second, never); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Wait() with a cancelled context error = %v", err)
+	}
+
+	errNoKeys := errors.New("no active keys")
+	if err := s.Wait(context.Background(), 1, 1, 5*time.Second, func() (bool, error) { return false, errNoKeys }); err != errNoKeys {
+		t.Errorf("Wait() error = %v, want the error of try", err)
+	}
+
+	stats, _ := s.GetStats(1)
+	if stats.Waiting != 0 || stats.TotalQueued != 3 || stats.TotalTimeouts != 1 || stats.TotalServed != 0 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
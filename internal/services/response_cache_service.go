@@ -0,0 +1,126 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gpt-load/internal/store"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// CachedResponse is an upstream response stored in the response cache.
+type CachedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+}
+
+// skippedCacheHeaders are response headers that must not be replayed from the cache.
+var skippedCacheHeaders = map[string]struct{}{
+	"Content-Length":    {},
+	"Date":              {},
+	"Set-Cookie":        {},
+	"Connection":        {},
+	"Keep-Alive":        {},
+	"Transfer-Encoding": {},
+	"X-Request-Id":      {},
+}
+
+// ResponseCacheService caches exact-match upstream responses in the shared store.
+type ResponseCacheService struct {
+	store store.Store
+}
+
+// NewResponseCacheService creates a new ResponseCacheService.
+func NewResponseCacheService(store store.Store) *ResponseCacheService {
+	return &ResponseCacheService{store: store}
+}
+
+// BuildKey builds the cache key from the group, method, path, accepted encodings, normalized body and,
+// optionally, the client key. The accepted encodings are part of the key because the cached body is stored
+// as the upstream encoded it, e.g. gzip only for clients that asked for it.
+func (s *ResponseCacheService) BuildKey(groupID uint, method, path, acceptEncoding string, body []byte, clientKey string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%d\n%s\n%s\n%s\n", groupID, method, path, acceptEncoding)
+	h.Write(normalizeJSONBody(body))
+	if clientKey != "" {
+		h.Write([]byte("\n"))
+		h.Write([]byte(clientKey))
+	}
+	return fmt.Sprintf("response_cache:%d:%s", groupID, hex.EncodeToString(h.Sum(nil)))
+}
+
+// Get returns the cached response for the key, if any.
+func (s *ResponseCacheService) Get(key string) (*CachedResponse, bool) {
+	data, err := s.store.Get(key)
+	if err != nil {
+		if !errors.Is(err, store.ErrNotFound) {
+			logrus.WithError(err).Warn("Failed to read response cache")
+		}
+		return nil, false
+	}
+
+	var cached CachedResponse
+	if err := json.Unmarshal(data, &cached); err != nil {
+		logrus.WithError(err).Warn("Failed to decode cached response")
+		return nil, false
+	}
+	return &cached, true
+}
+
+// Set stores a response in the cache. Responses larger than maxBytes are not cached.
+func (s *ResponseCacheService) Set(key string, statusCode int, header http.Header, body []byte, ttl time.Duration, maxBytes int) {
+	if len(body) > maxBytes {
+		return
+	}
+
+	cachedHeader := make(http.Header)
+	for name, values := range header {
+		if _, skip := skippedCacheHeaders[http.CanonicalHeaderKey(name)]; skip {
+			continue
+		}
+		cachedHeader[name] = values
+	}
+
+	data, err := json.Marshal(&CachedResponse{
+		StatusCode: statusCode,
+		Header:     cachedHeader,
+		Body:       body,
+	})
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to encode response for cache")
+		return
+	}
+
+	if err := s.store.Set(key, data, ttl); err != nil {
+		logrus.WithError(err).Warn("Failed to write response cache")
+	}
+}
+
+// normalizeJSONBody re-encodes a JSON body so that key order and whitespace do not affect the cache key.
+// Non-JSON bodies are returned as-is.
+func normalizeJSONBody(body []byte) []byte {
+	trimmed := strings.TrimSpace(string(body))
+	if trimmed == "" {
+		return nil
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(trimmed))
+	decoder.UseNumber()
+	var data any
+	if err := decoder.Decode(&data); err != nil {
+		return body
+	}
+
+	normalized, err := json.Marshal(data)
+	if err != nil {
+		return body
+	}
+	return normalized
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"gpt-load/internal/store"
+)
+
+func TestResponseCacheBuildKey(t *testing.T) {
+	s := NewResponseCacheService(store.NewMemoryStore())
+	body := []byte(`{"model":"gpt-4o","messages":[{"role":"user","content":"hi"}],"temperature":0.10}`)
+	key := s.BuildKey(1, http.MethodPost, "/v1/chat/completions", "", body, "")
+
+	// 键的顺序和空白不影响缓存键
+	reordered := []byte(" {\"temperature\": 0.10,\n \"messages\":[{\"content\":\"hi\",\"role\":\"user\"}], \"model\":\"gpt-4o\"}")
+	if got := s.BuildKey(1, http.MethodPost, "/v1/chat/completions", "", reordered, ""); got != key {
+		t.Errorf("BuildKey() of a reformatted body = %s, want %s", got, key)
+	}
+
+	tests := []struct {
+		name           string
+		groupID        uint
+		path           string
+		acceptEncoding string
+		body           string
+		clientKey      string
+	}{
+		{"group", 2, "/v1/chat/completions", "", string(body), ""},
+		{"path", 1, "/v1/chat/completions?stream=false", "", string(body), ""},
+		{"accept encoding", 1, "/v1/chat/completions", "gzip", string(body), ""},
+		{"client key", 1, "/v1/chat/completions", "", string(body), "sk-client"},
+	}
+	for _, tt := range tests {
+		if got := s.BuildKey(tt.groupID, http.MethodPost, tt.path, tt.acceptEncoding, []byte(tt.body), tt.clientKey); got == key {
+			t.Errorf("%s: BuildKey() did not change", tt.name)
+		}
+	}
+
+	// 非 JSON 请求体按原样参与计算
+	if s.BuildKey(1, http.MethodPost, "/upload", "", []byte("a b"), "") == s.BuildKey(1, http.MethodPost, "/upload", "", []byte("a  b"), "") {
+		t.Error("BuildKey() normalized a non-JSON body")
+	}
+}
+
+func TestResponseCacheSetGet(t *testing.T) {
+	s := NewResponseCacheService(store.NewMemoryStore())
+
+	if _, ok := s.Get("response_cache:1:missing"); ok {
+		t.Error("Get() of a missing key hit")
+	}
+
+	header := http.Header{
+		"Content-Type":   {"application/json"},
+		"X-Request-Id":   {"req-1"},
+		"Set-Cookie":     {"session=1"},
+		"Content-Length": {"11"},
+	}
+	s.Set("response_cache:1:a", http.StatusOK, header, []byte(`{"id":"1"}`), time.Minute, 1024)
+
+	cached, ok := s.Get("response_cache:1:a")
+	if !ok {
+		t.Fatal("Get() after Set() missed")
+	}
+	if cached.StatusCode != http.StatusOK || string(cached.Body) != `{"id":"1"}` || cached.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("cached = %+v", cached)
+	}
+	for _, name := range []string{"Set-Cookie", "Content-Length", "X-Request-Id"} {
+		if cached.Header.Get(name) != "" {
+			t.Errorf("header %s replayed from the cache", name)
+		}
+	}
+
+	// 超过大小上限的响应不缓存
+	s.Set("response_cache:1:large", http.StatusOK, nil, make([]byte, 11), time.Minute, 10)
+	if _, ok := s.Get("response_cache:1:large"); ok {
+		t.Error("response over the size limit cached")
+	}
+
+	s.Set("response_cache:1:short", http.StatusOK, nil, []byte("x"), 50*time.Millisecond, 10)
+	time.Sleep(100 * time.Millisecond)
+	if _, ok := s.Get("response_cache:1:short"); ok {
+		t.Error("expired response still cached")
+	}
+}
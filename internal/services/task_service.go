@@ -17,6 +17,7 @@ const (
 	TaskTypeKeyValidation = "KEY_VALIDATION"
 	TaskTypeKeyImport     = "KEY_IMPORT"
 	TaskTypeKeyDelete     = "KEY_DELETE"
+	TaskTypeKeyReencrypt  = "KEY_REENCRYPT"
 )
 
 // TaskStatus represents the full lifecycle of a long-running task.
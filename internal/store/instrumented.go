@@ -0,0 +1,214 @@
+package store
+
+import (
+	"time"
+)
+
+// OperationObserver receives the duration and result of every store operation.
+type OperationObserver func(operation string, duration time.Duration, err error)
+
+// instrumentedStore wraps a Store and reports operation latencies to an observer.
+type instrumentedStore struct {
+	inner   Store
+	observe OperationObserver
+}
+
+// instrumentedPipelineStore is used when the wrapped store supports pipelining,
+// so that callers can still detect RedisPipeliner through the wrapper.
+type instrumentedPipelineStore struct {
+	*instrumentedStore
+}
+
+type instrumentedPipeliner struct {
+	inner   Pipeliner
+	observe OperationObserver
+}
+
+// NewInstrumentedStore wraps a store so that every operation is timed.
+func NewInstrumentedStore(inner Store, observe OperationObserver) Store {
+	s := &instrumentedStore{inner: inner, observe: observe}
+	if _, ok := inner.(RedisPipeliner); ok {
+		return &instrumentedPipelineStore{instrumentedStore: s}
+	}
+	return s
+}
+
+func (s *instrumentedStore) track(operation string, start time.Time, err error) {
+	s.observe(operation, time.Since(start), err)
+}
+
+func (s *instrumentedStore) Set(key string, value []byte, ttl time.Duration) error {
+	start := time.Now()
+	err := s.inner.Set(key, value, ttl)
+	s.track("set", start, err)
+	return err
+}
+
+func (s *instrumentedStore) Get(key string) ([]byte, error) {
+	start := time.Now()
+	value, err := s.inner.Get(key)
+	// 未命中不算作错误
+	if err == ErrNotFound {
+		s.track("get", start, nil)
+	} else {
+		s.track("get", start, err)
+	}
+	return value, err
+}
+
+func (s *instrumentedStore) Delete(key string) error {
+	start := time.Now()
+	err := s.inner.Delete(key)
+	s.track("delete", start, err)
+	return err
+}
+
+func (s *instrumentedStore) Del(keys ...string) error {
+	start := time.Now()
+	err := s.inner.Del(keys...)
+	s.track("del", start, err)
+	return err
+}
+
+func (s *instrumentedStore) Exists(key string) (bool, error) {
+	start := time.Now()
+	exists, err := s.inner.Exists(key)
+	s.track("exists", start, err)
+	return exists, err
+}
+
+func (s *instrumentedStore) SetNX(key string, value []byte, ttl time.Duration) (bool, error) {
+	start := time.Now()
+	ok, err := s.inner.SetNX(key, value, ttl)
+	s.track("setnx", start, err)
+	return ok, err
+}
+
+func (s *instrumentedStore) IncrBy(key string, incr int64) (int64, error) {
+	start := time.Now()
+	value, err := s.inner.IncrBy(key, incr)
+	s.track("incrby", start, err)
+	return value, err
+}
+
+func (s *instrumentedStore) IncrByWithTTL(key string, incr int64, ttl time.Duration) (int64, error) {
+	start := time.Now()
+	value, err := s.inner.IncrByWithTTL(key, incr, ttl)
+	s.track("incrby_ttl", start, err)
+	return value, err
+}
+
+func (s *instrumentedStore) Expire(key string, ttl time.Duration) error {
+	start := time.Now()
+	err := s.inner.Expire(key, ttl)
+	s.track("expire", start, err)
+	return err
+}
+
+func (s *instrumentedStore) HSet(key string, values map[string]any) error {
+	start := time.Now()
+	err := s.inner.HSet(key, values)
+	s.track("hset", start, err)
+	return err
+}
+
+func (s *instrumentedStore) HGetAll(key string) (map[string]string, error) {
+	start := time.Now()
+	values, err := s.inner.HGetAll(key)
+	s.track("hgetall", start, err)
+	return values, err
+}
+
+func (s *instrumentedStore) HIncrBy(key, field string, incr int64) (int64, error) {
+	start := time.Now()
+	value, err := s.inner.HIncrBy(key, field, incr)
+	s.track("hincrby", start, err)
+	return value, err
+}
+
+func (s *instrumentedStore) LPush(key string, values ...any) error {
+	start := time.Now()
+	err := s.inner.LPush(key, values...)
+	s.track("lpush", start, err)
+	return err
+}
+
+func (s *instrumentedStore) LRem(key string, count int64, value any) error {
+	start := time.Now()
+	err := s.inner.LRem(key, count, value)
+	s.track("lrem", start, err)
+	return err
+}
+
+func (s *instrumentedStore) Rotate(key string) (string, error) {
+	start := time.Now()
+	value, err := s.inner.Rotate(key)
+	if err == ErrNotFound {
+		s.track("rotate", start, nil)
+	} else {
+		s.track("rotate", start, err)
+	}
+	return value, err
+}
+
+func (s *instrumentedStore) SAdd(key string, members ...any) error {
+	start := time.Now()
+	err := s.inner.SAdd(key, members...)
+	s.track("sadd", start, err)
+	return err
+}
+
+func (s *instrumentedStore) SPopN(key string, count int64) ([]string, error) {
+	start := time.Now()
+	members, err := s.inner.SPopN(key, count)
+	s.track("spopn", start, err)
+	return members, err
+}
+
+func (s *instrumentedStore) SCard(key string) (int64, error) {
+	start := time.Now()
+	count, err := s.inner.SCard(key)
+	s.track("scard", start, err)
+	return count, err
+}
+
+func (s *instrumentedStore) Close() error {
+	return s.inner.Close()
+}
+
+func (s *instrumentedStore) Publish(channel string, message []byte) error {
+	start := time.Now()
+	err := s.inner.Publish(channel, message)
+	s.track("publish", start, err)
+	return err
+}
+
+func (s *instrumentedStore) Subscribe(channel string) (Subscription, error) {
+	return s.inner.Subscribe(channel)
+}
+
+func (s *instrumentedStore) Clear() error {
+	start := time.Now()
+	err := s.inner.Clear()
+	s.track("clear", start, err)
+	return err
+}
+
+// Pipeline returns a pipeliner whose Exec is timed.
+func (s *instrumentedPipelineStore) Pipeline() Pipeliner {
+	return &instrumentedPipeliner{
+		inner:   s.inner.(RedisPipeliner).Pipeline(),
+		observe: s.observe,
+	}
+}
+
+func (p *instrumentedPipeliner) HSet(key string, values map[string]any) {
+	p.inner.HSet(key, values)
+}
+
+func (p *instrumentedPipeliner) Exec() error {
+	start := time.Now()
+	err := p.inner.Exec()
+	p.observe("pipeline_exec", time.Since(start), err)
+	return err
+}
@@ -146,6 +146,72 @@ func (s *MemoryStore) SetNX(key string, value []byte, ttl time.Duration) (bool,
 	return true, nil
 }
 
+// IncrBy atomically increments the integer value of a key.
+func (s *MemoryStore) IncrBy(key string, incr int64) (int64, error) {
+	return s.incrBy(key, incr, 0)
+}
+
+// IncrByWithTTL atomically increments the integer value of a key and sets the TTL if the key has none.
+func (s *MemoryStore) IncrByWithTTL(key string, incr int64, ttl time.Duration) (int64, error) {
+	return s.incrBy(key, incr, ttl)
+}
+
+// incrBy increments the integer value of a key under a single lock. A positive ttl is set on keys without one.
+func (s *MemoryStore) incrBy(key string, incr int64, ttl time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var current int64
+	var expiresAt int64
+	if rawItem, exists := s.data[key]; exists {
+		item, ok := rawItem.(memoryStoreItem)
+		if !ok {
+			return 0, fmt.Errorf("type mismatch: key '%s' holds a different data type", key)
+		}
+		if item.expiresAt == 0 || time.Now().UnixNano() < item.expiresAt {
+			parsed, err := strconv.ParseInt(string(item.value), 10, 64)
+			if err != nil {
+				return 0, fmt.Errorf("value for key '%s' is not an integer", key)
+			}
+			current = parsed
+			expiresAt = item.expiresAt
+		}
+	}
+	if expiresAt == 0 && ttl > 0 {
+		expiresAt = time.Now().UnixNano() + ttl.Nanoseconds()
+	}
+
+	newVal := current + incr
+	s.data[key] = memoryStoreItem{
+		value:     []byte(strconv.FormatInt(newVal, 10)),
+		expiresAt: expiresAt,
+	}
+	return newVal, nil
+}
+
+// Expire sets a TTL on an existing key. Only simple K/V items support expiry.
+func (s *MemoryStore) Expire(key string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rawItem, exists := s.data[key]
+	if !exists {
+		return nil
+	}
+	item, ok := rawItem.(memoryStoreItem)
+	if !ok {
+		return nil
+	}
+
+	if ttl > 0 {
+		item.expiresAt = time.Now().UnixNano() + ttl.Nanoseconds()
+	} else {
+		item.expiresAt = 0
+	}
+	s.data[key] = item
+	return nil
+}
+
 // --- HASH operations ---
 
 func (s *MemoryStore) HSet(key string, values map[string]any) error {
@@ -359,6 +425,23 @@ func (s *MemoryStore) SPopN(key string, count int64) ([]string, error) {
 	return popped, nil
 }
 
+// SCard returns the number of members in a set.
+func (s *MemoryStore) SCard(key string) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rawSet, exists := s.data[key]
+	if !exists {
+		return 0, nil
+	}
+
+	set, ok := rawSet.(map[string]struct{})
+	if !ok {
+		return 0, fmt.Errorf("type mismatch: key '%s' holds a different data type", key)
+	}
+	return int64(len(set)), nil
+}
+
 // --- Pub/Sub operations ---
 
 // memorySubscription implements the Subscription interface for the in-memory store.
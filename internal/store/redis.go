@@ -13,6 +13,15 @@ import (
 // RedisKeyPrefix is the prefix for all Redis keys used by GPT-Load
 const RedisKeyPrefix = "gpt-load:"
 
+// incrByWithTTLScript increments a key and sets its TTL in one step when the key has none.
+var incrByWithTTLScript = redis.NewScript(`
+local value = redis.call('INCRBY', KEYS[1], ARGV[1])
+if redis.call('PTTL', KEYS[1]) < 0 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return value
+`)
+
 // RedisStore is a Redis-backed key-value store.
 type RedisStore struct {
 	client *redis.Client
@@ -81,6 +90,21 @@ func (s *RedisStore) SetNX(key string, value []byte, ttl time.Duration) (bool, e
 	return s.client.SetNX(context.Background(), s.prefixKey(key), value, ttl).Result()
 }
 
+// IncrBy atomically increments the integer value of a key in Redis.
+func (s *RedisStore) IncrBy(key string, incr int64) (int64, error) {
+	return s.client.IncrBy(context.Background(), s.prefixKey(key), incr).Result()
+}
+
+// IncrByWithTTL atomically increments the integer value of a key in Redis and sets the TTL if the key has none.
+func (s *RedisStore) IncrByWithTTL(key string, incr int64, ttl time.Duration) (int64, error) {
+	return incrByWithTTLScript.Run(context.Background(), s.client, []string{s.prefixKey(key)}, incr, ttl.Milliseconds()).Int64()
+}
+
+// Expire sets a TTL on a key in Redis.
+func (s *RedisStore) Expire(key string, ttl time.Duration) error {
+	return s.client.Expire(context.Background(), s.prefixKey(key), ttl).Err()
+}
+
 // Close closes the Redis client connection.
 func (s *RedisStore) Close() error {
 	return s.client.Close()
@@ -132,6 +156,10 @@ func (s *RedisStore) SPopN(key string, count int64) ([]string, error) {
 	return s.client.SPopN(context.Background(), s.prefixKey(key), count).Result()
 }
 
+func (s *RedisStore) SCard(key string) (int64, error) {
+	return s.client.SCard(context.Background(), s.prefixKey(key)).Result()
+}
+
 // --- Pipeliner implementation ---
 
 type redisPipeliner struct {
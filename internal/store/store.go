@@ -40,6 +40,16 @@ type Store interface {
 	// SetNX sets a key-value pair if the key does not already exist.
 	SetNX(key string, value []byte, ttl time.Duration) (bool, error)
 
+	// IncrBy atomically increments the integer value of a key, creating it if needed.
+	IncrBy(key string, incr int64) (int64, error)
+
+	// IncrByWithTTL atomically increments the integer value of a key and sets the TTL when the key
+	// has none, e.g. because the increment created it, so the counter can never be left without a TTL.
+	IncrByWithTTL(key string, incr int64, ttl time.Duration) (int64, error)
+
+	// Expire sets a TTL on an existing key.
+	Expire(key string, ttl time.Duration) error
+
 	// HASH operations
 	HSet(key string, values map[string]any) error
 	HGetAll(key string) (map[string]string, error)
@@ -53,6 +63,7 @@ type Store interface {
 	// SET operations
 	SAdd(key string, members ...any) error
 	SPopN(key string, count int64) ([]string, error)
+	SCard(key string) (int64, error)
 
 	// Close closes the store and releases any underlying resources.
 	Close() error
@@ -0,0 +1,130 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"gpt-load/internal/types"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	incomingTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	incomingParent  = "00f067aa0ba902b7"
+)
+
+type testConfigManager struct {
+	types.ConfigManager
+	tracing types.TracingConfig
+}
+
+func (m *testConfigManager) GetTracingConfig() types.TracingConfig {
+	return m.tracing
+}
+
+func incomingHeader() http.Header {
+	header := make(http.Header)
+	header.Set("traceparent", "00-"+incomingTraceID+"-"+incomingParent+"-01")
+	return header
+}
+
+func TestDisabledTracer(t *testing.T) {
+	tracer, err := NewTracer(&testConfigManager{})
+	if err != nil {
+		t.Fatalf("NewTracer() error = %v", err)
+	}
+	defer tracer.Stop(context.Background())
+
+	ctx, span := tracer.StartServer(context.Background(), incomingHeader(), "gpt-load.proxy")
+	defer span.End()
+	if span.IsRecording() {
+		t.Error("span of a disabled tracer is recording")
+	}
+
+	// 未启用时仍把客户端的 trace context 传给上游
+	outgoing := make(http.Header)
+	tracer.Inject(ctx, outgoing)
+	if !strings.Contains(outgoing.Get("traceparent"), incomingTraceID) {
+		t.Errorf("traceparent = %q, want trace %s", outgoing.Get("traceparent"), incomingTraceID)
+	}
+}
+
+func TestTracerRecordsPipelineSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	tracer := &Tracer{
+		provider:   provider,
+		tracer:     provider.Tracer(instrumentationName),
+		propagator: propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}),
+	}
+
+	ctx, root := tracer.StartServer(context.Background(), incomingHeader(), "gpt-load.proxy", attribute.String("gpt_load.group", "openai"))
+	_, selectSpan := tracer.Start(ctx, "gpt-load.select_key")
+	selectSpan.End()
+	attemptCtx, attempt := tracer.StartClient(ctx, "gpt-load.upstream_attempt", attribute.Int("gpt_load.attempt", 1))
+
+	outgoing := make(http.Header)
+	tracer.Inject(attemptCtx, outgoing)
+	attempt.End()
+	root.End()
+	tracer.Stop(context.Background())
+
+	// 上游请求的 traceparent 指向本次尝试的 span
+	want := "00-" + incomingTraceID + "-" + attempt.SpanContext().SpanID().String() + "-01"
+	if got := outgoing.Get("traceparent"); got != want {
+		t.Errorf("traceparent = %q, want %q", got, want)
+	}
+
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	if len(spans) != 3 {
+		t.Fatalf("recorded %d spans, want 3", len(spans))
+	}
+
+	server := spans["gpt-load.proxy"]
+	if server.SpanKind() != trace.SpanKindServer || server.Parent().SpanID().String() != incomingParent || !server.Parent().IsRemote() {
+		t.Errorf("root span kind %v, parent %v", server.SpanKind(), server.Parent())
+	}
+	if server.SpanContext().TraceID().String() != incomingTraceID {
+		t.Errorf("root span trace = %s, want %s", server.SpanContext().TraceID(), incomingTraceID)
+	}
+	for _, name := range []string{"gpt-load.select_key", "gpt-load.upstream_attempt"} {
+		if parent := spans[name].Parent().SpanID(); parent != server.SpanContext().SpanID() {
+			t.Errorf("%s parent = %s, want the root span", name, parent)
+		}
+	}
+	if spans["gpt-load.upstream_attempt"].SpanKind() != trace.SpanKindClient {
+		t.Errorf("attempt span kind = %v", spans["gpt-load.upstream_attempt"].SpanKind())
+	}
+	if attrs := server.Attributes(); len(attrs) != 1 || attrs[0] != attribute.String("gpt_load.group", "openai") {
+		t.Errorf("root span attributes = %v", attrs)
+	}
+}
+
+func TestNewTracerEnabled(t *testing.T) {
+	for _, endpoint := range []string{"http://127.0.0.1:4318/v1/traces", "127.0.0.1:4318"} {
+		tracer, err := NewTracer(&testConfigManager{tracing: types.TracingConfig{
+			Enabled:     true,
+			Endpoint:    endpoint,
+			Insecure:    true,
+			ServiceName: "gpt-load-test",
+			SampleRatio: 1,
+		}})
+		if err != nil {
+			t.Fatalf("NewTracer(%q) error = %v", endpoint, err)
+		}
+		if tracer.provider == nil {
+			t.Errorf("NewTracer(%q) did not create a provider", endpoint)
+		}
+		tracer.Stop(context.Background())
+	}
+}
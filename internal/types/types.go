@@ -4,11 +4,15 @@ package types
 type ConfigManager interface {
 	IsMaster() bool
 	GetAuthConfig() AuthConfig
+	GetOIDCConfig() OIDCConfig
 	GetCORSConfig() CORSConfig
 	GetPerformanceConfig() PerformanceConfig
 	GetLogConfig() LogConfig
 	GetDatabaseConfig() DatabaseConfig
+	GetTracingConfig() TracingConfig
+	GetLogSinkConfig() LogSinkConfig
 	GetEncryptionKey() string
+	GetEncryptionConfig() EncryptionConfig
 	GetEffectiveServerConfig() ServerConfig
 	GetRedisDSN() string
 	Validate() error
@@ -22,8 +26,13 @@ type SystemSettings struct {
 	AppUrl                         string `json:"app_url" default:"http://localhost:3001" name:"项目地址" category:"基础参数" desc:"项目的基础 URL，用于拼接分组终端节点地址。系统配置优先于环境变量 APP_URL。" validate:"required"`
 	ProxyKeys                      string `json:"proxy_keys" name:"全局代理密钥" category:"基础参数" desc:"全局代理密钥，用于访问所有分组的代理端点。多个密钥请用逗号分隔。" validate:"required"`
 	RequestLogRetentionDays        int    `json:"request_log_retention_days" default:"7" name:"日志保留时长（天）" category:"基础参数" desc:"请求日志在数据库中的保留天数，0为不清理日志。" validate:"required,min=0"`
+	RequestLogFailedRetentionDays  int    `json:"request_log_failed_retention_days" default:"0" name:"失败日志保留时长（天）" category:"基础参数" desc:"失败请求日志在数据库中的保留天数，0 表示与日志保留时长相同。" validate:"required,min=0"`
+	RequestLogArchiveEnabled       bool   `json:"request_log_archive_enabled" default:"false" name:"启用日志归档" category:"基础参数" desc:"清理过期日志前，先按天导出为 gzip 压缩文件并保存到 REQUEST_LOG_ARCHIVE_DIR 目录，归档成功后才删除数据库中的记录。"`
+	RequestLogArchiveFormat        string `json:"request_log_archive_format" default:"jsonl" name:"日志归档格式" category:"基础参数" desc:"归档文件的格式：jsonl 或 csv。" validate:"required,oneof=jsonl csv"`
 	RequestLogWriteIntervalMinutes int    `json:"request_log_write_interval_minutes" default:"1" name:"日志延迟写入周期（分钟）" category:"基础参数" desc:"请求日志从缓存写入数据库的周期（分钟），0为实时写入数据。" validate:"required,min=0"`
 	EnableRequestBodyLogging       bool   `json:"enable_request_body_logging" default:"false" name:"启用日志详情" category:"基础参数" desc:"是否在请求日志中记录完整的请求体内容。启用此功能会增加内存以及存储空间的占用。"`
+	EnableResponseBodyLogging      bool   `json:"enable_response_body_logging" default:"false" name:"记录响应内容" category:"基础参数" desc:"是否在请求日志中记录上游返回的响应内容。流式响应会拼接为最终文本，gzip 压缩的响应会先解压。启用此功能会增加内存以及存储空间的占用。"`
+	ResponseBodyLogMaxBytes        int    `json:"response_body_log_max_bytes" default:"65000" name:"响应内容记录上限（字节）" category:"基础参数" desc:"每条请求日志记录的响应内容上限，超出部分会被截断。" validate:"required,min=1024,max=65000"`
 
 	// 请求设置
 	RequestTimeout        int    `json:"request_timeout" default:"600" name:"请求超时（秒）" category:"请求设置" desc:"转发请求的完整生命周期超时（秒）等。" validate:"required,min=1"`
@@ -41,6 +50,34 @@ type SystemSettings struct {
 	KeyValidationConcurrency     int `json:"key_validation_concurrency" default:"10" name:"密钥验证并发数" category:"密钥配置" desc:"后台定时验证无效 Key 时的并发数，如果使用SQLite或者运行环境性能不佳，请尽量保证20以下，避免过高的并发导致数据不一致问题。" validate:"required,min=1"`
 	KeyValidationTimeoutSeconds  int `json:"key_validation_timeout_seconds" default:"20" name:"密钥验证超时（秒）" category:"密钥配置" desc:"后台定时验证单个 Key 时的 API 请求超时时间（秒）。" validate:"required,min=1"`
 
+	// 限流设置
+	RateLimitWindowSeconds int `json:"rate_limit_window_seconds" default:"60" name:"限流窗口（秒）" category:"限流设置" desc:"入站限流的滑动窗口长度（秒），例如 1 表示按秒限流，60 表示按分钟限流。" validate:"required,min=1"`
+	GroupRateLimit         int `json:"group_rate_limit" default:"0" name:"分组限流" category:"限流设置" desc:"每个分组在一个限流窗口内允许的最大请求数，所有节点共享计数，0为不限流。" validate:"required,min=0"`
+	ProxyKeyRateLimit      int `json:"proxy_key_rate_limit" default:"0" name:"代理密钥限流" category:"限流设置" desc:"单个代理密钥在一个分组内、一个限流窗口内允许的最大请求数，所有节点共享计数，0为不限流。" validate:"required,min=0"`
+	QueueMaxDepth          int `json:"queue_max_depth" default:"0" name:"排队最大长度" category:"限流设置" desc:"没有可用密钥时，每个节点上该分组最多允许排队等待的请求数，0为不排队直接失败。" validate:"required,min=0"`
+	QueueMaxWaitSeconds    int `json:"queue_max_wait_seconds" default:"20" name:"排队最长等待（秒）" category:"限流设置" desc:"请求在队列中等待可用密钥的最长时间（秒），超时后返回错误。" validate:"required,min=1"`
+
+	// 响应缓存
+	EnableResponseCache           bool `json:"enable_response_cache" default:"false" name:"启用响应缓存" category:"响应缓存" desc:"对完全相同的非流式请求缓存上游响应，命中时直接返回且不消耗密钥。适用于 temperature 为 0 等确定性请求。"`
+	ResponseCacheTTLSeconds       int  `json:"response_cache_ttl_seconds" default:"300" name:"缓存有效期（秒）" category:"响应缓存" desc:"响应缓存的保留时间（秒）。" validate:"required,min=1"`
+	ResponseCacheMaxBytes         int  `json:"response_cache_max_bytes" default:"1048576" name:"单条缓存最大字节数" category:"响应缓存" desc:"超过该大小的响应体不会被缓存。" validate:"required,min=1"`
+	ResponseCacheIncludeClientKey bool `json:"response_cache_include_client_key" default:"false" name:"缓存区分代理密钥" category:"响应缓存" desc:"开启后，不同代理密钥的相同请求使用各自独立的缓存。"`
+	EnableRequestCoalescing       bool `json:"enable_request_coalescing" default:"false" name:"启用请求合并" category:"响应缓存" desc:"多个完全相同的非流式请求同时到达时，只向上游发送一次，其余请求等待并共享其结果。"`
+
+	// 告警设置
+	AlertWebhooks               string `json:"alert_webhooks" name:"告警 Webhook" category:"告警设置" desc:"告警通知地址，多个地址用逗号分隔。可添加前缀指定消息格式：slack=、dingtalk=、feishu=，无前缀时发送通用 JSON。留空为不发送告警。"`
+	AlertCooldownMinutes        int    `json:"alert_cooldown_minutes" default:"30" name:"告警冷却时间（分钟）" category:"告警设置" desc:"同一告警在冷却时间内只发送一次，所有节点共享。" validate:"required,min=1"`
+	AlertLowActiveKeysThreshold int    `json:"alert_low_active_keys_threshold" default:"0" name:"有效密钥告警阈值" category:"告警设置" desc:"分组有效密钥数低于该值时告警，0为仅在有效密钥耗尽时告警。" validate:"required,min=0"`
+	AlertErrorRateThreshold     int    `json:"alert_error_rate_threshold" default:"0" name:"错误率告警阈值（%）" category:"告警设置" desc:"分组在统计窗口内的请求错误率达到该百分比时告警，0为不告警。" validate:"required,min=0,max=100"`
+	AlertErrorRateWindowMinutes int    `json:"alert_error_rate_window_minutes" default:"5" name:"错误率统计窗口（分钟）" category:"告警设置" desc:"计算分组错误率的时间窗口（分钟）。" validate:"required,min=1,max=60"`
+	AlertErrorRateMinRequests   int    `json:"alert_error_rate_min_requests" default:"20" name:"错误率最小请求数" category:"告警设置" desc:"统计窗口内请求数达到该值后才计算错误率，避免少量请求误报。" validate:"required,min=1"`
+
+	// 日志脱敏
+	LogRedactionEnabled   bool   `json:"log_redaction_enabled" default:"true" name:"启用日志脱敏" category:"日志脱敏" desc:"写入请求日志前，替换请求体、响应内容和错误信息中的 API 密钥、邮箱、电话和银行卡号等敏感信息。分组的自定义脱敏规则同样生效。"`
+	LogRedactionDetectors string `json:"log_redaction_detectors" default:"api_key,email,phone,card" name:"内置检测器" category:"日志脱敏" desc:"启用的内置检测器，多个用逗号分隔：api_key、email、phone、card。"`
+	LogRedactionMode      string `json:"log_redaction_mode" default:"mask" name:"脱敏方式" category:"日志脱敏" desc:"mask 替换为 [REDACTED:类型]；hash 替换为 [类型:哈希]，相同的值得到相同的哈希，便于排查同一用户的请求。" validate:"required,oneof=mask hash"`
+	LogRedactionFields    string `json:"log_redaction_fields" name:"脱敏字段" category:"日志脱敏" desc:"按 JSON 路径整体替换的请求体字段，多个用逗号分隔，* 匹配任意字段或数组元素，例如 user,metadata.*,messages.*.name。"`
+
 	// For cache
 	ProxyKeysMap map[string]struct{} `json:"-"`
 }
@@ -54,11 +91,40 @@ type ServerConfig struct {
 	WriteTimeout            int    `json:"write_timeout"`
 	IdleTimeout             int    `json:"idle_timeout"`
 	GracefulShutdownTimeout int    `json:"graceful_shutdown_timeout"`
+	// TrustedProxies 可信任的反向代理，只有来自这些地址的 X-Forwarded-For 才会被用作客户端 IP
+	TrustedProxies []string `json:"trusted_proxies"`
 }
 
 // AuthConfig represents authentication configuration
 type AuthConfig struct {
-	Key string `json:"key"`
+	Key                     string `json:"key"`
+	MetricsToken            string `json:"-"`
+	SessionTTLMinutes       int    `json:"session_ttl_minutes"`
+	SessionMaxLifetimeHours int    `json:"session_max_lifetime_hours"`
+	LoginMaxAttempts        int    `json:"login_max_attempts"`
+	LoginLockoutMinutes     int    `json:"login_lockout_minutes"`
+}
+
+// OIDCConfig represents OpenID Connect single sign-on configuration
+type OIDCConfig struct {
+	Enabled       bool              `json:"enabled"`
+	IssuerURL     string            `json:"issuer_url"`
+	ClientID      string            `json:"client_id"`
+	ClientSecret  string            `json:"-"`
+	RedirectURL   string            `json:"redirect_url"`
+	Scopes        []string          `json:"scopes"`
+	ProviderName  string            `json:"provider_name"`
+	UsernameClaim string            `json:"username_claim"`
+	RoleClaim     string            `json:"role_claim"`
+	RoleMappings  []OIDCRoleMapping `json:"role_mappings"`
+	DefaultRole   string            `json:"default_role"`
+}
+
+// OIDCRoleMapping maps a value of the role claim to an admin role
+type OIDCRoleMapping struct {
+	ClaimValue string `json:"claim_value"`
+	Role       string `json:"role"`
+	GroupIDs   []uint `json:"group_ids,omitempty"`
 }
 
 // CORSConfig represents CORS configuration
@@ -73,6 +139,7 @@ type CORSConfig struct {
 // PerformanceConfig represents performance configuration
 type PerformanceConfig struct {
 	MaxConcurrentRequests int `json:"max_concurrent_requests"`
+	MaxConcurrentWait     int `json:"max_concurrent_wait"`
 }
 
 // LogConfig represents logging configuration
@@ -88,6 +155,97 @@ type DatabaseConfig struct {
 	DSN string `json:"dsn"`
 }
 
+// Encryption backends
+const (
+	EncryptionBackendLocal    = "local"    // 由 ENCRYPTION_KEY 派生密钥
+	EncryptionBackendVault    = "vault"    // HashiCorp Vault Transit 加密数据密钥
+	EncryptionBackendEnvelope = "envelope" // 通用 KMS HTTP 接口加密数据密钥
+)
+
+// EncryptionConfig represents the keys that encrypt API keys at rest
+type EncryptionConfig struct {
+	Backend          string             `json:"backend"`
+	Key              string             `json:"-"`
+	PreviousKeys     []string           `json:"-"` // 轮换前的密钥，仅用于解密
+	DataKey          string             `json:"-"` // 由 KMS 加密的数据密钥
+	PreviousDataKeys []string           `json:"-"`
+	KMSTimeout       int                `json:"kms_timeout_seconds"`
+	Vault            VaultTransitConfig `json:"vault"`
+	Envelope         EnvelopeKMSConfig  `json:"envelope"`
+}
+
+// VaultTransitConfig represents the HashiCorp Vault Transit backend configuration
+type VaultTransitConfig struct {
+	Address   string `json:"address"`
+	Token     string `json:"-"`
+	Namespace string `json:"namespace"`
+	Mount     string `json:"mount"`
+	KeyName   string `json:"key_name"`
+}
+
+// EnvelopeKMSConfig represents the generic KMS HTTP API used by the envelope backend
+type EnvelopeKMSConfig struct {
+	URL   string `json:"url"`
+	Token string `json:"-"`
+	KeyID string `json:"key_id"`
+}
+
+// TracingConfig represents OpenTelemetry tracing configuration
+type TracingConfig struct {
+	Enabled     bool    `json:"enabled"`
+	Endpoint    string  `json:"endpoint"`
+	Insecure    bool    `json:"insecure"`
+	ServiceName string  `json:"service_name"`
+	SampleRatio float64 `json:"sample_ratio"`
+}
+
+// LogSinkConfig represents the request log sink configuration
+type LogSinkConfig struct {
+	DatabaseEnabled bool             `json:"database_enabled"`
+	ArchiveDir      string           `json:"archive_dir"`
+	File            FileSinkConfig   `json:"file"`
+	Syslog          SyslogSinkConfig `json:"syslog"`
+	HTTP            HTTPSinkConfig   `json:"http"`
+}
+
+// SinkFieldConfig holds the field selection and redaction settings shared by all sinks
+type SinkFieldConfig struct {
+	Fields          []string `json:"fields"`
+	Redact          []string `json:"redact"`
+	IncludeKeyValue bool     `json:"include_key_value"`
+}
+
+// FileSinkConfig represents the rotating JSONL file sink configuration
+type FileSinkConfig struct {
+	SinkFieldConfig
+	Enabled    bool   `json:"enabled"`
+	Path       string `json:"path"`
+	MaxSizeMB  int    `json:"max_size_mb"`
+	MaxBackups int    `json:"max_backups"`
+}
+
+// SyslogSinkConfig represents the RFC 5424 syslog sink configuration
+type SyslogSinkConfig struct {
+	SinkFieldConfig
+	Enabled         bool   `json:"enabled"`
+	Network         string `json:"network"`
+	Address         string `json:"address"`
+	AppName         string `json:"app_name"`
+	MaxMessageBytes int    `json:"max_message_bytes"`
+}
+
+// HTTPSinkConfig represents the batched HTTP webhook sink configuration
+type HTTPSinkConfig struct {
+	SinkFieldConfig
+	Enabled              bool     `json:"enabled"`
+	URL                  string   `json:"url"`
+	Headers              []string `json:"-"`
+	BatchSize            int      `json:"batch_size"`
+	FlushIntervalSeconds int      `json:"flush_interval_seconds"`
+	MaxRetries           int      `json:"max_retries"`
+	TimeoutSeconds       int      `json:"timeout_seconds"`
+}
+
 type RetryError struct {
 	StatusCode         int    `json:"status_code"`
 	ErrorMessage       string `json:"error_message"`
@@ -0,0 +1,245 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"gpt-load/internal/models"
+	"path"
+	"slices"
+	"strconv"
+)
+
+// Body rule actions
+const (
+	BodyRuleActionSet         = "set"
+	BodyRuleActionSetIfAbsent = "set_if_absent"
+	BodyRuleActionRemove      = "remove"
+	BodyRuleActionRename      = "rename"
+	BodyRuleActionClamp       = "clamp"
+	BodyRuleActionAppend      = "append"
+	BodyRuleActionPrepend     = "prepend"
+)
+
+// BodyRuleContext holds the request attributes that body rule conditions are matched against.
+type BodyRuleContext struct {
+	Model     string
+	Path      string
+	ClientKey string
+}
+
+// ValidateBodyRule checks that a body rule is well-formed.
+func ValidateBodyRule(rule models.BodyRule) error {
+	if _, err := ParseJSONPath(rule.Path); err != nil {
+		return err
+	}
+
+	switch rule.Action {
+	case BodyRuleActionSet, BodyRuleActionSetIfAbsent, BodyRuleActionAppend, BodyRuleActionPrepend:
+		if rule.Value == nil {
+			return fmt.Errorf("action '%s' on '%s' requires a value", rule.Action, rule.Path)
+		}
+	case BodyRuleActionRemove:
+	case BodyRuleActionRename:
+		if _, err := ParseJSONPath(rule.To); err != nil {
+			return fmt.Errorf("action 'rename' on '%s' requires a valid target path: %w", rule.Path, err)
+		}
+	case BodyRuleActionClamp:
+		if rule.Min == nil && rule.Max == nil {
+			return fmt.Errorf("action 'clamp' on '%s' requires min or max", rule.Path)
+		}
+		if rule.Min != nil && rule.Max != nil && *rule.Min > *rule.Max {
+			return fmt.Errorf("action 'clamp' on '%s' has min greater than max", rule.Path)
+		}
+	default:
+		return fmt.Errorf("unsupported body rule action '%s'", rule.Action)
+	}
+
+	if rule.When != nil {
+		for _, pattern := range append(slices.Clone(rule.When.Models), rule.When.Paths...) {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid condition pattern '%s': %w", pattern, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ApplyBodyRules applies the rules in order to a JSON request body.
+// Bodies that are not JSON objects are returned unchanged.
+func ApplyBodyRules(bodyBytes []byte, rules []models.BodyRule, ctx *BodyRuleContext) ([]byte, error) {
+	if len(rules) == 0 || len(bytes.TrimSpace(bodyBytes)) == 0 {
+		return bodyBytes, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(bodyBytes))
+	decoder.UseNumber()
+	var requestData map[string]any
+	if err := decoder.Decode(&requestData); err != nil {
+		return bodyBytes, nil
+	}
+
+	changed := false
+	for _, rule := range rules {
+		if !matchBodyRuleCondition(rule.When, ctx) {
+			continue
+		}
+
+		applied, err := applyBodyRule(requestData, rule)
+		if err != nil {
+			return nil, fmt.Errorf("body rule '%s %s': %w", rule.Action, rule.Path, err)
+		}
+		changed = changed || applied
+	}
+
+	if !changed {
+		return bodyBytes, nil
+	}
+	return json.Marshal(requestData)
+}
+
+// applyBodyRule applies a single rule and reports whether the body was modified.
+func applyBodyRule(data map[string]any, rule models.BodyRule) (bool, error) {
+	segments, err := ParseJSONPath(rule.Path)
+	if err != nil {
+		return false, err
+	}
+	current, exists := GetJSONPath(data, segments)
+
+	switch rule.Action {
+	case BodyRuleActionSet:
+		_, err := SetJSONPath(data, segments, cloneJSONValue(rule.Value))
+		return err == nil, err
+
+	case BodyRuleActionSetIfAbsent:
+		if exists {
+			return false, nil
+		}
+		_, err := SetJSONPath(data, segments, cloneJSONValue(rule.Value))
+		return err == nil, err
+
+	case BodyRuleActionRemove:
+		return DeleteJSONPath(data, segments), nil
+
+	case BodyRuleActionRename:
+		if !exists {
+			return false, nil
+		}
+		target, err := ParseJSONPath(rule.To)
+		if err != nil {
+			return false, err
+		}
+		DeleteJSONPath(data, segments)
+		_, err = SetJSONPath(data, target, current)
+		return err == nil, err
+
+	case BodyRuleActionClamp:
+		if !exists {
+			return false, nil
+		}
+		number, ok := toFloat(current)
+		if !ok {
+			return false, nil
+		}
+		clamped := number
+		if rule.Min != nil && clamped < *rule.Min {
+			clamped = *rule.Min
+		}
+		if rule.Max != nil && clamped > *rule.Max {
+			clamped = *rule.Max
+		}
+		if clamped == number {
+			return false, nil
+		}
+		_, err := SetJSONPath(data, segments, json.Number(formatJSONNumber(clamped)))
+		return err == nil, err
+
+	case BodyRuleActionAppend, BodyRuleActionPrepend:
+		var list []any
+		if exists {
+			existing, ok := current.([]any)
+			if !ok {
+				return false, fmt.Errorf("target is not an array")
+			}
+			list = existing
+		}
+		value := cloneJSONValue(rule.Value)
+		if rule.Action == BodyRuleActionAppend {
+			list = append(list, value)
+		} else {
+			list = append([]any{value}, list...)
+		}
+		_, err := SetJSONPath(data, segments, list)
+		return err == nil, err
+
+	default:
+		return false, fmt.Errorf("unsupported action")
+	}
+}
+
+// matchBodyRuleCondition reports whether the request matches the rule condition.
+func matchBodyRuleCondition(cond *models.BodyRuleCondition, ctx *BodyRuleContext) bool {
+	if cond == nil {
+		return true
+	}
+	if ctx == nil {
+		ctx = &BodyRuleContext{}
+	}
+
+	if len(cond.Models) > 0 && !matchAnyPattern(cond.Models, ctx.Model) {
+		return false
+	}
+	if len(cond.Paths) > 0 && !matchAnyPattern(cond.Paths, ctx.Path) {
+		return false
+	}
+	if len(cond.ClientKeys) > 0 && !slices.Contains(cond.ClientKeys, ctx.ClientKey) {
+		return false
+	}
+	return true
+}
+
+func matchAnyPattern(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, value); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// cloneJSONValue deep-copies a rule value so that request bodies never share state with the cached rules.
+func cloneJSONValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		cloned := make(map[string]any, len(v))
+		for key, item := range v {
+			cloned[key] = cloneJSONValue(item)
+		}
+		return cloned
+	case []any:
+		cloned := make([]any, len(v))
+		for i, item := range v {
+			cloned[i] = cloneJSONValue(item)
+		}
+		return cloned
+	default:
+		return v
+	}
+}
+
+func toFloat(value any) (float64, bool) {
+	switch v := value.(type) {
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
+
+func formatJSONNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
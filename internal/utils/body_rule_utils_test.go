@@ -0,0 +1,193 @@
+package utils
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"gpt-load/internal/models"
+)
+
+func floatPtr(f float64) *float64 {
+	return &f
+}
+
+func TestApplyBodyRules(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  string
+		rules []models.BodyRule
+		ctx   *BodyRuleContext
+		want  string
+	}{
+		{
+			name:  "set nested path",
+			body:  `{"model":"m"}`,
+			rules: []models.BodyRule{{Action: BodyRuleActionSet, Path: "generationConfig.maxOutputTokens", Value: 1024}},
+			want:  `{"generationConfig":{"maxOutputTokens":1024},"model":"m"}`,
+		},
+		{
+			name: "set if absent keeps the client value",
+			body: `{"temperature":0.2}`,
+			rules: []models.BodyRule{
+				{Action: BodyRuleActionSetIfAbsent, Path: "temperature", Value: 1},
+				{Action: BodyRuleActionSetIfAbsent, Path: "top_p", Value: 0.9},
+			},
+			want: `{"temperature":0.2,"top_p":0.9}`,
+		},
+		{
+			name:  "remove array element",
+			body:  `{"messages":[{"role":"system"},{"role":"user"}]}`,
+			rules: []models.BodyRule{{Action: BodyRuleActionRemove, Path: "messages[0]"}},
+			want:  `{"messages":[{"role":"user"}]}`,
+		},
+		{
+			name:  "rename",
+			body:  `{"max_tokens":100}`,
+			rules: []models.BodyRule{{Action: BodyRuleActionRename, Path: "max_tokens", To: "max_completion_tokens"}},
+			want:  `{"max_completion_tokens":100}`,
+		},
+		{
+			name: "clamp",
+			body: `{"max_tokens":9007199254740993,"temperature":3}`,
+			rules: []models.BodyRule{
+				{Action: BodyRuleActionClamp, Path: "temperature", Min: floatPtr(0), Max: floatPtr(2)},
+				{Action: BodyRuleActionClamp, Path: "max_tokens", Max: floatPtr(4096)},
+			},
+			want: `{"max_tokens":4096,"temperature":2}`,
+		},
+		{
+			name: "append and prepend",
+			body: `{"stop":["a"]}`,
+			rules: []models.BodyRule{
+				{Action: BodyRuleActionAppend, Path: "stop", Value: "z"},
+				{Action: BodyRuleActionPrepend, Path: "stop", Value: "0"},
+				{Action: BodyRuleActionAppend, Path: "tags", Value: "new"},
+			},
+			want: `{"stop":["0","a","z"],"tags":["new"]}`,
+		},
+		{
+			name: "rules run in order",
+			body: `{"a":1}`,
+			rules: []models.BodyRule{
+				{Action: BodyRuleActionRename, Path: "a", To: "b"},
+				{Action: BodyRuleActionSet, Path: "a", Value: 2},
+			},
+			want: `{"a":2,"b":1}`,
+		},
+		{
+			name: "conditions",
+			body: `{"model":"gpt-4o-mini"}`,
+			rules: []models.BodyRule{
+				{Action: BodyRuleActionSet, Path: "matched", Value: true, When: &models.BodyRuleCondition{Models: []string{"gpt-4o*"}, Paths: []string{"/v1/chat/*"}}},
+				{Action: BodyRuleActionSet, Path: "other_model", Value: true, When: &models.BodyRuleCondition{Models: []string{"claude-*"}}},
+				{Action: BodyRuleActionSet, Path: "other_key", Value: true, When: &models.BodyRuleCondition{ClientKeys: []string{"sk-other"}}},
+			},
+			ctx:  &BodyRuleContext{Model: "gpt-4o-mini", Path: "/v1/chat/completions", ClientKey: "sk-client"},
+			want: `{"matched":true,"model":"gpt-4o-mini"}`,
+		},
+		{
+			name:  "unchanged body keeps its formatting",
+			body:  "{ \"a\": 1 }",
+			rules: []models.BodyRule{{Action: BodyRuleActionRemove, Path: "b"}},
+			want:  "{ \"a\": 1 }",
+		},
+		{
+			name:  "non-object body",
+			body:  `[1,2]`,
+			rules: []models.BodyRule{{Action: BodyRuleActionSet, Path: "a", Value: 1}},
+			want:  `[1,2]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ApplyBodyRules([]byte(tt.body), tt.rules, tt.ctx)
+			if err != nil {
+				t.Fatalf("ApplyBodyRules() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("ApplyBodyRules() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyBodyRulesErrors(t *testing.T) {
+	tests := []struct {
+		body string
+		rule models.BodyRule
+	}{
+		{`{"stop":"a"}`, models.BodyRule{Action: BodyRuleActionAppend, Path: "stop", Value: "b"}},
+		{`{"model":"m"}`, models.BodyRule{Action: BodyRuleActionSet, Path: "model.name", Value: "x"}},
+		{`{"messages":[]}`, models.BodyRule{Action: BodyRuleActionSet, Path: "messages[3].content", Value: "x"}},
+	}
+	for _, tt := range tests {
+		if _, err := ApplyBodyRules([]byte(tt.body), []models.BodyRule{tt.rule}, nil); err == nil {
+			t.Errorf("ApplyBodyRules(%s, %+v) = nil error, want an error", tt.body, tt.rule)
+		}
+	}
+}
+
+func TestApplyBodyRulesDoesNotShareValues(t *testing.T) {
+	rules := []models.BodyRule{{Action: BodyRuleActionSet, Path: "metadata", Value: map[string]any{"tags": []any{"a"}}}}
+	first, _ := ApplyBodyRules([]byte(`{}`), rules, nil)
+	second, _ := ApplyBodyRules([]byte(`{}`), append(rules, models.BodyRule{Action: BodyRuleActionAppend, Path: "metadata.tags", Value: "b"}), nil)
+	third, _ := ApplyBodyRules([]byte(`{}`), rules, nil)
+
+	if string(first) != string(third) {
+		t.Errorf("rule value modified by an earlier request: %s, then %s", first, third)
+	}
+	if string(second) != `{"metadata":{"tags":["a","b"]}}` {
+		t.Errorf("second body = %s", second)
+	}
+}
+
+func TestValidateBodyRule(t *testing.T) {
+	tests := []struct {
+		rule models.BodyRule
+		want string
+	}{
+		{models.BodyRule{Action: BodyRuleActionSet, Path: "a", Value: 1}, ""},
+		{models.BodyRule{Action: BodyRuleActionRemove, Path: "messages[0].content"}, ""},
+		{models.BodyRule{Action: BodyRuleActionClamp, Path: "a", Min: floatPtr(1)}, ""},
+		{models.BodyRule{Action: BodyRuleActionSet, Path: "a"}, "requires a value"},
+		{models.BodyRule{Action: BodyRuleActionSet, Path: "", Value: 1}, "path cannot be empty"},
+		{models.BodyRule{Action: BodyRuleActionRemove, Path: "a..b"}, "empty segment"},
+		{models.BodyRule{Action: BodyRuleActionRemove, Path: "a[x]"}, "not a number"},
+		{models.BodyRule{Action: BodyRuleActionRename, Path: "a"}, "valid target path"},
+		{models.BodyRule{Action: BodyRuleActionClamp, Path: "a"}, "requires min or max"},
+		{models.BodyRule{Action: BodyRuleActionClamp, Path: "a", Min: floatPtr(2), Max: floatPtr(1)}, "min greater than max"},
+		{models.BodyRule{Action: "merge", Path: "a"}, "unsupported body rule action"},
+		{models.BodyRule{Action: BodyRuleActionRemove, Path: "a", When: &models.BodyRuleCondition{Models: []string{"gpt-["}}}, "invalid condition pattern"},
+	}
+	for _, tt := range tests {
+		err := ValidateBodyRule(tt.rule)
+		if tt.want == "" {
+			if err != nil {
+				t.Errorf("ValidateBodyRule(%+v) error = %v", tt.rule, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ValidateBodyRule(%+v) error = %v, want %q", tt.rule, err, tt.want)
+		}
+	}
+}
+
+func TestParseJSONPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"model", []string{"model"}},
+		{"$.messages[0].content", []string{"messages", "0", "content"}},
+		{"messages.0.content", []string{"messages", "0", "content"}},
+		{"a[1][2]", []string{"a", "1", "2"}},
+	}
+	for _, tt := range tests {
+		got, err := ParseJSONPath(tt.path)
+		if err != nil || !slices.Equal(got, tt.want) {
+			t.Errorf("ParseJSONPath(%q) = %v, %v, want %v", tt.path, got, err, tt.want)
+		}
+	}
+}
@@ -126,6 +126,17 @@ func ParseInteger(value string, defaultValue int) int {
 	return defaultValue
 }
 
+// ParseFloat parses float environment variable
+func ParseFloat(value string, defaultValue float64) float64 {
+	if value == "" {
+		return defaultValue
+	}
+	if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+		return parsed
+	}
+	return defaultValue
+}
+
 // ParseBoolean parses boolean environment variable
 func ParseBoolean(value string, defaultValue bool) bool {
 	if value == "" {
@@ -1,8 +1,11 @@
 package utils
 
 import (
+	"fmt"
 	"gpt-load/internal/models"
 	"net/http"
+	"os"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -10,11 +13,29 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// envVariablePattern matches ${ENV:NAME} placeholders in header values
+var envVariablePattern = regexp.MustCompile(`\$\{ENV:([^}]*)\}`)
+
+// HeaderEnvVariablePrefix is the prefix environment variables need to be usable in header rules.
+// Header rules can be edited by group operators, so other variables such as AUTH_KEY or
+// ENCRYPTION_KEY must never be resolved.
+const HeaderEnvVariablePrefix = "GPT_LOAD_HEADER_"
+
+// validEnvVariableName matches the names allowed in ${ENV:NAME} placeholders
+var validEnvVariableName = regexp.MustCompile(`^` + HeaderEnvVariablePrefix + `[A-Za-z0-9_]+$`)
+
 // HeaderVariableContext holds context data for variable resolution
 type HeaderVariableContext struct {
-	ClientIP string
-	Group    *models.Group
-	APIKey   *models.APIKey
+	ClientIP      string
+	Group         *models.Group
+	APIKey        *models.APIKey
+	RequestID     string
+	Model         string
+	Path          string
+	ClientKeyName string
+	UpstreamHost  string
+
+	hideAPIKey bool // 响应头规则不解析 ${API_KEY}，避免把上游密钥发送给客户端
 }
 
 // ResolveHeaderVariables resolves dynamic variables in header values
@@ -28,9 +49,13 @@ func ResolveHeaderVariables(value string, ctx *HeaderVariableContext) string {
 
 	// Replace all supported variables
 	variables := map[string]string{
-		"${CLIENT_IP}":    ctx.ClientIP,
-		"${TIMESTAMP_MS}": strconv.FormatInt(now.UnixMilli(), 10),
-		"${TIMESTAMP_S}":  strconv.FormatInt(now.Unix(), 10),
+		"${CLIENT_IP}":       ctx.ClientIP,
+		"${TIMESTAMP_MS}":    strconv.FormatInt(now.UnixMilli(), 10),
+		"${TIMESTAMP_S}":     strconv.FormatInt(now.Unix(), 10),
+		"${REQUEST_ID}":      ctx.RequestID,
+		"${MODEL}":           ctx.Model,
+		"${CLIENT_KEY_NAME}": ctx.ClientKeyName,
+		"${UPSTREAM_HOST}":   ctx.UpstreamHost,
 	}
 
 	if ctx.Group != nil {
@@ -38,7 +63,12 @@ func ResolveHeaderVariables(value string, ctx *HeaderVariableContext) string {
 	}
 
 	if ctx.APIKey != nil {
-		variables["${API_KEY}"] = ctx.APIKey.KeyValue
+		variables["${KEY_ID}"] = strconv.FormatUint(uint64(ctx.APIKey.ID), 10)
+		if ctx.hideAPIKey {
+			variables["${API_KEY}"] = ""
+		} else {
+			variables["${API_KEY}"] = ctx.APIKey.KeyValue
+		}
 	}
 
 	// Replace variables in the value
@@ -46,46 +76,120 @@ func ResolveHeaderVariables(value string, ctx *HeaderVariableContext) string {
 		result = strings.ReplaceAll(result, variable, replacement)
 	}
 
+	// Replace environment variables
+	result = envVariablePattern.ReplaceAllStringFunc(result, func(match string) string {
+		name := envVariablePattern.FindStringSubmatch(match)[1]
+		if !validEnvVariableName.MatchString(name) {
+			return ""
+		}
+		return os.Getenv(name)
+	})
+
 	return result
 }
 
+// ValidateHeaderValue checks that the ${ENV:NAME} placeholders of a header value only reference
+// environment variables with the HeaderEnvVariablePrefix.
+func ValidateHeaderValue(value string) error {
+	for _, match := range envVariablePattern.FindAllStringSubmatch(value, -1) {
+		if !validEnvVariableName.MatchString(match[1]) {
+			return fmt.Errorf("environment variable '%s' is not allowed, only variables starting with %s can be used", match[1], HeaderEnvVariablePrefix)
+		}
+	}
+	return nil
+}
+
 // ApplyHeaderRules applies header rules to the HTTP request
 func ApplyHeaderRules(req *http.Request, rules []models.HeaderRule, ctx *HeaderVariableContext) {
 	if req == nil || len(rules) == 0 {
 		return
 	}
 
+	applyHeaderRules(req.Header, rules, ctx)
+}
+
+// ApplyResponseHeaderRules applies header rules to the response headers sent to the client
+func ApplyResponseHeaderRules(header http.Header, rules []models.HeaderRule, ctx *HeaderVariableContext) {
+	if header == nil || len(rules) == 0 {
+		return
+	}
+
+	if ctx != nil {
+		responseCtx := *ctx
+		responseCtx.hideAPIKey = true
+		ctx = &responseCtx
+	}
+	applyHeaderRules(header, rules, ctx)
+}
+
+func applyHeaderRules(header http.Header, rules []models.HeaderRule, ctx *HeaderVariableContext) {
 	for _, rule := range rules {
+		if !matchHeaderRuleCondition(rule, ctx) {
+			continue
+		}
+
 		canonicalKey := http.CanonicalHeaderKey(rule.Key)
 
 		switch rule.Action {
 		case "remove":
-			req.Header.Del(canonicalKey)
+			header.Del(canonicalKey)
 		case "set":
 			resolvedValue := ResolveHeaderVariables(rule.Value, ctx)
-			req.Header.Set(canonicalKey, resolvedValue)
+			header.Set(canonicalKey, resolvedValue)
 		}
 	}
 }
 
+// matchHeaderRuleCondition checks the optional path and model conditions of a rule
+func matchHeaderRuleCondition(rule models.HeaderRule, ctx *HeaderVariableContext) bool {
+	if len(rule.Paths) == 0 && len(rule.Models) == 0 {
+		return true
+	}
+	if ctx == nil {
+		return false
+	}
+
+	if len(rule.Paths) > 0 && !matchAnyPattern(rule.Paths, ctx.Path) {
+		return false
+	}
+	if len(rule.Models) > 0 && !matchAnyPattern(rule.Models, ctx.Model) {
+		return false
+	}
+	return true
+}
+
 // NewHeaderVariableContextFromGin creates HeaderVariableContext from Gin context
 func NewHeaderVariableContextFromGin(c *gin.Context, group *models.Group, apiKey *models.APIKey) *HeaderVariableContext {
 	if c == nil {
 		return nil
 	}
 
+	var clientKeyName string
+	if proxyKey := c.GetString("proxyKey"); proxyKey != "" {
+		clientKeyName = MaskAPIKey(proxyKey)
+	}
+
 	return &HeaderVariableContext{
-		ClientIP: c.ClientIP(),
-		Group:    group,
-		APIKey:   apiKey,
+		ClientIP:      c.ClientIP(),
+		Group:         group,
+		APIKey:        apiKey,
+		RequestID:     c.GetString("requestID"),
+		Model:         c.GetString("model"),
+		Path:          c.Param("path"),
+		ClientKeyName: clientKeyName,
 	}
 }
 
 // NewHeaderVariableContext creates HeaderVariableContext without Gin context
 func NewHeaderVariableContext(group *models.Group, apiKey *models.APIKey) *HeaderVariableContext {
-	return &HeaderVariableContext{
+	ctx := &HeaderVariableContext{
 		ClientIP: "127.0.0.1",
 		Group:    group,
 		APIKey:   apiKey,
 	}
+	if group != nil {
+		ctx.Model = group.TestModel
+		ctx.Path = group.ValidationEndpoint
+	}
+	return ctx
 }
@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"net/http"
+	"testing"
+
+	"gpt-load/internal/models"
+)
+
+func TestResolveHeaderVariablesEnv(t *testing.T) {
+	t.Setenv("GPT_LOAD_HEADER_TENANT", "tenant-a")
+	t.Setenv("AUTH_KEY", "sk-admin-secret")
+	t.Setenv("ENCRYPTION_KEY", "encryption-secret")
+
+	ctx := &HeaderVariableContext{}
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"${ENV:GPT_LOAD_HEADER_TENANT}", "tenant-a"},
+		{"id=${ENV:GPT_LOAD_HEADER_TENANT};", "id=tenant-a;"},
+		{"${ENV:GPT_LOAD_HEADER_MISSING}", ""},
+		{"${ENV:AUTH_KEY}", ""},
+		{"x${ENV:ENCRYPTION_KEY}x", "xx"},
+		{"${ENV:GPT_LOAD_HEADER_}", ""},
+	}
+	for _, tt := range tests {
+		if got := ResolveHeaderVariables(tt.value, ctx); got != tt.want {
+			t.Errorf("ResolveHeaderVariables(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestValidateHeaderValue(t *testing.T) {
+	valid := []string{"", "static", "${CLIENT_IP}", "${ENV:GPT_LOAD_HEADER_TENANT}", "a ${ENV:GPT_LOAD_HEADER_A} b ${ENV:GPT_LOAD_HEADER_B}"}
+	for _, value := range valid {
+		if err := ValidateHeaderValue(value); err != nil {
+			t.Errorf("ValidateHeaderValue(%q) error = %v, want nil", value, err)
+		}
+	}
+
+	invalid := []string{"${ENV:AUTH_KEY}", "${ENV:DATABASE_DSN}", "${ENV:GPT_LOAD_HEADER_A}${ENV:ENCRYPTION_KEY}", "${ENV:}", "${ENV:gpt_load_header_a}"}
+	for _, value := range invalid {
+		if err := ValidateHeaderValue(value); err == nil {
+			t.Errorf("ValidateHeaderValue(%q) = nil, want an error", value)
+		}
+	}
+}
+
+func TestApplyHeaderRulesAPIKey(t *testing.T) {
+	ctx := &HeaderVariableContext{APIKey: &models.APIKey{ID: 7, KeyValue: "sk-upstream"}}
+	rules := []models.HeaderRule{
+		{Key: "X-Key", Value: "${API_KEY}", Action: "set"},
+		{Key: "X-Key-Id", Value: "${KEY_ID}", Action: "set"},
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, "http://upstream", nil)
+	ApplyHeaderRules(req, rules, ctx)
+	if got := req.Header.Get("X-Key"); got != "sk-upstream" {
+		t.Errorf("request header X-Key = %q, want the upstream key", got)
+	}
+
+	// 响应头规则不能把上游密钥返回给客户端
+	header := make(http.Header)
+	ApplyResponseHeaderRules(header, rules, ctx)
+	if got := header.Get("X-Key"); got != "" {
+		t.Errorf("response header X-Key = %q, want empty", got)
+	}
+	if got := header.Get("X-Key-Id"); got != "7" {
+		t.Errorf("response header X-Key-Id = %q, want 7", got)
+	}
+	if ctx.hideAPIKey {
+		t.Error("ApplyResponseHeaderRules modified the shared context")
+	}
+}
+
+func TestApplyHeaderRulesConditions(t *testing.T) {
+	rules := []models.HeaderRule{
+		{Key: "X-Chat", Value: "1", Action: "set", Paths: []string{"/v1/chat/*"}},
+		{Key: "X-Model", Value: "${MODEL}", Action: "set", Models: []string{"gpt-4*"}},
+		{Key: "X-Remove", Action: "remove"},
+	}
+
+	header := http.Header{"X-Remove": {"1"}}
+	ApplyResponseHeaderRules(header, rules, &HeaderVariableContext{Path: "/v1/chat/completions", Model: "gpt-4o"})
+	if header.Get("X-Chat") != "1" || header.Get("X-Model") != "gpt-4o" || header.Get("X-Remove") != "" {
+		t.Errorf("matching conditions: header = %v", header)
+	}
+
+	header = make(http.Header)
+	ApplyResponseHeaderRules(header, rules, &HeaderVariableContext{Path: "/v1/embeddings", Model: "text-embedding-3"})
+	if header.Get("X-Chat") != "" || header.Get("X-Model") != "" {
+		t.Errorf("non-matching conditions: header = %v", header)
+	}
+}
@@ -0,0 +1,144 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseJSONPath splits a path such as "messages[0].content" or "messages.0.content"
+// into its segments. Numeric segments address array elements.
+func ParseJSONPath(path string) ([]string, error) {
+	path = strings.TrimSpace(path)
+	path = strings.TrimPrefix(path, "$.")
+	if path == "" {
+		return nil, fmt.Errorf("path cannot be empty")
+	}
+
+	var segments []string
+	for _, part := range strings.Split(path, ".") {
+		if part == "" {
+			return nil, fmt.Errorf("invalid path '%s': empty segment", path)
+		}
+
+		for part != "" {
+			open := strings.Index(part, "[")
+			if open == -1 {
+				segments = append(segments, part)
+				break
+			}
+			if open > 0 {
+				segments = append(segments, part[:open])
+			}
+			closeIdx := strings.Index(part, "]")
+			if closeIdx < open {
+				return nil, fmt.Errorf("invalid path '%s': unbalanced brackets", path)
+			}
+			index := part[open+1 : closeIdx]
+			if _, err := strconv.Atoi(index); err != nil {
+				return nil, fmt.Errorf("invalid path '%s': array index '%s' is not a number", path, index)
+			}
+			segments = append(segments, index)
+			part = part[closeIdx+1:]
+		}
+	}
+
+	return segments, nil
+}
+
+// GetJSONPath returns the value at the given path segments.
+func GetJSONPath(data any, segments []string) (any, bool) {
+	current := data
+	for _, segment := range segments {
+		switch node := current.(type) {
+		case map[string]any:
+			value, ok := node[segment]
+			if !ok {
+				return nil, false
+			}
+			current = value
+		case []any:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil, false
+			}
+			current = node[index]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// SetJSONPath sets the value at the given path segments, creating intermediate objects as needed.
+// It returns the updated root, since replacing the root itself is not possible in place.
+func SetJSONPath(data any, segments []string, value any) (any, error) {
+	if len(segments) == 0 {
+		return value, nil
+	}
+
+	segment := segments[0]
+	switch node := data.(type) {
+	case map[string]any:
+		child, err := SetJSONPath(node[segment], segments[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		node[segment] = child
+		return node, nil
+	case []any:
+		index, err := strconv.Atoi(segment)
+		if err != nil || index < 0 || index >= len(node) {
+			return nil, fmt.Errorf("array index '%s' out of range", segment)
+		}
+		child, err := SetJSONPath(node[index], segments[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		node[index] = child
+		return node, nil
+	case nil:
+		child, err := SetJSONPath(nil, segments[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{segment: child}, nil
+	default:
+		return nil, fmt.Errorf("cannot set field '%s' on a non-object value", segment)
+	}
+}
+
+// DeleteJSONPath removes the value at the given path segments. It reports whether a value was removed.
+func DeleteJSONPath(data any, segments []string) bool {
+	if len(segments) == 0 {
+		return false
+	}
+
+	parent, ok := GetJSONPath(data, segments[:len(segments)-1])
+	if !ok {
+		return false
+	}
+
+	last := segments[len(segments)-1]
+	switch node := parent.(type) {
+	case map[string]any:
+		if _, exists := node[last]; !exists {
+			return false
+		}
+		delete(node, last)
+		return true
+	case []any:
+		index, err := strconv.Atoi(last)
+		if err != nil || index < 0 || index >= len(node) {
+			return false
+		}
+		// 数组元素删除需要替换父节点中的切片
+		updated := append(node[:index:index], node[index+1:]...)
+		if _, err := SetJSONPath(data, segments[:len(segments)-1], updated); err != nil {
+			return false
+		}
+		return true
+	default:
+		return false
+	}
+}
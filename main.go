@@ -41,6 +41,24 @@ func runCommand() {
 	switch command {
 	case "migrate-keys":
 		commands.RunMigrateKeys(args)
+	case "migrate":
+		commands.RunMigrate(args)
+	case "encryption":
+		commands.RunEncryption(args)
+	case "config":
+		commands.RunConfig(args)
+	case "group":
+		commands.RunGroup(args)
+	case "key":
+		commands.RunKey(args)
+	case "settings":
+		commands.RunSettings(args)
+	case "logs":
+		commands.RunLogs(args)
+	case "backup":
+		commands.RunBackup(args)
+	case "restore":
+		commands.RunRestore(args)
 	case "help", "-h", "--help":
 		printHelp()
 	default:
@@ -60,6 +78,15 @@ func printHelp() {
 	fmt.Println()
 	fmt.Println("Available Commands:")
 	fmt.Println("  migrate-keys    Migrate encryption keys")
+	fmt.Println("  migrate         Show and apply schema migrations")
+	fmt.Println("  encryption      Generate KMS data keys and show the encryption status")
+	fmt.Println("  config          Export, diff and apply the declarative configuration")
+	fmt.Println("  group           List, show, create and delete groups")
+	fmt.Println("  key             Import, export and validate keys")
+	fmt.Println("  settings        Get and set system settings")
+	fmt.Println("  logs            Purge request logs")
+	fmt.Println("  backup          Back up the database to a portable archive")
+	fmt.Println("  restore         Restore a backup archive into the configured database")
 	fmt.Println("  help            Display this help message")
 	fmt.Println()
 	fmt.Println("Use 'gpt-load <command> --help' for more information about a command.")
@@ -1,4 +1,4 @@
-import type { ApiResponse, Group, LogFilter, LogsResponse } from "@/types/models";
+import type { ApiResponse, Group, LogFilter, LogsResponse, RequestLog } from "@/types/models";
 import http from "@/utils/http";
 
 export const logApi = {
@@ -7,6 +7,11 @@ export const logApi = {
     return http.get("/logs", { params });
   },
 
+  // 获取日志详情（包含响应内容）
+  getLog: (id: string): Promise<ApiResponse<RequestLog>> => {
+    return http.get(`/logs/${id}`);
+  },
+
   // 获取分组列表（用于筛选）
   getGroups: (): Promise<ApiResponse<Group[]>> => {
     return http.get("/groups");
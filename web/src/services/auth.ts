@@ -1,20 +1,85 @@
 import http from "@/utils/http";
 import { useState } from "@/utils/state";
+import axios from "axios";
 
 const AUTH_KEY = "authKey";
+const AUTH_EXPIRES_AT = "authExpiresAt";
+
+// 会话过期前多久自动刷新
+const REFRESH_BEFORE_MS = 5 * 60 * 1000;
+
+interface LoginResponse {
+  token?: string;
+  expires_at?: string;
+}
+
+interface SessionResponse {
+  data: {
+    token: string;
+    expires_at: string;
+  };
+}
+
+let refreshing: Promise<void> | null = null;
 
 export const useAuthKey = () => {
   return useState<string | null>(AUTH_KEY, () => null);
 };
 
+function saveSession(token: string, expiresAt?: string) {
+  localStorage.setItem(AUTH_KEY, token);
+  if (expiresAt) {
+    localStorage.setItem(AUTH_EXPIRES_AT, expiresAt);
+  } else {
+    localStorage.removeItem(AUTH_EXPIRES_AT);
+  }
+  useAuthKey().value = token;
+}
+
+// 清除本地会话，不通知服务端
+export function clearSession(): void {
+  localStorage.removeItem(AUTH_KEY);
+  localStorage.removeItem(AUTH_EXPIRES_AT);
+  useAuthKey().value = null;
+}
+
+// 会话即将过期时换发新令牌，并发请求共用同一次刷新
+export function refreshSessionIfNeeded(): Promise<void> {
+  const token = localStorage.getItem(AUTH_KEY);
+  const expiresAt = localStorage.getItem(AUTH_EXPIRES_AT);
+  if (!token || !expiresAt) {
+    return Promise.resolve();
+  }
+  if (new Date(expiresAt).getTime() - Date.now() > REFRESH_BEFORE_MS) {
+    return Promise.resolve();
+  }
+
+  if (!refreshing) {
+    // 直接使用 axios，避免经过 http 拦截器造成递归
+    refreshing = axios
+      .post<SessionResponse>("/api/auth/refresh", null, {
+        headers: { Authorization: `Bearer ${token}` },
+      })
+      .then(res => saveSession(res.data.data.token, res.data.data.expires_at))
+      .catch(() => {
+        // 刷新失败时沿用旧令牌，过期后由 401 处理跳转登录
+      })
+      .finally(() => {
+        refreshing = null;
+      });
+  }
+  return refreshing;
+}
+
 export function useAuthService() {
   const authKey = useAuthKey();
 
-  const login = async (key: string): Promise<boolean> => {
+  const login = async (key: string, username?: string): Promise<boolean> => {
     try {
-      await http.post("/auth/login", { auth_key: key });
-      localStorage.setItem(AUTH_KEY, key);
-      authKey.value = key;
+      const payload = username ? { username, password: key } : { auth_key: key };
+      const res = (await http.post("/auth/login", payload)) as LoginResponse;
+      // 旧版本服务端不返回令牌时继续使用授权密钥
+      saveSession(res.token ?? key, res.expires_at);
       return true;
     } catch (_error) {
       // 错误已记录
@@ -22,9 +87,20 @@ export function useAuthService() {
     }
   };
 
-  const logout = (): void => {
-    localStorage.removeItem(AUTH_KEY);
-    authKey.value = null;
+  // 单点登录回调后保存服务端签发的会话
+  const loginWithSession = (token: string, expiresAt?: string): void => {
+    saveSession(token, expiresAt);
+  };
+
+  const logout = async (): Promise<void> => {
+    if (localStorage.getItem(AUTH_EXPIRES_AT)) {
+      try {
+        await http.post("/auth/logout", null, { hideMessage: true });
+      } catch (_error) {
+        // 会话可能已失效，忽略
+      }
+    }
+    clearSession();
   };
 
   const checkLogin = (): boolean => {
@@ -41,6 +117,7 @@ export function useAuthService() {
 
   return {
     login,
+    loginWithSession,
     logout,
     checkLogin,
   };
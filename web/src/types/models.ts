@@ -33,6 +33,29 @@ export interface HeaderRule {
   key: string;
   value: string;
   action: "set" | "remove";
+  paths?: string[];
+  models?: string[];
+}
+
+export interface BodyRuleCondition {
+  models?: string[];
+  paths?: string[];
+  client_keys?: string[];
+}
+
+export interface BodyRule {
+  action: "set" | "set_if_absent" | "remove" | "rename" | "clamp" | "append" | "prepend";
+  path: string;
+  value?: unknown;
+  to?: string;
+  min?: number;
+  max?: number;
+  when?: BodyRuleCondition;
+}
+
+export interface RedactionRule {
+  name: string;
+  pattern: string;
 }
 
 export interface Group {
@@ -50,6 +73,9 @@ export interface Group {
   endpoint?: string;
   param_overrides: Record<string, unknown>;
   header_rules?: HeaderRule[];
+  response_header_rules?: HeaderRule[];
+  body_rules?: BodyRule[];
+  redaction_rules?: RedactionRule[];
   proxy_keys: string;
   created_at?: string;
   updated_at?: string;
@@ -84,7 +110,7 @@ export interface RequestStats {
   failure_rate: number;
 }
 
-export type TaskType = "KEY_VALIDATION" | "KEY_IMPORT" | "KEY_DELETE";
+export type TaskType = "KEY_VALIDATION" | "KEY_IMPORT" | "KEY_DELETE" | "KEY_REENCRYPT";
 
 export interface KeyValidationResult {
   invalid_keys: number;
@@ -102,6 +128,14 @@ export interface KeyDeleteResult {
   ignored_count: number;
 }
 
+export interface KeyReencryptResult {
+  key_id: string;
+  reencrypted_keys: number;
+  reencrypted_logs: number;
+  failed_keys: number;
+  failed_logs: number;
+}
+
 export interface TaskInfo {
   task_type: TaskType;
   is_running: boolean;
@@ -110,7 +144,7 @@ export interface TaskInfo {
   total?: number;
   started_at?: string;
   finished_at?: string;
-  result?: KeyValidationResult | KeyImportResult | KeyDeleteResult;
+  result?: KeyValidationResult | KeyImportResult | KeyDeleteResult | KeyReencryptResult;
   error?: string;
 }
 
@@ -127,13 +161,17 @@ export interface RequestLog {
   duration_ms: number;
   error_message: string;
   user_agent: string;
-  request_type: "retry" | "final";
+  request_type: "retry" | "final" | "cached" | "coalesced";
+  request_id?: string;
+  attempt?: number;
+  upstream_request_id?: string;
   group_name?: string;
   key_value?: string;
   model: string;
   upstream_addr: string;
   is_stream: boolean;
   request_body?: string;
+  response_body?: string;
 }
 
 export interface Pagination {
@@ -160,7 +198,8 @@ export interface LogFilter {
   error_contains?: string;
   start_time?: string | null;
   end_time?: string | null;
-  request_type?: "retry" | "final";
+  request_type?: "retry" | "final" | "cached" | "coalesced";
+  request_id?: string;
 }
 
 export interface DashboardStats {
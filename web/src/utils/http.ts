@@ -1,4 +1,4 @@
-import { useAuthService } from "@/services/auth";
+import { clearSession, refreshSessionIfNeeded } from "@/services/auth";
 import axios from "axios";
 import { appState } from "./app-state";
 
@@ -18,11 +18,14 @@ const http = axios.create({
 });
 
 // 请求拦截器
-http.interceptors.request.use(config => {
+http.interceptors.request.use(async config => {
   // 检查当前请求的 URL 是否在屏蔽列表中
   if (config.url && !noLoadingUrls.includes(config.url)) {
     appState.loading = true;
   }
+  if (!config.url?.startsWith("/auth/")) {
+    await refreshSessionIfNeeded();
+  }
   const authKey = localStorage.getItem("authKey");
   if (authKey) {
     config.headers.Authorization = `Bearer ${authKey}`;
@@ -44,8 +47,7 @@ http.interceptors.response.use(
     if (error.response) {
       if (error.response.status === 401) {
         if (window.location.pathname !== "/login") {
-          const { logout } = useAuthService();
-          logout();
+          clearSession();
           window.location.href = "/login";
         }
       }